@@ -4,65 +4,337 @@
 //
 //	go run ./cmd/contract > egress_path.json
 //	go run ./cmd/contract -o frontend/public/data/egress_path.json
+//	go run ./cmd/contract -ts -o frontend/src/contract.d.ts
+//	go run ./cmd/contract -info
+//	go run ./cmd/contract -format trace -path tcp_ipv4_ingress > trace.json
+//	go run ./cmd/contract -format ndjson -path tcp_ipv4_egress | jq .function.id
+//	go run ./cmd/contract -format svg -path tcp_ipv4_ingress > ingress.svg
+//	go run ./cmd/contract -format dot -path tcp_ipv4_egress -overlay tcp_ipv4_esp_egress | dot -Tsvg > overlay.svg
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/rzkiamr/linux-packet-visualizer/internal/contract"
 )
 
 func main() {
-	outputFile := flag.String("o", "", "Output file path (default: stdout)")
-	compact := flag.Bool("compact", false, "Output compact JSON (no indentation)")
-	noSim := flag.Bool("no-sim", false, "Exclude pre-computed simulation")
-	bufferSize := flag.Int("buffer", 2048, "sk_buff buffer size for simulation")
-	payloadSize := flag.Int("payload", 1000, "Initial payload size for simulation")
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run parses the command-line arguments and writes the requested output.
+// Output goes to the -o file if given, otherwise to stdout; progress and
+// usage messages go to stderr.
+func run(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("contract", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	outputFile := fs.String("o", "", "Output file path (default: stdout)")
+	compact := fs.Bool("compact", false, "Output compact JSON (no indentation)")
+	minify := fs.Bool("minify", false, "Alias for -compact")
+	indent := fs.Int("indent", 2, "Spaces per indentation level of JSON output (0 = compact)")
+	noSim := fs.Bool("no-sim", false, "Exclude pre-computed simulation")
+	transitions := fs.Bool("transitions", false, "Include layer transition events derived from the simulation")
+	animation := fs.Bool("animation", false, "Include keyframe hints for tweening the sk_buff pointers between steps")
+	delta := fs.Bool("delta", false, "Export the simulation as per-step sk_buff deltas instead of full snapshots")
+	userspace := fs.Bool("userspace", false, "Annotate functions with their gVisor netstack equivalents")
+	collapse := fs.Bool("collapse-wrappers", false, "Remove thin wrapper functions such as dev_queue_xmit for a higher-level view")
+	bufferSize := fs.Int("buffer", 2048, "sk_buff buffer size for simulation")
+	payloadSize := fs.Int("payload", 1000, "Initial payload size for simulation")
+	sndBuf := fs.Int("sndbuf", 0, "Modeled SO_SNDBUF in bytes (0 = unlimited)")
+	rcvBuf := fs.Int("rcvbuf", 0, "Modeled SO_RCVBUF in bytes (0 = unlimited)")
+	fragments := fs.Int("fragments", 0, "Number of IP fragments for the ingress simulation")
+	taps := fs.Int("taps", 0, "Number of AF_PACKET sniffers tapping the ingress simulation")
+	groFlush := fs.String("gro-flush", "", "Coalesce the ingress packet in GRO and flush it for a reason: flow_limit, napi_budget, timeout")
+	lookup := fs.String("lookup", "", "Socket lookup result for the ingress simulation: established, listen, syn_recv, timewait, none")
+	quickAck := fs.Bool("quickack", false, "Put the ingress receiver in quick-ACK mode so data is ACKed immediately")
+	backlog := fs.Int("backlog", 0, "listen() backlog of the listening socket (0 = somaxconn, 4096)")
+	synQueue := fs.Int("synq", 0, "Half-open connections already in the listener's SYN queue")
+	acceptQueue := fs.Int("acceptq", 0, "Connections already waiting in the listener's accept queue")
+	xdpGeneric := fs.String("xdp-generic", "", "Verdict of a generic-mode XDP program on ingress: pass, drop")
+	udpSegment := fs.Int("udp-segment", 0, "UDP_SEGMENT (GSO) size for the UDP egress simulation (0 = no GSO)")
+	mtu := fs.Int("mtu", 1500, "Egress path MTU (at least 68; 0 = 1500): a larger non-GSO packet is split into IP fragments")
+	mss := fs.Int("mss", 0, "MSS of the sending TCP connection (0 = 1460, or 1440 over IPv6)")
+	noGSO := fs.Bool("no-gso", false, "Take GSO and TSO off the egress route so a TCP payload larger than the MSS is sent as separate segments")
+	gsoPartial := fs.Int("gso-partial", 0, "Partial-GSO boundary in segments: software GSO of forwarded packets stops at chunks this large for the NIC to finish (0 = full software segmentation)")
+	sockFilter := fs.String("sockfilter", "", "Verdict of a socket filter on the receiving socket: allow, deny")
+	sockFilterLen := fs.Int("sockfilter-len", 0, "Bytes an allowing socket filter keeps (0 = whole packet)")
+	ipOptions := fs.String("ipopts", "", "IPv4 option carried by the ingress packet: record_route, timestamp, source_route, invalid")
+	corrupt := fs.String("corrupt", "", "Corrupt the ingress checksum of the given header: ip, tcp")
+	rttSample := fs.Int("rtt", 0, "RTT in microseconds measured by an ingress pure ACK (0 = 20ms)")
+	tcIngress := fs.String("tc-ingress", "", "Verdict of the ingress qdisc's classifiers: ok, shot, redirect")
+	owned := fs.Bool("owned", false, "Make a process own the receiving socket so the ingress segment is backlogged until release_sock")
+	coalesce := fs.Bool("coalesce", false, "Coalesce the ingress data into the receive queue's tail sk_buff (tcp_try_coalesce)")
+	bridgeFDB := fs.String("fdb", "", "Bridge FDB lookup result for the frame's destination MAC: known, unknown (flooded)")
+	bridgePorts := fs.Int("bridge-ports", 0, "Number of ports of the bridge (0 = 4)")
+	arpOp := fs.String("arp", "", "Operation of the received ARP packet: request (answered), reply (flushes queued packets)")
+	txQueues := fs.Int("txqueues", 0, "Number of TX queues of the transmitting device (0 = 4)")
+	xps := fs.Bool("xps", false, "Select the TX queue from the sending CPU's XPS map instead of the flow hash")
+	cpu := fs.Int("cpu", 0, "CPU the sender runs on (used with -xps)")
+	qdiscBacklog := fs.Int("qdisc-backlog", 0, "Bytes of the sending socket's earlier segments still in the qdisc and driver; above the TSQ limit tcp_write_xmit stops")
+	tsoDefer := fs.Bool("tso-defer", false, "Make tcp_tso_should_defer hold back the TSO send until ACKs open the congestion window")
+	txBusy := fs.Bool("txbusy", false, "Make the driver refuse the egress packet with NETDEV_TX_BUSY so it is requeued")
+	icmpError := fs.String("icmp-error", "", "Make the ingress packet an ICMP error quoting a TCP segment: frag_needed, port_unreach")
+	ctHelper := fs.String("ct-helper", "", "Conntrack helper assigned to the connection: ftp (parses a PORT command and expects the data connection)")
+	zeroCopy := fs.Bool("zerocopy", false, "Simulate sends with MSG_ZEROCOPY")
+	var natRules []contract.NATRule
+	fs.Func("nat", "NAT rule matching the simulated flow: snat:ADDR, dnat:ADDR or masquerade (repeatable)", func(s string) error {
+		rule, err := contract.ParseNATRule(s)
+		if err != nil {
+			return err
+		}
+		natRules = append(natRules, rule)
+		return nil
+	})
+	nonBlock := fs.Bool("nonblock", false, "Send on a non-blocking socket: a full send buffer returns EAGAIN instead of blocking")
+	mark := fs.Uint("mark", 0, "skb->mark for the simulation (selects policy routing)")
+	timestamp := fs.String("timestamp", "", "Fixed generatedAt value (RFC 3339) for reproducible output (default: now)")
+	typescript := fs.Bool("ts", false, "Output TypeScript type definitions instead of JSON")
+	renderers := contract.DefaultRenderers(true)
+	format := fs.String("format", "json", "Output format: "+strings.Join(renderers.Formats(), ", ")+" (all but json render the single -path)")
+	pathID := fs.String("path", "tcp_ipv4_egress", "Path to simulate for single-path formats")
+	overlay := fs.String("overlay", "", "Second path to overlay on -path in dot and mermaid diagrams")
+	info := fs.Bool("info", false, "Print the supported schema version, paths, formats and simulation parameters as JSON")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
 
-	flag.Parse()
+	out := output{name: *outputFile, stdout: stdout, stderr: stderr}
+
+	if *typescript {
+		return out.write([]byte(contract.GenerateTypeScript()))
+	}
+
+	if *info {
+		data, err := json.MarshalIndent(contract.PackageInfo(), "", "  ")
+		if err != nil {
+			return fmt.Errorf("generating info: %w", err)
+		}
+		return out.write(append(data, '\n'))
+	}
+
+	if *groFlush != "" && !contract.IsValidGROFlushReason(*groFlush) {
+		return fmt.Errorf("-gro-flush must be flow_limit, napi_budget or timeout, got %q", *groFlush)
+	}
+	if *lookup != "" && !contract.IsValidSocketLookup(*lookup) {
+		return fmt.Errorf("-lookup must be established, listen, syn_recv, timewait or none, got %q", *lookup)
+	}
+	if *ipOptions != "" && !contract.IsValidIPOption(*ipOptions) {
+		return fmt.Errorf("-ipopts must be record_route, timestamp, source_route or invalid, got %q", *ipOptions)
+	}
+	if *tcIngress != "" && !contract.IsValidTCAction(*tcIngress) {
+		return fmt.Errorf("-tc-ingress must be ok, shot or redirect, got %q", *tcIngress)
+	}
+	if *bridgeFDB != "" && !contract.IsValidBridgeFDB(*bridgeFDB) {
+		return fmt.Errorf("-fdb must be known or unknown, got %q", *bridgeFDB)
+	}
+	if *arpOp != "" && !contract.IsValidARPOperation(*arpOp) {
+		return fmt.Errorf("-arp must be request or reply, got %q", *arpOp)
+	}
+	if *icmpError != "" && !contract.IsValidICMPError(*icmpError) {
+		return fmt.Errorf("-icmp-error must be frag_needed or port_unreach, got %q", *icmpError)
+	}
+	if *ctHelper != "" && !contract.IsValidConntrackHelper(*ctHelper) {
+		return fmt.Errorf("-ct-helper must be ftp, got %q", *ctHelper)
+	}
+	if *xdpGeneric != "" && !contract.IsValidGenericXDPVerdict(*xdpGeneric) {
+		return fmt.Errorf("-xdp-generic must be pass or drop, got %q", *xdpGeneric)
+	}
+	if *sockFilter != "" && !contract.IsValidSocketFilterVerdict(*sockFilter) {
+		return fmt.Errorf("-sockfilter must be allow or deny, got %q", *sockFilter)
+	}
+	if !contract.IsValidMTU(*mtu) {
+		return fmt.Errorf("-mtu must be 0 or at least 68 (the IPv4 minimum), got %d", *mtu)
+	}
+	if !contract.IsValidMSS(*mss) {
+		return fmt.Errorf("-mss must be 0 or at least 88 (TCP_MIN_MSS), got %d", *mss)
+	}
+	counts := []struct {
+		flag  string
+		value int
+	}{
+		{"indent", *indent},
+		{"buffer", *bufferSize},
+		{"payload", *payloadSize},
+		{"sndbuf", *sndBuf},
+		{"rcvbuf", *rcvBuf},
+		{"fragments", *fragments},
+		{"taps", *taps},
+		{"backlog", *backlog},
+		{"synq", *synQueue},
+		{"acceptq", *acceptQueue},
+		{"udp-segment", *udpSegment},
+		{"gso-partial", *gsoPartial},
+		{"sockfilter-len", *sockFilterLen},
+		{"rtt", *rttSample},
+		{"bridge-ports", *bridgePorts},
+		{"txqueues", *txQueues},
+		{"cpu", *cpu},
+		{"qdisc-backlog", *qdiscBacklog},
+	}
+	for _, count := range counts {
+		if count.value < 0 {
+			return fmt.Errorf("-%s must not be negative, got %d", count.flag, count.value)
+		}
+	}
+	generatedAt := time.Now().UTC()
+	if *timestamp != "" {
+		t, err := time.Parse(time.RFC3339, *timestamp)
+		if err != nil {
+			return fmt.Errorf("-timestamp must be RFC 3339: %w", err)
+		}
+		generatedAt = t.UTC()
+	}
+	if *corrupt != "" && !contract.IsValidCorruptHeader(*corrupt) {
+		return fmt.Errorf("-corrupt must be ip or tcp, got %q", *corrupt)
+	}
 
 	opts := contract.ExportOptions{
-		Pretty:            !*compact,
-		IncludeSimulation: !*noSim,
-		BufferSize:        *bufferSize,
-		PayloadSize:       *payloadSize,
+		Pretty:                      !*compact && !*minify && *indent > 0,
+		IndentWidth:                 *indent,
+		IncludeSimulation:           !*noSim,
+		IncludeLayerTransitions:     *transitions,
+		IncludeAnimationHints:       *animation,
+		IncludeUserspaceEquivalents: *userspace,
+		CollapseWrappers:            *collapse,
+		DeltaSimulation:             *delta,
+		BufferSize:                  *bufferSize,
+		PayloadSize:                 *payloadSize,
+		SendBufferSize:              *sndBuf,
+		RecvBufferSize:              *rcvBuf,
+		FragmentCount:               *fragments,
+		PacketTaps:                  *taps,
+		GROFlush:                    *groFlush,
+		SocketLookup:                *lookup,
+		GenericXDP:                  *xdpGeneric,
+		UDPSegment:                  *udpSegment,
+		GSOPartial:                  *gsoPartial,
+		MTU:                         *mtu,
+		MSS:                         *mss,
+		NoGSO:                       *noGSO,
+		QuickAck:                    *quickAck,
+		ListenBacklog:               *backlog,
+		SynQueueLen:                 *synQueue,
+		AcceptQueueLen:              *acceptQueue,
+		SocketFilter:                *sockFilter,
+		SocketFilterLen:             *sockFilterLen,
+		IPOptions:                   *ipOptions,
+		CorruptChecksum:             *corrupt != "",
+		CorruptHeader:               *corrupt,
+		DeviceBusy:                  *txBusy,
+		QdiscBacklog:                *qdiscBacklog,
+		TSODefer:                    *tsoDefer,
+		RTTSample:                   *rttSample,
+		TCIngress:                   *tcIngress,
+		RecvCoalesce:                *coalesce,
+		SocketOwnedByUser:           *owned,
+		BridgeFDB:                   *bridgeFDB,
+		BridgePorts:                 *bridgePorts,
+		ARPOperation:                *arpOp,
+		TxQueues:                    *txQueues,
+		XPS:                         *xps,
+		CPU:                         *cpu,
+		ZeroCopy:                    *zeroCopy,
+		NonBlocking:                 *nonBlock,
+		NATRules:                    natRules,
+		ConntrackHelper:             *ctHelper,
+		ICMPError:                   *icmpError,
+		Mark:                        uint32(*mark),
 	}
 
-	data, err := contract.ExportTCPIPv4EgressPath(opts)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error generating contract: %v\n", err)
-		os.Exit(1)
+	renderers.Register(contract.JSONRenderer{Pretty: opts.Pretty, IndentWidth: opts.IndentWidth})
+	renderer, ok := renderers.Lookup(*format)
+	if !ok {
+		return fmt.Errorf("unknown format %q", *format)
 	}
 
-	// Add timestamp to the output
-	var export contract.ExportPacket
-	if err := json.Unmarshal(data, &export); err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing generated contract: %v\n", err)
-		os.Exit(1)
+	registry := contract.DefaultRegistry()
+
+	// Single-path formats export only -path, with -overlay merged into it
+	if _, ok := renderer.(contract.SinglePathRenderer); ok {
+		path, ok := registry.Build(*pathID)
+		if !ok {
+			return fmt.Errorf("unknown path %q", *pathID)
+		}
+		if *overlay != "" {
+			other, ok := registry.Build(*overlay)
+			if !ok {
+				return fmt.Errorf("unknown path %q", *overlay)
+			}
+			path = contract.OverlayVersions(path, other)
+		}
+		registry = contract.NewPathRegistry()
+		registry.Register(path.ID, func() *contract.PacketPath { return path })
 	}
-	export.GeneratedAt = time.Now().UTC().Format(time.RFC3339)
 
-	if opts.Pretty {
-		data, err = json.MarshalIndent(export, "", "  ")
-	} else {
-		data, err = json.Marshal(export)
+	if opts.IncludeSimulation {
+		warnBufferSize(stderr, opts, registry.Paths()...)
 	}
+
+	export, err := contract.BuildExport(registry, opts)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error re-marshaling contract: %v\n", err)
-		os.Exit(1)
+		return err
 	}
+	export.GeneratedAt = generatedAt.Format(time.RFC3339)
 
-	if *outputFile != "" {
-		if err := os.WriteFile(*outputFile, data, 0644); err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing to file: %v\n", err)
-			os.Exit(1)
+	data, err := renderer.Render(export)
+	if err != nil {
+		return fmt.Errorf("generating %s: %w", *format, err)
+	}
+
+	return out.write(data)
+}
+
+// warnBufferSize warns when the simulation buffer is too small for a path,
+// which makes header pushes fail silently partway through the simulation.
+func warnBufferSize(stderr io.Writer, opts contract.ExportOptions, paths ...*contract.PacketPath) {
+	for _, path := range paths {
+		if need := path.MinimumBufferSize(opts.PayloadSize); opts.BufferSize < need {
+			fmt.Fprintf(stderr, "Warning: -buffer %d is too small for %s with a %d-byte payload (needs %d bytes); the simulated sk_buff cannot hold the packet and its headers\n",
+				opts.BufferSize, path.ID, opts.PayloadSize, need)
 		}
-		fmt.Fprintf(os.Stderr, "Contract written to %s\n", *outputFile)
-	} else {
-		fmt.Println(string(data))
 	}
 }
+
+// output is the destination of the command's result: the named file, or
+// stdout if name is empty.
+type output struct {
+	name   string
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// write writes data to the destination. Output to stdout always ends with
+// exactly one newline.
+func (o output) write(data []byte) error {
+	if o.name != "" {
+		if err := os.WriteFile(o.name, data, 0644); err != nil {
+			return fmt.Errorf("writing output file %s: %w", o.name, err)
+		}
+		fmt.Fprintf(o.stderr, "Contract written to %s\n", o.name)
+		return nil
+	}
+
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	if _, err := o.stdout.Write(data); err != nil {
+		return fmt.Errorf("writing to stdout: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRunRejectsInvalidArguments(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"-mtu", "0"}, ""},
+		{[]string{"-mtu", "20"}, `-mtu must be 0 or at least 68 (the IPv4 minimum), got 20`},
+		{[]string{"-mtu", "24"}, `-mtu must be 0 or at least 68 (the IPv4 minimum), got 24`},
+		{[]string{"-mtu", "67"}, `-mtu must be 0 or at least 68 (the IPv4 minimum), got 67`},
+		{[]string{"-mtu", "68"}, ""},
+		{[]string{"-mtu", "-1500"}, `-mtu must be 0 or at least 68 (the IPv4 minimum), got -1500`},
+		{[]string{"-mss", "1"}, `-mss must be 0 or at least 88 (TCP_MIN_MSS), got 1`},
+		{[]string{"-mss", "88"}, ""},
+		{[]string{"-indent", "-1"}, `-indent must not be negative, got -1`},
+		{[]string{"-payload", "-1"}, `-payload must not be negative, got -1`},
+		{[]string{"-fragments", "-2"}, `-fragments must not be negative, got -2`},
+		{[]string{"-txqueues", "-1"}, `-txqueues must not be negative, got -1`},
+		{[]string{"-cpu", "-1"}, `-cpu must not be negative, got -1`},
+		{[]string{"-xdp-generic", "aborted"}, `-xdp-generic must be pass or drop, got "aborted"`},
+		{[]string{"-xdp-generic", "drop"}, ""},
+		{[]string{"-sockfilter", "drop"}, `-sockfilter must be allow or deny, got "drop"`},
+		{[]string{"-sockfilter", "deny"}, ""},
+		{[]string{"-corrupt", "udp"}, `-corrupt must be ip or tcp, got "udp"`},
+		{[]string{"-corrupt", "ip"}, ""},
+		{[]string{"-gro-flush", "never"}, `-gro-flush must be flow_limit, napi_budget or timeout, got "never"`},
+		{[]string{"-tc-ingress", "pipe"}, `-tc-ingress must be ok, shot or redirect, got "pipe"`},
+		{[]string{"-timestamp", "yesterday"}, `-timestamp must be RFC 3339`},
+		{[]string{"-no-such-flag"}, `flag provided but not defined: -no-such-flag`},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			args := append([]string{"-compact", "-timestamp", "2024-01-01T00:00:00Z"}, tt.args...)
+			var stdout, stderr bytes.Buffer
+			err := run(args, &stdout, &stderr)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("run(%q) = %v, want success", tt.args, err)
+			case tt.wantErr == "" && stdout.Len() == 0:
+				t.Errorf("run(%q) wrote no contract", tt.args)
+			case tt.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.wantErr)):
+				t.Errorf("run(%q) = %v, want %s", tt.args, err, tt.wantErr)
+			case tt.wantErr != "" && stdout.Len() != 0:
+				t.Errorf("run(%q) failed but wrote %d bytes", tt.args, stdout.Len())
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+// Command pathcheck validates packet path files, for authors of custom paths.
+//
+// It reads a JSON PacketPath, or a full export with several paths, and runs
+// the same checks the contract applies to its built-in paths: structural
+// validation (entry and exit points, terminal functions), lint findings and
+// cycle detection. Validation problems are errors; lint findings and cycles
+// are warnings unless -strict is given. The command exits nonzero if any
+// error is found, so it can run in a pre-commit hook.
+//
+// Usage:
+//
+//	go run ./cmd/pathcheck my_path.json
+//	go run ./cmd/contract | go run ./cmd/pathcheck
+//	go run ./cmd/pathcheck -strict -q paths/*.json
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rzkiamr/linux-packet-visualizer/internal/contract"
+)
+
+// errProblemsFound is returned when a checked path has errors.
+var errProblemsFound = errors.New("problems found")
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	if errors.Is(err, errProblemsFound) {
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+}
+
+// run checks every path in the named files, or in stdin if no file (or "-")
+// is given, and writes a report to stdout.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("pathcheck", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	strict := fs.Bool("strict", false, "Treat lint findings and cycles as errors")
+	quiet := fs.Bool("q", false, "Only report paths with findings")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	failed := false
+	for _, name := range files {
+		data, err := readInput(name, stdin)
+		if err != nil {
+			return err
+		}
+		paths, err := decodePaths(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", displayName(name), err)
+		}
+		for _, path := range paths {
+			if !checkPath(stdout, displayName(name), path, *strict, *quiet) {
+				failed = true
+			}
+		}
+	}
+
+	if failed {
+		return errProblemsFound
+	}
+	return nil
+}
+
+// readInput reads the named file, or stdin for "-".
+func readInput(name string, stdin io.Reader) ([]byte, error) {
+	if name == "-" {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("reading stdin: %w", err)
+		}
+		return data, nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// displayName returns the name of an input for the report.
+func displayName(name string) string {
+	if name == "-" {
+		return "<stdin>"
+	}
+	return name
+}
+
+// decodePaths decodes either a full export, recognized by its "paths"
+// array, or a single path.
+func decodePaths(data []byte) ([]*contract.PacketPath, error) {
+	var probe struct {
+		Paths json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("parsing JSON: %w", err)
+	}
+
+	if probe.Paths != nil {
+		var export contract.ExportPacket
+		if err := json.Unmarshal(data, &export); err != nil {
+			return nil, fmt.Errorf("parsing export: %w", err)
+		}
+		paths := make([]*contract.PacketPath, len(export.Paths))
+		for i := range export.Paths {
+			paths[i] = &export.Paths[i].Path
+		}
+		return paths, nil
+	}
+
+	var path contract.PacketPath
+	if err := json.Unmarshal(data, &path); err != nil {
+		return nil, fmt.Errorf("parsing path: %w", err)
+	}
+	if path.ID == "" && len(path.Functions) == 0 {
+		return nil, errors.New("input is neither a packet path nor an export")
+	}
+	return []*contract.PacketPath{&path}, nil
+}
+
+// checkPath writes the report for one path and reports whether it passed.
+func checkPath(w io.Writer, source string, path *contract.PacketPath, strict, quiet bool) bool {
+	var errs, warnings []string
+
+	var verr *contract.ValidationError
+	if err := path.Validate(); errors.As(err, &verr) {
+		errs = append(errs, verr.Problems...)
+	} else if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	warnings = append(warnings, path.Lint()...)
+	for _, cycle := range path.Cycles() {
+		warnings = append(warnings, "cycle: "+strings.Join(cycle, " -> "))
+	}
+
+	if strict {
+		errs = append(errs, warnings...)
+		warnings = nil
+	}
+
+	if quiet && len(errs) == 0 && len(warnings) == 0 {
+		return true
+	}
+
+	status := "ok"
+	if len(errs) > 0 {
+		status = "FAIL"
+	}
+	fmt.Fprintf(w, "%s: %s (%d functions, %d edges): %s\n", source, path.ID, len(path.Functions), len(path.Edges), status)
+	for _, e := range errs {
+		fmt.Fprintf(w, "  error: %s\n", e)
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "  warning: %s\n", warning)
+	}
+	return len(errs) == 0
+}
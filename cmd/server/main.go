@@ -0,0 +1,64 @@
+// Command server serves the Linux Packet Visualizer contract over HTTP.
+//
+// Usage:
+//
+//	go run ./cmd/server -addr :8080
+//	curl localhost:8080/api/export?payload=1400
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rzkiamr/linux-packet-visualizer/internal/contract"
+	"github.com/rzkiamr/linux-packet-visualizer/internal/server"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "Listen address")
+	cacheTTL := flag.Duration("cache-ttl", server.DefaultCacheTTL, "How long generated exports are cached (0 = no cache)")
+	rateLimit := flag.Int("rate-limit", server.DefaultRateLimit, "Export requests allowed per minute per client IP (0 = unlimited)")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 10*time.Second, "Time allowed for in-flight requests to finish on shutdown")
+
+	flag.Parse()
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           server.New(contract.DefaultRegistry(), server.WithCacheTTL(*cacheTTL), server.WithRateLimit(*rateLimit)),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		fmt.Fprintf(os.Stderr, "Listening on %s\n", *addr)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "Error serving: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	case <-ctx.Done():
+	}
+
+	fmt.Fprintln(os.Stderr, "Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		fmt.Fprintf(os.Stderr, "Error during shutdown: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -1,3 +1,13 @@
+/**
+ * Formats a conntrack timeout in seconds with the largest whole unit.
+ */
+function formatTimeout(seconds) {
+    if (seconds % 86400 === 0) return `${seconds / 86400} d`;
+    if (seconds % 3600 === 0) return `${seconds / 3600} h`;
+    if (seconds >= 60 && seconds % 60 === 0) return `${seconds / 60} min`;
+    return `${seconds} s`;
+}
+
 /**
  * ConntrackInfo - Displays current connection tracking state.
  */
@@ -39,6 +49,12 @@ export function ConntrackInfo({ conntrackState }) {
                 {conntrackState.description}
             </p>
 
+            {conntrackState.timeout > 0 && (
+                <p className="conntrack-timeout">
+                    Expires after {formatTimeout(conntrackState.timeout)} without traffic
+                </p>
+            )}
+
             <div className="conntrack-state-diagram">
                 <div className="state-machine">
                     <div className={`state-node ${conntrackState.state === 'NEW' ? 'active' : ''}`}>NEW</div>
@@ -0,0 +1,124 @@
+package contract
+
+// Easing curves suggested for a pointer tween
+const (
+	// EasingEaseOut suits a header push: data moves back into the headroom
+	EasingEaseOut = "ease-out"
+
+	// EasingEaseIn suits a header pull: data moves forward past the header
+	EasingEaseIn = "ease-in"
+
+	// EasingEaseInOut suits the tail growing or shrinking with the payload
+	EasingEaseInOut = "ease-in-out"
+
+	// EasingLinear suits head or end moving when the buffer is reallocated
+	EasingLinear = "linear"
+)
+
+// Duration buckets for a pointer tween, by how far the pointer moves. The
+// frontend maps each bucket to an actual duration.
+const (
+	DurationShort  = "short"
+	DurationMedium = "medium"
+	DurationLong   = "long"
+)
+
+// Pointer distances, in bytes, at which a tween moves to the next bucket
+const (
+	durationMediumBytes = 64
+	durationLongBytes   = 512
+)
+
+// AnimationHint describes how to tween one sk_buff pointer into a step.
+type AnimationHint struct {
+	// Pointer is the pointer that moves: "head", "data", "tail" or "end"
+	Pointer string `json:"pointer"`
+
+	// From is the offset in the previous step
+	From int `json:"from"`
+
+	// To is the offset in this step
+	To int `json:"to"`
+
+	// Easing is the suggested easing curve (see EasingEaseOut)
+	Easing string `json:"easing"`
+
+	// Duration is the suggested duration bucket (see DurationShort)
+	Duration string `json:"duration"`
+}
+
+// StepAnimation holds the keyframe hints for one step.
+type StepAnimation struct {
+	// StepIndex is the 0-based index of the step the tween ends at
+	StepIndex int `json:"stepIndex"`
+
+	// FunctionID is the function of that step
+	FunctionID string `json:"functionId"`
+
+	// Hints lists one tween per pointer that moves
+	Hints []AnimationHint `json:"hints"`
+}
+
+// AnimationHints returns keyframe hints for every step in which an sk_buff
+// pointer moves, tweening from the previous step's state. Steps where no
+// pointer moves are omitted, as is the first step, which has no previous
+// state.
+func (steps SimulateSteps) AnimationHints() []StepAnimation {
+	animations := []StepAnimation{}
+	for i := 1; i < len(steps); i++ {
+		prev, next := &steps[i-1].SKBuffState, &steps[i].SKBuffState
+		var hints []AnimationHint
+		hints = appendHint(hints, "head", prev.Head, next.Head, EasingLinear)
+		hints = appendHint(hints, "data", prev.Data, next.Data, dataEasing(prev.Data, next.Data))
+		hints = appendHint(hints, "tail", prev.Tail, next.Tail, EasingEaseInOut)
+		hints = appendHint(hints, "end", prev.End, next.End, EasingLinear)
+		if len(hints) == 0 {
+			continue
+		}
+		animations = append(animations, StepAnimation{
+			StepIndex:  i,
+			FunctionID: steps[i].Function.ID,
+			Hints:      hints,
+		})
+	}
+	return animations
+}
+
+// appendHint appends a tween for the pointer if it moved.
+func appendHint(hints []AnimationHint, pointer string, from, to int, easing string) []AnimationHint {
+	if from == to {
+		return hints
+	}
+	return append(hints, AnimationHint{
+		Pointer:  pointer,
+		From:     from,
+		To:       to,
+		Easing:   easing,
+		Duration: durationBucket(to - from),
+	})
+}
+
+// dataEasing returns the easing for a move of the data pointer: back for a
+// push, forward for a pull.
+func dataEasing(from, to int) string {
+	if to < from {
+		return EasingEaseOut
+	}
+	return EasingEaseIn
+}
+
+// durationBucket returns the duration bucket for a pointer moving by delta
+// bytes.
+func durationBucket(delta int) string {
+	if delta < 0 {
+		delta = -delta
+	}
+	switch {
+	case delta >= durationLongBytes:
+		return DurationLong
+	case delta >= durationMediumBytes:
+		return DurationMedium
+	default:
+		return DurationShort
+	}
+}
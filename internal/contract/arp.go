@@ -0,0 +1,271 @@
+package contract
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// ARP operations of the received ARP packet
+const (
+	// ARPOpRequest is a request for one of the host's addresses, which
+	// the host answers
+	ARPOpRequest = "request"
+
+	// ARPOpReply answers a request the host sent while resolving a
+	// neighbour, so packets queued on the entry can be sent
+	ARPOpReply = "reply"
+)
+
+// IsValidARPOperation reports whether op is a known ARP operation.
+func IsValidARPOperation(op string) bool {
+	return op == ARPOpRequest || op == ARPOpReply
+}
+
+// Neighbour entry states (NUD_*) set by the ARP path
+const (
+	// NeighStale is an entry learned without confirmation of reachability:
+	// it is used, but verified on first use
+	NeighStale = "NUD_STALE"
+
+	// NeighReachable is an entry confirmed by a reply to the host's own
+	// request
+	NeighReachable = "NUD_REACHABLE"
+)
+
+// AnnotationNeighbour marks neighbour table updates.
+const AnnotationNeighbour = "neighbour"
+
+// NeighbourEntry is the neighbour table entry for the ARP sender after the
+// packet was processed.
+type NeighbourEntry struct {
+	// IP is the sender's protocol address
+	IP string `json:"ip"`
+
+	// MAC is the sender's hardware address
+	MAC string `json:"mac"`
+
+	// State is the entry's NUD state (see NeighStale)
+	State string `json:"state"`
+
+	// Queued is the number of packets that were waiting on the entry's
+	// arp_queue for it to resolve and are now transmitted
+	Queued int `json:"queued,omitempty"`
+}
+
+// BuildARPIngressPath constructs the path of an ARP packet received by the
+// host, based on Linux Kernel 5.10.8.
+//
+// The frame is received like any other up to __netif_receive_skb_core,
+// whose ptype_base lookup hands ETH_P_ARP frames to arp_rcv. arp_process
+// learns the sender in the neighbour table. A request for one of the
+// host's addresses is answered with a reply sent down the egress transmit
+// path; a reply to the host's own request resolves the neighbour entry, and
+// the packets queued on it while resolution was pending leave through
+// neigh_output.
+func BuildARPIngressPath() *PacketPath {
+	ingress := BuildTCPIPv4IngressPath()
+	egress := BuildTCPIPv4EgressPath()
+
+	arp := []KernelFunction{
+		{
+			ID:               "arp_rcv",
+			Name:             "arp_rcv",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/arp.c",
+			LineNumber:       954,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "ETH_P_ARP protocol handler. Checks the ARP header is complete and sane for the device (no IFF_NOARP, matching address lengths) and runs the arptables NF_ARP_IN hook.",
+		},
+		{
+			ID:               "arp_process",
+			Name:             "arp_process",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/arp.c",
+			LineNumber:       697,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Parses the sender and target addresses, looks up a route to the target to decide if it is local, and learns the sender in the neighbour table.",
+			RCUProtected:     true,
+			RCUNote:          "The device's in_device and the route lookup are read under RCU.",
+		},
+		{
+			ID:               "neigh_update",
+			Name:             "neigh_update",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/core/neighbour.c",
+			LineNumber:       1426,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Records the sender's MAC in its neighbour entry and moves it to NUD_STALE (learned) or NUD_REACHABLE (confirmed). Refreshes the cached hardware header used by neigh_hh_output and sends the packets waiting on the entry's arp_queue.",
+			EstimatedCostNs:  300,
+		},
+		{
+			ID:               "arp_send_dst",
+			Name:             "arp_send_dst",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/arp.c",
+			LineNumber:       303,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Answers the request: arp_create allocates a new sk_buff holding the ARP reply and the Ethernet header addressed to the requester.",
+			SKBMutation:      NewAllocMutation(ARPHeaderSize+EthernetHeaderSize, "ARP reply"),
+		},
+		{
+			ID:               "arp_xmit",
+			Name:             "arp_xmit",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/arp.c",
+			LineNumber:       634,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Runs the arptables NF_ARP_OUT hook and queues the reply on the device with dev_queue_xmit.",
+		},
+	}
+
+	b := NewPathBuilder("arp_ingress", "ARP Ingress Path", "ingress", "ARP").
+		Description("The path of a received ARP packet: neighbour learning, and the reply or the flush of packets waiting for resolution (Linux 5.10.8)")
+
+	// Receive side up to the protocol dispatch, and the drop point
+	shared := make(map[string]bool)
+	received := false
+	for _, fn := range ingress.Functions {
+		if received && fn.ID != "kfree_skb" {
+			continue
+		}
+		switch fn.ID {
+		case "do_xdp_generic", "sch_handle_ingress", "skb_do_redirect":
+			continue
+		case "deliver_skb":
+			fn.Description = "Delivers the frame to the protocol handler registered for its EtherType: arp_rcv for ETH_P_ARP."
+			received = true
+		}
+		fn.IsEntryPoint, fn.IsExitPoint = false, false
+		shared[fn.ID] = true
+		b.AddFunction(fn)
+	}
+	for _, fn := range arp {
+		b.AddFunction(fn)
+	}
+
+	// Transmit side: the reply and the queued packets go out through the
+	// neighbour output and device queueing, run in softirq
+	transmit := map[string]bool{
+		"neigh_output":         true,
+		"neigh_hh_output":      true,
+		"neigh_resolve_output": true,
+		"dev_queue_xmit":       true,
+		"__dev_queue_xmit":     true,
+		"__dev_xmit_skb":       true,
+		"sch_direct_xmit":      true,
+		"dev_requeue_skb":      true,
+		"dev_hard_start_xmit":  true,
+		"ndo_start_xmit":       true,
+	}
+	for _, fn := range egress.Functions {
+		if !transmit[fn.ID] {
+			continue
+		}
+		fn.ExecutionContext = ContextSoftIRQ
+		fn.IsEntryPoint, fn.IsExitPoint = false, false
+		shared[fn.ID] = true
+		b.AddFunction(fn)
+	}
+
+	for _, edges := range [][]FunctionEdge{ingress.Edges, egress.Edges} {
+		for _, edge := range edges {
+			if !shared[edge.From] || !shared[edge.To] {
+				continue
+			}
+			opts := []EdgeOption{WithCondition(edge.Condition)}
+			if edge.IsErrorPath {
+				opts = append(opts, AsErrorPath())
+			}
+			b.Connect(edge.From, edge.To, opts...)
+		}
+	}
+
+	return b.
+		Connect("deliver_skb", "arp_rcv", WithCondition("Protocol is ARP")).
+		Connect("arp_rcv", "arp_process").
+		Connect("arp_rcv", "kfree_skb", WithCondition("Truncated header, IFF_NOARP device or NF_DROP"), AsErrorPath()).
+		Connect("arp_process", "neigh_update", WithCondition("Sender learned")).
+		Connect("neigh_update", "arp_send_dst", WithCondition("Request for a local address")).
+		Connect("neigh_update", "neigh_output", WithCondition("Reply resolves a pending entry (arp_queue flushed)")).
+		Connect("arp_send_dst", "arp_xmit").
+		Connect("arp_xmit", "dev_queue_xmit").
+		SetEntry("napi_poll").
+		SetExit("ndo_start_xmit", "dev_requeue_skb", "kfree_skb").
+		MustBuild()
+}
+
+// NewSKBuffForARP creates an sk_buff holding a received ARP packet: the
+// Ethernet header and the ARP header, with no payload.
+func NewSKBuffForARP(totalSize int) *SKBuff {
+	skb := &SKBuff{
+		Head: 0,
+		Data: 0,
+		Tail: EthernetHeaderSize + ARPHeaderSize,
+		End:  totalSize,
+		Layers: []ProtocolHeader{
+			{Protocol: "ethernet", Offset: 0, Size: EthernetHeaderSize},
+			{Protocol: "arp", Offset: EthernetHeaderSize, Size: ARPHeaderSize},
+		},
+	}
+	skb.ResetMacHeader()
+	return skb
+}
+
+// arpOperation returns the operation of the simulated ARP packet.
+func (ctx *simContext) arpOperation() string {
+	if ctx.opts.ARPOperation == "" {
+		return ARPOpRequest
+	}
+	return ctx.opts.ARPOperation
+}
+
+// neighbourMAC returns a stable, locally administered MAC for an IPv4
+// address, so the simulated neighbour has a plausible hardware address.
+func neighbourMAC(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !addr.Is4() {
+		return "02:00:00:00:00:01"
+	}
+	a := addr.As4()
+	return fmt.Sprintf("02:00:%02x:%02x:%02x:%02x", a[0], a[1], a[2], a[3])
+}
+
+// effectNeighUpdate models neigh_update recording the ARP sender. For a
+// reply, the simulation continues with the first packet that was queued
+// waiting for the entry, as ip_finish_output2 left it.
+func effectNeighUpdate(ctx *simContext, step *SimulateStep) {
+	// The ARP sender is the remote end of the flow
+	entry := &NeighbourEntry{IP: ctx.flow.SrcIP, MAC: neighbourMAC(ctx.flow.SrcIP), State: NeighStale}
+	if ctx.arpOperation() == ARPOpReply {
+		entry.State = NeighReachable
+		entry.Queued = 1
+		queued := NewSKBuffWithPayload(ctx.skb.End, ctx.opts.PayloadSize)
+		queued.Push("tcp", TCPHeaderSize)
+		queued.ResetTransportHeader()
+		queued.Push("ip", IPv4HeaderSize)
+		queued.ResetNetworkHeader()
+		*ctx.skb = *queued
+		step.annotate(AnnotationNeighbour, fmt.Sprintf(
+			"The reply confirms %s is at %s: the entry becomes %s and its hardware header is cached. "+
+				"The packet queued on arp_queue while resolution was pending is sent; the simulation follows it from neigh_output. The ARP packet itself is consumed.",
+			entry.IP, entry.MAC, entry.State))
+	} else {
+		step.annotate(AnnotationNeighbour, fmt.Sprintf(
+			"The request for %s teaches the host that %s is at %s: the entry is created %s, so the host can answer without resolving the requester itself.",
+			ctx.flow.DstIP, entry.IP, entry.MAC, entry.State))
+	}
+	step.Neighbour = entry
+}
+
+// effectARPReply models arp_send_dst building the reply in a new sk_buff
+// with the ARP header and the Ethernet header already in place.
+func effectARPReply(ctx *simContext, step *SimulateStep) {
+	reply := NewSKBuffWithPayload(ctx.skb.End, 0)
+	reply.Push("arp", ARPHeaderSize)
+	reply.ResetNetworkHeader()
+	reply.Push("ethernet", EthernetHeaderSize)
+	*ctx.skb = *reply
+	step.annotate(AnnotationNeighbour, fmt.Sprintf(
+		"Reply: %s is at the receiving device's MAC, sent to %s. The received request is consumed; the simulation follows the reply.",
+		ctx.flow.DstIP, neighbourMAC(ctx.flow.SrcIP)))
+}
@@ -14,6 +14,10 @@ type BPFHook struct {
 
 	// Actions lists the possible return values for this hook type
 	Actions []string `json:"actions"`
+
+	// XDPMode is where an XDP hook runs: XDPModeNative, XDPModeGeneric or
+	// XDPModeOffload (empty for other hook types)
+	XDPMode string `json:"xdpMode,omitempty"`
 }
 
 // BPF hook type constants
@@ -25,14 +29,47 @@ const (
 	BPFHookSocket    = "SOCKET"
 )
 
-// NewXDPHook creates an XDP hook annotation.
+// XDP attachment modes
+const (
+	// XDPModeNative runs the program in the driver's NAPI poll loop on the
+	// raw receive buffer, before an sk_buff is allocated (XDP_FLAGS_DRV_MODE)
+	XDPModeNative = "native"
+
+	// XDPModeGeneric runs the program in the core stack on an already
+	// allocated sk_buff, for drivers without XDP support (XDP_FLAGS_SKB_MODE)
+	XDPModeGeneric = "generic"
+
+	// XDPModeOffload runs the program on the NIC itself, so the host kernel
+	// never sees dropped packets (XDP_FLAGS_HW_MODE). It has no position on
+	// the kernel path.
+	XDPModeOffload = "offload"
+)
+
+// xdpActions are the return values of an XDP program
+var xdpActions = []string{"XDP_PASS", "XDP_DROP", "XDP_TX", "XDP_REDIRECT", "XDP_ABORTED"}
+
+// NewXDPHook creates a native-mode XDP hook annotation.
 // XDP runs at the earliest point, before sk_buff allocation.
 func NewXDPHook() *BPFHook {
 	return &BPFHook{
 		Type:        BPFHookXDP,
 		AttachPoint: "NIC driver RX path",
 		Description: "eXpress Data Path. Runs before sk_buff allocation for maximum performance. Can drop, pass, or redirect packets.",
-		Actions:     []string{"XDP_PASS", "XDP_DROP", "XDP_TX", "XDP_REDIRECT", "XDP_ABORTED"},
+		Actions:     xdpActions,
+		XDPMode:     XDPModeNative,
+	}
+}
+
+// NewGenericXDPHook creates a generic-mode (SKB mode) XDP hook annotation.
+// Generic XDP runs the same programs after sk_buff allocation, so it works
+// with any driver but saves none of the allocation cost.
+func NewGenericXDPHook() *BPFHook {
+	return &BPFHook{
+		Type:        BPFHookXDP,
+		AttachPoint: "Core receive path (__netif_receive_skb_core)",
+		Description: "Generic XDP. Runs an XDP program on an already allocated sk_buff for drivers without native XDP support. Same actions, but slower than native mode.",
+		Actions:     xdpActions,
+		XDPMode:     XDPModeGeneric,
 	}
 }
 
@@ -0,0 +1,241 @@
+package contract
+
+import "fmt"
+
+// Bridge FDB lookup results for the bridged frame's destination MAC
+const (
+	// BridgeFDBKnown means the destination MAC was learned on one port
+	BridgeFDBKnown = "known"
+
+	// BridgeFDBUnknown means the destination MAC is not in the FDB, so the
+	// frame is flooded
+	BridgeFDBUnknown = "unknown"
+)
+
+// IsValidBridgeFDB reports whether result is a known FDB lookup result.
+func IsValidBridgeFDB(result string) bool {
+	return result == BridgeFDBKnown || result == BridgeFDBUnknown
+}
+
+// defaultBridgePorts is the number of ports of the modeled bridge when none
+// is given
+const defaultBridgePorts = 4
+
+// AnnotationBridge marks bridge FDB learning, lookup and forwarding steps.
+const AnnotationBridge = "bridge"
+
+// BuildBridgePath constructs the path of an Ethernet frame switched by a
+// Linux bridge from one port to others, based on Linux Kernel 5.10.8.
+//
+// The frame is received on a bridge port like any other up to
+// __netif_receive_skb_core, which hands it to the port's rx_handler,
+// br_handle_frame, instead of a protocol handler. The bridge learns the
+// source MAC, looks up the destination in its forwarding database (FDB) and
+// either forwards the frame to the one port behind which the destination
+// was learned or floods it to every other port. The frame never reaches
+// the IP layer.
+func BuildBridgePath() *PacketPath {
+	ingress := BuildTCPIPv4IngressPath()
+	egress := BuildTCPIPv4EgressPath()
+
+	bridge := []KernelFunction{
+		{
+			ID:               "br_handle_frame",
+			Name:             "br_handle_frame",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/bridge/br_input.c",
+			LineNumber:       282,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "The bridge port's rx_handler. Consumes the frame for the bridge (RX_HANDLER_CONSUMED) and runs the bridge PRE_ROUTING hook when br_netfilter is loaded.",
+			ConfigDeps:       []string{"CONFIG_BRIDGE"},
+			RCUProtected:     true,
+			RCUNote:          "The port (net_bridge_port) is the device's rx_handler_data, dereferenced under RCU.",
+		},
+		{
+			ID:               "br_handle_frame_finish",
+			Name:             "br_handle_frame_finish",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/bridge/br_input.c",
+			LineNumber:       69,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Learns the source MAC on the ingress port (br_fdb_update) and looks up the destination MAC in the forwarding database (br_fdb_find_rcu) to choose between forwarding and flooding.",
+			ConfigDeps:       []string{"CONFIG_BRIDGE"},
+			RCUProtected:     true,
+			RCUNote:          "FDB entries live in an RCU-protected hash table.",
+		},
+		{
+			ID:               "br_forward",
+			Name:             "br_forward",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/bridge/br_forward.c",
+			LineNumber:       143,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Known unicast: sends the frame to the single port the destination MAC was learned on, unless that is the port it arrived on.",
+			ConfigDeps:       []string{"CONFIG_BRIDGE"},
+		},
+		{
+			ID:               "br_flood",
+			Name:             "br_flood",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/bridge/br_forward.c",
+			LineNumber:       197,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Unknown unicast, broadcast or multicast: sends a clone of the frame to every port except the ingress one, and the original to the last.",
+			ConfigDeps:       []string{"CONFIG_BRIDGE"},
+			EstimatedCostNs:  600,
+		},
+		{
+			ID:               "__br_forward",
+			Name:             "__br_forward",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/bridge/br_forward.c",
+			LineNumber:       113,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Retargets the frame to the egress port's device and runs the bridge FORWARD hook.",
+			ConfigDeps:       []string{"CONFIG_BRIDGE"},
+		},
+		{
+			ID:               "br_forward_finish",
+			Name:             "br_forward_finish",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/bridge/br_forward.c",
+			LineNumber:       57,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Runs the bridge POST_ROUTING hook and hands the frame to br_dev_queue_push_xmit.",
+			ConfigDeps:       []string{"CONFIG_BRIDGE"},
+		},
+		{
+			ID:               "br_dev_queue_push_xmit",
+			Name:             "br_dev_queue_push_xmit",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/bridge/br_forward.c",
+			LineNumber:       32,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Restores the Ethernet header pulled on receive and queues the unchanged frame on the egress port with dev_queue_xmit.",
+			SKBMutation:      NewPushMutation("ethernet", EthernetHeaderSize),
+			ConfigDeps:       []string{"CONFIG_BRIDGE"},
+		},
+	}
+
+	b := NewPathBuilder("tcp_ipv4_bridge", "Bridged Ethernet Frame Path", "forward", "TCP").
+		Family(FamilyIPv4).
+		Description("The path of a frame carrying TCP/IPv4 switched by a Linux bridge between two of its ports, with FDB learning, lookup and flooding (Linux 5.10.8)")
+
+	// Receive side up to the rx_handler, and the drop point
+	shared := make(map[string]bool)
+	received := false
+	for _, fn := range ingress.Functions {
+		if received && fn.ID != "kfree_skb" {
+			continue
+		}
+		switch fn.ID {
+		case "do_xdp_generic":
+			continue
+		case "__netif_receive_skb_core":
+			fn.Description = "Core packet classification. Strips the Ethernet header, runs the ptype_all taps and passes the frame to the device's rx_handler: the device is a bridge port."
+			received = true
+		}
+		fn.IsEntryPoint, fn.IsExitPoint = false, false
+		shared[fn.ID] = true
+		b.AddFunction(fn)
+	}
+	for _, fn := range bridge {
+		b.AddFunction(fn)
+	}
+
+	// Transmit side: device queueing on the egress port, run in softirq
+	transmit := map[string]bool{
+		"dev_queue_xmit":      true,
+		"__dev_queue_xmit":    true,
+		"__dev_xmit_skb":      true,
+		"sch_direct_xmit":     true,
+		"dev_requeue_skb":     true,
+		"dev_hard_start_xmit": true,
+		"ndo_start_xmit":      true,
+	}
+	for _, fn := range egress.Functions {
+		if !transmit[fn.ID] {
+			continue
+		}
+		fn.ExecutionContext = ContextSoftIRQ
+		fn.IsEntryPoint, fn.IsExitPoint = false, false
+		shared[fn.ID] = true
+		b.AddFunction(fn)
+	}
+
+	for _, edges := range [][]FunctionEdge{ingress.Edges, egress.Edges} {
+		for _, edge := range edges {
+			if !shared[edge.From] || !shared[edge.To] {
+				continue
+			}
+			opts := []EdgeOption{WithCondition(edge.Condition)}
+			if edge.IsErrorPath {
+				opts = append(opts, AsErrorPath())
+			}
+			b.Connect(edge.From, edge.To, opts...)
+		}
+	}
+
+	return b.
+		Connect("__netif_receive_skb_core", "br_handle_frame", WithCondition("Device is a bridge port (rx_handler)")).
+		Connect("br_handle_frame", "br_handle_frame_finish").
+		Connect("br_handle_frame_finish", "br_forward", WithCondition("Destination MAC is in the FDB")).
+		Connect("br_handle_frame_finish", "br_flood", WithCondition("Destination MAC unknown, or broadcast/multicast")).
+		Connect("br_forward", "__br_forward", WithCondition("Egress port differs from the ingress port")).
+		Connect("br_forward", "kfree_skb", WithCondition("Destination is behind the ingress port"), AsErrorPath()).
+		Connect("br_flood", "__br_forward", WithCondition("For each other port (clones)")).
+		Connect("__br_forward", "br_forward_finish").
+		Connect("br_forward_finish", "br_dev_queue_push_xmit").
+		Connect("br_dev_queue_push_xmit", "dev_queue_xmit").
+		SetEntry("napi_poll").
+		SetExit("ndo_start_xmit", "dev_requeue_skb").
+		MustBuild()
+}
+
+// bridgePorts returns the number of ports of the simulated bridge.
+func (ctx *simContext) bridgePorts() int {
+	if ctx.opts.BridgePorts > 1 {
+		return ctx.opts.BridgePorts
+	}
+	return defaultBridgePorts
+}
+
+// bridgePortName names the bridge's i-th port; the frame arrives on port 0.
+func bridgePortName(i int) string {
+	return fmt.Sprintf("eth%d", i)
+}
+
+// effectFDBLookup models br_handle_frame_finish learning the source MAC and
+// looking up the destination.
+func effectFDBLookup(ctx *simContext, step *SimulateStep) {
+	result := "found on port " + bridgePortName(1)
+	if ctx.opts.BridgeFDB == BridgeFDBUnknown {
+		result = "not found"
+	}
+	step.annotate(AnnotationBridge, fmt.Sprintf(
+		"The source MAC is learned (or refreshed) on ingress port %s, so replies to it can be forwarded directly. "+
+			"The destination MAC is %s in the FDB of a %d-port bridge.",
+		bridgePortName(0), result, ctx.bridgePorts()))
+}
+
+// effectBridgeForward models br_forward sending a known-unicast frame to a
+// single port.
+func effectBridgeForward(ctx *simContext, step *SimulateStep) {
+	step.EgressPorts = []string{bridgePortName(1)}
+	step.annotate(AnnotationBridge, fmt.Sprintf(
+		"Known unicast: the frame goes out of %s only; no other port sees it.", bridgePortName(1)))
+}
+
+// effectBridgeFlood models br_flood fanning the frame out to every port but
+// the ingress one. The simulation follows the copy sent to the first port.
+func effectBridgeFlood(ctx *simContext, step *SimulateStep) {
+	ports := ctx.bridgePorts()
+	for i := 1; i < ports; i++ {
+		step.EgressPorts = append(step.EgressPorts, bridgePortName(i))
+	}
+	step.annotate(AnnotationBridge, fmt.Sprintf(
+		"Unknown destination: the frame is flooded to %d ports (%v). Each but the last gets a clone sharing the data (deliver_clone). "+
+			"Once the destination replies, its MAC is learned and later frames are forwarded to one port. "+
+			"The simulation follows the copy sent to %s.",
+		len(step.EgressPorts), step.EgressPorts, step.EgressPorts[0]))
+}
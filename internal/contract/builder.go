@@ -0,0 +1,173 @@
+package contract
+
+import "fmt"
+
+// EdgeOption customizes an edge added with PathBuilder.Connect.
+type EdgeOption func(*FunctionEdge)
+
+// WithCondition sets the condition under which the edge is taken.
+func WithCondition(condition string) EdgeOption {
+	return func(e *FunctionEdge) {
+		e.Condition = condition
+	}
+}
+
+// WithOrder overrides the edge's order among edges from the same function.
+func WithOrder(order int) EdgeOption {
+	return func(e *FunctionEdge) {
+		e.Order = order
+	}
+}
+
+// AsErrorPath marks the edge as an error handling path.
+func AsErrorPath() EdgeOption {
+	return func(e *FunctionEdge) {
+		e.IsErrorPath = true
+	}
+}
+
+// PathBuilder assembles a PacketPath step by step and checks it for
+// mistakes, such as edges to misspelled function IDs, when it is built.
+//
+//	path, err := NewPathBuilder("tcp_ipv4_egress", "TCP/IPv4 Egress Path", "egress", "TCP").
+//		AddFunction(sendmsg).
+//		AddFunction(xmit).
+//		Connect("tcp_sendmsg", "ndo_start_xmit", WithCondition("Fast path")).
+//		SetEntry("tcp_sendmsg").
+//		SetExit("ndo_start_xmit").
+//		Build()
+type PathBuilder struct {
+	path     *PacketPath
+	problems []string
+}
+
+// NewPathBuilder starts a path with the given identity.
+func NewPathBuilder(id, name, direction, protocol string) *PathBuilder {
+	return &PathBuilder{
+		path: &PacketPath{
+			ID:         id,
+			Name:       name,
+			Direction:  direction,
+			Protocol:   protocol,
+			Functions:  []KernelFunction{},
+			Edges:      []FunctionEdge{},
+			ExitPoints: []string{},
+		},
+	}
+}
+
+// Family sets the path's network-layer address family (see FamilyIPv4).
+func (b *PathBuilder) Family(family string) *PathBuilder {
+	b.path.Family = family
+	return b
+}
+
+// Description sets the path description.
+func (b *PathBuilder) Description(description string) *PathBuilder {
+	b.path.Description = description
+	return b
+}
+
+// AddFunction appends a function to the path. Adding a second function with
+// the same ID is reported by Build.
+func (b *PathBuilder) AddFunction(fn KernelFunction) *PathBuilder {
+	if b.hasFunction(fn.ID) {
+		b.problems = append(b.problems, fmt.Sprintf("function %q added more than once", fn.ID))
+		return b
+	}
+	b.path.Functions = append(b.path.Functions, fn)
+	return b
+}
+
+// Connect adds an edge from one function to another. Unless overridden with
+// WithOrder, edges from the same function are ordered as they are connected.
+// Both functions must already have been added.
+func (b *PathBuilder) Connect(fromID, toID string, opts ...EdgeOption) *PathBuilder {
+	for _, id := range []string{fromID, toID} {
+		if !b.hasFunction(id) {
+			b.problems = append(b.problems, fmt.Sprintf("edge %s -> %s references unknown function %q", fromID, toID, id))
+			return b
+		}
+	}
+
+	edge := FunctionEdge{From: fromID, To: toID, Order: 1}
+	for _, e := range b.path.Edges {
+		if e.From == fromID {
+			edge.Order++
+		}
+	}
+	for _, opt := range opts {
+		opt(&edge)
+	}
+	b.path.Edges = append(b.path.Edges, edge)
+	return b
+}
+
+// SetEntry sets the entry point and flags the function as such.
+func (b *PathBuilder) SetEntry(id string) *PathBuilder {
+	b.path.EntryPoint = id
+	return b
+}
+
+// SetExit appends exit points and flags the functions as such.
+func (b *PathBuilder) SetExit(ids ...string) *PathBuilder {
+	b.path.ExitPoints = append(b.path.ExitPoints, ids...)
+	return b
+}
+
+// Build finishes the path and validates it. It returns a *ValidationError
+// listing every authoring mistake and structural problem found.
+func (b *PathBuilder) Build() (*PacketPath, error) {
+	problems := append([]string{}, b.problems...)
+
+	if b.path.EntryPoint == "" {
+		problems = append(problems, "no entry point set")
+	} else if !b.hasFunction(b.path.EntryPoint) {
+		problems = append(problems, fmt.Sprintf("entry point %q is not a function of the path", b.path.EntryPoint))
+	}
+	for _, id := range b.path.ExitPoints {
+		if !b.hasFunction(id) {
+			problems = append(problems, fmt.Sprintf("exit point %q is not a function of the path", id))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{PathID: b.path.ID, Problems: problems}
+	}
+
+	for i := range b.path.Functions {
+		fn := &b.path.Functions[i]
+		if fn.ID == b.path.EntryPoint {
+			fn.IsEntryPoint = true
+		}
+		for _, id := range b.path.ExitPoints {
+			if fn.ID == id {
+				fn.IsExitPoint = true
+			}
+		}
+	}
+
+	if err := b.path.Validate(); err != nil {
+		return nil, err
+	}
+	return b.path, nil
+}
+
+// MustBuild is like Build but panics if the path is invalid. It is meant
+// for the package's built-in paths, where an error is a programming mistake.
+func (b *PathBuilder) MustBuild() *PacketPath {
+	path, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return path
+}
+
+// hasFunction reports whether a function with the given ID has been added.
+func (b *PathBuilder) hasFunction(id string) bool {
+	for _, fn := range b.path.Functions {
+		if fn.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package contract
+
+// ByteLocation is where one byte of the payload is at a simulation step.
+type ByteLocation struct {
+	// StepNumber is the 1-based step of the simulation
+	StepNumber int `json:"stepNumber"`
+
+	// FunctionID is the function of the step
+	FunctionID string `json:"functionId"`
+
+	// Offset is the byte's buffer offset from Head, in the sk_buff that
+	// carries it (-1 if the step's sk_buff carries no payload, as an ARP
+	// packet does)
+	Offset int `json:"offset"`
+
+	// DataOffset is the byte's offset from Data: the headers in front of
+	// it plus its position in the payload (-1 with Offset)
+	DataOffset int `json:"dataOffset"`
+
+	// SKBIndex is the sk_buff carrying the byte once the packet was split
+	// by segmentation: 0 is the simulated (first) sk_buff, whose layout
+	// the others share
+	SKBIndex int `json:"skbIndex"`
+
+	// Segment is the packet on the wire that carries the byte, counting
+	// from 0: for a GSO sk_buff, the segment it will be cut into
+	Segment int `json:"segment"`
+}
+
+// TracePayloadByte simulates the path and returns, for every step, where
+// payload byte byteIndex (counting from 0) is in the buffer. On egress the
+// byte stays put while headers are pushed in front of it and Data moves
+// back; on ingress it stays put while headers are pulled. Returns nil if
+// byteIndex is outside the payload.
+//
+// Once segmentation splits the packet, the simulation follows the first
+// sk_buff; a byte in a later one is located in that sk_buff, which has the
+// same layout with its own copy of the headers.
+func (path *PacketPath) TracePayloadByte(byteIndex, bufferSize, payloadSize int) []ByteLocation {
+	if byteIndex < 0 || byteIndex >= payloadSize {
+		return nil
+	}
+	steps := path.SimulateWithOptions(SimulateOptions{BufferSize: bufferSize, PayloadSize: payloadSize})
+
+	locations := make([]ByteLocation, 0, len(steps))
+	for _, step := range steps {
+		locations = append(locations, locatePayloadByte(step, byteIndex, payloadSize))
+	}
+	return locations
+}
+
+// locatePayloadByte locates payload byte byteIndex in the sk_buff of step.
+func locatePayloadByte(step SimulateStep, byteIndex, payloadSize int) ByteLocation {
+	skb := &step.SKBuffState
+	loc := ByteLocation{StepNumber: step.StepNumber, FunctionID: step.Function.ID, Offset: -1, DataOffset: -1}
+
+	length := payloadLen(skb)
+	if length <= 0 {
+		return loc
+	}
+	within := byteIndex
+	if length < payloadSize {
+		// The packet was split into sk_buffs of length payload bytes, but
+		// for the remainder shorter than one segment, split off on its own
+		segSize := length
+		if skb.GSO != nil {
+			segSize = skb.GSO.Size
+		}
+		full := payloadSize / segSize * segSize
+		if byteIndex < full {
+			loc.SKBIndex, within = byteIndex/length, byteIndex%length
+		} else {
+			loc.SKBIndex, within = (full+length-1)/length, byteIndex-full
+		}
+		loc.Segment = loc.SKBIndex
+	}
+	if skb.GSO != nil {
+		loc.Segment = byteIndex / skb.GSO.Size
+	}
+
+	loc.Offset = skb.Tail - length + within - skb.Head
+	loc.DataOffset = skb.Tail - length + within - skb.Data
+	return loc
+}
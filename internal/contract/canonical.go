@@ -0,0 +1,103 @@
+package contract
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Canonical returns a stable, line-oriented text form of the path meant
+// for committing next to the source, so model changes review as readable
+// diffs. The header lines come first, then one line per function sorted by
+// ID and one line per edge sorted by caller, order and callee. Optional
+// attributes are written only when set; free text is quoted.
+func (p *PacketPath) Canonical() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "path %s %q\n", p.ID, p.Name)
+	fmt.Fprintf(&b, "direction %s\n", p.Direction)
+	fmt.Fprintf(&b, "protocol %s\n", p.Protocol)
+	if p.Family != "" {
+		fmt.Fprintf(&b, "family %s\n", p.Family)
+	}
+	fmt.Fprintf(&b, "entry %s\n", p.EntryPoint)
+	exits := append([]string{}, p.ExitPoints...)
+	sort.Strings(exits)
+	fmt.Fprintf(&b, "exits %s\n", strings.Join(exits, " "))
+
+	functions := append([]KernelFunction{}, p.Functions...)
+	sort.Slice(functions, func(i, j int) bool { return functions[i].ID < functions[j].ID })
+	for _, fn := range functions {
+		b.WriteString(canonicalFunction(fn))
+		b.WriteByte('\n')
+	}
+
+	edges := append([]FunctionEdge{}, p.Edges...)
+	sort.Slice(edges, func(i, j int) bool {
+		a, c := edges[i], edges[j]
+		if a.From != c.From {
+			return a.From < c.From
+		}
+		if a.Order != c.Order {
+			return a.Order < c.Order
+		}
+		return a.To < c.To
+	})
+	for _, edge := range edges {
+		fmt.Fprintf(&b, "edge %s -> %s order=%d", edge.From, edge.To, edge.Order)
+		if edge.IsErrorPath {
+			b.WriteString(" error")
+		}
+		if edge.Condition != "" {
+			fmt.Fprintf(&b, " when=%q", edge.Condition)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// canonicalFunction returns the canonical line of a function.
+func canonicalFunction(fn KernelFunction) string {
+	fields := []string{
+		"fn", fn.ID,
+		"layer=" + strings.TrimPrefix(fn.Layer.CSSClass(), "layer-"),
+		fmt.Sprintf("src=%s:%d", fn.SourceFile, fn.LineNumber),
+	}
+	if fn.Name != fn.ID {
+		fields = append(fields, fmt.Sprintf("name=%q", fn.Name))
+	}
+	if fn.ExecutionContext != "" {
+		fields = append(fields, "ctx="+fn.ExecutionContext)
+	}
+	if m := fn.SKBMutation; m != nil {
+		fields = append(fields, fmt.Sprintf("skb=%s:%s:%d", m.Operation, m.HeaderType, m.Size))
+	}
+	if fn.NetfilterHook != nil {
+		fields = append(fields, "nf="+fn.NetfilterHook.Hook)
+	}
+	if fn.BPFHook != nil {
+		fields = append(fields, "bpf="+fn.BPFHook.Type)
+	}
+	if fn.RCUProtected {
+		fields = append(fields, "rcu")
+	}
+	if fn.WrapperOf != "" {
+		fields = append(fields, "wraps="+fn.WrapperOf)
+	}
+	if fn.ConntrackEvent != "" {
+		fields = append(fields, "ct="+fn.ConntrackEvent)
+	}
+	if len(fn.ConfigDeps) > 0 {
+		fields = append(fields, "config="+strings.Join(fn.ConfigDeps, ","))
+	}
+	if fn.EstimatedCostNs != 0 {
+		fields = append(fields, fmt.Sprintf("cost=%dns", fn.EstimatedCostNs))
+	}
+	if fn.IsEntryPoint {
+		fields = append(fields, "entry")
+	}
+	if fn.IsExitPoint {
+		fields = append(fields, "exit")
+	}
+	fields = append(fields, fmt.Sprintf("desc=%q", fn.Description))
+	return strings.Join(fields, " ")
+}
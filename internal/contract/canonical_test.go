@@ -0,0 +1,59 @@
+package contract
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestCanonicalStableAcrossBuilds(t *testing.T) {
+	registry := DefaultRegistry()
+	for _, id := range registry.IDs() {
+		t.Run(id, func(t *testing.T) {
+			first, _ := registry.Build(id)
+			want := first.Canonical()
+			for range 5 {
+				path, _ := registry.Build(id)
+				if got := path.Canonical(); got != want {
+					t.Fatalf("Canonical differs between builds:\n%s\nwant\n%s", got, want)
+				}
+			}
+			if again := first.Canonical(); again != want {
+				t.Fatalf("Canonical differs between calls on the same path:\n%s\nwant\n%s", again, want)
+			}
+		})
+	}
+}
+
+func TestCanonicalIgnoresDeclarationOrder(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, path := range DefaultRegistry().Paths() {
+		t.Run(path.ID, func(t *testing.T) {
+			want := path.Canonical()
+			before := *path
+			before.Functions = append([]KernelFunction{}, path.Functions...)
+			before.Edges = append([]FunctionEdge{}, path.Edges...)
+
+			shuffled := *path
+			shuffled.Functions = append([]KernelFunction{}, path.Functions...)
+			shuffled.Edges = append([]FunctionEdge{}, path.Edges...)
+			shuffled.ExitPoints = append([]string{}, path.ExitPoints...)
+			rng.Shuffle(len(shuffled.Functions), func(i, j int) {
+				shuffled.Functions[i], shuffled.Functions[j] = shuffled.Functions[j], shuffled.Functions[i]
+			})
+			rng.Shuffle(len(shuffled.Edges), func(i, j int) {
+				shuffled.Edges[i], shuffled.Edges[j] = shuffled.Edges[j], shuffled.Edges[i]
+			})
+			rng.Shuffle(len(shuffled.ExitPoints), func(i, j int) {
+				shuffled.ExitPoints[i], shuffled.ExitPoints[j] = shuffled.ExitPoints[j], shuffled.ExitPoints[i]
+			})
+
+			if got := shuffled.Canonical(); got != want {
+				t.Errorf("Canonical depends on declaration order:\n%s\nwant\n%s", got, want)
+			}
+			if !reflect.DeepEqual(*path, before) {
+				t.Error("Canonical modified the path")
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package contract
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Drop reasons recorded on the step where a packet is freed
+const (
+	// DropReasonChecksumError is a packet that failed checksum validation
+	DropReasonChecksumError = "CHECKSUM_ERROR"
+
+	// DropReasonNoSocket is a segment for which no socket exists
+	DropReasonNoSocket = "NO_SOCKET"
+
+	// DropReasonTimeWait is a segment consumed by a TIME_WAIT socket
+	DropReasonTimeWait = "TIME_WAIT"
+
+	// DropReasonXDP is a packet dropped by an XDP program
+	DropReasonXDP = "XDP_DROP"
+
+	// DropReasonSocketFilter is a packet rejected by a socket filter
+	DropReasonSocketFilter = "SOCKET_FILTER"
+)
+
+// Annotation kind for packet drops
+const AnnotationDrop = "drop"
+
+// Checksum states of an sk_buff (skb->ip_summed)
+const (
+	// CsumNone means the checksum has not been verified (receive) or is
+	// already complete in the packet (transmit)
+	CsumNone = "CHECKSUM_NONE"
+
+	// CsumUnnecessary means the checksum was verified, by the NIC or by
+	// the stack, and need not be checked again (receive)
+	CsumUnnecessary = "CHECKSUM_UNNECESSARY"
+
+	// CsumComplete means the NIC summed the whole packet into skb->csum,
+	// so validating the checksum only needs the pseudo-header (receive)
+	CsumComplete = "CHECKSUM_COMPLETE"
+
+	// CsumPartial means the checksum field holds the pseudo-header sum and
+	// the device, or skb_checksum_help, sums the rest from CsumStart
+	// (transmit)
+	CsumPartial = "CHECKSUM_PARTIAL"
+)
+
+// csumFieldOffsets are the offsets of the checksum field in each transport
+// header, the csum_offset of a CHECKSUM_PARTIAL sk_buff.
+var csumFieldOffsets = map[string]int{
+	"tcp": 16,
+	"udp": 6,
+}
+
+// SetCsum sets the checksum state. For CsumPartial the checksum starts at
+// the header of the given protocol, which must be present; other states
+// clear CsumStart and CsumOffset. Returns false if the header is missing.
+func (s *SKBuff) SetCsum(state, protocol string) bool {
+	s.CsumStart, s.CsumOffset = 0, 0
+	if state != CsumPartial {
+		s.CsumState = state
+		return true
+	}
+	header, ok := s.Header(protocol)
+	if !ok {
+		return false
+	}
+	s.CsumState = state
+	s.CsumStart = s.Data - s.Head + header.Offset
+	s.CsumOffset = csumFieldOffsets[protocol]
+	return true
+}
+
+// IsValidCorruptHeader reports whether header names a checksum that can be
+// corrupted: "ip" or "tcp".
+func IsValidCorruptHeader(header string) bool {
+	switch header {
+	case "ip", "tcp":
+		return true
+	}
+	return false
+}
+
+// checksumValidator returns the ID of the function that validates the
+// checksum of the given header.
+func checksumValidator(header string) string {
+	if header == "ip" {
+		return "ip_rcv"
+	}
+	return "tcp_v4_rcv"
+}
+
+// checksumErrorCounters are the SNMP counters incremented when each header's
+// checksum fails, as shown by `nstat` and /proc/net/snmp.
+var checksumErrorCounters = map[string][]string{
+	"ip_rcv":     {"IpInHdrErrors", "IpExtInCsumErrors"},
+	"tcp_v4_rcv": {"TcpInErrs", "TcpInCsumErrors"},
+}
+
+// effectChecksumError models a failed checksum check: the error counters are
+// incremented and the packet is diverted to kfree_skb, still unverified.
+func effectChecksumError(ctx *simContext, step *SimulateStep) {
+	if !ctx.opts.CorruptChecksum || checksumValidator(ctx.opts.CorruptHeader) != step.Function.ID {
+		return
+	}
+	ctx.skb.SetCsum(CsumNone, "")
+
+	counters := checksumErrorCounters[step.Function.ID]
+	for _, name := range counters {
+		ctx.count(name)
+	}
+	ctx.dropReason = DropReasonChecksumError
+
+	step.annotate(AnnotationDrop, fmt.Sprintf(
+		"Checksum validation failed: %s incremented and the packet is dropped before any further processing.",
+		strings.Join(counters, " and ")))
+}
+
+// effectDrop records why the packet reached a drop point.
+func effectDrop(ctx *simContext, step *SimulateStep) {
+	step.DropReason = ctx.dropReason
+}
@@ -0,0 +1,102 @@
+package contract
+
+import "testing"
+
+// stepAt returns the first step in the function with the given ID.
+func stepAt(t *testing.T, steps SimulateSteps, id string) SimulateStep {
+	t.Helper()
+	for _, step := range steps {
+		if step.Function.ID == id {
+			return step
+		}
+	}
+	t.Fatalf("no %s step", id)
+	return SimulateStep{}
+}
+
+func TestSetCsum(t *testing.T) {
+	skb := NewSKBuffWithPayload(2048, 100)
+	if skb.SetCsum(CsumPartial, "tcp") {
+		t.Error("SetCsum(CHECKSUM_PARTIAL) succeeded without a TCP header")
+	}
+	if skb.CsumState != "" {
+		t.Errorf("failed SetCsum set the state to %q", skb.CsumState)
+	}
+
+	skb.Push("tcp", TCPHeaderSize)
+	if !skb.SetCsum(CsumPartial, "tcp") {
+		t.Fatal("SetCsum(CHECKSUM_PARTIAL) failed with a TCP header")
+	}
+	if skb.CsumStart != skb.Data-skb.Head || skb.CsumOffset != 16 {
+		t.Errorf("csum_start %d, csum_offset %d, want %d and 16", skb.CsumStart, skb.CsumOffset, skb.Data-skb.Head)
+	}
+
+	skb.SetCsum(CsumUnnecessary, "tcp")
+	if skb.CsumState != CsumUnnecessary || skb.CsumStart != 0 || skb.CsumOffset != 0 {
+		t.Errorf("state %q, csum_start %d, csum_offset %d, want CHECKSUM_UNNECESSARY without offsets",
+			skb.CsumState, skb.CsumStart, skb.CsumOffset)
+	}
+}
+
+func TestEgressChecksumPartial(t *testing.T) {
+	tests := []struct {
+		path       *PacketPath
+		function   string
+		protocol   string
+		csumOffset int
+	}{
+		{BuildTCPIPv4EgressPath(), "__tcp_transmit_skb", "tcp", 16},
+		{BuildUDPIPv4EgressPath(), "udp_send_skb", "udp", 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path.ID, func(t *testing.T) {
+			steps := tt.path.SimulateWithOptions(DefaultSimulateOptions())
+
+			skb := stepAt(t, steps, tt.function).SKBuffState
+			header, ok := skb.Header(tt.protocol)
+			if !ok {
+				t.Fatalf("no %s header at %s", tt.protocol, tt.function)
+			}
+			if skb.CsumState != CsumPartial {
+				t.Errorf("checksum state at %s = %q, want %s", tt.function, skb.CsumState, CsumPartial)
+			}
+			csumStart := skb.Data - skb.Head + header.Offset
+			if skb.CsumStart != csumStart || skb.CsumOffset != tt.csumOffset {
+				t.Errorf("csum_start %d, csum_offset %d, want %d and %d", skb.CsumStart, skb.CsumOffset, csumStart, tt.csumOffset)
+			}
+
+			// Pushing the lower headers moves Data, not the transport header
+			last := steps[len(steps)-1].SKBuffState
+			if last.CsumState != CsumPartial || last.CsumStart != csumStart || last.CsumOffset != tt.csumOffset {
+				t.Errorf("at the driver: state %q, csum_start %d, csum_offset %d, want unchanged",
+					last.CsumState, last.CsumStart, last.CsumOffset)
+			}
+		})
+	}
+}
+
+func TestIngressChecksumVerified(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      SimulateOptions
+		wantState string
+	}{
+		{"valid", DefaultSimulateOptions(), CsumUnnecessary},
+		{"corrupt tcp", SimulateOptions{BufferSize: 2048, PayloadSize: 1000, CorruptChecksum: true}, CsumNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps := BuildTCPIPv4IngressPath().SimulateWithOptions(tt.opts)
+
+			if got := stepAt(t, steps, "ip_rcv").SKBuffState.CsumState; got != "" {
+				t.Errorf("checksum state before tcp_v4_rcv = %q, want unverified", got)
+			}
+			if got := stepAt(t, steps, "tcp_v4_rcv").SKBuffState.CsumState; got != tt.wantState {
+				t.Errorf("checksum state at tcp_v4_rcv = %q, want %q", got, tt.wantState)
+			}
+			if got := steps[len(steps)-1].SKBuffState.CsumState; got != tt.wantState {
+				t.Errorf("checksum state at %s = %q, want %q", steps[len(steps)-1].Function.ID, got, tt.wantState)
+			}
+		})
+	}
+}
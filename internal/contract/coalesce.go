@@ -0,0 +1,47 @@
+package contract
+
+import "fmt"
+
+// AnnotationCoalesce marks a received segment merged into the receive
+// queue's tail sk_buff.
+const AnnotationCoalesce = "coalesce"
+
+// coalescing reports whether the received data is merged into the tail
+// sk_buff of the receive queue instead of being queued on its own.
+func (ctx *simContext) coalescing() bool {
+	if !ctx.opts.RecvCoalesce {
+		return false
+	}
+	limit := ctx.opts.RecvBufferSize
+	return limit <= 0 || ctx.opts.PayloadSize <= limit
+}
+
+// effectTryCoalesce models tcp_try_coalesce merging the segment into the
+// previous in-order segment at the tail of the receive queue. The tail is
+// modeled as an sk_buff of the same shape as the incoming one. Data that
+// fits its tailroom is copied; otherwise the incoming sk_buff's data pages
+// are stolen as fragments. Either way the incoming sk_buff's metadata is
+// freed at once.
+func effectTryCoalesce(ctx *simContext, step *SimulateStep) {
+	tail := ctx.skb.Clone()
+	payload := ctx.opts.PayloadSize
+	tailroom := tail.End - tail.Tail
+
+	// skb_try_coalesce reports the truesize the tail grows by
+	delta, how := 0, fmt.Sprintf("the %d bytes fit the tail's %d bytes of tailroom and are copied into it", payload, tailroom)
+	if payload > tailroom {
+		delta = payload
+		how = fmt.Sprintf("the %d bytes do not fit the tail's %d bytes of tailroom, so the incoming data is attached to it as a page fragment", payload, tailroom)
+	}
+
+	mem := ctx.socketMemory()
+	mem.RmemAlloc += tail.TrueSize() + delta
+	ctx.count("TCPRcvCoalesce")
+
+	step.annotate(AnnotationCoalesce, fmt.Sprintf(
+		"The receive queue's tail sk_buff holds the previous in-order segment: %s. "+
+			"The incoming sk_buff is freed (kfree_skb_partial), so the queue holds 1 sk_buff for 2 segments. "+
+			"sk_rmem_alloc grows by only %d bytes instead of a full truesize (%d) and now holds %d bytes including the tail. "+
+			"This is why the number of sk_buffs in the receive queue is usually lower than the number of segments received (TCPRcvCoalesce).",
+		how, delta, ctx.skb.TrueSize(), mem.RmemAlloc))
+}
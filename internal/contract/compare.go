@@ -0,0 +1,96 @@
+package contract
+
+import "slices"
+
+// PathComparison aligns the primary paths of two packet paths by layer,
+// showing which functions both run and where they diverge.
+type PathComparison struct {
+	// A and B are the IDs of the compared paths
+	A string `json:"a"`
+	B string `json:"b"`
+
+	// Layers aligns the functions of each layer either path passes
+	// through, in rendering order
+	Layers []LayerAlignment `json:"layers"`
+
+	// Shared are the functions on both primary paths, in the order of A
+	Shared []string `json:"shared"`
+
+	// ConvergeAt is the function of A from which the rest of A's primary
+	// path is shared with B ("" if the paths never converge)
+	ConvergeAt string `json:"convergeAt,omitempty"`
+}
+
+// LayerAlignment lists the functions of one layer on each primary path.
+type LayerAlignment struct {
+	// Layer is the aligned layer
+	Layer Layer `json:"layer"`
+
+	// A and B are the functions of the layer on each path, in path order
+	A []string `json:"a"`
+	B []string `json:"b"`
+
+	// Common are the functions of the layer on both paths, in the order
+	// of A
+	Common []string `json:"common"`
+
+	// Shared is true if both paths run the same functions in this layer:
+	// the layer is protocol-agnostic for this pair
+	Shared bool `json:"shared"`
+}
+
+// ComparePaths aligns the primary paths of a and b by layer. Functions are
+// matched by ID, so paths derived from a common path (or sharing the lower
+// stack, like TCP and UDP egress) line up where they run the same code.
+func ComparePaths(a, b *PacketPath) PathComparison {
+	primaryA, primaryB := a.primaryPath(), b.primaryPath()
+
+	inB := make(map[string]bool, len(primaryB))
+	for _, fn := range primaryB {
+		inB[fn.ID] = true
+	}
+
+	cmp := PathComparison{A: a.ID, B: b.ID, Shared: []string{}}
+	for _, fn := range primaryA {
+		if inB[fn.ID] {
+			cmp.Shared = append(cmp.Shared, fn.ID)
+		}
+	}
+	for i := len(primaryA) - 1; i >= 0 && inB[primaryA[i].ID]; i-- {
+		cmp.ConvergeAt = primaryA[i].ID
+	}
+
+	for _, layer := range allLayers {
+		align := LayerAlignment{Layer: layer, A: layerFunctions(primaryA, layer), B: layerFunctions(primaryB, layer)}
+		if len(align.A) == 0 && len(align.B) == 0 {
+			continue
+		}
+		align.Common = []string{}
+		for _, id := range align.A {
+			if slices.Contains(align.B, id) {
+				align.Common = append(align.Common, id)
+			}
+		}
+		align.Shared = slices.Equal(align.A, align.B)
+		cmp.Layers = append(cmp.Layers, align)
+	}
+	return cmp
+}
+
+// CompareTCPUDP compares the TCP and UDP egress paths: they differ in the
+// transport layer and in how they enter IP, converge at ip_local_out and
+// share everything below it.
+func CompareTCPUDP() PathComparison {
+	return ComparePaths(BuildTCPIPv4EgressPath(), BuildUDPIPv4EgressPath())
+}
+
+// layerFunctions returns the IDs of the functions of path in layer.
+func layerFunctions(path []*KernelFunction, layer Layer) []string {
+	ids := []string{}
+	for _, fn := range path {
+		if fn.Layer == layer {
+			ids = append(ids, fn.ID)
+		}
+	}
+	return ids
+}
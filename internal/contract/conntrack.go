@@ -1,5 +1,10 @@
 package contract
 
+import (
+	"fmt"
+	"maps"
+)
+
 // ConntrackState represents a connection tracking state.
 // Linux conntrack maintains state for stateful firewalling and NAT.
 type ConntrackState string
@@ -34,6 +39,19 @@ const (
 	ConntrackClosed ConntrackState = "CLOSED"
 )
 
+// allConntrackStates lists every conntrack state in lifecycle order.
+var allConntrackStates = []ConntrackState{
+	ConntrackNew,
+	ConntrackSynSent,
+	ConntrackSynRecv,
+	ConntrackEstablished,
+	ConntrackFinWait,
+	ConntrackCloseWait,
+	ConntrackLastAck,
+	ConntrackTimeWait,
+	ConntrackClosed,
+}
+
 // ConntrackEntry represents the current connection tracking state
 type ConntrackEntry struct {
 	// State is the current conntrack state
@@ -59,10 +77,210 @@ var ConntrackStateDescriptions = map[ConntrackState]string{
 	ConntrackClosed:      "Connection fully closed. Entry will be removed.",
 }
 
-// NewConntrackEntry creates a conntrack entry with description
+// conntrackTimeouts are the kernel's default timeouts for each TCP conntrack
+// state in seconds (nf_conntrack_tcp_timeout_*). A packet moving the entry
+// to a state resets its timeout to the state's value. NEW has none: an
+// entry is created straight into a TCP state.
+var conntrackTimeouts = map[ConntrackState]int{
+	ConntrackSynSent:     120,
+	ConntrackSynRecv:     60,
+	ConntrackEstablished: 432000,
+	ConntrackFinWait:     120,
+	ConntrackCloseWait:   60,
+	ConntrackLastAck:     30,
+	ConntrackTimeWait:    120,
+	ConntrackClosed:      10,
+}
+
+// DefaultConntrackTimeouts returns a copy of the kernel's default timeout
+// of each TCP conntrack state in seconds.
+//
+// The map itself is not exported: every simulation reads it, concurrently
+// in the server, so a caller writing to it would race with them and change
+// the timeouts of all later ones. Custom timeouts go through
+// NewConntrackEntryWithTimeout instead.
+func DefaultConntrackTimeouts() map[ConntrackState]int {
+	return maps.Clone(conntrackTimeouts)
+}
+
+// NewConntrackEntry creates a conntrack entry with description and the
+// state's default timeout
 func NewConntrackEntry(state ConntrackState) *ConntrackEntry {
+	return NewConntrackEntryWithTimeout(state, conntrackTimeouts[state])
+}
+
+// NewConntrackEntryWithTimeout creates a conntrack entry with description
+// and a custom timeout in seconds, as set by the
+// net.netfilter.nf_conntrack_tcp_timeout_* sysctls
+func NewConntrackEntryWithTimeout(state ConntrackState, timeout int) *ConntrackEntry {
 	return &ConntrackEntry{
 		State:       state,
 		Description: ConntrackStateDescriptions[state],
+		Timeout:     timeout,
+	}
+}
+
+// Conntrack events: the TCP segments, by flag and direction, and the
+// timeout that move an entry between states. The original direction is the
+// connection's first packet's; the reply direction is the other. A segment
+// with several flags is classified by the first of RST, SYN-ACK, SYN, FIN
+// and ACK it carries, as get_conntrack_index does.
+const (
+	ConntrackEventSYN         = "syn"
+	ConntrackEventSYNACK      = "syn_ack"
+	ConntrackEventFIN         = "fin"
+	ConntrackEventACK         = "ack"
+	ConntrackEventRST         = "rst"
+	ConntrackEventReplySYN    = "reply_syn"
+	ConntrackEventReplySYNACK = "reply_syn_ack"
+	ConntrackEventReplyFIN    = "reply_fin"
+	ConntrackEventReplyACK    = "reply_ack"
+	ConntrackEventReplyRST    = "reply_rst"
+
+	// ConntrackEventTimeout is the entry's timeout expiring, which removes
+	// it
+	ConntrackEventTimeout = "timeout"
+)
+
+// allConntrackEvents lists every conntrack event.
+var allConntrackEvents = []string{
+	ConntrackEventSYN,
+	ConntrackEventSYNACK,
+	ConntrackEventFIN,
+	ConntrackEventACK,
+	ConntrackEventRST,
+	ConntrackEventReplySYN,
+	ConntrackEventReplySYNACK,
+	ConntrackEventReplyFIN,
+	ConntrackEventReplyACK,
+	ConntrackEventReplyRST,
+	ConntrackEventTimeout,
+}
+
+// conntrackEventDescriptions explain each event in annotations.
+var conntrackEventDescriptions = map[string]string{
+	ConntrackEventSYN:         "a SYN in the original direction",
+	ConntrackEventSYNACK:      "a SYN-ACK in the original direction",
+	ConntrackEventFIN:         "a FIN in the original direction",
+	ConntrackEventACK:         "an ACK in the original direction",
+	ConntrackEventRST:         "a RST in the original direction",
+	ConntrackEventReplySYN:    "a SYN in the reply direction",
+	ConntrackEventReplySYNACK: "a SYN-ACK in the reply direction",
+	ConntrackEventReplyFIN:    "a FIN in the reply direction",
+	ConntrackEventReplyACK:    "an ACK in the reply direction",
+	ConntrackEventReplyRST:    "a RST in the reply direction",
+	ConntrackEventTimeout:     "its timeout expiring",
+}
+
+// IsValidConntrackEvent reports whether event is a known conntrack event.
+func IsValidConntrackEvent(event string) bool {
+	_, ok := conntrackEventDescriptions[event]
+	return ok
+}
+
+// tcpConntracks is the TCP conntrack state table (tcp_conntracks in
+// nf_conntrack_proto_tcp.c), without the states this model does not have:
+// SYN_SENT2, for a simultaneous open, is folded into SYN_SENT. NEW stands
+// for an entry not created yet. A state missing for an event marks the
+// segment invalid or ignored there.
+var tcpConntracks = map[string]map[ConntrackState]ConntrackState{
+	ConntrackEventSYN: {
+		ConntrackNew:      ConntrackSynSent,
+		ConntrackSynSent:  ConntrackSynSent,
+		ConntrackTimeWait: ConntrackSynSent,
+		ConntrackClosed:   ConntrackSynSent,
+	},
+	ConntrackEventSYNACK: {
+		ConntrackSynRecv: ConntrackSynRecv,
+	},
+	ConntrackEventFIN: {
+		ConntrackSynRecv:     ConntrackFinWait,
+		ConntrackEstablished: ConntrackFinWait,
+		ConntrackFinWait:     ConntrackLastAck,
+		ConntrackCloseWait:   ConntrackLastAck,
+		ConntrackLastAck:     ConntrackLastAck,
+		ConntrackTimeWait:    ConntrackTimeWait,
+		ConntrackClosed:      ConntrackClosed,
+	},
+	ConntrackEventACK: {
+		ConntrackNew:         ConntrackEstablished,
+		ConntrackSynRecv:     ConntrackEstablished,
+		ConntrackEstablished: ConntrackEstablished,
+		ConntrackFinWait:     ConntrackCloseWait,
+		ConntrackCloseWait:   ConntrackCloseWait,
+		ConntrackLastAck:     ConntrackTimeWait,
+		ConntrackTimeWait:    ConntrackTimeWait,
+		ConntrackClosed:      ConntrackClosed,
+	},
+	ConntrackEventReplySYN: {
+		ConntrackSynSent:  ConntrackSynSent,
+		ConntrackTimeWait: ConntrackSynSent,
+	},
+	ConntrackEventReplySYNACK: {
+		ConntrackSynSent: ConntrackSynRecv,
+	},
+	ConntrackEventReplyFIN: {
+		ConntrackSynRecv:     ConntrackFinWait,
+		ConntrackEstablished: ConntrackFinWait,
+		ConntrackFinWait:     ConntrackLastAck,
+		ConntrackCloseWait:   ConntrackLastAck,
+		ConntrackLastAck:     ConntrackLastAck,
+		ConntrackTimeWait:    ConntrackTimeWait,
+		ConntrackClosed:      ConntrackClosed,
+	},
+	ConntrackEventReplyACK: {
+		ConntrackSynRecv:     ConntrackSynRecv,
+		ConntrackEstablished: ConntrackEstablished,
+		ConntrackFinWait:     ConntrackCloseWait,
+		ConntrackCloseWait:   ConntrackCloseWait,
+		ConntrackLastAck:     ConntrackTimeWait,
+		ConntrackTimeWait:    ConntrackTimeWait,
+		ConntrackClosed:      ConntrackClosed,
+	},
+}
+
+// ConntrackMachine is the TCP conntrack state machine: it advances an
+// entry's state with each segment the hooks see.
+type ConntrackMachine struct{}
+
+// Transition returns the state an entry in the current state moves to on
+// event. A RST closes an entry from any state it has been created in, and
+// a timeout from any state. An event invalid or ignored in the current
+// state, such as an ACK while the SYN is unanswered, leaves it unchanged,
+// as conntrack does with a segment it marks invalid.
+func (ConntrackMachine) Transition(current ConntrackState, event string) ConntrackState {
+	switch event {
+	case ConntrackEventRST, ConntrackEventReplyRST:
+		if current == ConntrackNew {
+			return current
+		}
+		return ConntrackClosed
+	case ConntrackEventTimeout:
+		return ConntrackClosed
+	}
+	if next, ok := tcpConntracks[event][current]; ok {
+		return next
+	}
+	return current
+}
+
+// setConntrack moves the simulated conntrack entry to state, with the
+// state's default timeout, and records it on the step.
+func (ctx *simContext) setConntrack(step *SimulateStep, state ConntrackState) {
+	ctx.conntrack = NewConntrackEntry(state)
+	step.ConntrackState = ctx.conntrack
+}
+
+// conntrackEvent advances the simulated conntrack entry on event at the
+// step's function.
+func (ctx *simContext) conntrackEvent(step *SimulateStep, event string) {
+	current := ctx.conntrack.State
+	next := ConntrackMachine{}.Transition(current, event)
+	if next == current {
+		return
 	}
+	ctx.setConntrack(step, next)
+	step.annotate(AnnotationConntrack, fmt.Sprintf(
+		"Conntrack moves the entry from %s to %s on %s; it is removed after %d seconds without further packets.",
+		current, next, conntrackEventDescriptions[event], ctx.conntrack.Timeout))
 }
@@ -0,0 +1,122 @@
+package contract
+
+import (
+	"maps"
+	"testing"
+)
+
+// Short state names for the transition table, as in nf_conntrack_proto_tcp.c
+const (
+	sNO = ConntrackNew
+	sSS = ConntrackSynSent
+	sSR = ConntrackSynRecv
+	sES = ConntrackEstablished
+	sFW = ConntrackFinWait
+	sCW = ConntrackCloseWait
+	sLA = ConntrackLastAck
+	sTW = ConntrackTimeWait
+	sCL = ConntrackClosed
+)
+
+// wantTransitions is the expected next state for each event, by current
+// state in the order of allConntrackStates. An event invalid in a state
+// leaves it unchanged.
+var wantTransitions = map[string][]ConntrackState{
+	//                         sNO  sSS  sSR  sES  sFW  sCW  sLA  sTW  sCL
+	ConntrackEventSYN:         {sSS, sSS, sSR, sES, sFW, sCW, sLA, sSS, sSS},
+	ConntrackEventSYNACK:      {sNO, sSS, sSR, sES, sFW, sCW, sLA, sTW, sCL},
+	ConntrackEventFIN:         {sNO, sSS, sFW, sFW, sLA, sLA, sLA, sTW, sCL},
+	ConntrackEventACK:         {sES, sSS, sES, sES, sCW, sCW, sTW, sTW, sCL},
+	ConntrackEventRST:         {sNO, sCL, sCL, sCL, sCL, sCL, sCL, sCL, sCL},
+	ConntrackEventReplySYN:    {sNO, sSS, sSR, sES, sFW, sCW, sLA, sSS, sCL},
+	ConntrackEventReplySYNACK: {sNO, sSR, sSR, sES, sFW, sCW, sLA, sTW, sCL},
+	ConntrackEventReplyFIN:    {sNO, sSS, sFW, sFW, sLA, sLA, sLA, sTW, sCL},
+	ConntrackEventReplyACK:    {sNO, sSS, sSR, sES, sCW, sCW, sTW, sTW, sCL},
+	ConntrackEventReplyRST:    {sNO, sCL, sCL, sCL, sCL, sCL, sCL, sCL, sCL},
+	ConntrackEventTimeout:     {sCL, sCL, sCL, sCL, sCL, sCL, sCL, sCL, sCL},
+}
+
+func TestConntrackTransitions(t *testing.T) {
+	if len(wantTransitions) != len(allConntrackEvents) {
+		t.Fatalf("table covers %d events, want all %d", len(wantTransitions), len(allConntrackEvents))
+	}
+	for _, event := range allConntrackEvents {
+		row, ok := wantTransitions[event]
+		if !ok || len(row) != len(allConntrackStates) {
+			t.Fatalf("table row for %s does not cover every state", event)
+		}
+		for i, current := range allConntrackStates {
+			if got := (ConntrackMachine{}).Transition(current, event); got != row[i] {
+				t.Errorf("Transition(%s, %s) = %s, want %s", current, event, got, row[i])
+			}
+		}
+	}
+}
+
+func TestConntrackTransitionUnknownEvent(t *testing.T) {
+	for _, current := range allConntrackStates {
+		if got := (ConntrackMachine{}).Transition(current, "bogus"); got != current {
+			t.Errorf("Transition(%s, bogus) = %s, want the state unchanged", current, got)
+		}
+	}
+}
+
+func TestConntrackEventAnnotatesChanges(t *testing.T) {
+	ctx := &simContext{conntrack: NewConntrackEntry(ConntrackSynRecv)}
+
+	var step SimulateStep
+	ctx.conntrackEvent(&step, ConntrackEventACK)
+	if ctx.conntrack.State != ConntrackEstablished || step.ConntrackState != ctx.conntrack {
+		t.Fatalf("after ack: entry %+v, step %+v, want ESTABLISHED on both", ctx.conntrack, step.ConntrackState)
+	}
+	if len(step.Annotations) != 1 || step.Annotations[0].Kind != AnnotationConntrack {
+		t.Errorf("annotations = %+v, want one conntrack annotation", step.Annotations)
+	}
+
+	// An invalid segment leaves the entry and the step alone
+	var invalid SimulateStep
+	ctx.conntrackEvent(&invalid, ConntrackEventSYNACK)
+	if ctx.conntrack.State != ConntrackEstablished || invalid.ConntrackState != nil || len(invalid.Annotations) != 0 {
+		t.Errorf("invalid syn_ack changed the entry to %s or annotated the step", ctx.conntrack.State)
+	}
+}
+
+func TestDefaultConntrackTimeouts(t *testing.T) {
+	// The defaults of the nf_conntrack_tcp_timeout_* sysctls, from
+	// tcp_timeouts in nf_conntrack_proto_tcp.c
+	const (
+		secs = 1
+		mins = 60 * secs
+		days = 24 * 60 * mins
+	)
+	want := map[ConntrackState]int{
+		ConntrackSynSent:     2 * mins,
+		ConntrackSynRecv:     60 * secs,
+		ConntrackEstablished: 5 * days,
+		ConntrackFinWait:     2 * mins,
+		ConntrackCloseWait:   60 * secs,
+		ConntrackLastAck:     30 * secs,
+		ConntrackTimeWait:    2 * mins,
+		ConntrackClosed:      10 * secs,
+	}
+
+	timeouts := DefaultConntrackTimeouts()
+	if !maps.Equal(timeouts, want) {
+		t.Errorf("DefaultConntrackTimeouts() = %v, want %v", timeouts, want)
+	}
+	for _, state := range allConntrackStates {
+		if got := NewConntrackEntry(state).Timeout; got != want[state] {
+			t.Errorf("NewConntrackEntry(%s).Timeout = %d, want %d", state, got, want[state])
+		}
+	}
+
+	// The result is a copy: changing it leaves the defaults alone
+	timeouts[ConntrackEstablished] = 1
+	delete(timeouts, ConntrackClosed)
+	if got := NewConntrackEntry(ConntrackEstablished).Timeout; got != 5*days {
+		t.Errorf("changing the returned map changed the ESTABLISHED timeout to %d", got)
+	}
+	if got := DefaultConntrackTimeouts(); !maps.Equal(got, want) {
+		t.Errorf("DefaultConntrackTimeouts() after a change to a copy = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,79 @@
+package contract
+
+// Execution contexts a kernel function can run in
+const (
+	// ContextProcess is process context: a system call or kernel thread on
+	// behalf of a task. The code may sleep (wait for memory, socket locks).
+	ContextProcess = "process"
+
+	// ContextSoftIRQ is software interrupt context (NET_RX_SOFTIRQ,
+	// NET_TX_SOFTIRQ). It runs deferred work after hardware interrupts,
+	// may not sleep, and batches packets up to the NAPI budget.
+	ContextSoftIRQ = "softirq"
+
+	// ContextHardIRQ is hardware interrupt context: the driver's interrupt
+	// handler. Interrupts are masked, so it does the minimum and defers the
+	// rest to a softirq.
+	ContextHardIRQ = "hardirq"
+)
+
+// contextTransitionReasons explains how the kernel moves between contexts,
+// keyed by [from, to].
+var contextTransitionReasons = map[[2]string]string{
+	{ContextHardIRQ, ContextSoftIRQ}: "The interrupt handler queues the packet on a per-CPU list and raises NET_RX_SOFTIRQ; processing continues when the hard IRQ returns, with interrupts enabled again.",
+	{ContextSoftIRQ, ContextProcess}: "The softirq cannot take a socket lock owned by a task (it may not sleep), so the packet waits on the socket backlog until the task releases the lock and processes it in process context.",
+	{ContextHardIRQ, ContextProcess}: "Work deferred from the interrupt handler runs later in a kernel thread.",
+}
+
+// contextRank orders contexts from most to least restrictive. Interrupt
+// contexts are only entered by the hardware or on irq/bh exit, never by a
+// function call, so an edge into a more restrictive context means the
+// callee runs in its caller's context (e.g. tcp_v4_do_rcv called from
+// __release_sock runs in process context).
+var contextRank = map[string]int{
+	ContextHardIRQ: 2,
+	ContextSoftIRQ: 1,
+	ContextProcess: 0,
+}
+
+// ContextTransition is an edge of the path whose endpoints run in different
+// execution contexts.
+type ContextTransition struct {
+	// From and To are the function IDs of the edge
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	// FromContext and ToContext are the execution contexts on either side
+	FromContext string `json:"fromContext"`
+	ToContext   string `json:"toContext"`
+
+	// Reason explains what causes the context switch
+	Reason string `json:"reason"`
+}
+
+// ContextTransitions returns the edges that cross from one execution context
+// to another, in edge order. Only edges that defer work to a less
+// restrictive context are transitions (see contextRank); functions without
+// an ExecutionContext are skipped.
+func (p *PacketPath) ContextTransitions() []ContextTransition {
+	byID := make(map[string]string, len(p.Functions))
+	for _, fn := range p.Functions {
+		byID[fn.ID] = fn.ExecutionContext
+	}
+
+	transitions := []ContextTransition{}
+	for _, edge := range p.Edges {
+		from, to := byID[edge.From], byID[edge.To]
+		if from == "" || to == "" || contextRank[to] >= contextRank[from] {
+			continue
+		}
+		transitions = append(transitions, ContextTransition{
+			From:        edge.From,
+			To:          edge.To,
+			FromContext: from,
+			ToContext:   to,
+			Reason:      contextTransitionReasons[[2]string{from, to}],
+		})
+	}
+	return transitions
+}
@@ -0,0 +1,189 @@
+// Package contracttest provides small, controllable packet paths for
+// testing code that consumes the contract, such as graph algorithms. The
+// built-in kernel paths are large and change as the model grows; fixtures
+// from this package have a known shape.
+//
+//	path := contracttest.NewTestPath(contracttest.WithLength(4), contracttest.WithCycle())
+package contracttest
+
+import (
+	"fmt"
+
+	"github.com/rzkiamr/linux-packet-visualizer/internal/contract"
+)
+
+// TestPathOption customizes a path built by NewTestPath.
+type TestPathOption func(*testPathConfig)
+
+// testPathConfig is the shape of a fixture path.
+type testPathConfig struct {
+	id        string
+	direction string
+	length    int
+	branches  int
+	cycle     bool
+	hooks     bool
+	dropPoint bool
+}
+
+// WithID sets the path ID (default "test_path").
+func WithID(id string) TestPathOption {
+	return func(c *testPathConfig) {
+		c.id = id
+	}
+}
+
+// WithDirection sets the path direction (default "egress").
+func WithDirection(direction string) TestPathOption {
+	return func(c *testPathConfig) {
+		c.direction = direction
+	}
+}
+
+// WithLength sets the number of functions on the main line, from the entry
+// point fn0 to the exit point fn<n-1> (default 3, minimum 1).
+func WithLength(n int) TestPathOption {
+	return func(c *testPathConfig) {
+		c.length = n
+	}
+}
+
+// WithBranches adds n alternative functions branch0..branch<n-1> called
+// from the entry point, each rejoining the main line at the exit point.
+// The main line stays the default edge. On a path of a single function the
+// entry point cannot also be the exit point, so the branches end the path
+// themselves and are its exit points.
+func WithBranches(n int) TestPathOption {
+	return func(c *testPathConfig) {
+		c.branches = n
+	}
+}
+
+// WithCycle adds an edge from the function before the exit point back to
+// the entry point. It has no effect on paths shorter than 2 functions.
+func WithCycle() TestPathOption {
+	return func(c *testPathConfig) {
+		c.cycle = true
+	}
+}
+
+// WithHooks attaches the hooks a packet meets first and last in the path's
+// direction: on egress the netfilter OUTPUT hook at the entry point and the
+// TC egress BPF hook at the exit point, on ingress the TC ingress BPF hook
+// at the entry point and the netfilter INPUT hook at the exit point.
+func WithHooks() TestPathOption {
+	return func(c *testPathConfig) {
+		c.hooks = true
+	}
+}
+
+// WithDropPoint adds an error edge from the entry point to kfree_skb.
+func WithDropPoint() TestPathOption {
+	return func(c *testPathConfig) {
+		c.dropPoint = true
+	}
+}
+
+// layers are assigned to the main line in order, top to bottom on egress
+// and bottom to top on ingress, so that layer-based code sees a realistic
+// progression.
+var layers = []contract.Layer{
+	contract.LayerSocket,
+	contract.LayerTransport,
+	contract.LayerNetwork,
+	contract.LayerDataLink,
+	contract.LayerDriver,
+}
+
+// NewTestPath builds a valid fixture path. Without options it is a linear
+// path of three functions, fn0 -> fn1 -> fn2. It panics if the options
+// describe an invalid path, as that is a mistake in the test.
+func NewTestPath(opts ...TestPathOption) *contract.PacketPath {
+	c := testPathConfig{id: "test_path", direction: "egress", length: 3}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.length < 1 {
+		c.length = 1
+	}
+
+	ingress := c.direction == "ingress"
+	b := contract.NewPathBuilder(c.id, "Test Path "+c.id, c.direction, "TEST")
+	for i := 0; i < c.length; i++ {
+		fn := testFunction(fmt.Sprintf("fn%d", i), i, ingress)
+		if c.hooks && i == 0 {
+			if ingress {
+				fn.BPFHook = contract.NewTCIngressHook()
+			} else {
+				fn.NetfilterHook = contract.NewOutputHook()
+			}
+		}
+		if c.hooks && i == c.length-1 {
+			if ingress {
+				fn.NetfilterHook = contract.NewInputHook()
+			} else {
+				fn.BPFHook = contract.NewTCEgressHook()
+			}
+		}
+		b.AddFunction(fn)
+	}
+	for i := 0; i < c.branches; i++ {
+		b.AddFunction(testFunction(fmt.Sprintf("branch%d", i), 1, ingress))
+	}
+	if c.dropPoint {
+		b.AddFunction(contract.KernelFunction{
+			ID:               "kfree_skb",
+			Name:             "kfree_skb",
+			Layer:            contract.LayerNetwork,
+			SourceFile:       "net/core/skbuff.c",
+			LineNumber:       1,
+			ExecutionContext: contract.ContextProcess,
+			Description:      "Test drop point.",
+			SKBMutation:      contract.NewFreeMutation("Free sk_buff (test drop)"),
+		})
+	}
+
+	entry, exit := "fn0", fmt.Sprintf("fn%d", c.length-1)
+	exits := []string{exit}
+	if c.length == 1 && c.branches > 0 {
+		exits = exits[:0]
+	}
+	for i := 0; i+1 < c.length; i++ {
+		b.Connect(fmt.Sprintf("fn%d", i), fmt.Sprintf("fn%d", i+1))
+	}
+	for i := 0; i < c.branches; i++ {
+		id := fmt.Sprintf("branch%d", i)
+		b.Connect(entry, id, contract.WithCondition("Test branch "+id))
+		if c.length == 1 {
+			exits = append(exits, id)
+		} else {
+			b.Connect(id, exit)
+		}
+	}
+	if c.dropPoint {
+		b.Connect(entry, "kfree_skb", contract.WithCondition("Test drop"), contract.AsErrorPath())
+	}
+	if c.cycle && c.length >= 2 {
+		b.Connect(fmt.Sprintf("fn%d", c.length-2), entry, contract.WithCondition("Test loop"))
+	}
+
+	return b.SetEntry(entry).SetExit(exits...).MustBuild()
+}
+
+// testFunction returns a fixture function whose layer follows its position
+// on the path in its direction.
+func testFunction(id string, position int, ingress bool) contract.KernelFunction {
+	layer := layers[position%len(layers)]
+	if ingress {
+		layer = layers[len(layers)-1-position%len(layers)]
+	}
+	return contract.KernelFunction{
+		ID:               id,
+		Name:             id,
+		Layer:            layer,
+		SourceFile:       "test/" + id + ".c",
+		LineNumber:       position + 1,
+		ExecutionContext: contract.ContextProcess,
+		Description:      "Test function " + id + ".",
+	}
+}
@@ -0,0 +1,141 @@
+package contracttest
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/rzkiamr/linux-packet-visualizer/internal/contract"
+)
+
+// functionIDs returns the IDs of the path's functions in order.
+func functionIDs(path *contract.PacketPath) []string {
+	var ids []string
+	for _, fn := range path.Functions {
+		ids = append(ids, fn.ID)
+	}
+	return ids
+}
+
+func TestNewTestPathLinear(t *testing.T) {
+	path := NewTestPath()
+
+	if err := path.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := functionIDs(path), []string{"fn0", "fn1", "fn2"}; !slices.Equal(got, want) {
+		t.Errorf("functions = %v, want %v", got, want)
+	}
+	if path.EntryPoint != "fn0" || !slices.Equal(path.ExitPoints, []string{"fn2"}) {
+		t.Errorf("entry %q, exits %v, want fn0 and [fn2]", path.EntryPoint, path.ExitPoints)
+	}
+	if len(path.Edges) != 2 {
+		t.Errorf("%d edges, want 2", len(path.Edges))
+	}
+	if cycles := path.Cycles(); len(cycles) != 0 {
+		t.Errorf("linear path has cycles %v", cycles)
+	}
+}
+
+func TestNewTestPathBranches(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []TestPathOption
+		wantExits []string
+	}{
+		{"rejoin exit", []TestPathOption{WithLength(3), WithBranches(2)}, []string{"fn2"}},
+		{"two functions", []TestPathOption{WithLength(2), WithBranches(1)}, []string{"fn1"}},
+		{"single function", []TestPathOption{WithLength(1), WithBranches(2)}, []string{"branch0", "branch1"}},
+		{"single function with drop", []TestPathOption{WithLength(1), WithBranches(1), WithDropPoint()}, []string{"branch0"}},
+		{"zero length", []TestPathOption{WithLength(0), WithBranches(2)}, []string{"branch0", "branch1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := NewTestPath(tt.opts...)
+
+			if err := path.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(path.ExitPoints, tt.wantExits) {
+				t.Errorf("exits = %v, want %v", path.ExitPoints, tt.wantExits)
+			}
+			if got := contract.NewFunctionGraph(path).GetOutgoingEdges("fn0"); len(got) < 2 {
+				t.Errorf("fn0 has %d outgoing edges, want a branch point", len(got))
+			}
+		})
+	}
+}
+
+func TestNewTestPathCycle(t *testing.T) {
+	path := NewTestPath(WithLength(4), WithCycle())
+
+	if err := path.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	cycles := path.Cycles()
+	if want := [][]string{{"fn0", "fn1", "fn2", "fn0"}}; !slices.EqualFunc(cycles, want, slices.Equal[[]string]) {
+		t.Errorf("cycles = %v, want %v", cycles, want)
+	}
+
+	if cycles := NewTestPath(WithLength(1), WithCycle()).Cycles(); len(cycles) != 0 {
+		t.Errorf("single function path has cycles %v", cycles)
+	}
+}
+
+func TestNewTestPathDropPoint(t *testing.T) {
+	path := NewTestPath(WithDropPoint())
+
+	if err := path.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	fn := contract.NewFunctionGraph(path).GetFunction("kfree_skb")
+	if fn == nil || !fn.IsDropPoint() {
+		t.Fatalf("kfree_skb = %+v, want a drop point", fn)
+	}
+}
+
+func TestNewTestPathHooks(t *testing.T) {
+	tests := []struct {
+		direction  string
+		entryHook  string
+		exitHook   string
+		entryLayer contract.Layer
+	}{
+		{"egress", contract.HookOutput, contract.BPFHookTCEgress, contract.LayerSocket},
+		{"ingress", contract.BPFHookTCIngress, contract.HookInput, contract.LayerDriver},
+	}
+	for _, tt := range tests {
+		t.Run(tt.direction, func(t *testing.T) {
+			path := NewTestPath(WithDirection(tt.direction), WithHooks())
+
+			if err := path.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			graph := contract.NewFunctionGraph(path)
+			entry, exit := graph.GetFunction("fn0"), graph.GetFunction("fn2")
+			if got := hookName(entry); got != tt.entryHook {
+				t.Errorf("entry hook = %q, want %q", got, tt.entryHook)
+			}
+			if got := hookName(exit); got != tt.exitHook {
+				t.Errorf("exit hook = %q, want %q", got, tt.exitHook)
+			}
+			if hookName(graph.GetFunction("fn1")) != "" {
+				t.Error("fn1 has a hook, want hooks on the entry and exit points only")
+			}
+			if entry.Layer != tt.entryLayer {
+				t.Errorf("entry layer = %s, want %s", entry.Layer, tt.entryLayer)
+			}
+		})
+	}
+}
+
+// hookName returns the netfilter hook or BPF hook type of fn, or "" if it
+// has neither.
+func hookName(fn *contract.KernelFunction) string {
+	switch {
+	case fn.NetfilterHook != nil:
+		return fn.NetfilterHook.Hook
+	case fn.BPFHook != nil:
+		return fn.BPFHook.Type
+	}
+	return ""
+}
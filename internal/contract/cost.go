@@ -0,0 +1,22 @@
+package contract
+
+// layerCostNs holds default per-function cost estimates in nanoseconds for
+// functions without an explicit EstimatedCostNs. These are rough figures for
+// a modern x86 server and are only meant to convey relative weight.
+var layerCostNs = map[Layer]int{
+	LayerUserSpace: 500,
+	LayerSocket:    150,
+	LayerTransport: 250,
+	LayerNetwork:   150,
+	LayerDataLink:  200,
+	LayerDriver:    300,
+}
+
+// CostEstimate returns the estimated execution cost of the function in
+// nanoseconds, falling back to the default for its layer.
+func (f KernelFunction) CostEstimate() int {
+	if f.EstimatedCostNs > 0 {
+		return f.EstimatedCostNs
+	}
+	return layerCostNs[f.Layer]
+}
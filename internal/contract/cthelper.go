@@ -0,0 +1,118 @@
+package contract
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConntrackHelperFTP is the name of the FTP conntrack helper.
+const ConntrackHelperFTP = "ftp"
+
+// AnnotationConntrackHelper marks a conntrack helper parsing the payload.
+const AnnotationConntrackHelper = "conntrack_helper"
+
+// ftpExpectTimeout is the lifetime in seconds of an FTP data connection
+// expectation (the helper's expect_policy timeout)
+const ftpExpectTimeout = 5 * 60
+
+// ConntrackHelper is a conntrack helper, also called an application layer
+// gateway (ALG): a module that parses the payload of a control connection
+// to learn of the related connections its protocol negotiates, so they can
+// pass a stateful firewall as RELATED.
+type ConntrackHelper struct {
+	// Name is the helper name used by the CT target (-j CT --helper)
+	Name string `json:"name"`
+
+	// Protocol is the transport protocol of the control connection
+	Protocol string `json:"protocol"`
+
+	// Port is the well-known port of the control connection
+	Port uint16 `json:"port"`
+
+	// Description explains what the helper parses
+	Description string `json:"description"`
+}
+
+// conntrackHelpers are the modeled helpers, by name.
+var conntrackHelpers = map[string]ConntrackHelper{
+	ConntrackHelperFTP: {
+		Name:        ConntrackHelperFTP,
+		Protocol:    "TCP",
+		Port:        21,
+		Description: "nf_conntrack_ftp: parses PORT and EPRT commands and PASV and EPSV replies on the control connection and expects the data connection they announce.",
+	},
+}
+
+// IsValidConntrackHelper reports whether name is a modeled conntrack helper.
+func IsValidConntrackHelper(name string) bool {
+	_, ok := conntrackHelpers[name]
+	return ok
+}
+
+// ConntrackExpectation is a connection a helper expects because the control
+// connection announced it. Its first packet matches the expectation in
+// nf_conntrack_in and its entry starts RELATED to the control connection.
+type ConntrackExpectation struct {
+	// Helper is the helper that created the expectation
+	Helper string `json:"helper"`
+
+	// Tuple is the expected connection; a zero SrcPort matches any port
+	Tuple FlowKey `json:"tuple"`
+
+	// Command is the control connection payload the helper parsed
+	Command string `json:"command"`
+
+	// Timeout is the time in seconds the expectation waits for the
+	// connection
+	Timeout int `json:"timeout"`
+}
+
+// ftpPortCommand formats the FTP PORT command announcing addr and port.
+func ftpPortCommand(addr string, port uint16) string {
+	return fmt.Sprintf("PORT %s,%d,%d", strings.ReplaceAll(addr, ".", ","), port>>8, port&0xff)
+}
+
+// effectConntrackHelper models the conntrack helper assigned to the
+// connection parsing the packet's payload as nf_confirm runs it, at
+// POSTROUTING for sent and forwarded packets and at INPUT for received
+// ones. The modeled FTP control segment carries an active-mode PORT
+// command from the flow's source, which announces the conventional data
+// port one above its control port. Encrypted payload cannot be parsed.
+func effectConntrackHelper(ctx *simContext, step *SimulateStep) {
+	helper, ok := conntrackHelpers[ctx.opts.ConntrackHelper]
+	hook := step.Function.NetfilterHook
+	if !ok || hook == nil || (hook.Hook != HookPostrouting && hook.Hook != HookInput) || ctx.dropReason != "" {
+		return
+	}
+	if !ctx.skb.HasLayer(strings.ToLower(helper.Protocol)) || ctx.skb.PayloadEncrypted {
+		return
+	}
+	if ctx.opts.PayloadSize == 0 {
+		step.annotate(AnnotationConntrackHelper, fmt.Sprintf(
+			"The %s helper is assigned to the connection but this segment carries no payload for it to parse.", helper.Name))
+		return
+	}
+
+	// The command announces the address the client knows as its own; with
+	// SNAT at this hook, the helper's NAT half rewrites it
+	client, port := ctx.flow.SrcIP, ctx.flow.SrcPort+1
+	announced, nat := client, ""
+	if step.NAT != nil && step.NAT.OrigSrc != step.NAT.NewSrc {
+		announced = step.NAT.OrigSrc
+		nat = fmt.Sprintf(" nf_nat_ftp rewrites it to %q and adjusts the TCP sequence numbers for the change in length, so the expectation is for the translated address.",
+			ftpPortCommand(client, port))
+	}
+	command := ftpPortCommand(announced, port)
+
+	step.Expectation = &ConntrackExpectation{
+		Helper:  helper.Name,
+		Tuple:   FlowKey{SrcIP: ctx.flow.DstIP, DstIP: client, DstPort: port, Protocol: helper.Protocol},
+		Command: command,
+		Timeout: ftpExpectTimeout,
+	}
+	step.annotate(AnnotationConntrackHelper, fmt.Sprintf(
+		"The %s helper (assigned with -j CT --helper %s; automatic assignment by port %d is off since Linux 4.7) parses the control connection's payload and finds %q: "+
+			"%s will accept the data connection on port %d.%s It creates an expectation for %s -> %s:%d from any port, valid for %ds. "+
+			"The server's connection matches it in nf_conntrack_in and starts RELATED, so a firewall accepting RELATED lets it in without opening the port.",
+		helper.Name, helper.Name, helper.Port, command, announced, port, nat, ctx.flow.DstIP, client, port, ftpExpectTimeout))
+}
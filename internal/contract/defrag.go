@@ -0,0 +1,64 @@
+package contract
+
+import "fmt"
+
+// AnnotationReassembly marks a fragment being collected by ip_defrag.
+const AnnotationReassembly = "reassembly"
+
+// fragmentSizes splits the L4 data (transport header + payload) into the
+// sizes carried by each IP fragment. Every fragment except the last carries
+// a multiple of 8 bytes, as required by the IPv4 fragment offset field.
+func fragmentSizes(l4Len, count int) []int {
+	if count < 1 {
+		count = 1
+	}
+	chunk := (l4Len / count) &^ 7
+	if chunk == 0 {
+		chunk = 8
+	}
+
+	sizes := []int{}
+	remaining := l4Len
+	for i := 0; i < count-1 && remaining > chunk; i++ {
+		sizes = append(sizes, chunk)
+		remaining -= chunk
+	}
+	return append(sizes, remaining)
+}
+
+// trimToFirstFragment reduces an ingress sk_buff to the first IP fragment.
+// The remaining fragments are appended when ip_defrag collects them.
+func trimToFirstFragment(skb *SKBuff, opts SimulateOptions) {
+	sizes := fragmentSizes(TCPHeaderSize+opts.PayloadSize, opts.FragmentCount)
+	skb.Tail = skb.Data + EthernetHeaderSize + IPv4HeaderSize + sizes[0]
+}
+
+// effectIPDefrag models ip_defrag collecting the remaining fragments of the
+// datagram. Each arriving fragment is emitted as its own step so the
+// sk_buff length can be seen growing until reassembly completes.
+func effectIPDefrag(ctx *simContext, step *SimulateStep) {
+	sizes := fragmentSizes(TCPHeaderSize+ctx.opts.PayloadSize, ctx.opts.FragmentCount)
+	if len(sizes) < 2 {
+		return
+	}
+
+	first := *step
+	first.annotate(AnnotationReassembly, fmt.Sprintf(
+		"Fragment 1/%d (%d bytes) queued; waiting for the rest of the datagram.", len(sizes), sizes[0]))
+	ctx.emit(first)
+
+	for i := 1; i < len(sizes)-1; i++ {
+		ctx.skb.Put(sizes[i])
+		frag := *step
+		frag.annotate(AnnotationReassembly, fmt.Sprintf(
+			"Fragment %d/%d (%d bytes) collected; reassembled length is now %d bytes.",
+			i+1, len(sizes), sizes[i], ctx.skb.Len()))
+		ctx.emit(frag)
+	}
+
+	last := sizes[len(sizes)-1]
+	ctx.skb.Put(last)
+	step.annotate(AnnotationReassembly, fmt.Sprintf(
+		"Final fragment %d/%d (%d bytes) collected; datagram reassembled into a single %d-byte sk_buff.",
+		len(sizes), len(sizes), last, ctx.skb.Len()))
+}
@@ -0,0 +1,44 @@
+package contract
+
+import "fmt"
+
+// Receive-side ACK timing, from include/net/tcp.h
+const (
+	// tcpDefaultMSS is the receiver's estimate of the sender's MSS
+	// (rcv_mss) for a 1500-byte MTU path
+	tcpDefaultMSS = 1460
+
+	// tcpDelackMinMs and tcpDelackMaxMs bound the delayed-ACK timeout
+	// (TCP_DELACK_MIN and TCP_DELACK_MAX at HZ=1000)
+	tcpDelackMinMs = 40
+	tcpDelackMaxMs = 200
+)
+
+// immediateAck reports whether __tcp_ack_snd_check sends the ACK at once:
+// in quick-ACK mode, or when more than one full-sized segment is waiting to
+// be acknowledged (a GRO-merged packet larger than rcv_mss).
+func (ctx *simContext) immediateAck() bool {
+	return ctx.opts.QuickAck || ctx.opts.PayloadSize > tcpDefaultMSS
+}
+
+// effectDelayedAck models arming the delayed-ACK timer.
+func effectDelayedAck(ctx *simContext, step *SimulateStep) {
+	step.annotate(AnnotationRouting, fmt.Sprintf(
+		"Delayed ACK: %d bytes received is less than one full %d-byte segment, so no ACK is sent yet. "+
+			"The delayed-ACK timer is armed (%d-%d ms); a reply from the application or a second segment will carry or trigger the ACK. "+
+			"This halves ACK traffic for bulk transfers but can stall request/response protocols that combine it with Nagle.",
+		ctx.opts.PayloadSize, tcpDefaultMSS, tcpDelackMinMs, tcpDelackMaxMs))
+}
+
+// effectQuickAck models an immediate ACK spawning a small egress.
+func effectQuickAck(ctx *simContext, step *SimulateStep) {
+	reason := fmt.Sprintf("%d bytes received covers more than one full %d-byte segment", ctx.opts.PayloadSize, tcpDefaultMSS)
+	if ctx.opts.QuickAck {
+		reason = "the receiver is in quick-ACK mode (TCP_QUICKACK, connection start or out-of-order data)"
+	}
+	ack := TCPHeaderSize + IPv4HeaderSize + EthernetHeaderSize
+	step.annotate(AnnotationRouting, fmt.Sprintf(
+		"Immediate ACK: %s. A new %d-byte header-only sk_buff is allocated and sent through __tcp_transmit_skb, "+
+			"ip_queue_xmit and the rest of the egress path, independently of the received packet.",
+		reason, ack))
+}
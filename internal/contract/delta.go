@@ -0,0 +1,307 @@
+package contract
+
+// SKBDelta records the sk_buff fields that changed between two consecutive
+// simulation steps. Unchanged fields are nil and omitted from JSON, so a
+// state is reconstructed by copying every present field onto the previous
+// state (see Apply). A header offset that becomes unset is recorded as -1,
+// GSO metadata that is removed as an empty GSOInfo, and shared info that is
+// removed as an empty SharedInfo.
+type SKBDelta struct {
+	Head             *int              `json:"head,omitempty"`
+	Data             *int              `json:"data,omitempty"`
+	Tail             *int              `json:"tail,omitempty"`
+	End              *int              `json:"end,omitempty"`
+	Layers           *[]ProtocolHeader `json:"layers,omitempty"`
+	FClone           *bool             `json:"fclone,omitempty"`
+	Cloned           *bool             `json:"cloned,omitempty"`
+	ZeroCopy         *bool             `json:"zeroCopy,omitempty"`
+	PayloadEncrypted *bool             `json:"payloadEncrypted,omitempty"`
+	Mark             *uint32           `json:"mark,omitempty"`
+	Users            *int              `json:"users,omitempty"`
+	FlowHash         *uint32           `json:"flowHash,omitempty"`
+	MacHeader        *int              `json:"macHeader,omitempty"`
+	NetworkHeader    *int              `json:"networkHeader,omitempty"`
+	TransportHeader  *int              `json:"transportHeader,omitempty"`
+	GSO              *GSOInfo          `json:"gso,omitempty"`
+	SharedInfo       *SharedInfo       `json:"sharedInfo,omitempty"`
+	CsumState        *string           `json:"csumState,omitempty"`
+	CsumStart        *int              `json:"csumStart,omitempty"`
+	CsumOffset       *int              `json:"csumOffset,omitempty"`
+}
+
+// DiffSKBuff returns the delta that turns prev into next.
+func DiffSKBuff(prev, next *SKBuff) SKBDelta {
+	var d SKBDelta
+	diffField(&d.Head, prev.Head, next.Head)
+	diffField(&d.Data, prev.Data, next.Data)
+	diffField(&d.Tail, prev.Tail, next.Tail)
+	diffField(&d.End, prev.End, next.End)
+	diffField(&d.FClone, prev.FClone, next.FClone)
+	diffField(&d.Cloned, prev.Cloned, next.Cloned)
+	diffField(&d.ZeroCopy, prev.ZeroCopy, next.ZeroCopy)
+	diffField(&d.PayloadEncrypted, prev.PayloadEncrypted, next.PayloadEncrypted)
+	diffField(&d.Mark, prev.Mark, next.Mark)
+	diffField(&d.Users, prev.Users, next.Users)
+	diffField(&d.FlowHash, prev.FlowHash, next.FlowHash)
+	diffOffset(&d.MacHeader, prev.MacHeader, next.MacHeader)
+	diffOffset(&d.NetworkHeader, prev.NetworkHeader, next.NetworkHeader)
+	diffOffset(&d.TransportHeader, prev.TransportHeader, next.TransportHeader)
+	diffGSO(&d.GSO, prev.GSO, next.GSO)
+	diffSharedInfo(&d.SharedInfo, prev.SharedInfo, next.SharedInfo)
+	diffField(&d.CsumState, prev.CsumState, next.CsumState)
+	diffField(&d.CsumStart, prev.CsumStart, next.CsumStart)
+	diffField(&d.CsumOffset, prev.CsumOffset, next.CsumOffset)
+	if !layersEqual(prev.Layers, next.Layers) {
+		layers := append([]ProtocolHeader{}, next.Layers...)
+		d.Layers = &layers
+	}
+	return d
+}
+
+// Apply copies the changed fields onto skb.
+func (d SKBDelta) Apply(skb *SKBuff) {
+	applyField(&skb.Head, d.Head)
+	applyField(&skb.Data, d.Data)
+	applyField(&skb.Tail, d.Tail)
+	applyField(&skb.End, d.End)
+	applyField(&skb.FClone, d.FClone)
+	applyField(&skb.Cloned, d.Cloned)
+	applyField(&skb.ZeroCopy, d.ZeroCopy)
+	applyField(&skb.PayloadEncrypted, d.PayloadEncrypted)
+	applyField(&skb.Mark, d.Mark)
+	applyField(&skb.Users, d.Users)
+	applyField(&skb.FlowHash, d.FlowHash)
+	applyOffset(&skb.MacHeader, d.MacHeader)
+	applyOffset(&skb.NetworkHeader, d.NetworkHeader)
+	applyOffset(&skb.TransportHeader, d.TransportHeader)
+	applyGSO(&skb.GSO, d.GSO)
+	applySharedInfo(&skb.SharedInfo, d.SharedInfo)
+	applyField(&skb.CsumState, d.CsumState)
+	applyField(&skb.CsumStart, d.CsumStart)
+	applyField(&skb.CsumOffset, d.CsumOffset)
+	if d.Layers != nil {
+		skb.Layers = append([]ProtocolHeader{}, (*d.Layers)...)
+	}
+}
+
+// diffField sets *dst to a pointer to next if it differs from prev.
+func diffField[T comparable](dst **T, prev, next T) {
+	if prev != next {
+		*dst = &next
+	}
+}
+
+// applyField sets *dst to *v if v is present.
+func applyField[T any](dst *T, v *T) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+// unsetOffset encodes an unset header offset in a delta
+const unsetOffset = -1
+
+// diffOffset sets *dst to the next header offset if it differs from prev,
+// using unsetOffset for an offset that became unset.
+func diffOffset(dst **int, prev, next *int) {
+	p, n := unsetOffset, unsetOffset
+	if prev != nil {
+		p = *prev
+	}
+	if next != nil {
+		n = *next
+	}
+	diffField(dst, p, n)
+}
+
+// applyOffset sets *dst to the header offset in v if v is present.
+func applyOffset(dst **int, v *int) {
+	switch {
+	case v == nil:
+	case *v == unsetOffset:
+		*dst = nil
+	default:
+		*dst = headerOffset(*v)
+	}
+}
+
+// diffGSO sets *dst to the next GSO metadata if it differs from prev,
+// using an empty GSOInfo for metadata that was removed.
+func diffGSO(dst **GSOInfo, prev, next *GSOInfo) {
+	var p, n GSOInfo
+	if prev != nil {
+		p = *prev
+	}
+	if next != nil {
+		n = *next
+	}
+	diffField(dst, p, n)
+}
+
+// applyGSO sets *dst to the GSO metadata in v if v is present.
+func applyGSO(dst **GSOInfo, v *GSOInfo) {
+	switch {
+	case v == nil:
+	case *v == GSOInfo{}:
+		*dst = nil
+	default:
+		gso := *v
+		*dst = &gso
+	}
+}
+
+// diffSharedInfo sets *dst to a copy of the next shared info if it differs
+// from prev, using an empty SharedInfo for shared info that was removed.
+func diffSharedInfo(dst **SharedInfo, prev, next *SharedInfo) {
+	p, n := &SharedInfo{}, &SharedInfo{}
+	if prev != nil {
+		p = prev
+	}
+	if next != nil {
+		n = next
+	}
+	if !sharedInfoEqual(p, n) {
+		*dst = n.Clone()
+	}
+}
+
+// applySharedInfo sets *dst to a copy of the shared info in v if v is
+// present.
+func applySharedInfo(dst **SharedInfo, v *SharedInfo) {
+	switch {
+	case v == nil:
+	case v.NrFrags == 0 && len(v.FragList) == 0:
+		*dst = nil
+	default:
+		*dst = v.Clone()
+	}
+}
+
+// sharedInfoEqual reports whether two shared infos describe the same
+// fragments.
+func sharedInfoEqual(a, b *SharedInfo) bool {
+	if a.NrFrags != b.NrFrags || len(a.Frags) != len(b.Frags) || len(a.FragList) != len(b.FragList) {
+		return false
+	}
+	for i := range a.Frags {
+		if a.Frags[i] != b.Frags[i] {
+			return false
+		}
+	}
+	for i := range a.FragList {
+		if !skbuffEqual(&a.FragList[i], &b.FragList[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// skbuffEqual reports whether two sk_buffs are identical, which is the case
+// when the delta between them is empty.
+func skbuffEqual(a, b *SKBuff) bool {
+	return DiffSKBuff(a, b) == SKBDelta{}
+}
+
+// layersEqual reports whether two layer stacks are identical.
+func layersEqual(a, b []ProtocolHeader) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// DeltaStep is a SimulateStep with the full sk_buff snapshot replaced by the
+// change from the previous step. Its other fields mirror SimulateStep.
+type DeltaStep struct {
+	StepNumber     int                   `json:"stepNumber"`
+	Function       KernelFunction        `json:"function"`
+	SKBDelta       SKBDelta              `json:"skbDelta"`
+	EdgeTaken      *FunctionEdge         `json:"edgeTaken,omitempty"`
+	ConntrackState *ConntrackEntry       `json:"conntrackState,omitempty"`
+	WriteQueue     *WriteQueue           `json:"writeQueue,omitempty"`
+	SocketMemory   *SocketMemory         `json:"socketMemory,omitempty"`
+	Route          *RouteDecision        `json:"route,omitempty"`
+	NAT            *NATRewrite           `json:"nat,omitempty"`
+	Expectation    *ConntrackExpectation `json:"expectation,omitempty"`
+	ICMPError      *ICMPErrorResult      `json:"icmpError,omitempty"`
+	Neighbour      *NeighbourEntry       `json:"neighbour,omitempty"`
+	ListenQueue    *ListenQueue          `json:"listenQueue,omitempty"`
+	SocketLock     string                `json:"socketLock,omitempty"`
+	EgressPorts    []string              `json:"egressPorts,omitempty"`
+	Fragments      []SKBuff              `json:"fragments,omitempty"`
+	TxQueue        *TxQueueSelection     `json:"txQueue,omitempty"`
+	RTT            *RTTEstimate          `json:"rtt,omitempty"`
+	DeferredBytes  int                   `json:"deferredBytes,omitempty"`
+	DropReason     string                `json:"dropReason,omitempty"`
+	ErrorCounters  map[string]int        `json:"errorCounters,omitempty"`
+	Annotations    []StepAnnotation      `json:"annotations,omitempty"`
+}
+
+// DeltaSimulation is a compact encoding of a simulation: the sk_buff state
+// of the first step plus per-step deltas. The first step's delta is empty.
+type DeltaSimulation struct {
+	// Initial is the sk_buff state after the first step
+	Initial SKBuff `json:"initial"`
+
+	// Steps are the simulation steps with sk_buff deltas
+	Steps []DeltaStep `json:"steps"`
+}
+
+// ToDeltas encodes the simulation as an initial state plus per-step deltas.
+// Returns nil for an empty simulation.
+func (steps SimulateSteps) ToDeltas() *DeltaSimulation {
+	if len(steps) == 0 {
+		return nil
+	}
+
+	sim := &DeltaSimulation{
+		Initial: *steps[0].SKBuffState.Clone(),
+		Steps:   make([]DeltaStep, len(steps)),
+	}
+	prev := &steps[0].SKBuffState
+	for i := range steps {
+		step := &steps[i]
+		sim.Steps[i] = DeltaStep{
+			StepNumber:     step.StepNumber,
+			Function:       step.Function,
+			SKBDelta:       DiffSKBuff(prev, &step.SKBuffState),
+			EdgeTaken:      step.EdgeTaken,
+			ConntrackState: step.ConntrackState,
+			WriteQueue:     step.WriteQueue,
+			SocketMemory:   step.SocketMemory,
+			Route:          step.Route,
+			NAT:            step.NAT,
+			Expectation:    step.Expectation,
+			ICMPError:      step.ICMPError,
+			Neighbour:      step.Neighbour,
+			ListenQueue:    step.ListenQueue,
+			SocketLock:     step.SocketLock,
+			EgressPorts:    step.EgressPorts,
+			Fragments:      step.Fragments,
+			TxQueue:        step.TxQueue,
+			RTT:            step.RTT,
+			DeferredBytes:  step.DeferredBytes,
+			DropReason:     step.DropReason,
+			ErrorCounters:  step.ErrorCounters,
+			Annotations:    step.Annotations,
+		}
+		prev = &step.SKBuffState
+	}
+	return sim
+}
+
+// States reconstructs the full sk_buff state of every step.
+func (sim *DeltaSimulation) States() []SKBuff {
+	states := make([]SKBuff, len(sim.Steps))
+	current := sim.Initial.Clone()
+	for i, step := range sim.Steps {
+		step.SKBDelta.Apply(current)
+		states[i] = *current.Clone()
+	}
+	return states
+}
@@ -0,0 +1,58 @@
+package contract
+
+import (
+	"reflect"
+	"testing"
+)
+
+// deltaVariants are simulation options that exercise the sk_buff mutations
+// of the different paths: segmentation, fragments, shared info, checksums,
+// drops and clones.
+var deltaVariants = map[string]SimulateOptions{
+	"default":      DefaultSimulateOptions(),
+	"nogso":        {BufferSize: 8192, PayloadSize: 5000, MSS: 1460, NoGSO: true},
+	"gso":          {BufferSize: 8192, PayloadSize: 5000, MSS: 1460},
+	"small mtu":    {BufferSize: 8192, PayloadSize: 4000, MTU: 1500, NoGSO: true},
+	"fragments":    {BufferSize: 2048, PayloadSize: 1000, FragmentCount: 3},
+	"taps":         {BufferSize: 2048, PayloadSize: 1000, PacketTaps: 2},
+	"zerocopy":     {BufferSize: 2048, PayloadSize: 1000, ZeroCopy: true},
+	"corrupt":      {BufferSize: 2048, PayloadSize: 1000, CorruptChecksum: true},
+	"gro flush":    {BufferSize: 2048, PayloadSize: 1000, GROFlush: GROFlushTimeout},
+	"frag needed":  {BufferSize: 2048, PayloadSize: 1000, ICMPError: ICMPErrorFragNeeded},
+	"ip options":   {BufferSize: 2048, PayloadSize: 1000, IPOptions: IPOptionRecordRoute},
+	"filter deny":  {BufferSize: 2048, PayloadSize: 1000, SocketFilter: SocketFilterDeny},
+	"tc redirect":  {BufferSize: 2048, PayloadSize: 1000, TCIngress: TCActRedirect},
+	"tiny sndbuf":  {BufferSize: 2048, PayloadSize: 1000, SendBufferSize: 512},
+	"device busy":  {BufferSize: 2048, PayloadSize: 1000, DeviceBusy: true},
+	"owned socket": {BufferSize: 2048, PayloadSize: 1000, SocketOwnedByUser: true},
+}
+
+func TestDeltaStatesMatchSnapshots(t *testing.T) {
+	for _, path := range DefaultRegistry().Paths() {
+		for name, opts := range deltaVariants {
+			t.Run(path.ID+"/"+name, func(t *testing.T) {
+				steps := path.SimulateWithOptions(opts)
+				if len(steps) == 0 {
+					t.Fatal("empty simulation")
+				}
+
+				states := steps.ToDeltas().States()
+				if len(states) != len(steps) {
+					t.Fatalf("%d states from deltas, want %d", len(states), len(steps))
+				}
+				for i, step := range steps {
+					if !reflect.DeepEqual(states[i], step.SKBuffState) {
+						t.Fatalf("step %d (%s): state from deltas\n%+v\nwant snapshot\n%+v",
+							step.StepNumber, step.Function.ID, states[i], step.SKBuffState)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestToDeltasEmpty(t *testing.T) {
+	if sim := SimulateSteps(nil).ToDeltas(); sim != nil {
+		t.Errorf("ToDeltas of an empty simulation = %+v, want nil", sim)
+	}
+}
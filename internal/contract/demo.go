@@ -0,0 +1,33 @@
+package contract
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// demoServicePorts are the destination ports random demo flows pick from.
+var demoServicePorts = []uint16{22, 53, 80, 443, 3306, 5432, 6379, 8080}
+
+// RandomFlowKey returns a pseudo-random TCP flow between two private
+// addresses. The same seed always yields the same flow on every machine,
+// so a shared seed reproduces a demo exactly.
+func RandomFlowKey(seed int64) FlowKey {
+	rng := rand.New(rand.NewSource(seed))
+	return FlowKey{
+		SrcIP:    fmt.Sprintf("10.%d.%d.%d", rng.Intn(256), rng.Intn(256), 1+rng.Intn(254)),
+		DstIP:    fmt.Sprintf("192.168.%d.%d", rng.Intn(256), 1+rng.Intn(254)),
+		SrcPort:  uint16(32768 + rng.Intn(60999-32768+1)), // Linux ip_local_port_range
+		DstPort:  demoServicePorts[rng.Intn(len(demoServicePorts))],
+		Protocol: "TCP",
+	}
+}
+
+// RandomPayloadSize returns a pseudo-random payload size in [min, max]
+// derived from the seed. The bounds are swapped if given in reverse.
+func RandomPayloadSize(seed int64, min, max int) int {
+	if max < min {
+		min, max = max, min
+	}
+	rng := rand.New(rand.NewSource(seed))
+	return min + rng.Intn(max-min+1)
+}
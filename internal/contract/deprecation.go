@@ -0,0 +1,31 @@
+package contract
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// deprecationHandler receives a message each time a deprecated function is
+// called (nil = calls are not reported)
+var deprecationHandler atomic.Pointer[func(string)]
+
+// SetDeprecationHandler registers a callback that receives a message each
+// time a deprecated function of this package is called, so consumers can
+// find and migrate the calls (for example by logging them). A nil handler
+// stops reporting, which is the default. It is safe to call concurrently
+// with the functions it reports on.
+func SetDeprecationHandler(handler func(message string)) {
+	if handler == nil {
+		deprecationHandler.Store(nil)
+		return
+	}
+	deprecationHandler.Store(&handler)
+}
+
+// deprecated reports a call to the deprecated function name to the
+// deprecation handler, naming its replacement.
+func deprecated(name, replacement string) {
+	if handler := deprecationHandler.Load(); handler != nil {
+		(*handler)(fmt.Sprintf("contract.%s is deprecated; use contract.%s instead", name, replacement))
+	}
+}
@@ -0,0 +1,106 @@
+package contract
+
+import (
+	"fmt"
+	"strings"
+)
+
+// presenceColors are the fill and stroke colors used by the diagram exporters
+// for each version presence tag (see OverlayVersions).
+var presenceColors = map[string]struct{ fill, stroke string }{
+	PresenceV1Only: {fill: "#fde2e2", stroke: "#c0392b"},
+	PresenceV2Only: {fill: "#e2f7e2", stroke: "#27ae60"},
+}
+
+// ToDOT renders the path as a Graphviz DOT digraph. Error edges are dashed,
+// and functions and edges tagged by OverlayVersions are colored by version.
+func (p *PacketPath) ToDOT() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n", p.ID)
+	b.WriteString("  rankdir=TB;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fillcolor=\"white\", fontname=\"Helvetica\"];\n")
+	b.WriteString("  edge [fontname=\"Helvetica\", fontsize=10];\n")
+
+	for _, fn := range p.Functions {
+		attrs := []string{fmt.Sprintf("label=%q", fn.Name+"\n"+fn.Layer.String())}
+		if c, ok := presenceColors[fn.VersionPresence]; ok {
+			attrs = append(attrs, fmt.Sprintf("fillcolor=%q", c.fill), fmt.Sprintf("color=%q", c.stroke))
+		}
+		if fn.IsEntryPoint || fn.IsExitPoint {
+			attrs = append(attrs, "penwidth=2")
+		}
+		fmt.Fprintf(&b, "  %q [%s];\n", fn.ID, strings.Join(attrs, ", "))
+	}
+
+	for _, edge := range p.Edges {
+		var attrs []string
+		if edge.Condition != "" {
+			attrs = append(attrs, fmt.Sprintf("label=%q", edge.Condition))
+		}
+		if edge.IsErrorPath {
+			attrs = append(attrs, "style=dashed")
+		}
+		if c, ok := presenceColors[edge.VersionPresence]; ok {
+			attrs = append(attrs, fmt.Sprintf("color=%q", c.stroke), fmt.Sprintf("fontcolor=%q", c.stroke))
+		}
+		if len(attrs) == 0 {
+			fmt.Fprintf(&b, "  %q -> %q;\n", edge.From, edge.To)
+			continue
+		}
+		fmt.Fprintf(&b, "  %q -> %q [%s];\n", edge.From, edge.To, strings.Join(attrs, ", "))
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// ToMermaid renders the path as a Mermaid flowchart. Error edges are dotted,
+// and functions and edges tagged by OverlayVersions are colored by version.
+func (p *PacketPath) ToMermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+
+	for _, fn := range p.Functions {
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", fn.ID, mermaidEscape(fn.Name))
+	}
+
+	var linkStyles []string
+	for i, edge := range p.Edges {
+		arrow := "-->"
+		if edge.IsErrorPath {
+			arrow = "-.->"
+		}
+		if edge.Condition != "" {
+			fmt.Fprintf(&b, "  %s %s|\"%s\"| %s\n", edge.From, arrow, mermaidEscape(edge.Condition), edge.To)
+		} else {
+			fmt.Fprintf(&b, "  %s %s %s\n", edge.From, arrow, edge.To)
+		}
+		if c, ok := presenceColors[edge.VersionPresence]; ok {
+			linkStyles = append(linkStyles, fmt.Sprintf("  linkStyle %d stroke:%s\n", i, c.stroke))
+		}
+	}
+	for _, style := range linkStyles {
+		b.WriteString(style)
+	}
+
+	for _, presence := range []string{PresenceV1Only, PresenceV2Only} {
+		var ids []string
+		for _, fn := range p.Functions {
+			if fn.VersionPresence == presence {
+				ids = append(ids, fn.ID)
+			}
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		c := presenceColors[presence]
+		fmt.Fprintf(&b, "  classDef %s fill:%s,stroke:%s\n", presence, c.fill, c.stroke)
+		fmt.Fprintf(&b, "  class %s %s\n", strings.Join(ids, ","), presence)
+	}
+	return b.String()
+}
+
+// mermaidEscape replaces characters that would end a quoted Mermaid label.
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
@@ -6,230 +6,383 @@ package contract
 // This path represents a typical socket send operation using TCP,
 // from the initial tcp_sendmsg call down to the NIC driver.
 func BuildTCPIPv4EgressPath() *PacketPath {
-	path := &PacketPath{
-		ID:          "tcp_ipv4_egress",
-		Name:        "TCP/IPv4 Egress Path",
-		Description: "The path of a TCP packet from user space through the kernel to the network interface (Linux 5.10.8)",
-		Direction:   "egress",
-		Protocol:    "TCP",
-		EntryPoint:  "tcp_sendmsg",
-		ExitPoints:  []string{"ndo_start_xmit"},
-	}
-
 	// Define all functions in the egress path
-	path.Functions = []KernelFunction{
+	functions := []KernelFunction{
 		// Transport Layer - TCP
 		{
-			ID:           "tcp_sendmsg",
-			Name:         "tcp_sendmsg",
-			Layer:        LayerTransport,
-			SourceFile:   "net/ipv4/tcp.c",
-			LineNumber:   1439,
-			Description:  "Entry point for TCP send operations. Acquires socket lock and delegates to tcp_sendmsg_locked.",
-			IsEntryPoint: true,
+			ID:               "tcp_sendmsg",
+			Name:             "tcp_sendmsg",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp.c",
+			LineNumber:       1439,
+			ExecutionContext: ContextProcess,
+			Description:      "Entry point for TCP send operations. Acquires socket lock and delegates to tcp_sendmsg_locked.",
+			IsEntryPoint:     true,
+		},
+		{
+			ID:               "tcp_sendmsg_locked",
+			Name:             "tcp_sendmsg_locked",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp.c",
+			LineNumber:       1189,
+			ExecutionContext: ContextProcess,
+			Description:      "Core TCP send logic. Allocates sk_buff and copies user data into kernel space.",
+			SKBMutation:      NewAllocMutation(2048, "Allocate sk_buff with headroom for all protocol headers"),
+			EstimatedCostNs:  1200,
+		},
+		{
+			ID:               "skb_zerocopy_iter_stream",
+			Name:             "skb_zerocopy_iter_stream",
+			Layer:            LayerTransport,
+			SourceFile:       "net/core/skbuff.c",
+			LineNumber:       1290,
+			ExecutionContext: ContextProcess,
+			Description:      "MSG_ZEROCOPY path. Pins the user pages and attaches them to the sk_buff as paged fragments instead of copying the data.",
+		},
+		{
+			ID:               "sk_stream_wait_memory",
+			Name:             "sk_stream_wait_memory",
+			Layer:            LayerTransport,
+			SourceFile:       "net/core/stream.c",
+			LineNumber:       117,
+			ExecutionContext: ContextProcess,
+			Description:      "Waits for send memory when the send buffer is full. A blocking socket sleeps, with the socket lock released, until transmit completions free memory; a non-blocking socket returns -EAGAIN at once.",
+		},
+		{
+			ID:               "tcp_push",
+			Name:             "tcp_push",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp.c",
+			LineNumber:       706,
+			ExecutionContext: ContextProcess,
+			Description:      "Pushes pending data. Sets PSH flag if socket is being closed or buffer is full.",
+			WrapperOf:        "__tcp_push_pending_frames",
 		},
 		{
-			ID:          "tcp_sendmsg_locked",
-			Name:        "tcp_sendmsg_locked",
-			Layer:       LayerTransport,
-			SourceFile:  "net/ipv4/tcp.c",
-			LineNumber:  1189,
-			Description: "Core TCP send logic. Allocates sk_buff and copies user data into kernel space.",
-			SKBMutation: NewAllocMutation(2048, "Allocate sk_buff with headroom for all protocol headers"),
+			ID:               "__tcp_push_pending_frames",
+			Name:             "__tcp_push_pending_frames",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_output.c",
+			LineNumber:       2855,
+			ExecutionContext: ContextProcess,
+			Description:      "Checks if there is data to send and initiates transmission.",
 		},
 		{
-			ID:          "tcp_push",
-			Name:        "tcp_push",
-			Layer:       LayerTransport,
-			SourceFile:  "net/ipv4/tcp.c",
-			LineNumber:  706,
-			Description: "Pushes pending data. Sets PSH flag if socket is being closed or buffer is full.",
+			ID:               "tcp_write_xmit",
+			Name:             "tcp_write_xmit",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_output.c",
+			LineNumber:       2594,
+			ExecutionContext: ContextProcess,
+			Description:      "Main TCP transmission loop. Handles congestion control, pacing, and TSO segmentation.",
 		},
 		{
-			ID:          "__tcp_push_pending_frames",
-			Name:        "__tcp_push_pending_frames",
-			Layer:       LayerTransport,
-			SourceFile:  "net/ipv4/tcp_output.c",
-			LineNumber:  2855,
-			Description: "Checks if there is data to send and initiates transmission.",
+			ID:               "tcp_tso_should_defer",
+			Name:             "tcp_tso_should_defer",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_output.c",
+			LineNumber:       2014,
+			ExecutionContext: ContextProcess,
+			Description:      "Decides to hold a small amount of pending data so it can be coalesced into a larger TSO segment. Data stays in the write queue until an ACK or timer triggers transmission.",
+			IsExitPoint:      true,
 		},
 		{
-			ID:          "tcp_write_xmit",
-			Name:        "tcp_write_xmit",
-			Layer:       LayerTransport,
-			SourceFile:  "net/ipv4/tcp_output.c",
-			LineNumber:  2594,
-			Description: "Main TCP transmission loop. Handles congestion control, pacing, and TSO segmentation.",
+			ID:               "tcp_small_queue_check",
+			Name:             "tcp_small_queue_check",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_output.c",
+			LineNumber:       2524,
+			ExecutionContext: ContextProcess,
+			Description:      "TCP Small Queues: stops the transmission loop once the socket's sk_buffs in the qdisc and driver (sk_wmem_alloc) exceed about 1 ms of data at the pacing rate. TSQ_THROTTLED is set and transmission resumes from tcp_wfree when they are sent.",
+			IsExitPoint:      true,
 		},
 		{
-			ID:          "__tcp_transmit_skb",
-			Name:        "__tcp_transmit_skb",
-			Layer:       LayerTransport,
-			SourceFile:  "net/ipv4/tcp_output.c",
-			LineNumber:  1239,
-			Description: "Builds the TCP header. Calculates checksum and sets sequence numbers.",
-			SKBMutation: NewPushMutation("tcp", TCPHeaderSize),
+			ID:               "__tcp_transmit_skb",
+			Name:             "__tcp_transmit_skb",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_output.c",
+			LineNumber:       1239,
+			ExecutionContext: ContextProcess,
+			Description:      "Builds the TCP header. Calculates checksum and sets sequence numbers.",
+			SKBMutation:      NewPushMutation("tcp", TCPHeaderSize),
+			EstimatedCostNs:  400,
 		},
 
 		// Network Layer - IP
 		{
-			ID:          "ip_queue_xmit",
-			Name:        "ip_queue_xmit",
-			Layer:       LayerNetwork,
-			SourceFile:  "net/ipv4/ip_output.c",
-			LineNumber:  544,
-			Description: "Main IPv4 transmission entry point from transport layer. Handles routing lookup and IP header construction.",
-			SKBMutation: NewPushMutation("ip", IPv4HeaderSize),
-		},
-		{
-			ID:          "ip_local_out",
-			Name:        "ip_local_out",
-			Layer:       LayerNetwork,
-			SourceFile:  "net/ipv4/ip_output.c",
-			LineNumber:  120,
-			Description: "Wrapper for locally generated packets. Calls __ip_local_out.",
-		},
-		{
-			ID:            "__ip_local_out",
-			Name:          "__ip_local_out",
-			Layer:         LayerNetwork,
-			SourceFile:    "net/ipv4/ip_output.c",
-			LineNumber:    99,
-			Description:   "Sets IP packet length and checksum. Invokes LOCAL_OUT netfilter hook.",
-			NetfilterHook: NewOutputHook(),
-		},
-		{
-			ID:            "ip_output",
-			Name:          "ip_output",
-			Layer:         LayerNetwork,
-			SourceFile:    "net/ipv4/ip_output.c",
-			LineNumber:    423,
-			Description:   "Called after LOCAL_OUT hook. Invokes POST_ROUTING netfilter hook.",
-			NetfilterHook: NewPostroutingHook(),
-		},
-		{
-			ID:          "ip_finish_output",
-			Name:        "ip_finish_output",
-			Layer:       LayerNetwork,
-			SourceFile:  "net/ipv4/ip_output.c",
-			LineNumber:  311,
-			Description: "BPF cgroup egress hook point. Handles GSO segmentation if needed.",
-			BPFHook:     NewCgroupSKBHook("egress"),
-		},
-		{
-			ID:          "__ip_finish_output",
-			Name:        "__ip_finish_output",
-			Layer:       LayerNetwork,
-			SourceFile:  "net/ipv4/ip_output.c",
-			LineNumber:  290,
-			Description: "Checks MTU and fragments packet if necessary.",
-		},
-		{
-			ID:          "ip_finish_output2",
-			Name:        "ip_finish_output2",
-			Layer:       LayerNetwork,
-			SourceFile:  "net/ipv4/ip_output.c",
-			LineNumber:  187,
-			Description: "Resolves next-hop neighbor (ARP lookup) and prepares for L2 transmission.",
-		},
-		{
-			ID:          "neigh_output",
-			Name:        "neigh_output",
-			Layer:       LayerNetwork,
-			SourceFile:  "include/net/neighbour.h",
-			LineNumber:  502,
-			Description: "Neighbour subsystem output. Uses cached hardware header if available.",
-		},
-		{
-			ID:          "neigh_hh_output",
-			Name:        "neigh_hh_output",
-			Layer:       LayerDataLink,
-			SourceFile:  "include/net/neighbour.h",
-			LineNumber:  462,
-			Description: "Fast path using cached hardware header. Pushes Ethernet header.",
-			SKBMutation: NewPushMutation("ethernet", EthernetHeaderSize),
+			ID:               "ip_queue_xmit",
+			Name:             "ip_queue_xmit",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/ip_output.c",
+			LineNumber:       544,
+			ExecutionContext: ContextProcess,
+			Description:      "Main IPv4 transmission entry point from transport layer. Handles routing lookup and IP header construction.",
+			SKBMutation:      NewPushMutation("ip", IPv4HeaderSize),
+			EstimatedCostNs:  350,
+			RCUProtected:     true,
+			RCUNote:          "Route lookup and the socket's cached dst are read locklessly under rcu_read_lock.",
+		},
+		{
+			ID:               "fib_rules_lookup",
+			Name:             "fib_rules_lookup",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/core/fib_rules.c",
+			LineNumber:       271,
+			ExecutionContext: ContextProcess,
+			Description:      "Walks the policy routing rules (ip rule) and selects a routing table by fwmark, source address or other selectors before the FIB lookup.",
+		},
+		{
+			ID:               "ip_local_out",
+			Name:             "ip_local_out",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/ip_output.c",
+			LineNumber:       120,
+			ExecutionContext: ContextProcess,
+			Description:      "Wrapper for locally generated packets. Calls __ip_local_out.",
+		},
+		{
+			ID:               "__ip_local_out",
+			Name:             "__ip_local_out",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/ip_output.c",
+			LineNumber:       99,
+			ExecutionContext: ContextProcess,
+			Description:      "Sets IP packet length and checksum. Invokes LOCAL_OUT netfilter hook.",
+			NetfilterHook:    NewOutputHook(),
+			ConfigDeps:       []string{"CONFIG_NETFILTER"},
+		},
+		{
+			ID:               "ip_output",
+			Name:             "ip_output",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/ip_output.c",
+			LineNumber:       423,
+			ExecutionContext: ContextProcess,
+			Description:      "Called after LOCAL_OUT hook. Invokes POST_ROUTING netfilter hook.",
+			NetfilterHook:    NewPostroutingHook(),
+			ConfigDeps:       []string{"CONFIG_NETFILTER"},
+		},
+		{
+			ID:               "ip_finish_output",
+			Name:             "ip_finish_output",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/ip_output.c",
+			LineNumber:       311,
+			ExecutionContext: ContextProcess,
+			Description:      "BPF cgroup egress hook point. Passes the packet on to the GSO and fragmentation decision.",
+			BPFHook:          NewCgroupSKBHook("egress"),
+			ConfigDeps:       []string{"CONFIG_CGROUP_BPF"},
+		},
+		{
+			ID:               "__ip_finish_output",
+			Name:             "__ip_finish_output",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/ip_output.c",
+			LineNumber:       290,
+			ExecutionContext: ContextProcess,
+			Description:      "Output decision: a GSO sk_buff goes to ip_finish_output_gso, a non-GSO packet larger than the MTU goes to ip_fragment, anything else goes straight to ip_finish_output2.",
+		},
+		{
+			ID:               "ip_finish_output_gso",
+			Name:             "ip_finish_output_gso",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/ip_output.c",
+			LineNumber:       246,
+			ExecutionContext: ContextProcess,
+			Description:      "Checks that the segments the GSO sk_buff will be split into fit the MTU, then hands it down unsegmented so the stack below runs once for all of them.",
+		},
+		{
+			ID:               "ip_fragment",
+			Name:             "ip_fragment",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/ip_output.c",
+			LineNumber:       571,
+			ExecutionContext: ContextProcess,
+			Description:      "Splits a non-GSO packet larger than the MTU into IP fragments (ip_do_fragment), or sends ICMP Fragmentation Needed if DF is set.",
+			EstimatedCostNs:  800,
+		},
+		{
+			ID:               "ip_finish_output2",
+			Name:             "ip_finish_output2",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/ip_output.c",
+			LineNumber:       187,
+			ExecutionContext: ContextProcess,
+			Description:      "Resolves next-hop neighbor (ARP lookup) and prepares for L2 transmission.",
+			RCUProtected:     true,
+			RCUNote:          "rcu_read_lock_bh protects the neighbour entry lookup for the next hop.",
+		},
+		{
+			ID:               "neigh_output",
+			Name:             "neigh_output",
+			Layer:            LayerNetwork,
+			SourceFile:       "include/net/neighbour.h",
+			LineNumber:       502,
+			ExecutionContext: ContextProcess,
+			Description:      "Neighbour subsystem output. Uses cached hardware header if available.",
+			RCUProtected:     true,
+			RCUNote:          "Neighbour entry and its cached hardware header are read under RCU.",
+		},
+		{
+			ID:               "neigh_hh_output",
+			Name:             "neigh_hh_output",
+			Layer:            LayerDataLink,
+			SourceFile:       "include/net/neighbour.h",
+			LineNumber:       462,
+			ExecutionContext: ContextProcess,
+			Description:      "Fast path using cached hardware header. Pushes Ethernet header.",
+			SKBMutation:      NewPushMutation("ethernet", EthernetHeaderSize),
+			RCUProtected:     true,
+			RCUNote:          "Cached hardware header is copied under the neighbour's seqlock inside the RCU section.",
+		},
+		{
+			ID:               "neigh_resolve_output",
+			Name:             "neigh_resolve_output",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/core/neighbour.c",
+			LineNumber:       1464,
+			ExecutionContext: ContextProcess,
+			Description:      "Slow path without a cached hardware header. neigh_event_send starts resolution if needed (queuing the packet on arp_queue); once the entry is valid, dev_hard_header builds the Ethernet header and fills the cache.",
+			SKBMutation:      NewPushMutation("ethernet", EthernetHeaderSize),
+			RCUProtected:     true,
+			RCUNote:          "The neighbour's hardware address is read under its seqlock inside the RCU section.",
 		},
 
 		// Data Link Layer - Queueing Discipline
 		{
-			ID:          "dev_queue_xmit",
-			Name:        "dev_queue_xmit",
-			Layer:       LayerDataLink,
-			SourceFile:  "net/core/dev.c",
-			LineNumber:  4171,
-			Description: "Main device transmission entry point. Handles per-CPU processing.",
+			ID:               "dev_queue_xmit",
+			Name:             "dev_queue_xmit",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       4171,
+			ExecutionContext: ContextProcess,
+			Description:      "Main device transmission entry point. Handles per-CPU processing.",
+			WrapperOf:        "__dev_queue_xmit",
+			RCUProtected:     true,
+			RCUNote:          "Still inside the rcu_read_lock_bh section taken by ip_finish_output2.",
+		},
+		{
+			ID:               "__dev_queue_xmit",
+			Name:             "__dev_queue_xmit",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       4064,
+			ExecutionContext: ContextProcess,
+			Description:      "Core queuing logic. TC egress BPF programs run here, then netdev_core_pick_tx selects the TX queue (XPS or flow hash) and its qdisc.",
+			BPFHook:          NewTCEgressHook(),
+			ConfigDeps:       []string{"CONFIG_NET_CLS_ACT", "CONFIG_NET_EGRESS"},
+			RCUProtected:     true,
+			RCUNote:          "rcu_read_lock_bh keeps the device's TX queue and qdisc alive while transmitting.",
 		},
 		{
-			ID:          "__dev_queue_xmit",
-			Name:        "__dev_queue_xmit",
-			Layer:       LayerDataLink,
-			SourceFile:  "net/core/dev.c",
-			LineNumber:  4064,
-			Description: "Core queuing logic. TC egress BPF programs run here before qdisc.",
-			BPFHook:     NewTCEgressHook(),
+			ID:               "__dev_xmit_skb",
+			Name:             "__dev_xmit_skb",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       3742,
+			ExecutionContext: ContextProcess,
+			Description:      "Submits packet to qdisc. May queue or directly transmit based on qdisc state.",
+			RCUProtected:     true,
+			RCUNote:          "Qdisc pointer is dereferenced under RCU-bh.",
 		},
 		{
-			ID:          "__dev_xmit_skb",
-			Name:        "__dev_xmit_skb",
-			Layer:       LayerDataLink,
-			SourceFile:  "net/core/dev.c",
-			LineNumber:  3742,
-			Description: "Submits packet to qdisc. May queue or directly transmit based on qdisc state.",
+			ID:               "sch_direct_xmit",
+			Name:             "sch_direct_xmit",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/sched/sch_generic.c",
+			LineNumber:       285,
+			ExecutionContext: ContextProcess,
+			Description:      "Bypasses qdisc queue for direct transmission when possible.",
+			RCUProtected:     true,
+			RCUNote:          "Runs within the RCU-bh section; the qdisc lock is dropped around the driver call.",
 		},
 		{
-			ID:          "sch_direct_xmit",
-			Name:        "sch_direct_xmit",
-			Layer:       LayerDataLink,
-			SourceFile:  "net/sched/sch_generic.c",
-			LineNumber:  285,
-			Description: "Bypasses qdisc queue for direct transmission when possible.",
+			ID:               "dev_requeue_skb",
+			Name:             "dev_requeue_skb",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/sched/sch_generic.c",
+			LineNumber:       120,
+			ExecutionContext: ContextProcess,
+			Description:      "Puts a packet the driver could not take back at the head of the qdisc and reschedules the qdisc, so transmission is retried later from the TX softirq.",
+			RCUProtected:     true,
+			RCUNote:          "Called from sch_direct_xmit inside the same RCU-bh section.",
 		},
 
 		// Driver Layer
 		{
-			ID:          "dev_hard_start_xmit",
-			Name:        "dev_hard_start_xmit",
-			Layer:       LayerDriver,
-			SourceFile:  "net/core/dev.c",
-			LineNumber:  3570,
-			Description: "Final generic layer before driver. Handles XDP and calls driver's ndo_start_xmit.",
+			ID:               "dev_hard_start_xmit",
+			Name:             "dev_hard_start_xmit",
+			Layer:            LayerDriver,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       3570,
+			ExecutionContext: ContextProcess,
+			Description:      "Final generic layer before driver. Handles XDP and calls driver's ndo_start_xmit.",
+			RCUProtected:     true,
+			RCUNote:          "Device and TX queue remain protected by RCU-bh.",
 		},
 		{
-			ID:          "ndo_start_xmit",
-			Name:        "ndo_start_xmit",
-			Layer:       LayerDriver,
-			SourceFile:  "include/linux/netdevice.h",
-			LineNumber:  1288,
-			Description: "Driver-specific transmit function. Pointer to actual driver implementation (e.g., e1000, virtio-net).",
-			IsExitPoint: true,
+			ID:               "ndo_start_xmit",
+			Name:             "ndo_start_xmit",
+			Layer:            LayerDriver,
+			SourceFile:       "include/linux/netdevice.h",
+			LineNumber:       1288,
+			ExecutionContext: ContextProcess,
+			Description:      "Driver-specific transmit function. Pointer to actual driver implementation (e.g., e1000, virtio-net).",
+			IsExitPoint:      true,
+			EstimatedCostNs:  600,
+			RCUProtected:     true,
+			RCUNote:          "Driver transmit runs within the RCU-bh section.",
 		},
 	}
 
-	// Define the edges (function call relationships)
-	path.Edges = []FunctionEdge{
-		{From: "tcp_sendmsg", To: "tcp_sendmsg_locked", Order: 1},
-		{From: "tcp_sendmsg_locked", To: "tcp_push", Order: 1},
-		{From: "tcp_push", To: "__tcp_push_pending_frames", Order: 1},
-		{From: "__tcp_push_pending_frames", To: "tcp_write_xmit", Order: 1},
-		{From: "tcp_write_xmit", To: "__tcp_transmit_skb", Order: 1},
-		{From: "__tcp_transmit_skb", To: "ip_queue_xmit", Order: 1},
-		{From: "ip_queue_xmit", To: "ip_local_out", Order: 1},
-		{From: "ip_local_out", To: "__ip_local_out", Order: 1},
-		{From: "__ip_local_out", To: "ip_output", Order: 1},
-		{From: "ip_output", To: "ip_finish_output", Order: 1},
-		{From: "ip_finish_output", To: "__ip_finish_output", Order: 1},
-		{From: "__ip_finish_output", To: "ip_finish_output2", Order: 1},
-		{From: "ip_finish_output2", To: "neigh_output", Order: 1},
-		{From: "neigh_output", To: "neigh_hh_output", Order: 1, Condition: "Hardware header cached"},
-		{From: "neigh_hh_output", To: "dev_queue_xmit", Order: 1},
-		{From: "dev_queue_xmit", To: "__dev_queue_xmit", Order: 1},
-		{From: "__dev_queue_xmit", To: "__dev_xmit_skb", Order: 1},
-		{From: "__dev_xmit_skb", To: "sch_direct_xmit", Order: 1, Condition: "Direct transmit allowed"},
-		{From: "sch_direct_xmit", To: "dev_hard_start_xmit", Order: 1},
-		{From: "dev_hard_start_xmit", To: "ndo_start_xmit", Order: 1},
+	b := NewPathBuilder("tcp_ipv4_egress", "TCP/IPv4 Egress Path", "egress", "TCP").
+		Family(FamilyIPv4).
+		Description("The path of a TCP packet from user space through the kernel to the network interface (Linux 5.10.8)")
+	for _, fn := range functions {
+		b.AddFunction(fn)
 	}
 
-	return path
+	// Define the edges (function call relationships). Edges from the same
+	// function are ordered as connected: the default path comes first.
+	return b.
+		Connect("tcp_sendmsg", "tcp_sendmsg_locked").
+		Connect("tcp_sendmsg_locked", "tcp_push").
+		Connect("tcp_sendmsg_locked", "skb_zerocopy_iter_stream", WithCondition("MSG_ZEROCOPY set")).
+		Connect("skb_zerocopy_iter_stream", "tcp_push").
+		Connect("tcp_sendmsg_locked", "sk_stream_wait_memory", WithCondition("Send buffer full")).
+		Connect("sk_stream_wait_memory", "tcp_push", WithCondition("Woken by freed send memory, or -EAGAIN after a partial copy")).
+		Connect("tcp_push", "__tcp_push_pending_frames").
+		Connect("__tcp_push_pending_frames", "tcp_write_xmit").
+		Connect("tcp_write_xmit", "__tcp_transmit_skb").
+		Connect("tcp_write_xmit", "tcp_tso_should_defer", WithCondition("TSO deferral beneficial")).
+		Connect("tcp_write_xmit", "tcp_small_queue_check", WithCondition("Too many bytes queued below TCP (TSQ)")).
+		Connect("__tcp_transmit_skb", "ip_queue_xmit").
+		Connect("ip_queue_xmit", "ip_local_out").
+		Connect("ip_queue_xmit", "fib_rules_lookup", WithCondition("skb->mark matches an ip rule")).
+		Connect("fib_rules_lookup", "ip_local_out").
+		Connect("ip_local_out", "__ip_local_out").
+		Connect("__ip_local_out", "ip_output").
+		Connect("ip_output", "ip_finish_output").
+		Connect("ip_finish_output", "__ip_finish_output").
+		Connect("__ip_finish_output", "ip_finish_output2", WithCondition("Not GSO, fits the MTU")).
+		Connect("__ip_finish_output", "ip_finish_output_gso", WithCondition("skb_is_gso")).
+		Connect("__ip_finish_output", "ip_fragment", WithCondition("Not GSO, larger than the MTU")).
+		Connect("ip_finish_output_gso", "ip_finish_output2", WithCondition("Segments fit the MTU")).
+		Connect("ip_fragment", "ip_finish_output2", WithCondition("Once per fragment")).
+		Connect("ip_finish_output2", "neigh_output").
+		Connect("neigh_output", "neigh_hh_output", WithCondition("Hardware header cached")).
+		Connect("neigh_output", "neigh_resolve_output", WithCondition("No cached hardware header")).
+		Connect("neigh_hh_output", "dev_queue_xmit").
+		Connect("neigh_resolve_output", "dev_queue_xmit", WithCondition("Neighbour entry valid")).
+		Connect("dev_queue_xmit", "__dev_queue_xmit").
+		Connect("__dev_queue_xmit", "__dev_xmit_skb").
+		Connect("__dev_xmit_skb", "sch_direct_xmit", WithCondition("Direct transmit allowed")).
+		Connect("sch_direct_xmit", "dev_hard_start_xmit").
+		Connect("sch_direct_xmit", "dev_requeue_skb", WithCondition("Driver returned NETDEV_TX_BUSY"), AsErrorPath()).
+		Connect("dev_hard_start_xmit", "ndo_start_xmit").
+		SetEntry("tcp_sendmsg").
+		SetExit("ndo_start_xmit", "tcp_tso_should_defer", "tcp_small_queue_check", "dev_requeue_skb").
+		MustBuild()
 }
 
 // GetDefaultBufferSize returns the typical sk_buff allocation size
@@ -0,0 +1,83 @@
+package contract
+
+import "fmt"
+
+// BranchExplanation tells whether the simulation followed one outgoing edge
+// of a function, and why.
+type BranchExplanation struct {
+	// Edge is the outgoing edge
+	Edge FunctionEdge `json:"edge"`
+
+	// Taken reports whether the simulation followed the edge
+	Taken bool `json:"taken"`
+
+	// Fallback reports that the edge was taken only because nothing
+	// selected another one: it is the function's default edge
+	Fallback bool `json:"fallback,omitempty"`
+
+	// Reason explains the outcome
+	Reason string `json:"reason"`
+}
+
+// ExplainBranch explains, for each outgoing edge of fromID, whether a
+// simulation of the flow with the default options follows it. See
+// ExplainBranchWithOptions.
+func (path *PacketPath) ExplainBranch(flow FlowKey, fromID string) []BranchExplanation {
+	opts := DefaultSimulateOptions()
+	opts.Flow = flow
+	return path.ExplainBranchWithOptions(opts, fromID)
+}
+
+// ExplainBranchWithOptions explains, for each outgoing edge of fromID,
+// whether a simulation with the given options follows it. An edge is
+// selected either by the options (or the packet state at fromID), which
+// the explanation names, or, when nothing selects one, by falling back to
+// the first non-error edge. Edges are explained even if the simulation
+// never reaches fromID; none is then taken. Returns nil if fromID has no
+// outgoing edges.
+func (path *PacketPath) ExplainBranchWithOptions(opts SimulateOptions, fromID string) []BranchExplanation {
+	edges := NewFunctionGraph(path).GetOutgoingEdges(fromID)
+	if len(edges) == 0 {
+		return nil
+	}
+
+	ctx := path.run(opts, path.initialSKBuff(opts), nil)
+	reached := false
+	for _, step := range ctx.steps {
+		if step.Function.ID == fromID {
+			reached = true
+			break
+		}
+	}
+	takenTo := ""
+	if taken := ctx.nextEdge(fromID, edges); taken != nil {
+		takenTo = taken.To
+	}
+	choice, selected := ctx.branches[fromID]
+
+	explanations := make([]BranchExplanation, len(edges))
+	for i, edge := range edges {
+		e := BranchExplanation{Edge: edge}
+		switch {
+		case !reached:
+			e.Reason = fmt.Sprintf("not taken: the simulation does not reach %s", fromID)
+		case edge.To == takenTo && selected && choice.to == edge.To:
+			e.Taken = true
+			e.Reason = "taken: " + choice.reason
+		case edge.To == takenTo:
+			e.Taken, e.Fallback = true, true
+			e.Reason = "taken by default: nothing in the options or the packet state selects another edge"
+		case selected && choice.to == takenTo:
+			e.Reason = fmt.Sprintf("not taken: %s, so %s is followed instead", choice.reason, takenTo)
+		case edge.IsErrorPath:
+			e.Reason = "not taken: error path, followed only when its condition fails the packet"
+		default:
+			e.Reason = "not taken: nothing selects this edge"
+		}
+		if !e.Taken && edge.Condition != "" && reached {
+			e.Reason += fmt.Sprintf(" (requires: %s)", edge.Condition)
+		}
+		explanations[i] = e
+	}
+	return explanations
+}
@@ -1,22 +1,177 @@
 package contract
 
-import (
-	"encoding/json"
-)
-
 // ExportOptions configures the JSON export.
 type ExportOptions struct {
 	// Pretty enables indented JSON output
 	Pretty bool
 
+	// IndentWidth is the number of spaces per indentation level of pretty
+	// output (0 = 2)
+	IndentWidth int
+
 	// IncludeSimulation includes a pre-computed simulation run
 	IncludeSimulation bool
 
+	// IncludeLayerTransitions includes the layer-crossing events derived
+	// from the simulation (requires IncludeSimulation)
+	IncludeLayerTransitions bool
+
+	// IncludeAnimationHints includes per-step keyframe hints for tweening
+	// the sk_buff pointers (requires IncludeSimulation)
+	IncludeAnimationHints bool
+
+	// DeltaSimulation exports the simulation as an initial sk_buff plus
+	// per-step deltas instead of a full snapshot per step
+	DeltaSimulation bool
+
+	// IncludeUserspaceEquivalents annotates each function with its
+	// counterpart in a userspace TCP/IP stack (gVisor's netstack)
+	IncludeUserspaceEquivalents bool
+
+	// CollapseWrappers removes thin wrapper functions (see
+	// PacketPath.CollapseWrappers) for a higher-level view
+	CollapseWrappers bool
+
 	// BufferSize is the sk_buff size for simulation (default: 2048)
 	BufferSize int
 
 	// PayloadSize is the initial payload size for simulation (default: 1000)
 	PayloadSize int
+
+	// SendBufferSize models SO_SNDBUF for simulation (0 = unlimited)
+	SendBufferSize int
+
+	// RecvBufferSize models SO_RCVBUF for simulation (0 = unlimited)
+	RecvBufferSize int
+
+	// FragmentCount is the number of IP fragments for the ingress simulation
+	FragmentCount int
+
+	// PacketTaps is the number of AF_PACKET sniffers for the ingress simulation
+	PacketTaps int
+
+	// GROFlush coalesces the ingress packet in GRO, flushed for this reason
+	GROFlush string
+
+	// SocketLookup is the tcp_v4_rcv socket lookup result for the ingress
+	// simulation: "established" (default), "listen", "timewait" or "none"
+	SocketLookup string
+
+	// GenericXDP is the verdict of a generic-mode XDP program: "pass" or
+	// "drop" ("" = none attached)
+	GenericXDP string
+
+	// QuickAck makes the ingress receiver ACK immediately (quick-ACK mode)
+	QuickAck bool
+
+	// ListenBacklog, SynQueueLen and AcceptQueueLen set up the listening
+	// socket's queues for the handshake lookups (listen, syn_recv)
+	ListenBacklog  int
+	SynQueueLen    int
+	AcceptQueueLen int
+
+	// UDPSegment is the UDP_SEGMENT (GSO) size for UDP egress (0 = off)
+	UDPSegment int
+
+	// GSOPartial is the device's partial-GSO boundary in segments: software
+	// GSO stops at chunks that large and the NIC finishes (0 = off)
+	GSOPartial int
+
+	// MTU is the egress path MTU; a larger non-GSO packet is fragmented
+	// (0 = 1500)
+	MTU int
+
+	// MSS is the MSS of the sending TCP connection (0 = 1460, or 1440 over
+	// IPv6)
+	MSS int
+
+	// NoGSO takes GSO and TSO off the egress route, so a TCP payload larger
+	// than the MSS is sent as separate segments
+	NoGSO bool
+
+	// SocketFilter is the verdict of a socket filter on the receiving
+	// socket: "allow" or "deny" ("" = no filter)
+	SocketFilter string
+
+	// SocketFilterLen is the number of bytes an allowing filter keeps (0 = all)
+	SocketFilterLen int
+
+	// SocketOwnedByUser makes a process own the receiving socket, so the
+	// ingress segment is backlogged until release_sock
+	SocketOwnedByUser bool
+
+	// TCIngress attaches an ingress qdisc whose classifiers return the given
+	// verdict: "ok", "shot" or "redirect" ("" = none)
+	TCIngress string
+
+	// RecvCoalesce makes the received data coalesce into the receive
+	// queue's tail sk_buff (tcp_try_coalesce)
+	RecvCoalesce bool
+
+	// BridgeFDB is the bridge FDB lookup result for the frame's destination
+	// MAC: "known" or "unknown" ("" = known)
+	BridgeFDB string
+
+	// BridgePorts is the number of ports of the bridge (0 = 4)
+	BridgePorts int
+
+	// ARPOperation is the operation of the received ARP packet: "request"
+	// or "reply" ("" = request)
+	ARPOperation string
+
+	// TxQueues is the number of TX queues of the transmitting device (0 = 4)
+	TxQueues int
+
+	// XPS selects the TX queue from the sending CPU's XPS map
+	XPS bool
+
+	// CPU is the CPU the sender runs on (used by XPS)
+	CPU int
+
+	// RTTSample is the RTT in microseconds measured by an ingress pure
+	// ACK (0 = 20 ms)
+	RTTSample int
+
+	// IPOptions is the IPv4 option carried by the ingress packet:
+	// "record_route", "timestamp", "source_route" or "invalid" ("" = none)
+	IPOptions string
+
+	// CorruptChecksum makes the ingress packet fail checksum validation
+	CorruptChecksum bool
+
+	// CorruptHeader selects the failing checksum: "ip" or "tcp" (default)
+	CorruptHeader string
+
+	// DeviceBusy makes the driver refuse the egress packet (NETDEV_TX_BUSY)
+	DeviceBusy bool
+
+	// QdiscBacklog is the bytes of the sending socket's earlier segments
+	// still in the qdisc and driver, which TSQ limits (0 = none)
+	QdiscBacklog int
+
+	// TSODefer makes tcp_tso_should_defer hold back the TSO send
+	TSODefer bool
+
+	// ZeroCopy simulates sends with MSG_ZEROCOPY
+	ZeroCopy bool
+
+	// NATRules are nat table rules matching the simulated flow
+	NATRules []NATRule
+
+	// ICMPError makes the ingress packet an ICMP error quoting a TCP
+	// segment: "frag_needed" or "port_unreach" ("" = none)
+	ICMPError string
+
+	// ConntrackHelper assigns a conntrack helper to the connection: "ftp"
+	// ("" = none)
+	ConntrackHelper string
+
+	// NonBlocking simulates sends on a non-blocking socket, which get
+	// -EAGAIN instead of blocking when the send buffer is full
+	NonBlocking bool
+
+	// Mark is the skb->mark for simulation (0 = unmarked)
+	Mark uint32
 }
 
 // DefaultExportOptions returns sensible defaults for export.
@@ -26,6 +181,55 @@ func DefaultExportOptions() ExportOptions {
 		IncludeSimulation: true,
 		BufferSize:        GetDefaultBufferSize(),
 		PayloadSize:       GetDefaultPayloadSize(),
+		MTU:               defaultMTU,
+	}
+}
+
+// SimulateOptions returns the simulation options described by the export options.
+func (opts ExportOptions) SimulateOptions() SimulateOptions {
+	return SimulateOptions{
+		BufferSize:        opts.BufferSize,
+		PayloadSize:       opts.PayloadSize,
+		SendBufferSize:    opts.SendBufferSize,
+		RecvBufferSize:    opts.RecvBufferSize,
+		FragmentCount:     opts.FragmentCount,
+		PacketTaps:        opts.PacketTaps,
+		GROFlush:          opts.GROFlush,
+		SocketLookup:      opts.SocketLookup,
+		GenericXDP:        opts.GenericXDP,
+		UDPSegment:        opts.UDPSegment,
+		GSOPartial:        opts.GSOPartial,
+		MTU:               opts.MTU,
+		MSS:               opts.MSS,
+		NoGSO:             opts.NoGSO,
+		QuickAck:          opts.QuickAck,
+		ListenBacklog:     opts.ListenBacklog,
+		SynQueueLen:       opts.SynQueueLen,
+		AcceptQueueLen:    opts.AcceptQueueLen,
+		SocketFilter:      opts.SocketFilter,
+		SocketFilterLen:   opts.SocketFilterLen,
+		SocketOwnedByUser: opts.SocketOwnedByUser,
+		TCIngress:         opts.TCIngress,
+		RecvCoalesce:      opts.RecvCoalesce,
+		BridgeFDB:         opts.BridgeFDB,
+		BridgePorts:       opts.BridgePorts,
+		ARPOperation:      opts.ARPOperation,
+		TxQueues:          opts.TxQueues,
+		XPS:               opts.XPS,
+		CPU:               opts.CPU,
+		RTTSample:         opts.RTTSample,
+		IPOptions:         opts.IPOptions,
+		CorruptChecksum:   opts.CorruptChecksum,
+		CorruptHeader:     opts.CorruptHeader,
+		DeviceBusy:        opts.DeviceBusy,
+		QdiscBacklog:      opts.QdiscBacklog,
+		TSODefer:          opts.TSODefer,
+		ZeroCopy:          opts.ZeroCopy,
+		NATRules:          opts.NATRules,
+		NonBlocking:       opts.NonBlocking,
+		ConntrackHelper:   opts.ConntrackHelper,
+		ICMPError:         opts.ICMPError,
+		Mark:              opts.Mark,
 	}
 }
 
@@ -54,7 +258,26 @@ type PathWithSimulation struct {
 	Path PacketPath `json:"path"`
 
 	// Simulation is the pre-computed simulation (optional)
-	Simulation []SimulateStep `json:"simulation,omitempty"`
+	Simulation SimulateSteps `json:"simulation,omitempty"`
+
+	// DeltaSimulation replaces Simulation when ExportOptions.DeltaSimulation is set
+	DeltaSimulation *DeltaSimulation `json:"deltaSimulation,omitempty"`
+
+	// HookTimeline lists the hooks a packet passes on the primary path, in order
+	HookTimeline []HookEvent `json:"hookTimeline"`
+
+	// LayerTransitions lists the steps where the packet changes layer (optional)
+	LayerTransitions []LayerTransition `json:"layerTransitions,omitempty"`
+
+	// AnimationHints lists pointer tweens for the steps where the sk_buff
+	// pointers move (optional)
+	AnimationHints []StepAnimation `json:"animationHints,omitempty"`
+
+	// Glossary defines the acronyms used in the path's function descriptions
+	Glossary map[string]string `json:"glossary,omitempty"`
+
+	// SendResult is what the simulated send() returned (egress simulations only)
+	SendResult *SendResult `json:"sendResult,omitempty"`
 }
 
 // ExportMetadata contains frontend-relevant metadata.
@@ -62,6 +285,9 @@ type ExportMetadata struct {
 	// Layers lists all layers in order for rendering
 	Layers []LayerInfo `json:"layers"`
 
+	// LayerModels maps each layer to the OSI and TCP/IP reference models
+	LayerModels []LayerMapping `json:"layerModels"`
+
 	// HeaderSizes maps protocol names to their sizes
 	HeaderSizes map[string]int `json:"headerSizes"`
 
@@ -70,6 +296,9 @@ type ExportMetadata struct {
 
 	// PayloadSize is the initial payload size
 	PayloadSize int `json:"payloadSize"`
+
+	// PathMetrics maps path IDs to their graph complexity metrics
+	PathMetrics map[string]GraphMetrics `json:"pathMetrics"`
 }
 
 // LayerInfo provides rendering information for a layer.
@@ -87,27 +316,76 @@ type LayerInfo struct {
 	Order int `json:"order"`
 }
 
-// ExportAllPaths exports both egress and ingress paths as JSON.
+// ExportAllPaths exports every path in the default registry as JSON.
 func ExportAllPaths(opts ExportOptions) ([]byte, error) {
-	egressPath := BuildTCPIPv4EgressPath()
-	ingressPath := BuildTCPIPv4IngressPath()
+	return ExportRegistry(DefaultRegistry(), opts)
+}
 
-	paths := []PathWithSimulation{
-		{Path: *egressPath},
-		{Path: *ingressPath},
+// ExportRegistry exports every path in the given registry as JSON.
+func ExportRegistry(registry *PathRegistry, opts ExportOptions) ([]byte, error) {
+	export, err := BuildExport(registry, opts)
+	if err != nil {
+		return nil, err
 	}
+	return JSONRenderer{Pretty: opts.Pretty, IndentWidth: opts.IndentWidth}.Render(export)
+}
 
-	if opts.IncludeSimulation {
-		// Egress simulation: start with payload, push headers
-		paths[0].Simulation = egressPath.Simulate(opts.BufferSize, opts.PayloadSize)
+// BuildExport assembles the export structure for every path in the given
+// registry, ready to be handed to a Renderer. GeneratedAt is left empty for
+// the caller to set. It fails with the *ValidationError of the first path
+// that does not validate, rather than export a broken contract; overlays of
+// two versions are exempt.
+func BuildExport(registry *PathRegistry, opts ExportOptions) (*ExportPacket, error) {
+	paths := []PathWithSimulation{}
+	metrics := make(map[string]GraphMetrics)
+	for _, path := range registry.Paths() {
+		if opts.CollapseWrappers {
+			path = path.CollapseWrappers()
+		}
+		if !path.isOverlay() {
+			if err := path.Validate(); err != nil {
+				return nil, err
+			}
+		}
+		metrics[path.ID] = NewFunctionGraph(path).Metrics()
+		// Canonical edge order so the frontend never depends on authoring order
+		path.SortEdges()
+		if opts.IncludeUserspaceEquivalents {
+			path.annotateUserspaceEquivalents()
+		}
+		path.annotateGlossaryTerms()
+		paths = append(paths, PathWithSimulation{
+			Path:         *path,
+			HookTimeline: path.HookTimeline(),
+			Glossary:     path.Glossary(),
+		})
+	}
 
-		// Ingress simulation: start with full packet, pull headers
-		paths[1].Simulation = ingressPath.SimulateIngress(opts.BufferSize, opts.PayloadSize)
+	if opts.IncludeSimulation {
+		// Egress starts with payload and pushes headers;
+		// ingress starts with the full packet and pulls headers
+		simOpts := opts.SimulateOptions()
+		for i := range paths {
+			paths[i].Simulation = paths[i].Path.SimulateWithOptions(simOpts)
+			if opts.IncludeLayerTransitions {
+				paths[i].LayerTransitions = paths[i].Simulation.LayerTransitions()
+			}
+			if opts.IncludeAnimationHints {
+				paths[i].AnimationHints = paths[i].Simulation.AnimationHints()
+			}
+			if paths[i].Path.Direction == "egress" {
+				paths[i].SendResult = paths[i].Simulation.SendResult(opts.PayloadSize)
+			}
+			if opts.DeltaSimulation {
+				paths[i].DeltaSimulation = paths[i].Simulation.ToDeltas()
+				paths[i].Simulation = nil
+			}
+		}
 	}
 
-	export := ExportPacket{
-		Version:       "1.1.0",
-		KernelVersion: "5.10.8",
+	return &ExportPacket{
+		Version:       SchemaVersion,
+		KernelVersion: KernelVersion,
 		GeneratedAt:   "", // Will be set by caller if needed
 		Paths:         paths,
 		Metadata: ExportMetadata{
@@ -119,6 +397,7 @@ func ExportAllPaths(opts ExportOptions) ([]byte, error) {
 				{ID: "datalink", Name: "Data Link Layer", CSSClass: "layer-datalink", Order: 4},
 				{ID: "driver", Name: "Device Driver", CSSClass: "layer-driver", Order: 5},
 			},
+			LayerModels: LayerModelMapping(),
 			HeaderSizes: map[string]int{
 				"ethernet": EthernetHeaderSize,
 				"ip":       IPv4HeaderSize,
@@ -126,16 +405,14 @@ func ExportAllPaths(opts ExportOptions) ([]byte, error) {
 				"tcp":      TCPHeaderSize,
 				"udp":      UDPHeaderSize,
 				"icmp":     ICMPHeaderSize,
+				"esp":      ESPHeaderSize,
+				"arp":      ARPHeaderSize,
 			},
 			BufferSize:  opts.BufferSize,
 			PayloadSize: opts.PayloadSize,
+			PathMetrics: metrics,
 		},
-	}
-
-	if opts.Pretty {
-		return json.MarshalIndent(export, "", "  ")
-	}
-	return json.Marshal(export)
+	}, nil
 }
 
 // ExportAllPathsJSON is a convenience function with default options.
@@ -143,12 +420,20 @@ func ExportAllPathsJSON() ([]byte, error) {
 	return ExportAllPaths(DefaultExportOptions())
 }
 
-// Legacy: ExportTCPIPv4EgressPath exports only the egress path (for backward compatibility).
+// ExportTCPIPv4EgressPath exports every path in the default registry. It
+// predates the registry, when the egress path was the only one.
+//
+// Deprecated: Use ExportAllPaths, which it is equivalent to.
 func ExportTCPIPv4EgressPath(opts ExportOptions) ([]byte, error) {
+	deprecated("ExportTCPIPv4EgressPath", "ExportAllPaths")
 	return ExportAllPaths(opts)
 }
 
-// Legacy: ExportEgressPathJSON is a convenience function with default options.
+// ExportEgressPathJSON exports every path in the default registry with
+// default options.
+//
+// Deprecated: Use ExportAllPathsJSON, which it is equivalent to.
 func ExportEgressPathJSON() ([]byte, error) {
+	deprecated("ExportEgressPathJSON", "ExportAllPathsJSON")
 	return ExportAllPathsJSON()
 }
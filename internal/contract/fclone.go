@@ -0,0 +1,35 @@
+package contract
+
+// effectFCloneAlloc models sk_stream_alloc_skb allocating the TCP data
+// sk_buff from the fclone cache, since TCP always clones before transmit.
+func effectFCloneAlloc(ctx *simContext, step *SimulateStep) {
+	ctx.skb.FClone = true
+}
+
+// effectTransmitClone models __tcp_transmit_skb cloning the sk_buff: the
+// original stays on the retransmission queue and the clone is passed down
+// to IP. With an fclone'd sk_buff the clone uses the pre-reserved slot
+// instead of a fresh allocation, and neither copy duplicates the payload.
+func effectTransmitClone(ctx *simContext, step *SimulateStep) {
+	ctx.skb.Cloned = true
+
+	if ctx.skb.FClone {
+		step.annotate(AnnotationClone,
+			"skb_clone uses the fclone companion slot: the original stays queued for retransmission "+
+				"while the clone, sharing the same data buffer, is passed down to IP.")
+		return
+	}
+	step.annotate(AnnotationClone,
+		"skb_clone allocates a new sk_buff head sharing the data buffer: the original stays queued "+
+			"for retransmission while the clone is passed down to IP.")
+}
+
+// effectZeroCopy models MSG_ZEROCOPY: the payload stays in the user's pages,
+// which are pinned and referenced by the sk_buff instead of being copied.
+func effectZeroCopy(ctx *simContext, step *SimulateStep) {
+	ctx.skb.ZeroCopy = true
+	step.annotate(AnnotationZeroCopy,
+		"No copy into kernel memory: the user pages are pinned and referenced as paged fragments. "+
+			"The application must not reuse the buffer until a completion notification arrives on the "+
+			"socket error queue (SO_EE_ORIGIN_ZEROCOPY), sent once the data is acknowledged.")
+}
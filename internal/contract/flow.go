@@ -0,0 +1,79 @@
+package contract
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// FlowKey identifies a flow by its 5-tuple. It is the input the kernel's
+// flow dissector (__skb_flow_dissect) extracts from packet headers.
+type FlowKey struct {
+	// SrcIP is the source IP address
+	SrcIP string `json:"srcIp"`
+
+	// DstIP is the destination IP address
+	DstIP string `json:"dstIp"`
+
+	// SrcPort is the transport source port
+	SrcPort uint16 `json:"srcPort"`
+
+	// DstPort is the transport destination port
+	DstPort uint16 `json:"dstPort"`
+
+	// Protocol is the transport protocol (e.g., "TCP", "UDP")
+	Protocol string `json:"protocol"`
+}
+
+// DefaultFlowKey returns the flow used when a simulation does not specify one:
+// a client connection to a local web server.
+func DefaultFlowKey() FlowKey {
+	return FlowKey{
+		SrcIP:    "192.168.1.10",
+		DstIP:    "192.168.1.20",
+		SrcPort:  43512,
+		DstPort:  80,
+		Protocol: "TCP",
+	}
+}
+
+// DefaultFlowKeyIPv6 returns the IPv6 counterpart of DefaultFlowKey, with
+// addresses from the documentation prefix 2001:db8::/32.
+func DefaultFlowKeyIPv6() FlowKey {
+	flow := DefaultFlowKey()
+	flow.SrcIP = "2001:db8::10"
+	flow.DstIP = "2001:db8::20"
+	return flow
+}
+
+// IsZero reports whether no field of the flow key is set.
+func (k FlowKey) IsZero() bool {
+	return k == FlowKey{}
+}
+
+// String returns the flow in "PROTO src:port -> dst:port" form.
+func (k FlowKey) String() string {
+	return fmt.Sprintf("%s %s:%d -> %s:%d", k.Protocol, k.SrcIP, k.SrcPort, k.DstIP, k.DstPort)
+}
+
+// Hash returns the flow hash used for steering and GRO grouping.
+// The kernel computes skb->hash with siphash over the dissected keys; this
+// model uses FNV-1a, which is equally deterministic per flow.
+func (k FlowKey) Hash() uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(k.String()))
+	return h.Sum32()
+}
+
+// AnnotationFlowDissection marks the step where the flow hash is computed.
+const AnnotationFlowDissection = "flow_dissection"
+
+// effectFlowHash models GRO dissecting the packet headers to compute the
+// flow hash. The same hash later drives RPS/RFS CPU steering and groups
+// packets of one flow for coalescing.
+func effectFlowHash(ctx *simContext, step *SimulateStep) {
+	ctx.skb.FlowHash = ctx.flow.Hash()
+	step.annotate(AnnotationFlowDissection, fmt.Sprintf(
+		"__skb_flow_dissect extracts the 5-tuple (%s) and sets skb->hash = 0x%08x. "+
+			"GRO groups packets with the same hash for coalescing, and RPS/RFS use it to pick a CPU.",
+		ctx.flow, ctx.skb.FlowHash))
+}
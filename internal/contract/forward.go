@@ -0,0 +1,138 @@
+package contract
+
+// BuildTCPIPv4ForwardPath constructs the path of a TCP/IPv4 packet routed
+// through this host to another one, based on Linux Kernel 5.10.8.
+//
+// The packet is received like any other up to the routing decision in
+// ip_rcv_finish, which finds no local address and hands it to ip_forward.
+// From ip_output on it leaves through the same output path as locally
+// generated traffic, but in softirq context. When GRO coalesced the received
+// segments, the resulting super-sk_buff is forwarded whole and segmented
+// again by GSO on the way out, so the router pays the per-packet cost once
+// per aggregate instead of once per segment.
+func BuildTCPIPv4ForwardPath() *PacketPath {
+	ingress := BuildTCPIPv4IngressPath()
+	egress := BuildTCPIPv4EgressPath()
+
+	forward := []KernelFunction{
+		{
+			ID:               "ip_forward",
+			Name:             "ip_forward",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/ip_forward.c",
+			LineNumber:       86,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Checks that forwarding is enabled and the TTL allows another hop (sending ICMP Time Exceeded otherwise), decrements the TTL and invokes the FORWARD netfilter hook.",
+			NetfilterHook:    NewForwardHook(),
+			ConfigDeps:       []string{"CONFIG_NETFILTER"},
+		},
+		{
+			ID:               "ip_forward_finish",
+			Name:             "ip_forward_finish",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/ip_forward.c",
+			LineNumber:       63,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Counts the forwarded datagram (OutForwDatagrams) and sends it with dst_output, which is ip_output for a unicast route.",
+		},
+	}
+
+	gso := []KernelFunction{
+		{
+			ID:               "validate_xmit_skb",
+			Name:             "validate_xmit_skb",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       3637,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Checks the sk_buff against the device features. A GSO packet the device cannot segment is passed to skb_gso_segment.",
+		},
+		{
+			ID:               "__skb_gso_segment",
+			Name:             "__skb_gso_segment",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       3366,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Software GSO: calls the protocol's gso_segment callbacks (inet_gso_segment, tcp4_gso_segment) to split the GRO super-sk_buff back into MSS-sized segments with their own IP and TCP headers. With NETIF_F_GSO_PARTIAL it stops at a larger boundary and the NIC finishes the split.",
+			EstimatedCostNs:  2000,
+		},
+	}
+
+	b := NewPathBuilder("tcp_ipv4_forward", "TCP/IPv4 Forwarding Path", "forward", "TCP").
+		Family(FamilyIPv4).
+		Description("The path of a TCP/IPv4 packet received on one interface and routed out of another, including GRO on receive and GSO re-segmentation on transmit (Linux 5.10.8)")
+
+	// Receive side, up to and including the routing decision, and the
+	// drop point its error edges lead to
+	shared := make(map[string]bool)
+	received := false
+	for _, fn := range ingress.Functions {
+		if received && fn.ID != "kfree_skb" {
+			continue
+		}
+		if fn.ID == "ip_rcv_finish" {
+			// The IP header stays: the packet is forwarded, not delivered
+			fn.SKBMutation = nil
+			fn.Description = "Finishes IP header processing and performs the routing lookup. The destination is not local, so the route's input handler is ip_forward."
+			received = true
+		}
+		fn.IsEntryPoint, fn.IsExitPoint = false, false
+		shared[fn.ID] = true
+		b.AddFunction(fn)
+	}
+	for _, fn := range forward {
+		b.AddFunction(fn)
+	}
+
+	// Transmit side: the local output path from ip_output, run in softirq
+	reached := false
+	for _, fn := range egress.Functions {
+		if fn.ID == "ip_output" {
+			reached = true
+		}
+		if !reached {
+			continue
+		}
+		fn.ExecutionContext = ContextSoftIRQ
+		if fn.ID == "ip_finish_output" {
+			// cgroup egress programs need a local socket
+			fn.BPFHook, fn.ConfigDeps = nil, nil
+			fn.Description = "Passes the packet on to the GSO and fragmentation decision. Forwarded packets have no local socket, so cgroup egress programs do not run."
+		}
+		fn.IsEntryPoint, fn.IsExitPoint = false, false
+		shared[fn.ID] = true
+		b.AddFunction(fn)
+		if fn.ID == "__dev_queue_xmit" {
+			for _, g := range gso {
+				b.AddFunction(g)
+			}
+		}
+	}
+
+	for _, edges := range [][]FunctionEdge{ingress.Edges, egress.Edges} {
+		for _, edge := range edges {
+			if !shared[edge.From] || !shared[edge.To] {
+				continue
+			}
+			opts := []EdgeOption{WithCondition(edge.Condition)}
+			if edge.IsErrorPath {
+				opts = append(opts, AsErrorPath())
+			}
+			b.Connect(edge.From, edge.To, opts...)
+			if edge.From == "__dev_queue_xmit" {
+				b.Connect("__dev_queue_xmit", "validate_xmit_skb", WithCondition("GSO sk_buff, device lacks the needed segmentation offload"))
+			}
+		}
+	}
+
+	return b.
+		Connect("ip_rcv_finish", "ip_forward", WithCondition("Destination is not local")).
+		Connect("ip_forward", "ip_forward_finish").
+		Connect("ip_forward_finish", "ip_output").
+		Connect("validate_xmit_skb", "__skb_gso_segment").
+		Connect("__skb_gso_segment", "__dev_xmit_skb").
+		SetEntry("napi_poll").
+		SetExit("packet_rcv", "skb_do_redirect", "ndo_start_xmit", "dev_requeue_skb").
+		MustBuild()
+}
@@ -1,5 +1,10 @@
 package contract
 
+import (
+	"fmt"
+	"strings"
+)
+
 // KernelFunction represents a single function node in the kernel call graph.
 // Each function has metadata about its location, purpose, and how it
 // mutates the sk_buff structure.
@@ -31,16 +36,58 @@ type KernelFunction struct {
 	// BPFHook indicates if this function has a BPF/XDP attachment point (nil if none)
 	BPFHook *BPFHook `json:"bpfHook,omitempty"`
 
+	// EstimatedCostNs is an order-of-magnitude execution cost in nanoseconds
+	// (0 = use the layer default, see CostEstimate)
+	EstimatedCostNs int `json:"estimatedCostNs,omitempty"`
+
+	// RCUProtected indicates the function runs inside an rcu_read_lock section
+	RCUProtected bool `json:"rcuProtected,omitempty"`
+
+	// RCUNote explains what the RCU section protects at this function
+	RCUNote string `json:"rcuNote,omitempty"`
+
+	// ExecutionContext is the kernel context the function runs in on the
+	// path's primary route: ContextProcess, ContextSoftIRQ or ContextHardIRQ
+	ExecutionContext string `json:"executionContext,omitempty"`
+
+	// UserspaceEquivalent names the analogous function or concept in a
+	// userspace TCP/IP stack (gVisor's netstack); only set when requested
+	// at export
+	UserspaceEquivalent string `json:"userspaceEquivalent,omitempty"`
+
+	// VersionPresence is set by OverlayVersions to PresenceV1Only or
+	// PresenceV2Only (empty = present in both versions)
+	VersionPresence string `json:"versionPresence,omitempty"`
+
+	// Terms are the glossary terms mentioned in Description (set at export)
+	Terms []string `json:"glossaryTerms,omitempty"`
+
+	// WrapperOf is the ID of the function this one is a thin wrapper
+	// around (e.g., "__dev_queue_xmit" for dev_queue_xmit); wrappers are
+	// removed by CollapseWrappers
+	WrapperOf string `json:"wrapperOf,omitempty"`
+
+	// ConntrackEvent is the conntrack event the packet causes at this
+	// function (see ConntrackEventSYN); the simulation advances the
+	// conntrack entry with ConntrackMachine (empty = none)
+	ConntrackEvent string `json:"conntrackEvent,omitempty"`
+
+	// ConfigDeps lists the kernel CONFIG options this function's modeled role
+	// requires (e.g., "CONFIG_NETFILTER" for a netfilter hook point)
+	ConfigDeps []string `json:"configDeps,omitempty"`
+
 	// IsEntryPoint indicates if this is a valid starting point for a path
 	IsEntryPoint bool `json:"isEntryPoint,omitempty"`
 
-	// IsExitPoint indicates if this is an endpoint (packet leaves kernel)
+	// IsExitPoint indicates if this is an endpoint (packet leaves the kernel
+	// or its processing ends here)
 	IsExitPoint bool `json:"isExitPoint,omitempty"`
 }
 
 // SKBMutation describes how a function modifies the sk_buff structure.
 type SKBMutation struct {
-	// Operation is the type of mutation: "push", "pull", "put", "alloc", "free"
+	// Operation is the type of mutation: "push", "pull", "put", "alloc",
+	// "free", "encrypt", "csum"
 	Operation string `json:"operation"`
 
 	// HeaderType is the protocol header affected (e.g., "tcp", "ip", "ethernet")
@@ -51,6 +98,53 @@ type SKBMutation struct {
 
 	// Description is a human-readable explanation of the mutation
 	Description string `json:"description"`
+
+	// CsumState is the checksum state a "csum" mutation sets
+	CsumState string `json:"csumState,omitempty"`
+}
+
+// IsDropPoint reports whether the packet is freed at this function, which
+// makes it a legitimate terminal node without being an exit point.
+func (f KernelFunction) IsDropPoint() bool {
+	return f.SKBMutation != nil && f.SKBMutation.Operation == "free"
+}
+
+// SummaryCard returns a short multi-line summary of the function for
+// tooltips and quick reference: name, layer, source location, the first
+// sentence of the description, and any mutation or hooks.
+func (f KernelFunction) SummaryCard() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", f.Name)
+	fmt.Fprintf(&b, "Layer:  %s\n", f.Layer)
+	if f.LineNumber > 0 {
+		fmt.Fprintf(&b, "Source: %s:%d\n", f.SourceFile, f.LineNumber)
+	} else {
+		fmt.Fprintf(&b, "Source: %s\n", f.SourceFile)
+	}
+
+	desc := f.Description
+	if i := strings.Index(desc, ". "); i >= 0 {
+		desc = desc[:i+1]
+	}
+	fmt.Fprintf(&b, "%s\n", desc)
+
+	if m := f.SKBMutation; m != nil {
+		switch {
+		case m.HeaderType != "" && m.Size > 0:
+			fmt.Fprintf(&b, "Mutation:  %s %s (%d bytes)\n", m.Operation, m.HeaderType, m.Size)
+		case m.HeaderType != "":
+			fmt.Fprintf(&b, "Mutation:  %s %s\n", m.Operation, m.HeaderType)
+		default:
+			fmt.Fprintf(&b, "Mutation:  %s (%d bytes)\n", m.Operation, m.Size)
+		}
+	}
+	if h := f.NetfilterHook; h != nil {
+		fmt.Fprintf(&b, "Netfilter: %s [%s]\n", h.Hook, strings.Join(h.Tables, ", "))
+	}
+	if h := f.BPFHook; h != nil {
+		fmt.Fprintf(&b, "BPF:       %s (%s)\n", h.Type, h.AttachPoint)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
 }
 
 // Common header sizes in bytes
@@ -72,6 +166,12 @@ const (
 
 	// ICMPHeaderSize is the minimum ICMP header size
 	ICMPHeaderSize = 8
+
+	// ESPHeaderSize is the IPsec ESP header size (SPI + sequence number)
+	ESPHeaderSize = 8
+
+	// ARPHeaderSize is the ARP header size for IPv4 over Ethernet
+	ARPHeaderSize = 28
 )
 
 // NewPushMutation creates a mutation representing a header push operation.
@@ -94,6 +194,37 @@ func NewPullMutation(headerType string, size int) *SKBMutation {
 	}
 }
 
+// NewEncryptMutation creates a mutation representing encryption of
+// everything that follows the given header (e.g., ESP encrypting the inner
+// packet). The encrypted headers and payload become opaque.
+func NewEncryptMutation(headerType string) *SKBMutation {
+	return &SKBMutation{
+		Operation:   "encrypt",
+		HeaderType:  headerType,
+		Description: "Encrypt everything after the " + headerType + " header",
+	}
+}
+
+// NewCsumMutation creates a mutation representing a change of the checksum
+// state of the given header's checksum, such as the checksum being verified.
+func NewCsumMutation(headerType, state, description string) *SKBMutation {
+	return &SKBMutation{
+		Operation:   "csum",
+		HeaderType:  headerType,
+		Description: description,
+		CsumState:   state,
+	}
+}
+
+// NewFreeMutation creates a mutation representing the sk_buff being freed.
+// A function with this mutation is a drop point.
+func NewFreeMutation(description string) *SKBMutation {
+	return &SKBMutation{
+		Operation:   "free",
+		Description: description,
+	}
+}
+
 // NewAllocMutation creates a mutation representing sk_buff allocation.
 func NewAllocMutation(size int, description string) *SKBMutation {
 	return &SKBMutation{
@@ -0,0 +1,85 @@
+package contract
+
+import (
+	"regexp"
+	"sort"
+)
+
+// glossary defines the acronyms and jargon used in function descriptions.
+// Uppercase terms match case-sensitively; lowercase terms match any case.
+var glossary = map[string]string{
+	"ACK":       "Acknowledgment: a TCP segment confirming receipt of data up to a sequence number.",
+	"AEAD":      "Authenticated Encryption with Associated Data: a cipher mode that encrypts and authenticates in one pass (e.g., AES-GCM).",
+	"AF_PACKET": "Packet socket family giving raw access to link-layer frames; used by tcpdump and other sniffers.",
+	"BPF":       "Berkeley Packet Filter: in-kernel virtual machine running verified programs at hook points (eBPF).",
+	"CPU":       "Central processing unit; receive work is spread across CPUs by RSS, RPS and RFS.",
+	"ESP":       "Encapsulating Security Payload: the IPsec protocol providing encryption and integrity.",
+	"FIB":       "Forwarding Information Base: the kernel's routing table used for route lookups.",
+	"GRO":       "Generic Receive Offload: coalesces consecutive segments of a flow into one large sk_buff before the stack processes it.",
+	"GSO":       "Generic Segmentation Offload: keeps a large packet intact through the stack and segments it as late as possible.",
+	"MTU":       "Maximum Transmission Unit: the largest IP packet a link can carry without fragmentation.",
+	"NAPI":      "New API: the interrupt-mitigating polling interface drivers use to receive packets in batches.",
+	"NIC":       "Network Interface Card: the hardware device that sends and receives frames.",
+	"PSH":       "Push flag: asks the receiver to deliver buffered data to the application promptly.",
+	"RCU":       "Read-Copy-Update: a synchronization mechanism letting readers access shared data without locks.",
+	"RFS":       "Receive Flow Steering: steers a flow's packets to the CPU where the consuming application runs.",
+	"RPS":       "Receive Packet Steering: software distribution of received packets across CPUs by flow hash.",
+	"SA":        "Security Association: the IPsec state (keys, algorithms, SPI) protecting a traffic flow.",
+	"SACK":      "Selective Acknowledgment: TCP option reporting non-contiguous blocks of received data.",
+	"SPI":       "Security Parameters Index: identifies the Security Association an ESP packet belongs to.",
+	"SYN":       "Synchronize flag: opens a TCP connection during the three-way handshake.",
+	"TSO":       "TCP Segmentation Offload: the NIC splits a large TCP packet into MSS-sized segments.",
+	"TSQ":       "TCP Small Queues: limits the bytes a TCP socket has in the qdisc and driver, to keep queues below TCP short.",
+	"XDP":       "eXpress Data Path: BPF programs that run in the driver before an sk_buff is allocated.",
+	"XFRM":      "The kernel's IPsec transform framework, applying policies and states to packets.",
+	"qdisc":     "Queueing discipline: the traffic-control scheduler that queues packets before the driver.",
+	"conntrack": "Connection tracking: netfilter's record of flow state used for stateful filtering and NAT.",
+	"netfilter": "The kernel's packet filtering framework providing the iptables/nftables hooks.",
+	"softirq":   "Software interrupt: deferred work such as NET_RX_SOFTIRQ that processes packets outside hard IRQ context.",
+	"sk_buff":   "Socket buffer: the kernel structure describing a packet and its data buffer.",
+}
+
+// glossaryPatterns holds a compiled word-boundary pattern per glossary term.
+var glossaryPatterns = func() map[string]*regexp.Regexp {
+	patterns := make(map[string]*regexp.Regexp, len(glossary))
+	for term := range glossary {
+		flags := ""
+		if term[0] >= 'a' && term[0] <= 'z' {
+			flags = "(?i)"
+		}
+		patterns[term] = regexp.MustCompile(flags + `\b` + regexp.QuoteMeta(term) + `\b`)
+	}
+	return patterns
+}()
+
+// GlossaryTerms returns the glossary terms mentioned in the function's
+// description, sorted alphabetically.
+func (f KernelFunction) GlossaryTerms() []string {
+	var terms []string
+	for term, pattern := range glossaryPatterns {
+		if pattern.MatchString(f.Description) {
+			terms = append(terms, term)
+		}
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// Glossary returns the definitions of every glossary term mentioned in the
+// descriptions of the path's functions, keyed by term.
+func (p *PacketPath) Glossary() map[string]string {
+	result := make(map[string]string)
+	for _, fn := range p.Functions {
+		for _, term := range fn.GlossaryTerms() {
+			result[term] = glossary[term]
+		}
+	}
+	return result
+}
+
+// annotateGlossaryTerms fills in GlossaryTerms on every function of the path.
+func (p *PacketPath) annotateGlossaryTerms() {
+	for i := range p.Functions {
+		p.Functions[i].Terms = p.Functions[i].GlossaryTerms()
+	}
+}
@@ -0,0 +1,76 @@
+package contract
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden contract and canonical paths in testdata")
+
+const (
+	goldenFile      = "testdata/contract.golden.json"
+	canonicalDir    = "testdata/paths"
+	goldenTimestamp = "2000-01-01T00:00:00Z"
+)
+
+// goldenExport builds the export of every default path with the options of
+// a plain cmd/contract run, at a fixed timestamp.
+func goldenExport(t *testing.T) *ExportPacket {
+	t.Helper()
+	export, err := BuildExport(DefaultRegistry(), DefaultExportOptions())
+	if err != nil {
+		t.Fatalf("BuildExport: %v", err)
+	}
+	export.GeneratedAt = goldenTimestamp
+	return export
+}
+
+// checkGolden compares got with the golden file at name, or rewrites the
+// file with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(name, got, 0644); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("reading %s: %v (run go test -update to create it)", name, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the generated output; run 'go test ./internal/contract -update' if the change is intended", name)
+	}
+}
+
+// TestGoldenContract locks the full export, so any change to the model or
+// the simulation shows up as a diff of the golden file.
+func TestGoldenContract(t *testing.T) {
+	got, err := JSONRenderer{Pretty: true}.Render(goldenExport(t))
+	if err != nil {
+		t.Fatalf("rendering: %v", err)
+	}
+	checkGolden(t, goldenFile, got)
+}
+
+// TestGoldenCanonicalPaths locks the canonical text form of each path,
+// which reviews as a readable diff.
+func TestGoldenCanonicalPaths(t *testing.T) {
+	export := goldenExport(t)
+	if *update {
+		stale, _ := filepath.Glob(filepath.Join(canonicalDir, "*.txt"))
+		for _, name := range stale {
+			os.Remove(name)
+		}
+	}
+	for i := range export.Paths {
+		path := &export.Paths[i].Path
+		t.Run(path.ID, func(t *testing.T) {
+			checkGolden(t, filepath.Join(canonicalDir, path.ID+".txt"), []byte(path.Canonical()))
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package contract
 
+import "sort"
+
 // FunctionEdge represents a directed edge in the function call graph.
 // It connects two functions and optionally includes a condition that
 // determines when this path is taken.
@@ -20,6 +22,10 @@ type FunctionEdge struct {
 	// Order is the sequence number for edges from the same source
 	// Used to maintain consistent ordering in visualization
 	Order int `json:"order,omitempty"`
+
+	// VersionPresence is set by OverlayVersions to PresenceV1Only or
+	// PresenceV2Only (empty = present in both versions)
+	VersionPresence string `json:"versionPresence,omitempty"`
 }
 
 // PacketPath represents a complete path through the kernel networking stack.
@@ -34,12 +40,17 @@ type PacketPath struct {
 	// Description explains what this path represents
 	Description string `json:"description"`
 
-	// Direction is either "egress" (sending) or "ingress" (receiving)
+	// Direction is "egress" (sending), "ingress" (receiving) or "forward"
+	// (routed through the host)
 	Direction string `json:"direction"`
 
 	// Protocol is the primary protocol of this path (e.g., "TCP", "UDP")
 	Protocol string `json:"protocol"`
 
+	// Family is the network-layer address family of the packet: FamilyIPv4
+	// or FamilyIPv6 ("" for paths without an IP layer, such as ARP)
+	Family string `json:"family,omitempty"`
+
 	// Functions is the list of all functions in this path
 	Functions []KernelFunction `json:"functions"`
 
@@ -53,6 +64,44 @@ type PacketPath struct {
 	ExitPoints []string `json:"exitPoints"`
 }
 
+// Network-layer address families of a path
+const (
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+// SortEdges orders the edges by the position of their From function in
+// Functions, so the overall slice reads top-down like the path, and the
+// outgoing edges of each function by Order, then by To. The result does not
+// depend on the order the edges were added in. Edges from a function missing
+// from Functions come last, by From.
+func (p *PacketPath) SortEdges() {
+	fromRank := make(map[string]int, len(p.Functions))
+	for i, fn := range p.Functions {
+		fromRank[fn.ID] = i
+	}
+	rank := func(id string) int {
+		if r, ok := fromRank[id]; ok {
+			return r
+		}
+		return len(p.Functions)
+	}
+
+	sort.SliceStable(p.Edges, func(i, j int) bool {
+		a, b := p.Edges[i], p.Edges[j]
+		if a.From != b.From {
+			if ra, rb := rank(a.From), rank(b.From); ra != rb {
+				return ra < rb
+			}
+			return a.From < b.From
+		}
+		if a.Order != b.Order {
+			return a.Order < b.Order
+		}
+		return a.To < b.To
+	})
+}
+
 // FunctionGraph is a helper structure for traversing the call graph.
 type FunctionGraph struct {
 	// functions maps function ID to function definition
@@ -60,13 +109,21 @@ type FunctionGraph struct {
 
 	// adjacency maps function ID to outgoing edges
 	adjacency map[string][]FunctionEdge
+
+	// entryPoint is the ID of the path's starting function
+	entryPoint string
+
+	// edgeCount is the total number of edges
+	edgeCount int
 }
 
 // NewFunctionGraph creates a traversable graph from a PacketPath.
 func NewFunctionGraph(path *PacketPath) *FunctionGraph {
 	g := &FunctionGraph{
-		functions: make(map[string]*KernelFunction),
-		adjacency: make(map[string][]FunctionEdge),
+		functions:  make(map[string]*KernelFunction),
+		adjacency:  make(map[string][]FunctionEdge),
+		entryPoint: path.EntryPoint,
+		edgeCount:  len(path.Edges),
 	}
 
 	for i := range path.Functions {
@@ -101,6 +158,35 @@ func (g *FunctionGraph) GetNextFunctions(id string) []string {
 	return result
 }
 
+// primaryPath returns the functions visited from the entry point by always
+// taking the first non-error edge, which is the route the linear simulation
+// follows.
+func (p *PacketPath) primaryPath() []*KernelFunction {
+	graph := NewFunctionGraph(p)
+	result := []*KernelFunction{}
+	visited := make(map[string]bool)
+
+	currentID := p.EntryPoint
+	for currentID != "" && !visited[currentID] {
+		visited[currentID] = true
+
+		fn := graph.GetFunction(currentID)
+		if fn == nil {
+			break
+		}
+		result = append(result, fn)
+
+		currentID = ""
+		for _, edge := range graph.GetOutgoingEdges(fn.ID) {
+			if !edge.IsErrorPath {
+				currentID = edge.To
+				break
+			}
+		}
+	}
+	return result
+}
+
 // SimulateStep represents a single step in the packet simulation.
 type SimulateStep struct {
 	// StepNumber is the 1-indexed step number
@@ -117,126 +203,89 @@ type SimulateStep struct {
 
 	// ConntrackState is the current connection tracking state (for TCP)
 	ConntrackState *ConntrackEntry `json:"conntrackState,omitempty"`
-}
-
-// Simulate walks through the packet path and returns the sequence of steps.
-// This is the core function that the frontend uses for animation.
-func (path *PacketPath) Simulate(initialBufferSize int, payloadSize int) []SimulateStep {
-	graph := NewFunctionGraph(path)
-	steps := []SimulateStep{}
 
-	// Initialize sk_buff with payload
-	skb := NewSKBuffWithPayload(initialBufferSize, payloadSize)
+	// WriteQueue is the socket write queue state (egress only, nil before enqueue)
+	WriteQueue *WriteQueue `json:"writeQueue,omitempty"`
 
-	// Start at entry point
-	currentID := path.EntryPoint
-	stepNum := 1
+	// SocketMemory is the socket memory accounting state (nil before the first charge)
+	SocketMemory *SocketMemory `json:"socketMemory,omitempty"`
 
-	visited := make(map[string]bool)
+	// Route is the FIB lookup result at the step that makes the routing
+	// decision (nil elsewhere)
+	Route *RouteDecision `json:"route,omitempty"`
 
-	// For TCP data transfer, connection is already established
-	conntrackState := NewConntrackEntry(ConntrackEstablished)
-
-	for currentID != "" && !visited[currentID] {
-		visited[currentID] = true
+	// NAT is the address rewrite at the step where a NAT rule applies
+	// (nil elsewhere)
+	NAT *NATRewrite `json:"nat,omitempty"`
 
-		fn := graph.GetFunction(currentID)
-		if fn == nil {
-			break
-		}
+	// Expectation is the related connection a conntrack helper expects at
+	// the step where it parses the payload (nil elsewhere)
+	Expectation *ConntrackExpectation `json:"expectation,omitempty"`
 
-		// Apply mutation if present
-		if fn.SKBMutation != nil {
-			switch fn.SKBMutation.Operation {
-			case "push":
-				skb.Push(fn.SKBMutation.HeaderType, fn.SKBMutation.Size)
-			case "pull":
-				skb.Pull(fn.SKBMutation.Size)
-			case "put":
-				skb.Put(fn.SKBMutation.Size)
-			}
-		}
+	// ICMPError is the effect of an ICMP error on the connection at the
+	// steps that apply it (nil elsewhere)
+	ICMPError *ICMPErrorResult `json:"icmpError,omitempty"`
 
-		step := SimulateStep{
-			StepNumber:     stepNum,
-			Function:       *fn,
-			SKBuffState:    *skb.Clone(),
-			ConntrackState: conntrackState,
-		}
-		steps = append(steps, step)
-		stepNum++
+	// Neighbour is the neighbour entry of the ARP sender at the step that
+	// updates it (nil elsewhere)
+	Neighbour *NeighbourEntry `json:"neighbour,omitempty"`
 
-		// Get next function (take first non-error path for linear simulation)
-		edges := graph.GetOutgoingEdges(currentID)
-		currentID = ""
-		for _, edge := range edges {
-			if !edge.IsErrorPath {
-				currentID = edge.To
-				break
-			}
-		}
-	}
+	// ListenQueue is the listening socket's queue state (nil unless the
+	// packet is handled by a listener)
+	ListenQueue *ListenQueue `json:"listenQueue,omitempty"`
 
-	return steps
-}
+	// SocketLock is the state of the socket lock at this step (empty
+	// before the socket is first locked)
+	SocketLock string `json:"socketLock,omitempty"`
 
-// SimulateIngress walks through the ingress path, starting with a full packet.
-// Headers are progressively stripped (pulled) as the packet moves up the stack.
-func (path *PacketPath) SimulateIngress(initialBufferSize int, payloadSize int) []SimulateStep {
-	graph := NewFunctionGraph(path)
-	steps := []SimulateStep{}
+	// EgressPorts are the bridge ports the frame is sent out of (set at the
+	// forwarding or flooding step)
+	EgressPorts []string `json:"egressPorts,omitempty"`
 
-	// Initialize sk_buff with complete packet (all headers present)
-	skb := NewSKBuffForIngress(initialBufferSize, payloadSize)
+	// Fragments are the sk_buffs a packet was split into by IP
+	// fragmentation, in the order they are sent (set at the fragmenting
+	// step)
+	Fragments []SKBuff `json:"fragments,omitempty"`
 
-	// Start at entry point
-	currentID := path.EntryPoint
-	stepNum := 1
+	// TxQueue is the transmit queue chosen for the packet (set at the step
+	// that selects it)
+	TxQueue *TxQueueSelection `json:"txQueue,omitempty"`
 
-	visited := make(map[string]bool)
+	// RTT is the socket's RTT estimate at the step that takes an RTT
+	// sample (nil elsewhere)
+	RTT *RTTEstimate `json:"rtt,omitempty"`
 
-	// For TCP data reception, connection is already established
-	conntrackState := NewConntrackEntry(ConntrackEstablished)
+	// DeferredBytes is the payload tcp_tso_should_defer holds back in the
+	// write queue (set at the deferring step)
+	DeferredBytes int `json:"deferredBytes,omitempty"`
 
-	for currentID != "" && !visited[currentID] {
-		visited[currentID] = true
+	// DropReason is why the packet was dropped (drop points only)
+	DropReason string `json:"dropReason,omitempty"`
 
-		fn := graph.GetFunction(currentID)
-		if fn == nil {
-			break
-		}
+	// ErrorCounters are the modeled SNMP error counters (nil before any error)
+	ErrorCounters map[string]int `json:"errorCounters,omitempty"`
 
-		// Apply mutation if present
-		if fn.SKBMutation != nil {
-			switch fn.SKBMutation.Operation {
-			case "push":
-				skb.Push(fn.SKBMutation.HeaderType, fn.SKBMutation.Size)
-			case "pull":
-				skb.Pull(fn.SKBMutation.Size)
-			case "put":
-				skb.Put(fn.SKBMutation.Size)
-			}
-		}
+	// Annotations explain simulation outcomes at this step (nil if none)
+	Annotations []StepAnnotation `json:"annotations,omitempty"`
+}
 
-		step := SimulateStep{
-			StepNumber:     stepNum,
-			Function:       *fn,
-			SKBuffState:    *skb.Clone(),
-			ConntrackState: conntrackState,
-		}
-		steps = append(steps, step)
-		stepNum++
+// SimulateSteps is the ordered sequence of steps produced by a simulation run.
+type SimulateSteps []SimulateStep
 
-		// Get next function (take first non-error path for linear simulation)
-		edges := graph.GetOutgoingEdges(currentID)
-		currentID = ""
-		for _, edge := range edges {
-			if !edge.IsErrorPath {
-				currentID = edge.To
-				break
-			}
-		}
-	}
+// Simulate walks through the packet path and returns the sequence of steps.
+// This is the core function that the frontend uses for animation.
+func (path *PacketPath) Simulate(initialBufferSize int, payloadSize int) SimulateSteps {
+	return path.simulate(SimulateOptions{
+		BufferSize:  initialBufferSize,
+		PayloadSize: payloadSize,
+	}, NewSKBuffWithPayload(initialBufferSize, payloadSize))
+}
 
-	return steps
+// SimulateIngress walks through the ingress path, starting with a full packet.
+// Headers are progressively stripped (pulled) as the packet moves up the stack.
+func (path *PacketPath) SimulateIngress(initialBufferSize int, payloadSize int) SimulateSteps {
+	return path.simulate(SimulateOptions{
+		BufferSize:  initialBufferSize,
+		PayloadSize: payloadSize,
+	}, NewSKBuffForIngress(initialBufferSize, payloadSize))
 }
@@ -0,0 +1,62 @@
+package contract
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// edgeKeys returns each edge as "from->to" in slice order.
+func edgeKeys(edges []FunctionEdge) []string {
+	keys := make([]string, len(edges))
+	for i, edge := range edges {
+		keys[i] = edge.From + "->" + edge.To
+	}
+	return keys
+}
+
+func TestSortEdgesByOrder(t *testing.T) {
+	functions := []KernelFunction{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}, {ID: "e"}}
+	edges := []FunctionEdge{
+		{From: "a", To: "d", Order: 3},
+		{From: "a", To: "b", Order: 1},
+		{From: "b", To: "e", Order: 2},
+		{From: "a", To: "c", Order: 2},
+		{From: "b", To: "d", Order: 1},
+		{From: "b", To: "c", Order: 2},
+		{From: "c", To: "e", Order: 1},
+	}
+	want := []string{"a->b", "a->c", "a->d", "b->d", "b->c", "b->e", "c->e"}
+
+	rng := rand.New(rand.NewSource(1))
+	for range 50 {
+		shuffled := slices.Clone(edges)
+		rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		inserted := edgeKeys(shuffled)
+
+		path := &PacketPath{Functions: functions, Edges: shuffled}
+		path.SortEdges()
+		if got := edgeKeys(path.Edges); !slices.Equal(got, want) {
+			t.Fatalf("insertion order %v: sorted edges = %v, want %v", inserted, got, want)
+		}
+	}
+}
+
+func TestSortEdgesFollowsFunctionOrder(t *testing.T) {
+	path := &PacketPath{
+		Functions: []KernelFunction{{ID: "z"}, {ID: "y"}, {ID: "x"}, {ID: "a"}, {ID: "b"}},
+		Edges: []FunctionEdge{
+			{From: "a", To: "b", Order: 1},
+			{From: "q", To: "a", Order: 1},
+			{From: "z", To: "y", Order: 2},
+			{From: "p", To: "a", Order: 1},
+			{From: "z", To: "x", Order: 1},
+		},
+	}
+	path.SortEdges()
+
+	// Edges from functions missing from the path come last, by ID
+	if got, want := edgeKeys(path.Edges), []string{"z->x", "z->y", "a->b", "p->a", "q->a"}; !slices.Equal(got, want) {
+		t.Errorf("sorted edges = %v, want %v", got, want)
+	}
+}
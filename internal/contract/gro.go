@@ -0,0 +1,41 @@
+package contract
+
+// GRO flush reasons, selecting why a held GRO packet is released up the stack
+const (
+	// GROFlushFlowLimit is a flow reaching its coalescing limit
+	GROFlushFlowLimit = "flow_limit"
+
+	// GROFlushNAPIBudget is the NAPI poll exhausting its budget
+	GROFlushNAPIBudget = "napi_budget"
+
+	// GROFlushTimeout is the gro_flush_timeout timer expiring
+	GROFlushTimeout = "timeout"
+)
+
+// Annotation kind for GRO flushes
+const AnnotationGROFlush = "gro_flush"
+
+// groFlushExplanations describes each flush reason.
+var groFlushExplanations = map[string]string{
+	GROFlushFlowLimit: "Flow limit reached: the merged packet hit 64 KB, a segment arrived that cannot be merged " +
+		"(PSH, changed options), or a ninth flow evicted the oldest held flow (MAX_GRO_SKBS = 8). " +
+		"dev_gro_receive flushes it immediately.",
+	GROFlushNAPIBudget: "NAPI budget exhausted: net_rx_action calls napi_gro_flush for flows held longer than a jiffy. " +
+		"Younger flows stay held until the next poll, which is where GRO adds latency.",
+	GROFlushTimeout: "gro_flush_timeout expired: instead of flushing on napi_complete_done, the driver armed an hrtimer " +
+		"to keep aggregating across polls. The timer fired and released the held flows, trading latency for larger packets.",
+}
+
+// IsValidGROFlushReason reports whether reason is a known GRO flush reason.
+func IsValidGROFlushReason(reason string) bool {
+	_, ok := groFlushExplanations[reason]
+	return ok
+}
+
+// effectGROFlush models napi_gro_complete releasing a coalesced sk_buff that
+// GRO was holding, explaining why aggregation ended.
+func effectGROFlush(ctx *simContext, step *SimulateStep) {
+	if explanation, ok := groFlushExplanations[ctx.opts.GROFlush]; ok {
+		step.annotate(AnnotationGROFlush, explanation)
+	}
+}
@@ -0,0 +1,241 @@
+package contract
+
+import "fmt"
+
+// GSO types (skb_shinfo(skb)->gso_type) of a GSO sk_buff
+const (
+	// GSOTypeTCPv4 is a TCP/IPv4 packet cut into MSS-sized segments
+	// (SKB_GSO_TCPV4), by the NIC with TSO or by software GSO
+	GSOTypeTCPv4 = "tcpv4"
+
+	// GSOTypeTCPv6 is the same for TCP/IPv6 (SKB_GSO_TCPV6)
+	GSOTypeTCPv6 = "tcpv6"
+
+	// GSOTypeUDPL4 is a UDP_SEGMENT send cut into datagrams
+	// (SKB_GSO_UDP_L4)
+	GSOTypeUDPL4 = "udp_l4"
+
+	// GSOTypePartial is a chunk software GSO cut at a boundary larger than
+	// the MSS, with headers already fixed up as if it were one segment; a
+	// device with NETIF_F_GSO_PARTIAL cuts it into MSS-sized segments,
+	// updating only lengths and IP IDs (SKB_GSO_PARTIAL)
+	GSOTypePartial = "partial"
+)
+
+// GSOInfo is the segmentation metadata of a GSO sk_buff, kept in its shared
+// info. An sk_buff without it is sent as a single packet.
+type GSOInfo struct {
+	// Type is the kind of segmentation (see GSOTypeTCPv4)
+	Type string `json:"type"`
+
+	// Size is gso_size, the payload bytes of each segment but the last
+	Size int `json:"size"`
+
+	// Segs is gso_segs, the number of segments the sk_buff becomes
+	Segs int `json:"segs"`
+
+	// Boundary is the payload bytes of each partial chunk, a multiple of
+	// Size (partial only)
+	Boundary int `json:"boundary,omitempty"`
+}
+
+// newGSOInfo returns the metadata of a GSO sk_buff carrying payload bytes
+// cut into segments of size bytes.
+func newGSOInfo(gsoType string, payload, size int) *GSOInfo {
+	return &GSOInfo{Type: gsoType, Size: size, Segs: (payload + size - 1) / size}
+}
+
+// payloadLen returns the bytes of skb past its protocol headers.
+func payloadLen(skb *SKBuff) int {
+	payload := skb.Len()
+	for _, layer := range skb.Layers {
+		payload -= layer.Size
+	}
+	return payload
+}
+
+// segmentingFunction is the function that sends a TCP payload larger than
+// the MSS as separate segments when the route has no GSO.
+const segmentingFunction = "tcp_write_xmit"
+
+const (
+	// tcpMinMSS is TCP_MIN_MSS, the smallest MSS the kernel accepts
+	tcpMinMSS = 88
+
+	// tcpInitCwnd is TCP_INIT_CWND, the segments a connection may send
+	// before the first ACK. It bounds the segments the simulation sends
+	// one by one.
+	tcpInitCwnd = 10
+)
+
+// IsValidMSS reports whether mss can be set as the MSS of the sending
+// connection: 0 for the default, or at least TCP_MIN_MSS.
+func IsValidMSS(mss int) bool {
+	return mss == 0 || mss >= tcpMinMSS
+}
+
+// mss returns the MSS of the sending connection. By default it is what a
+// 1500-byte MTU leaves for TCP, smaller over IPv6 by the difference in
+// header size.
+func (ctx *simContext) mss() int {
+	if ctx.opts.MSS > 0 {
+		return max(ctx.opts.MSS, tcpMinMSS)
+	}
+	if ctx.family == FamilyIPv6 {
+		return tcpDefaultMSS - (IPv6HeaderSize - IPv4HeaderSize)
+	}
+	return tcpDefaultMSS
+}
+
+// effectTSOSegs models tcp_set_skb_tso_segs marking a send larger than one
+// MSS as a TSO sk_buff, which the stack handles as a single packet down to
+// the device. gso_size, kept in skb_shared_info, is the MSS.
+func effectTSOSegs(ctx *simContext, step *SimulateStep) {
+	payload, mss := payloadLen(ctx.skb), ctx.mss()
+	if ctx.opts.NoGSO || payload <= mss {
+		return
+	}
+	gsoType := GSOTypeTCPv4
+	if ctx.family == FamilyIPv6 {
+		gsoType = GSOTypeTCPv6
+	}
+	ctx.skb.GSO = newGSOInfo(gsoType, payload, mss)
+	step.annotate(AnnotationSegmentation, fmt.Sprintf(
+		"The %d-byte send is one TSO sk_buff: skb_shared_info records gso_size=%d (the MSS) and gso_segs=%d. "+
+			"It goes down the stack as a single packet, and the NIC, or GSO in validate_xmit_skb if the device lacks TSO, "+
+			"cuts it into %d segments, copying the headers and fixing up sequence numbers and lengths in each.",
+		payload, mss, ctx.skb.GSO.Segs, ctx.skb.GSO.Segs))
+}
+
+// tcpSegmentSKBuffs returns the MSS-sized sk_buffs a TCP payload without
+// headers is sent as when the route has no GSO. Each is laid out like skb,
+// with its share of the payload.
+func tcpSegmentSKBuffs(skb *SKBuff, mss int) []SKBuff {
+	payload := payloadLen(skb)
+	var segs []SKBuff
+	for offset := 0; offset < payload; offset += mss {
+		seg := skb.Clone()
+		seg.Tail = seg.Data + min(mss, payload-offset)
+		seg.GSO = nil
+		segs = append(segs, *seg)
+	}
+	return segs
+}
+
+// effectTCPSegments models tcp_write_xmit on a route without GSO: a payload
+// larger than the MSS goes out as separate MSS-sized sk_buffs (in the
+// kernel tcp_sendmsg already sized them to the MSS). The simulation sends
+// the first down the path, then each of the others the initial congestion
+// window allows in turn from here; the rest stay queued. Nothing is sent
+// now if the socket leaves by a branch, such as TSQ.
+func effectTCPSegments(ctx *simContext, step *SimulateStep) {
+	payload, mss := payloadLen(ctx.skb), ctx.mss()
+	if !ctx.opts.NoGSO || payload <= mss || ctx.branched(step.Function.ID) {
+		return
+	}
+	segs := tcpSegmentSKBuffs(ctx.skb, mss)
+	if ctx.writeQueue != nil {
+		ctx.writeQueue.splitHead(segs)
+	}
+	sent := segs[:min(len(segs), tcpInitCwnd)]
+	ctx.skb = sent[0].Clone()
+	ctx.segments, ctx.segmentTotal = sent[1:], len(sent)
+	step.annotate(AnnotationSegmentation, fmt.Sprintf(
+		"GSO is off on the route, so the %d-byte send cannot be one TSO sk_buff: it is %d sk_buffs of up to %d bytes (the MSS), "+
+			"each with no gso_size. tcp_write_xmit sends segment 1 of %d; routing, netfilter and the qdisc run for every one of them.",
+		payload, len(segs), mss, len(sent)))
+	if held := len(segs) - len(sent); held > 0 {
+		step.annotate(AnnotationSegmentation, fmt.Sprintf(
+			"The initial congestion window (TCP_INIT_CWND) lets %d segments out before the first ACK: the other %d stay on the write queue "+
+				"until ACKs open the window.",
+			len(sent), held))
+	}
+}
+
+// sendNextSegment makes the next segment effectTCPSegments cut the payload
+// into the simulated sk_buff, emits the step of tcp_write_xmit sending it
+// and returns the function it is passed to ("" if none).
+func (ctx *simContext) sendNextSegment(graph *FunctionGraph) string {
+	fn := graph.GetFunction(segmentingFunction)
+	if fn == nil {
+		ctx.segments = nil
+		return ""
+	}
+	ctx.skb = ctx.segments[0].Clone()
+	ctx.segments = ctx.segments[1:]
+	if ctx.writeQueue != nil {
+		ctx.writeQueue.Transmit()
+	}
+
+	step := SimulateStep{Function: *fn, ConntrackState: ctx.conntrack}
+	step.annotate(AnnotationSegmentation, fmt.Sprintf(
+		"tcp_write_xmit sends segment %d of %d, %d bytes.",
+		ctx.segmentTotal-len(ctx.segments), ctx.segmentTotal, payloadLen(ctx.skb)))
+	ctx.emit(step)
+
+	if next := ctx.nextEdge(fn.ID, graph.GetOutgoingEdges(fn.ID)); next != nil {
+		return next.To
+	}
+	return ""
+}
+
+// effectGROGSO models tcp_gro_complete recording how many segments GRO
+// merged, so the coalesced sk_buff can be segmented again if it is
+// forwarded.
+func effectGROGSO(ctx *simContext, step *SimulateStep) {
+	if payload := payloadLen(ctx.skb); ctx.opts.GROFlush != "" && payload > tcpDefaultMSS {
+		ctx.skb.GSO = newGSOInfo(GSOTypeTCPv4, payload, tcpDefaultMSS)
+	}
+}
+
+// gsoPartialBoundary returns the payload bytes of each partial chunk for a
+// GSO sk_buff of the given metadata and payload, or 0 if the device lacks
+// NETIF_F_GSO_PARTIAL or a chunk would hold no more than one full segment.
+func (ctx *simContext) gsoPartialBoundary(gso *GSOInfo, payload int) int {
+	segs := min(ctx.opts.GSOPartial, payload/gso.Size)
+	if segs < 2 {
+		return 0
+	}
+	return segs * gso.Size
+}
+
+// effectGSOSegment models __skb_gso_segment. Full software segmentation
+// leaves plain MSS-sized packets; with GSO partial, skb_segment cuts the
+// payload at the partial boundary instead and each chunk keeps GSO metadata
+// for the NIC to finish. The simulation continues with the first segment
+// or chunk.
+func effectGSOSegment(ctx *simContext, step *SimulateStep) {
+	gso := ctx.skb.GSO
+	if gso == nil {
+		return
+	}
+	payload := payloadLen(ctx.skb)
+	boundary := ctx.gsoPartialBoundary(gso, payload)
+	if boundary == 0 {
+		ctx.skb.GSO = nil
+		ctx.skb.Tail -= payload - gso.Size
+		step.annotate(AnnotationSegmentation, fmt.Sprintf(
+			"GRO merged %d bytes of payload into one sk_buff on receive; GSO now splits it back into %d segments of up to %d bytes. "+
+				"Routing, netfilter and the qdisc ran once for all of them. A NIC with TSO would do this split in hardware instead. "+
+				"The simulation follows the first segment.",
+			payload, gso.Segs, gso.Size))
+		return
+	}
+
+	// Full segments are grouped into chunks; the remainder shorter than
+	// one segment is split off on its own
+	full := payload / gso.Size
+	chunks := (full*gso.Size + boundary - 1) / boundary
+	packets := chunks
+	if payload%gso.Size != 0 {
+		packets++
+	}
+	ctx.skb.GSO = &GSOInfo{Type: GSOTypePartial, Size: gso.Size, Segs: boundary / gso.Size, Boundary: boundary}
+	ctx.skb.Tail -= payload - boundary
+	step.annotate(AnnotationSegmentation, fmt.Sprintf(
+		"GSO partial: the device can finish segmentation but not the headers this packet needs, so software cuts the %d-byte payload at a %d-byte boundary "+
+			"(%d segments of %d bytes) into %d sk_buffs instead of %d. Each chunk keeps gso_size=%d with SKB_GSO_PARTIAL, and the NIC cuts it into "+
+			"identical segments, only updating lengths and IP IDs. Full software segmentation would build all %d segments here. "+
+			"The simulation follows the first chunk.",
+		payload, boundary, boundary/gso.Size, gso.Size, packets, gso.Segs, gso.Size, gso.Segs))
+}
@@ -0,0 +1,95 @@
+package contract
+
+import (
+	"slices"
+	"testing"
+)
+
+// segmentPayloads returns the payload of the sk_buff at every step of
+// tcp_write_xmit, once per segment it sends.
+func segmentPayloads(steps SimulateSteps) []int {
+	var payloads []int
+	for _, step := range steps {
+		if step.Function.ID == segmentingFunction {
+			payloads = append(payloads, payloadLen(&step.SKBuffState))
+		}
+	}
+	return payloads
+}
+
+func TestTCPSegmentsWithoutGSO(t *testing.T) {
+	steps := BuildTCPIPv4EgressPath().SimulateWithOptions(SimulateOptions{
+		BufferSize:  8192,
+		PayloadSize: 5000,
+		MSS:         1460,
+		NoGSO:       true,
+	})
+
+	if got, want := segmentPayloads(steps), []int{1460, 1460, 1460, 620}; !slices.Equal(got, want) {
+		t.Fatalf("segment payloads = %v, want %v", got, want)
+	}
+	for _, step := range steps {
+		if step.SKBuffState.GSO != nil {
+			t.Fatalf("step %d (%s) has GSO metadata without GSO", step.StepNumber, step.Function.ID)
+		}
+	}
+	last := steps[len(steps)-1]
+	if last.Function.ID != "ndo_start_xmit" {
+		t.Errorf("last step = %s, want ndo_start_xmit for the last segment", last.Function.ID)
+	}
+	if q := last.WriteQueue; q == nil || len(q.Queued) != 0 || len(q.InFlight) != 4 {
+		t.Errorf("write queue at the end = %+v, want 0 queued and 4 in flight", q)
+	}
+}
+
+func TestTCPSegmentsWithTSO(t *testing.T) {
+	steps := BuildTCPIPv4EgressPath().SimulateWithOptions(SimulateOptions{
+		BufferSize:  8192,
+		PayloadSize: 5000,
+		MSS:         1460,
+	})
+
+	if got := segmentPayloads(steps); !slices.Equal(got, []int{5000}) {
+		t.Fatalf("segment payloads = %v, want a single 5000-byte TSO sk_buff", got)
+	}
+	gso := steps[len(steps)-1].SKBuffState.GSO
+	if gso == nil || gso.Type != GSOTypeTCPv4 || gso.Size != 1460 || gso.Segs != 4 {
+		t.Errorf("GSO = %+v, want tcpv4 with gso_size 1460 and 4 segments", gso)
+	}
+}
+
+func TestTCPSegmentsBoundedByInitCwnd(t *testing.T) {
+	steps := BuildTCPIPv4EgressPath().SimulateWithOptions(SimulateOptions{
+		BufferSize:  8192,
+		PayloadSize: 4000,
+		MSS:         tcpMinMSS,
+		NoGSO:       true,
+	})
+
+	if got := len(segmentPayloads(steps)); got != tcpInitCwnd {
+		t.Errorf("segments sent = %d, want %d (TCP_INIT_CWND)", got, tcpInitCwnd)
+	}
+	// 4000 bytes at an 88-byte MSS are 46 segments
+	if q := steps[len(steps)-1].WriteQueue; q == nil || len(q.Queued) != 46-tcpInitCwnd {
+		t.Errorf("write queue at the end = %+v, want %d segments still queued", q, 46-tcpInitCwnd)
+	}
+}
+
+func TestIsValidMSS(t *testing.T) {
+	tests := []struct {
+		mss  int
+		want bool
+	}{
+		{0, true},
+		{1, false},
+		{tcpMinMSS - 1, false},
+		{tcpMinMSS, true},
+		{tcpDefaultMSS, true},
+		{-1, false},
+	}
+	for _, tt := range tests {
+		if got := IsValidMSS(tt.mss); got != tt.want {
+			t.Errorf("IsValidMSS(%d) = %v, want %v", tt.mss, got, tt.want)
+		}
+	}
+}
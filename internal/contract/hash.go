@@ -0,0 +1,18 @@
+package contract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Hash returns a stable identifier of the export options: two option sets
+// hash equal exactly when they produce the same export from the same
+// registry. It is suitable as a cache key or HTTP ETag.
+func (opts ExportOptions) Hash() string {
+	// ExportOptions holds only plain fields, so encoding cannot fail and
+	// field order is fixed by the struct definition
+	data, _ := json.Marshal(opts)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
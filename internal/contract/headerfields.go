@@ -0,0 +1,110 @@
+package contract
+
+// Byte orders of header fields
+const (
+	// ByteOrderNetwork is network byte order (big-endian)
+	ByteOrderNetwork = "big-endian"
+
+	// ByteOrderNone marks fields where byte order does not apply
+	// (single bytes, bit fields within a byte, opaque byte strings)
+	ByteOrderNone = "none"
+)
+
+// HeaderField describes one field of a protocol header as laid out on the
+// wire. Offsets are relative to the start of the header.
+type HeaderField struct {
+	// Name is the field name (e.g., "Source Port")
+	Name string `json:"name"`
+
+	// Offset is the byte offset of the field (or of the bytes containing it)
+	Offset int `json:"offset"`
+
+	// Size is the field size in bytes; for bit fields, the size of the
+	// bytes containing it
+	Size int `json:"size"`
+
+	// BitOffset is the offset in bits from the most significant bit of the
+	// containing bytes (bit fields only)
+	BitOffset int `json:"bitOffset,omitempty"`
+
+	// BitWidth is the width in bits (0 = the field spans whole bytes)
+	BitWidth int `json:"bitWidth,omitempty"`
+
+	// ByteOrder is ByteOrderNetwork or ByteOrderNone
+	ByteOrder string `json:"byteOrder"`
+}
+
+// headerFieldLayouts holds the fixed part of each header, without options.
+var headerFieldLayouts = map[string][]HeaderField{
+	"ethernet": {
+		{Name: "Destination MAC", Offset: 0, Size: 6, ByteOrder: ByteOrderNone},
+		{Name: "Source MAC", Offset: 6, Size: 6, ByteOrder: ByteOrderNone},
+		{Name: "EtherType", Offset: 12, Size: 2, ByteOrder: ByteOrderNetwork},
+	},
+	"ip": {
+		{Name: "Version", Offset: 0, Size: 1, BitOffset: 0, BitWidth: 4, ByteOrder: ByteOrderNone},
+		{Name: "IHL", Offset: 0, Size: 1, BitOffset: 4, BitWidth: 4, ByteOrder: ByteOrderNone},
+		{Name: "DSCP", Offset: 1, Size: 1, BitOffset: 0, BitWidth: 6, ByteOrder: ByteOrderNone},
+		{Name: "ECN", Offset: 1, Size: 1, BitOffset: 6, BitWidth: 2, ByteOrder: ByteOrderNone},
+		{Name: "Total Length", Offset: 2, Size: 2, ByteOrder: ByteOrderNetwork},
+		{Name: "Identification", Offset: 4, Size: 2, ByteOrder: ByteOrderNetwork},
+		{Name: "Flags", Offset: 6, Size: 2, BitOffset: 0, BitWidth: 3, ByteOrder: ByteOrderNetwork},
+		{Name: "Fragment Offset", Offset: 6, Size: 2, BitOffset: 3, BitWidth: 13, ByteOrder: ByteOrderNetwork},
+		{Name: "TTL", Offset: 8, Size: 1, ByteOrder: ByteOrderNone},
+		{Name: "Protocol", Offset: 9, Size: 1, ByteOrder: ByteOrderNone},
+		{Name: "Header Checksum", Offset: 10, Size: 2, ByteOrder: ByteOrderNetwork},
+		{Name: "Source Address", Offset: 12, Size: 4, ByteOrder: ByteOrderNetwork},
+		{Name: "Destination Address", Offset: 16, Size: 4, ByteOrder: ByteOrderNetwork},
+	},
+	"ipv6": {
+		{Name: "Version", Offset: 0, Size: 4, BitOffset: 0, BitWidth: 4, ByteOrder: ByteOrderNetwork},
+		{Name: "Traffic Class", Offset: 0, Size: 4, BitOffset: 4, BitWidth: 8, ByteOrder: ByteOrderNetwork},
+		{Name: "Flow Label", Offset: 0, Size: 4, BitOffset: 12, BitWidth: 20, ByteOrder: ByteOrderNetwork},
+		{Name: "Payload Length", Offset: 4, Size: 2, ByteOrder: ByteOrderNetwork},
+		{Name: "Next Header", Offset: 6, Size: 1, ByteOrder: ByteOrderNone},
+		{Name: "Hop Limit", Offset: 7, Size: 1, ByteOrder: ByteOrderNone},
+		{Name: "Source Address", Offset: 8, Size: 16, ByteOrder: ByteOrderNetwork},
+		{Name: "Destination Address", Offset: 24, Size: 16, ByteOrder: ByteOrderNetwork},
+	},
+	"tcp": {
+		{Name: "Source Port", Offset: 0, Size: 2, ByteOrder: ByteOrderNetwork},
+		{Name: "Destination Port", Offset: 2, Size: 2, ByteOrder: ByteOrderNetwork},
+		{Name: "Sequence Number", Offset: 4, Size: 4, ByteOrder: ByteOrderNetwork},
+		{Name: "Acknowledgment Number", Offset: 8, Size: 4, ByteOrder: ByteOrderNetwork},
+		{Name: "Data Offset", Offset: 12, Size: 1, BitOffset: 0, BitWidth: 4, ByteOrder: ByteOrderNone},
+		{Name: "Reserved", Offset: 12, Size: 1, BitOffset: 4, BitWidth: 4, ByteOrder: ByteOrderNone},
+		{Name: "Flags", Offset: 13, Size: 1, ByteOrder: ByteOrderNone},
+		{Name: "Window", Offset: 14, Size: 2, ByteOrder: ByteOrderNetwork},
+		{Name: "Checksum", Offset: 16, Size: 2, ByteOrder: ByteOrderNetwork},
+		{Name: "Urgent Pointer", Offset: 18, Size: 2, ByteOrder: ByteOrderNetwork},
+	},
+	"udp": {
+		{Name: "Source Port", Offset: 0, Size: 2, ByteOrder: ByteOrderNetwork},
+		{Name: "Destination Port", Offset: 2, Size: 2, ByteOrder: ByteOrderNetwork},
+		{Name: "Length", Offset: 4, Size: 2, ByteOrder: ByteOrderNetwork},
+		{Name: "Checksum", Offset: 6, Size: 2, ByteOrder: ByteOrderNetwork},
+	},
+	"icmp": {
+		{Name: "Type", Offset: 0, Size: 1, ByteOrder: ByteOrderNone},
+		{Name: "Code", Offset: 1, Size: 1, ByteOrder: ByteOrderNone},
+		{Name: "Checksum", Offset: 2, Size: 2, ByteOrder: ByteOrderNetwork},
+		{Name: "Rest of Header", Offset: 4, Size: 4, ByteOrder: ByteOrderNetwork},
+	},
+	"esp": {
+		{Name: "SPI", Offset: 0, Size: 4, ByteOrder: ByteOrderNetwork},
+		{Name: "Sequence Number", Offset: 4, Size: 4, ByteOrder: ByteOrderNetwork},
+	},
+}
+
+// HeaderFieldLayout returns the wire layout of a protocol header's fixed
+// fields, using the same protocol names as ProtocolHeader (e.g., "ethernet",
+// "ip", "tcp"). Multi-byte integers are in network byte order, which parsers
+// must convert with ntohs/ntohl (or binary.BigEndian) before use. Returns nil
+// for an unknown protocol.
+func HeaderFieldLayout(protocol string) []HeaderField {
+	layout, ok := headerFieldLayouts[protocol]
+	if !ok {
+		return nil
+	}
+	return append([]HeaderField(nil), layout...)
+}
@@ -0,0 +1,54 @@
+package contract
+
+// Hook kinds used in HookEvent
+const (
+	HookKindNetfilter = "netfilter"
+	HookKindBPF       = "bpf"
+)
+
+// HookEvent is a single filtering/security checkpoint a packet passes on its
+// way through a path.
+type HookEvent struct {
+	// Position is the 0-based index of the function along the primary path
+	Position int `json:"position"`
+
+	// FunctionID is the function where the hook is invoked
+	FunctionID string `json:"functionId"`
+
+	// Kind is either "netfilter" or "bpf"
+	Kind string `json:"kind"`
+
+	// Hook is the hook name (e.g., "OUTPUT" or "TC_EGRESS")
+	Hook string `json:"hook"`
+
+	// Description explains what happens at this hook point
+	Description string `json:"description"`
+}
+
+// HookTimeline returns every netfilter and BPF hook along the primary path,
+// in entry-to-exit order. A function carrying both kinds of hook yields the
+// netfilter event first.
+func (p *PacketPath) HookTimeline() []HookEvent {
+	events := []HookEvent{}
+	for pos, fn := range p.primaryPath() {
+		if fn.NetfilterHook != nil {
+			events = append(events, HookEvent{
+				Position:    pos,
+				FunctionID:  fn.ID,
+				Kind:        HookKindNetfilter,
+				Hook:        fn.NetfilterHook.Hook,
+				Description: fn.NetfilterHook.Description,
+			})
+		}
+		if fn.BPFHook != nil {
+			events = append(events, HookEvent{
+				Position:    pos,
+				FunctionID:  fn.ID,
+				Kind:        HookKindBPF,
+				Hook:        fn.BPFHook.Type,
+				Description: fn.BPFHook.Description,
+			})
+		}
+	}
+	return events
+}
@@ -0,0 +1,157 @@
+package contract
+
+import "fmt"
+
+// ICMP errors the ingress packet can be, each quoting a TCP segment the host
+// sent
+const (
+	// ICMPErrorFragNeeded is Destination Unreachable, Fragmentation Needed
+	// (type 3, code 4): a router could not forward a DF packet over a
+	// smaller link
+	ICMPErrorFragNeeded = "frag_needed"
+
+	// ICMPErrorPortUnreach is Destination Unreachable, Port Unreachable
+	// (type 3, code 3): the destination host has no socket on the port
+	ICMPErrorPortUnreach = "port_unreach"
+)
+
+// IsValidICMPError reports whether kind is a known ICMP error.
+func IsValidICMPError(kind string) bool {
+	return kind == ICMPErrorFragNeeded || kind == ICMPErrorPortUnreach
+}
+
+// AnnotationICMPError marks the effect of an ICMP error on a connection.
+const AnnotationICMPError = "icmp_error"
+
+const (
+	// icmpQuoteMax is the most an ICMP error quotes of the offending
+	// packet: what fits in a 576-byte datagram
+	icmpQuoteMax = 576 - IPv4HeaderSize - ICMPHeaderSize
+
+	// icmpNextHopMTU is the next-hop MTU a Fragmentation Needed error
+	// reports, as for a tunnel along the path
+	icmpNextHopMTU = 1400
+)
+
+// ICMPErrorResult is the effect of an ICMP error on the TCP connection
+// whose segment it quotes.
+type ICMPErrorResult struct {
+	// Type and Code are the ICMP type and code
+	Type int `json:"type"`
+	Code int `json:"code"`
+
+	// Errno is the error the ICMP error converts to (icmp_err_convert)
+	Errno string `json:"errno"`
+
+	// SocketError is where the error is recorded: "sk_err", reported to
+	// the application at once, or "sk_err_soft", reported only if the
+	// connection later times out ("" if the error is not recorded)
+	SocketError string `json:"socketError,omitempty"`
+
+	// PMTU is the new path MTU (Fragmentation Needed only)
+	PMTU int `json:"pmtu,omitempty"`
+
+	// MSS is the MSS after tcp_sync_mss (set once the MTU is reduced)
+	MSS int `json:"mss,omitempty"`
+
+	// Deferred is true if the socket was owned by the user, so the work
+	// waits for release_sock
+	Deferred bool `json:"deferred,omitempty"`
+}
+
+// NewSKBuffForICMPError creates an sk_buff holding a received ICMP error:
+// the Ethernet, IP and ICMP headers, then as much of the offending TCP
+// segment as the error quotes (its IP and TCP headers and the start of its
+// payload).
+func NewSKBuffForICMPError(totalSize, payloadSize int) *SKBuff {
+	quoted := min(IPv4HeaderSize+TCPHeaderSize+payloadSize, icmpQuoteMax)
+	icmp := EthernetHeaderSize + IPv4HeaderSize
+	skb := &SKBuff{
+		Head: 0,
+		Data: 0,
+		Tail: icmp + ICMPHeaderSize + quoted,
+		End:  totalSize,
+		Layers: []ProtocolHeader{
+			{Protocol: "ethernet", Offset: 0, Size: EthernetHeaderSize},
+			{Protocol: "ip", Offset: EthernetHeaderSize, Size: IPv4HeaderSize},
+			{Protocol: "icmp", Offset: icmp, Size: ICMPHeaderSize},
+			{Protocol: "ip", Offset: icmp + ICMPHeaderSize, Size: IPv4HeaderSize},
+			{Protocol: "tcp", Offset: icmp + ICMPHeaderSize + IPv4HeaderSize, Size: TCPHeaderSize},
+		},
+	}
+	skb.ResetMacHeader()
+	return skb
+}
+
+// newICMPErrorResult returns the ICMP type, code and errno of an error kind.
+func newICMPErrorResult(kind string) *ICMPErrorResult {
+	if kind == ICMPErrorFragNeeded {
+		return &ICMPErrorResult{Type: 3, Code: 4, Errno: "EMSGSIZE"}
+	}
+	return &ICMPErrorResult{Type: 3, Code: 3, Errno: "ECONNREFUSED"}
+}
+
+// effectTCPv4Err models tcp_v4_err applying the error to the socket of the
+// quoted segment.
+func effectTCPv4Err(ctx *simContext, step *SimulateStep) {
+	kind := ctx.opts.ICMPError
+	if kind == "" {
+		return
+	}
+	result := newICMPErrorResult(kind)
+	step.ICMPError = result
+
+	switch ctx.opts.SocketLookup {
+	case SocketLookupNone, SocketLookupListen, SocketLookupTimeWait:
+		ctx.count("InErrors")
+		step.annotate(AnnotationICMPError,
+			"No connection matches the quoted segment: the error is counted (ICMP InErrors) and ignored, as it may be forged or stale.")
+		return
+	case SocketLookupSynRecv:
+		if kind == ICMPErrorPortUnreach {
+			step.annotate(AnnotationICMPError,
+				"The quoted segment is the SYN-ACK of a pending request: tcp_req_err drops the request sock, as the client is gone.")
+		} else {
+			step.annotate(AnnotationICMPError,
+				"The quoted segment is the SYN-ACK of a pending request, which is left alone: it will be retransmitted.")
+		}
+		return
+	}
+
+	if kind == ICMPErrorFragNeeded {
+		result.PMTU = icmpNextHopMTU
+		if ctx.opts.SocketOwnedByUser {
+			result.Deferred = true
+			step.annotate(AnnotationICMPError, fmt.Sprintf(
+				"Fragmentation needed, next-hop MTU %d: tp->mtu_info records it, but a process owns the socket, so TCP_MTU_REDUCED_DEFERRED is set "+
+					"and tcp_release_cb runs tcp_v4_mtu_reduced when release_sock is called.",
+				icmpNextHopMTU))
+			return
+		}
+		step.annotate(AnnotationICMPError, fmt.Sprintf(
+			"Fragmentation needed, next-hop MTU %d: tp->mtu_info records it and tcp_v4_mtu_reduced runs at once. No error reaches the application; "+
+				"the connection just sends smaller segments.",
+			icmpNextHopMTU))
+		return
+	}
+
+	// RFC 1122: hard errors do not abort an established connection
+	result.SocketError = "sk_err_soft"
+	step.annotate(AnnotationICMPError, fmt.Sprintf(
+		"Port unreachable on an established connection: %s is stored in sk_err_soft rather than aborting it, as RFC 1122 asks, "+
+			"and is reported only if the connection later times out. With IP_RECVERR set (and the socket not owned by the user) "+
+			"it goes to sk_err and the application sees it at once.",
+		result.Errno))
+}
+
+// effectMTUReduced models tcp_v4_mtu_reduced applying the reduced path MTU.
+func effectMTUReduced(ctx *simContext, step *SimulateStep) {
+	result := newICMPErrorResult(ctx.opts.ICMPError)
+	result.PMTU = icmpNextHopMTU
+	result.MSS = icmpNextHopMTU - IPv4HeaderSize - TCPHeaderSize
+	step.ICMPError = result
+	step.annotate(AnnotationICMPError, fmt.Sprintf(
+		"inet_csk_update_pmtu caches a PMTU of %d bytes in the route's exception, so every connection to the destination uses it. "+
+			"tcp_sync_mss lowers the MSS from %d to %d, and tcp_simple_retransmit resends the segments that were too large.",
+		result.PMTU, tcpDefaultMSS, result.MSS))
+}
@@ -0,0 +1,91 @@
+package contract
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Function IDs are namespaced by convention:
+//
+//   - A function's ID is its kernel symbol name (e.g. "ip_rcv"). Every path
+//     that calls the same kernel function uses the same ID and the same
+//     definition (source file, line, layer), so the frontend can treat the
+//     function as one node across paths.
+//   - When a path needs a node for a symbol that differs from the shared
+//     definition (another kernel version, or a distinct call site worth its
+//     own node), its ID is the symbol qualified with "@" and a short
+//     lowercase qualifier, built with FunctionID (e.g. "ip_rcv@legacy").
+//
+// CheckIDCollisions reports violations of the first rule.
+
+// functionIDQualifierSep separates a symbol from its qualifier in an ID.
+const functionIDQualifierSep = "@"
+
+// FunctionID returns the ID for a kernel symbol, qualified if qualifier is
+// non-empty. The result depends only on its arguments, so paths built
+// independently agree on the ID of a shared node.
+func FunctionID(symbol, qualifier string) string {
+	if qualifier == "" {
+		return symbol
+	}
+	return symbol + functionIDQualifierSep + strings.ToLower(qualifier)
+}
+
+// FunctionSymbol returns the kernel symbol part of a function ID.
+func FunctionSymbol(id string) string {
+	symbol, _, _ := strings.Cut(id, functionIDQualifierSep)
+	return symbol
+}
+
+// functionDefinition is the part of a KernelFunction that must agree across
+// paths sharing its ID.
+type functionDefinition struct {
+	Name       string
+	Layer      Layer
+	SourceFile string
+	LineNumber int
+}
+
+func (d functionDefinition) String() string {
+	return fmt.Sprintf("%s (%s) at %s:%d", d.Name, d.Layer, d.SourceFile, d.LineNumber)
+}
+
+// CheckIDCollisions reports function IDs that registered paths define
+// differently: same ID, but a different name, layer, source file or line.
+// Functions shared by several paths with identical definitions are not
+// collisions. The result is sorted by ID and empty if there are none.
+func (r *PathRegistry) CheckIDCollisions() []string {
+	// definitions[id][definition] lists the paths using that definition
+	definitions := make(map[string]map[functionDefinition][]string)
+	for _, path := range r.Paths() {
+		for _, fn := range path.Functions {
+			def := functionDefinition{
+				Name:       fn.Name,
+				Layer:      fn.Layer,
+				SourceFile: fn.SourceFile,
+				LineNumber: fn.LineNumber,
+			}
+			if definitions[fn.ID] == nil {
+				definitions[fn.ID] = make(map[functionDefinition][]string)
+			}
+			definitions[fn.ID][def] = append(definitions[fn.ID][def], path.ID)
+		}
+	}
+
+	var collisions []string
+	for id, defs := range definitions {
+		if len(defs) < 2 {
+			continue
+		}
+		variants := make([]string, 0, len(defs))
+		for def, paths := range defs {
+			variants = append(variants, fmt.Sprintf("%s in %s", def, strings.Join(paths, ", ")))
+		}
+		sort.Strings(variants)
+		collisions = append(collisions, fmt.Sprintf("function %q defined %d ways: %s",
+			id, len(defs), strings.Join(variants, "; ")))
+	}
+	sort.Strings(collisions)
+	return collisions
+}
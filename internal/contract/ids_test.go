@@ -0,0 +1,58 @@
+package contract
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultRegistryHasNoIDCollisions(t *testing.T) {
+	for _, collision := range DefaultRegistry().CheckIDCollisions() {
+		t.Error(collision)
+	}
+}
+
+// singleFunctionPath returns a path whose only function is fn.
+func singleFunctionPath(id string, fn KernelFunction) func() *PacketPath {
+	return func() *PacketPath {
+		fn.IsEntryPoint, fn.IsExitPoint = false, false
+		return NewPathBuilder(id, id, "egress", "TEST").
+			AddFunction(fn).
+			SetEntry(fn.ID).
+			SetExit(fn.ID).
+			MustBuild()
+	}
+}
+
+func TestCheckIDCollisions(t *testing.T) {
+	ipRcv := KernelFunction{
+		ID:               "ip_rcv",
+		Name:             "ip_rcv",
+		Layer:            LayerNetwork,
+		SourceFile:       "net/ipv4/ip_input.c",
+		LineNumber:       530,
+		ExecutionContext: ContextSoftIRQ,
+		Description:      "IPv4 receive.",
+	}
+	moved := ipRcv
+	moved.LineNumber = 517
+	described := ipRcv
+	described.Description = "The same function, described differently."
+
+	r := NewPathRegistry()
+	r.Register("a", singleFunctionPath("a", ipRcv))
+	r.Register("b", singleFunctionPath("b", described))
+	if collisions := r.CheckIDCollisions(); len(collisions) != 0 {
+		t.Fatalf("identical definitions reported as collisions: %v", collisions)
+	}
+
+	r.Register("c", singleFunctionPath("c", moved))
+	collisions := r.CheckIDCollisions()
+	if len(collisions) != 1 {
+		t.Fatalf("collisions = %v, want one for ip_rcv", collisions)
+	}
+	for _, want := range []string{`"ip_rcv" defined 2 ways`, "ip_input.c:517 in c", "ip_input.c:530 in a, b"} {
+		if !strings.Contains(collisions[0], want) {
+			t.Errorf("collision %q does not mention %q", collisions[0], want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package contract
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Versions of the contract and of the kernel it models
+const (
+	// SchemaVersion is the version of the exported contract schema
+	SchemaVersion = "1.1.0"
+
+	// KernelVersion is the Linux kernel version the paths are based on
+	KernelVersion = "5.10.8"
+)
+
+// PackageInfoResult describes what this build of the contract supports, so
+// clients can discover capabilities instead of assuming them.
+type PackageInfoResult struct {
+	// SchemaVersion is the contract schema version of exports
+	SchemaVersion string `json:"schemaVersion"`
+
+	// KernelVersions are the kernel versions paths are modeled on
+	KernelVersions []string `json:"kernelVersions"`
+
+	// Paths are the registered path IDs, in registration order
+	Paths []string `json:"paths"`
+
+	// Formats are the supported export formats
+	Formats []string `json:"formats"`
+
+	// Parameters are the supported simulation parameters
+	Parameters []ParameterInfo `json:"parameters"`
+}
+
+// ParameterInfo describes one simulation parameter (a SimulateOptions
+// field).
+type ParameterInfo struct {
+	// Name is the SimulateOptions field name
+	Name string `json:"name"`
+
+	// Type is the parameter's Go kind: "int", "bool", "string", ...
+	Type string `json:"type"`
+
+	// Values lists the accepted values of an enumerated string parameter
+	// ("" is always accepted and selects the default)
+	Values []string `json:"values,omitempty"`
+}
+
+// parameterValues are the accepted values of the enumerated simulation
+// parameters.
+var parameterValues = map[string][]string{
+	"GROFlush":        sortedKeys(groFlushExplanations),
+	"SocketLookup":    {SocketLookupEstablished, SocketLookupListen, SocketLookupSynRecv, SocketLookupTimeWait, SocketLookupNone},
+	"GenericXDP":      {XDPVerdictPass, XDPVerdictDrop},
+	"SocketFilter":    {SocketFilterAllow, SocketFilterDeny},
+	"IPOptions":       sortedKeys(ipOptionSizes),
+	"TCIngress":       {TCActOK, TCActShot, TCActRedirect},
+	"BridgeFDB":       {BridgeFDBKnown, BridgeFDBUnknown},
+	"ARPOperation":    {ARPOpRequest, ARPOpReply},
+	"ConntrackHelper": sortedKeys(conntrackHelpers),
+	"ICMPError":       {ICMPErrorFragNeeded, ICMPErrorPortUnreach},
+	"CorruptHeader":   {"ip", "tcp"},
+}
+
+// PackageInfo returns the capabilities of the default registry and
+// renderers.
+func PackageInfo() PackageInfoResult {
+	return PackageInfoResult{
+		SchemaVersion:  SchemaVersion,
+		KernelVersions: []string{KernelVersion},
+		Paths:          DefaultRegistry().IDs(),
+		Formats:        DefaultRenderers(false).Formats(),
+		Parameters:     simulationParameters(),
+	}
+}
+
+// simulationParameters describes the fields of SimulateOptions.
+func simulationParameters() []ParameterInfo {
+	t := reflect.TypeOf(SimulateOptions{})
+	params := make([]ParameterInfo, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		params = append(params, ParameterInfo{
+			Name:   field.Name,
+			Type:   field.Type.Kind().String(),
+			Values: parameterValues[field.Name],
+		})
+	}
+	return params
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
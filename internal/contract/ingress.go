@@ -12,189 +12,589 @@ func BuildTCPIPv4IngressPath() *PacketPath {
 		Description: "The path of a TCP packet from the network interface through the kernel to user space (Linux 5.10.8)",
 		Direction:   "ingress",
 		Protocol:    "TCP",
+		Family:      FamilyIPv4,
 		EntryPoint:  "napi_poll",
-		ExitPoints:  []string{"sk_data_ready"},
+		ExitPoints:  []string{"tcp_send_delayed_ack", "tcp_send_ack", "packet_rcv", "inet_csk_reqsk_queue_hash_add", "cookie_v4_init_sequence", "inet_csk_accept", "tcp_ack_update_rtt", "skb_do_redirect", "consume_skb"},
 	}
 
 	// Define all functions in the ingress path
 	path.Functions = []KernelFunction{
 		// Driver Layer - NAPI
 		{
-			ID:           "napi_poll",
-			Name:         "napi_poll",
-			Layer:        LayerDriver,
-			SourceFile:   "net/core/dev.c",
-			LineNumber:   6740,
-			Description:  "NAPI polling entry point. Called by softirq to process received packets from the driver's ring buffer.",
-			IsEntryPoint: true,
+			ID:               "napi_poll",
+			Name:             "napi_poll",
+			Layer:            LayerDriver,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       6740,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "NAPI polling entry point. Called by softirq to process received packets from the driver's ring buffer.",
+			IsEntryPoint:     true,
 		},
 		{
-			ID:          "napi_gro_receive",
-			Name:        "napi_gro_receive",
-			Layer:       LayerDriver,
-			SourceFile:  "net/core/dev.c",
-			LineNumber:  6081,
-			Description: "Generic Receive Offload handler. XDP programs run here before sk_buff allocation.",
-			BPFHook:     NewXDPHook(),
+			ID:               "napi_gro_receive",
+			Name:             "napi_gro_receive",
+			Layer:            LayerDriver,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       6081,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Generic Receive Offload handler. XDP programs run here before sk_buff allocation.",
+			BPFHook:          NewXDPHook(),
+			ConfigDeps:       []string{"CONFIG_BPF_SYSCALL"},
+			EstimatedCostNs:  400,
 		},
 		{
-			ID:          "napi_skb_finish",
-			Name:        "napi_skb_finish",
-			Layer:       LayerDriver,
-			SourceFile:  "net/core/dev.c",
-			LineNumber:  6052,
-			Description: "Finishes GRO processing and passes the sk_buff up the stack.",
+			ID:               "napi_gro_complete",
+			Name:             "napi_gro_complete",
+			Layer:            LayerDriver,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       5764,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Flushes a held GRO packet: fixes up the coalesced headers (gro_complete) and passes the aggregate up the stack.",
+		},
+		{
+			ID:               "napi_skb_finish",
+			Name:             "napi_skb_finish",
+			Layer:            LayerDriver,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       6052,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Finishes GRO processing and passes the sk_buff up the stack.",
 		},
 
 		// Data Link Layer
 		{
-			ID:          "netif_receive_skb",
-			Name:        "netif_receive_skb",
-			Layer:       LayerDataLink,
-			SourceFile:  "net/core/dev.c",
-			LineNumber:  5583,
-			Description: "Main entry point for receiving packets from the driver. Timestamps and prepares the packet.",
+			ID:               "netif_receive_skb",
+			Name:             "netif_receive_skb",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       5583,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Main entry point for receiving packets from the driver. Timestamps and prepares the packet.",
+			WrapperOf:        "netif_receive_skb_internal",
+		},
+		{
+			ID:               "netif_receive_skb_internal",
+			Name:             "netif_receive_skb_internal",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       5508,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Internal receive handler. Handles RPS (Receive Packet Steering) if enabled.",
+			RCUProtected:     true,
+			RCUNote:          "rcu_read_lock covers RPS CPU selection (rps_map) and delivery up the stack.",
+		},
+		{
+			ID:               "__netif_receive_skb",
+			Name:             "__netif_receive_skb",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       5405,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Core receive function. TC ingress BPF programs run here.",
+			BPFHook:          NewTCIngressHook(),
+			ConfigDeps:       []string{"CONFIG_NET_CLS_ACT", "CONFIG_NET_INGRESS"},
+			RCUProtected:     true,
+			RCUNote:          "Inside the RCU section taken by netif_receive_skb_internal.",
+		},
+		{
+			ID:               "__netif_receive_skb_one_core",
+			Name:             "__netif_receive_skb_one_core",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       5303,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Single-core receive path. Processes packet on current CPU.",
+			RCUProtected:     true,
+			RCUNote:          "Inside the RCU section taken by netif_receive_skb_internal.",
+		},
+		{
+			ID:               "__netif_receive_skb_core",
+			Name:             "__netif_receive_skb_core",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       5099,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Core packet classification. Strips Ethernet header and determines protocol handler.",
+			SKBMutation:      NewPullMutation("ethernet", EthernetHeaderSize),
+			RCUProtected:     true,
+			RCUNote:          "Walks the ptype_all and ptype_base protocol handler lists locklessly under RCU.",
 		},
 		{
-			ID:          "netif_receive_skb_internal",
-			Name:        "netif_receive_skb_internal",
-			Layer:       LayerDataLink,
-			SourceFile:  "net/core/dev.c",
-			LineNumber:  5508,
-			Description: "Internal receive handler. Handles RPS (Receive Packet Steering) if enabled.",
+			ID:               "do_xdp_generic",
+			Name:             "do_xdp_generic",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       4744,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Runs a generic-mode XDP program on the sk_buff, temporarily pushing the MAC header back so the program sees the frame as native XDP would.",
+			BPFHook:          NewGenericXDPHook(),
+			ConfigDeps:       []string{"CONFIG_BPF_SYSCALL"},
+			RCUProtected:     true,
+			RCUNote:          "The device's xdp_prog is dereferenced under RCU.",
 		},
 		{
-			ID:          "__netif_receive_skb",
-			Name:        "__netif_receive_skb",
-			Layer:       LayerDataLink,
-			SourceFile:  "net/core/dev.c",
-			LineNumber:  5405,
-			Description: "Core receive function. TC ingress BPF programs and generic XDP run here.",
-			BPFHook:     NewTCIngressHook(),
+			ID:               "sch_handle_ingress",
+			Name:             "sch_handle_ingress",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       4932,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Runs the classifiers of the ingress or clsact qdisc, including TC ingress BPF programs, and acts on the verdict: continue (TC_ACT_OK), drop (TC_ACT_SHOT) or redirect to another interface (TC_ACT_REDIRECT).",
+			ConfigDeps:       []string{"CONFIG_NET_CLS_ACT", "CONFIG_NET_INGRESS"},
+			RCUProtected:     true,
+			RCUNote:          "The device's miniq_ingress filter chain is dereferenced under RCU-bh.",
 		},
 		{
-			ID:          "__netif_receive_skb_one_core",
-			Name:        "__netif_receive_skb_one_core",
-			Layer:       LayerDataLink,
-			SourceFile:  "net/core/dev.c",
-			LineNumber:  5303,
-			Description: "Single-core receive path. Processes packet on current CPU.",
+			ID:               "skb_do_redirect",
+			Name:             "skb_do_redirect",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/core/filter.c",
+			LineNumber:       2440,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Performs a TC redirect to the egress of another device: pushes the MAC header back and calls dev_queue_xmit on the target, bypassing the IP layer entirely.",
+			SKBMutation:      NewPushMutation("ethernet", EthernetHeaderSize),
+			ConfigDeps:       []string{"CONFIG_NET_CLS_ACT"},
+			IsExitPoint:      true,
 		},
 		{
-			ID:          "__netif_receive_skb_core",
-			Name:        "__netif_receive_skb_core",
-			Layer:       LayerDataLink,
-			SourceFile:  "net/core/dev.c",
-			LineNumber:  5099,
-			Description: "Core packet classification. Strips Ethernet header and determines protocol handler.",
-			SKBMutation: NewPullMutation("ethernet", EthernetHeaderSize),
+			ID:               "deliver_skb",
+			Name:             "deliver_skb",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       2248,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Delivers packet to the registered protocol handler (e.g., ip_rcv for IPv4).",
+			RCUProtected:     true,
+			RCUNote:          "Protocol handler (packet_type) is invoked through an RCU-protected pointer.",
 		},
 		{
-			ID:          "deliver_skb",
-			Name:        "deliver_skb",
-			Layer:       LayerDataLink,
-			SourceFile:  "net/core/dev.c",
-			LineNumber:  2248,
-			Description: "Delivers packet to the registered protocol handler (e.g., ip_rcv for IPv4).",
+			ID:               "packet_rcv",
+			Name:             "packet_rcv",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/packet/af_packet.c",
+			LineNumber:       2056,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "AF_PACKET tap handler (e.g., tcpdump). Clones the shared sk_buff, queues the clone on the packet socket and drops its reference to the original.",
+			ConfigDeps:       []string{"CONFIG_PACKET"},
+			IsExitPoint:      true,
 		},
 
 		// Network Layer - IP
 		{
-			ID:            "ip_rcv",
-			Name:          "ip_rcv",
-			Layer:         LayerNetwork,
-			SourceFile:    "net/ipv4/ip_input.c",
-			LineNumber:    530,
-			Description:   "IPv4 receive entry point. Validates IP header checksum and invokes PREROUTING netfilter hook.",
-			NetfilterHook: NewPreroutingHook(),
+			ID:               "ip_rcv",
+			Name:             "ip_rcv",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/ip_input.c",
+			LineNumber:       530,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "IPv4 receive entry point. Validates IP header checksum and invokes PREROUTING netfilter hook.",
+			NetfilterHook:    NewPreroutingHook(),
+			ConfigDeps:       []string{"CONFIG_NETFILTER"},
 		},
 		{
-			ID:          "ip_rcv_finish",
-			Name:        "ip_rcv_finish",
-			Layer:       LayerNetwork,
-			SourceFile:  "net/ipv4/ip_input.c",
-			LineNumber:  414,
-			Description: "Finishes IP header processing. Performs routing lookup and strips IP header.",
-			SKBMutation: NewPullMutation("ip", IPv4HeaderSize),
+			ID:               "ip_rcv_finish",
+			Name:             "ip_rcv_finish",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/ip_input.c",
+			LineNumber:       414,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Finishes IP header processing. Performs routing lookup and strips IP header.",
+			SKBMutation:      NewPullMutation("ip", IPv4HeaderSize),
+			RCUProtected:     true,
+			RCUNote:          "FIB route lookup reads the routing trie locklessly under RCU.",
 		},
 		{
-			ID:          "ip_local_deliver",
-			Name:        "ip_local_deliver",
-			Layer:       LayerNetwork,
-			SourceFile:  "net/ipv4/ip_input.c",
-			LineNumber:  240,
-			Description: "Handles locally destined packets. Reassembles IP fragments if needed.",
+			ID:               "ip_rcv_options",
+			Name:             "ip_rcv_options",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/ip_input.c",
+			LineNumber:       263,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Called by ip_rcv_finish when IHL > 5. Parses the options with ip_options_compile, sending ICMP Parameter Problem for a malformed one, and drops source-routed packets unless accept_source_route is set.",
 		},
 		{
-			ID:            "ip_local_deliver_finish",
-			Name:          "ip_local_deliver_finish",
-			Layer:         LayerNetwork,
-			SourceFile:    "net/ipv4/ip_input.c",
-			LineNumber:    226,
-			Description:   "Invokes INPUT netfilter hook before passing to transport layer.",
-			NetfilterHook: NewInputHook(),
+			ID:               "ip_local_deliver",
+			Name:             "ip_local_deliver",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/ip_input.c",
+			LineNumber:       240,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Handles locally destined packets. Reassembles IP fragments if needed.",
 		},
 		{
-			ID:          "ip_protocol_deliver_rcu",
-			Name:        "ip_protocol_deliver_rcu",
-			Layer:       LayerNetwork,
-			SourceFile:  "net/ipv4/ip_input.c",
-			LineNumber:  187,
-			Description: "Dispatches packet to the transport protocol handler based on IP protocol field.",
+			ID:               "ip_defrag",
+			Name:             "ip_defrag",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/ip_fragment.c",
+			LineNumber:       467,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Collects IP fragments in a reassembly queue keyed by source, destination, protocol and ID. Passes a single reassembled sk_buff on once all fragments have arrived.",
+		},
+		{
+			ID:               "ip_local_deliver_finish",
+			Name:             "ip_local_deliver_finish",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/ip_input.c",
+			LineNumber:       226,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Invokes INPUT netfilter hook before passing to transport layer.",
+			NetfilterHook:    NewInputHook(),
+			ConfigDeps:       []string{"CONFIG_NETFILTER"},
+			RCUProtected:     true,
+			RCUNote:          "rcu_read_lock around dispatch to the transport protocol.",
+		},
+		{
+			ID:               "ip_protocol_deliver_rcu",
+			Name:             "ip_protocol_deliver_rcu",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/ip_input.c",
+			LineNumber:       187,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Dispatches packet to the transport protocol handler based on IP protocol field.",
+			RCUProtected:     true,
+			RCUNote:          "inet_protos[] handler table is read under RCU (hence the _rcu suffix).",
+		},
+
+		// Network Layer - ICMP errors
+		{
+			ID:               "icmp_rcv",
+			Name:             "icmp_rcv",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/icmp.c",
+			LineNumber:       1063,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "ICMP receive handler. Validates the ICMP checksum, pulls the ICMP header and dispatches on the type. Conntrack has already matched an error to the connection it quotes and marked it RELATED.",
+			SKBMutation:      NewPullMutation("icmp", ICMPHeaderSize),
+		},
+		{
+			ID:               "icmp_unreach",
+			Name:             "icmp_unreach",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/icmp.c",
+			LineNumber:       868,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Handles Destination Unreachable, Source Quench and Time Exceeded. For Fragmentation Needed it reads the next-hop MTU; it checks the quoted IP header is complete.",
+		},
+		{
+			ID:               "icmp_socket_deliver",
+			Name:             "icmp_socket_deliver",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/icmp.c",
+			LineNumber:       831,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Makes sure the quoted IP header and the first 8 bytes of its transport header are present, then calls the err_handler of the quoted protocol.",
+			RCUProtected:     true,
+			RCUNote:          "inet_protos[] is read under RCU to find the quoted protocol's handler.",
 		},
 
 		// Transport Layer - TCP
 		{
-			ID:          "tcp_v4_rcv",
-			Name:        "tcp_v4_rcv",
-			Layer:       LayerTransport,
-			SourceFile:  "net/ipv4/tcp_ipv4.c",
-			LineNumber:  1915,
-			Description: "TCP receive entry point. Validates TCP checksum and looks up socket.",
+			ID:               "tcp_v4_rcv",
+			Name:             "tcp_v4_rcv",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_ipv4.c",
+			LineNumber:       1915,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "TCP receive entry point. Validates TCP checksum and looks up socket.",
+			SKBMutation:      NewCsumMutation("tcp", CsumUnnecessary, "Checksum verified (skb_checksum_init); later code skips the check"),
+			EstimatedCostNs:  400,
+			RCUProtected:     true,
+			RCUNote:          "Established and listening socket hash tables are looked up locklessly under RCU.",
+		},
+		{
+			ID:               "tcp_filter",
+			Name:             "tcp_filter",
+			Layer:            LayerSocket,
+			SourceFile:       "net/ipv4/tcp_ipv4.c",
+			LineNumber:       1871,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Runs the socket's attached filter (SO_ATTACH_FILTER, e.g. a pcap program) via sk_filter_trim_cap. The filter can drop the packet or trim it, but never below the TCP header.",
+			BPFHook:          NewSocketBPFHook(),
+			ConfigDeps:       []string{"CONFIG_BPF"},
+		},
+		{
+			ID:               "sk_add_backlog",
+			Name:             "sk_add_backlog",
+			Layer:            LayerTransport,
+			SourceFile:       "include/net/sock.h",
+			LineNumber:       949,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Socket is owned by a user process. Queues the sk_buff on the socket backlog for deferred processing.",
+		},
+		{
+			ID:               "release_sock",
+			Name:             "release_sock",
+			Layer:            LayerSocket,
+			SourceFile:       "net/core/sock.c",
+			LineNumber:       3052,
+			ExecutionContext: ContextProcess,
+			Description:      "Called when the user process releases the socket lock. Drains the backlog before unlocking.",
+		},
+		{
+			ID:               "__release_sock",
+			Name:             "__release_sock",
+			Layer:            LayerSocket,
+			SourceFile:       "net/core/sock.c",
+			LineNumber:       2524,
+			ExecutionContext: ContextProcess,
+			Description:      "Processes each backlogged sk_buff via sk_backlog_rcv, which is tcp_v4_do_rcv for TCP.",
+		},
+		{
+			ID:               "tcp_v4_err",
+			Name:             "tcp_v4_err",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_ipv4.c",
+			LineNumber:       436,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Looks up the socket of the quoted segment and checks its sequence number is in the window, so forged errors are ignored. Records a reduced MTU or converts the error to an errno for the socket.",
+		},
+		{
+			ID:               "tcp_v4_mtu_reduced",
+			Name:             "tcp_v4_mtu_reduced",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_ipv4.c",
+			LineNumber:       345,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Path MTU discovery: updates the route's PMTU, shrinks the MSS with tcp_sync_mss and retransmits the segments that were too large.",
+		},
+		{
+			ID:               "consume_skb",
+			Name:             "consume_skb",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/core/skbuff.c",
+			LineNumber:       863,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "icmp_rcv frees the handled ICMP error. Unlike kfree_skb this is not a drop: the packet was consumed, so drop monitors do not report it.",
+			SKBMutation:      NewFreeMutation("Free sk_buff (ICMP error handled)"),
+			IsExitPoint:      true,
+		},
+		{
+			ID:               "tcp_v4_do_rcv",
+			Name:             "tcp_v4_do_rcv",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_ipv4.c",
+			LineNumber:       1655,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Main TCP receive handler. Processes TCP header and updates connection state.",
+			SKBMutation:      NewPullMutation("tcp", TCPHeaderSize),
 		},
 		{
-			ID:          "tcp_v4_do_rcv",
-			Name:        "tcp_v4_do_rcv",
-			Layer:       LayerTransport,
-			SourceFile:  "net/ipv4/tcp_ipv4.c",
-			LineNumber:  1655,
-			Description: "Main TCP receive handler. Processes TCP header and updates connection state.",
-			SKBMutation: NewPullMutation("tcp", TCPHeaderSize),
+			ID:               "tcp_rcv_state_process",
+			Name:             "tcp_rcv_state_process",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_input.c",
+			LineNumber:       6294,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "TCP state machine for every state except ESTABLISHED. On a listening socket, hands a SYN to the address family's conn_request.",
 		},
 		{
-			ID:          "tcp_rcv_established",
-			Name:        "tcp_rcv_established",
-			Layer:       LayerTransport,
-			SourceFile:  "net/ipv4/tcp_input.c",
-			LineNumber:  5704,
-			Description: "Fast path for established connections. Handles ACKs, window updates, and data.",
+			ID:               "tcp_v4_conn_request",
+			Name:             "tcp_v4_conn_request",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_ipv4.c",
+			LineNumber:       1479,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "IPv4 handler for a SYN on a listening socket. Rejects SYNs to broadcast or multicast addresses, then calls tcp_conn_request.",
 		},
 		{
-			ID:          "tcp_data_queue",
-			Name:        "tcp_data_queue",
-			Layer:       LayerTransport,
-			SourceFile:  "net/ipv4/tcp_input.c",
-			LineNumber:  4919,
-			Description: "Queues received data. Handles out-of-order segments and SACK.",
+			ID:               "tcp_conn_request",
+			Name:             "tcp_conn_request",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_input.c",
+			LineNumber:       6718,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Handles a SYN on a listening socket: allocates a request sock and sends the SYN-ACK. If the SYN queue is full, answers with a SYN cookie instead of keeping state.",
 		},
 		{
-			ID:          "tcp_queue_rcv",
-			Name:        "tcp_queue_rcv",
-			Layer:       LayerTransport,
-			SourceFile:  "net/ipv4/tcp_input.c",
-			LineNumber:  4837,
-			Description: "Adds data to socket receive queue. Updates TCP receive window.",
+			ID:               "inet_csk_reqsk_queue_hash_add",
+			Name:             "inet_csk_reqsk_queue_hash_add",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/inet_connection_sock.c",
+			LineNumber:       921,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Inserts the request sock into the established hash and the SYN queue and arms the SYN-ACK retransmit timer. The full socket is created when the final ACK arrives.",
+			IsExitPoint:      true,
+		},
+		{
+			ID:               "cookie_v4_init_sequence",
+			Name:             "cookie_v4_init_sequence",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/syncookies.c",
+			LineNumber:       173,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "SYN cookie: encodes the connection's MSS and a keyed hash of the 4-tuple and time into the SYN-ACK's initial sequence number. The request sock is freed instead of queued; the final ACK rebuilds it from the cookie.",
+			ConfigDeps:       []string{"CONFIG_SYN_COOKIES"},
+			IsExitPoint:      true,
+		},
+		{
+			ID:               "tcp_check_req",
+			Name:             "tcp_check_req",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_minisocks.c",
+			LineNumber:       578,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Validates the final ACK of the handshake against the request sock (sequence numbers, SYN-ACK retransmits) and asks for the full child socket.",
+		},
+		{
+			ID:               "tcp_v4_syn_recv_sock",
+			Name:             "tcp_v4_syn_recv_sock",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_ipv4.c",
+			LineNumber:       1488,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Creates the full child socket for the connection. Fails if the listener's accept queue is full (listen overflow).",
+		},
+		{
+			ID:               "inet_csk_complete_hashdance",
+			Name:             "inet_csk_complete_hashdance",
+			Layer:            LayerSocket,
+			SourceFile:       "net/ipv4/inet_connection_sock.c",
+			LineNumber:       1046,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Removes the request sock from the SYN queue, adds the established child to the accept queue (inet_csk_reqsk_queue_add) and wakes the listener.",
+		},
+		{
+			ID:               "inet_csk_accept",
+			Name:             "inet_csk_accept",
+			Layer:            LayerSocket,
+			SourceFile:       "net/ipv4/inet_connection_sock.c",
+			LineNumber:       467,
+			ExecutionContext: ContextProcess,
+			Description:      "accept() system call: sleeps until the accept queue is non-empty, then dequeues the first established connection.",
+			IsExitPoint:      true,
+		},
+		{
+			ID:               "tcp_timewait_state_process",
+			Name:             "tcp_timewait_state_process",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_minisocks.c",
+			LineNumber:       96,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Handles a segment for a connection in TIME_WAIT: re-ACKs stray segments, resets on RST, or lets a new SYN reuse the port.",
+		},
+		{
+			ID:               "tcp_v4_send_reset",
+			Name:             "tcp_v4_send_reset",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_ipv4.c",
+			LineNumber:       650,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "No socket matches the segment: replies with a TCP RST (TCP does not use ICMP port unreachable) and the segment is dropped.",
+		},
+		{
+			ID:               "tcp_rcv_established",
+			Name:             "tcp_rcv_established",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_input.c",
+			LineNumber:       5704,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Fast path for established connections. Handles ACKs, window updates, and data.",
+			EstimatedCostNs:  350,
+		},
+		{
+			ID:               "tcp_ack",
+			Name:             "tcp_ack",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_input.c",
+			LineNumber:       3724,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Processes the ACK field: advances snd_una, updates the send window, and runs congestion control for the newly acknowledged data.",
+		},
+		{
+			ID:               "tcp_clean_rtx_queue",
+			Name:             "tcp_clean_rtx_queue",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_input.c",
+			LineNumber:       3206,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Frees the fully acknowledged segments from the retransmission queue and notes the send time of the newest one for RTT sampling.",
+		},
+		{
+			ID:               "tcp_ack_update_rtt",
+			Name:             "tcp_ack_update_rtt",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_input.c",
+			LineNumber:       3076,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Takes an RTT sample from the TCP timestamp echo or the acknowledged segment's send time, updates srtt and rttvar, and recomputes the retransmission timeout. The pure ACK's sk_buff is then freed.",
+			IsExitPoint:      true,
+		},
+		{
+			ID:               "tcp_data_queue",
+			Name:             "tcp_data_queue",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_input.c",
+			LineNumber:       4919,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Queues received data. Handles out-of-order segments and SACK.",
+		},
+		{
+			ID:               "tcp_queue_rcv",
+			Name:             "tcp_queue_rcv",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_input.c",
+			LineNumber:       4837,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Adds data to socket receive queue. Updates TCP receive window.",
+		},
+		{
+			ID:               "tcp_try_coalesce",
+			Name:             "tcp_try_coalesce",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_input.c",
+			LineNumber:       4559,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Merges the segment into the sk_buff at the tail of the receive queue (skb_try_coalesce), copying into its tailroom or stealing the data pages, and frees the emptied sk_buff. Saves memory and queue entries.",
 		},
 
 		// Socket Layer
 		{
-			ID:          "sk_data_ready",
-			Name:        "sk_data_ready",
-			Layer:       LayerSocket,
-			SourceFile:  "net/core/sock.c",
-			LineNumber:  2990,
-			Description: "Wakes up any process waiting to read from the socket. Data is now available for recv().",
-			IsExitPoint: true,
+			ID:               "kfree_skb",
+			Name:             "kfree_skb",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/core/skbuff.c",
+			LineNumber:       697,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Drops the packet: releases the sk_buff and fires the skb:kfree_skb tracepoint that drop monitors (dropwatch, perf) observe.",
+			SKBMutation:      NewFreeMutation("Free sk_buff (packet dropped)"),
+		},
+		{
+			ID:               "sk_data_ready",
+			Name:             "sk_data_ready",
+			Layer:            LayerSocket,
+			SourceFile:       "net/core/sock.c",
+			LineNumber:       2990,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Wakes up any process waiting to read from the socket. Data is now available for recv().",
+		},
+		{
+			ID:               "__tcp_ack_snd_check",
+			Name:             "__tcp_ack_snd_check",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_input.c",
+			LineNumber:       5335,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Decides whether to ACK the received data now or delay the ACK, hoping to piggyback it on reply data or cover more segments with one ACK.",
+		},
+		{
+			ID:               "tcp_send_delayed_ack",
+			Name:             "tcp_send_delayed_ack",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_output.c",
+			LineNumber:       3853,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Arms the delayed-ACK timer (between 40 ms and 200 ms, adapted to the observed inter-arrival time). The ACK goes out when the timer fires unless reply data carries it first.",
+			IsExitPoint:      true,
+		},
+		{
+			ID:               "tcp_send_ack",
+			Name:             "tcp_send_ack",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_output.c",
+			LineNumber:       3968,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Sends a pure ACK immediately: a header-only segment allocated here and transmitted through __tcp_transmit_skb down the egress path.",
+			IsExitPoint:      true,
 		},
 	}
 
@@ -202,23 +602,76 @@ func BuildTCPIPv4IngressPath() *PacketPath {
 	path.Edges = []FunctionEdge{
 		{From: "napi_poll", To: "napi_gro_receive", Order: 1},
 		{From: "napi_gro_receive", To: "napi_skb_finish", Order: 1},
+		{From: "napi_gro_receive", To: "napi_gro_complete", Order: 2, Condition: "Packet merged into a held GRO flow"},
+		{From: "napi_gro_complete", To: "netif_receive_skb_internal", Order: 1, Condition: "Held flow flushed"},
 		{From: "napi_skb_finish", To: "netif_receive_skb", Order: 1},
 		{From: "netif_receive_skb", To: "netif_receive_skb_internal", Order: 1},
 		{From: "netif_receive_skb_internal", To: "__netif_receive_skb", Order: 1},
 		{From: "__netif_receive_skb", To: "__netif_receive_skb_one_core", Order: 1},
 		{From: "__netif_receive_skb_one_core", To: "__netif_receive_skb_core", Order: 1},
 		{From: "__netif_receive_skb_core", To: "deliver_skb", Order: 1},
+		{From: "__netif_receive_skb_core", To: "do_xdp_generic", Order: 2, Condition: "Generic XDP program attached"},
+		{From: "do_xdp_generic", To: "deliver_skb", Order: 1, Condition: "XDP_PASS"},
+		{From: "do_xdp_generic", To: "kfree_skb", Order: 2, Condition: "XDP_DROP", IsErrorPath: true},
+		{From: "do_xdp_generic", To: "sch_handle_ingress", Order: 3, Condition: "XDP_PASS, ingress qdisc attached"},
+		{From: "__netif_receive_skb_core", To: "sch_handle_ingress", Order: 3, Condition: "Ingress or clsact qdisc attached"},
+		{From: "sch_handle_ingress", To: "deliver_skb", Order: 1, Condition: "TC_ACT_OK"},
+		{From: "sch_handle_ingress", To: "skb_do_redirect", Order: 2, Condition: "TC_ACT_REDIRECT (bpf_redirect, mirred)"},
+		{From: "sch_handle_ingress", To: "kfree_skb", Order: 3, Condition: "TC_ACT_SHOT", IsErrorPath: true},
 		{From: "deliver_skb", To: "ip_rcv", Order: 1, Condition: "Protocol is IPv4"},
+		{From: "deliver_skb", To: "packet_rcv", Order: 2, Condition: "AF_PACKET socket registered (ptype_all)"},
 		{From: "ip_rcv", To: "ip_rcv_finish", Order: 1},
+		{From: "ip_rcv", To: "kfree_skb", Order: 2, Condition: "IP header checksum invalid", IsErrorPath: true},
 		{From: "ip_rcv_finish", To: "ip_local_deliver", Order: 1, Condition: "Destination is local"},
+		{From: "ip_rcv_finish", To: "ip_rcv_options", Order: 2, Condition: "IP header has options (IHL > 5)"},
+		{From: "ip_rcv_options", To: "ip_local_deliver", Order: 1, Condition: "Options valid"},
+		{From: "ip_rcv_options", To: "kfree_skb", Order: 2, Condition: "Malformed option or source route not accepted", IsErrorPath: true},
 		{From: "ip_local_deliver", To: "ip_local_deliver_finish", Order: 1},
+		{From: "ip_local_deliver", To: "ip_defrag", Order: 2, Condition: "Packet is a fragment"},
+		{From: "ip_defrag", To: "ip_local_deliver_finish", Order: 1, Condition: "All fragments received"},
 		{From: "ip_local_deliver_finish", To: "ip_protocol_deliver_rcu", Order: 1},
 		{From: "ip_protocol_deliver_rcu", To: "tcp_v4_rcv", Order: 1, Condition: "Protocol is TCP"},
-		{From: "tcp_v4_rcv", To: "tcp_v4_do_rcv", Order: 1, Condition: "Socket found"},
+		{From: "ip_protocol_deliver_rcu", To: "icmp_rcv", Order: 2, Condition: "Protocol is ICMP"},
+		{From: "icmp_rcv", To: "icmp_unreach", Order: 1, Condition: "Destination Unreachable"},
+		{From: "icmp_unreach", To: "icmp_socket_deliver", Order: 1},
+		{From: "icmp_socket_deliver", To: "tcp_v4_err", Order: 1, Condition: "Quoted protocol is TCP"},
+		{From: "tcp_v4_err", To: "consume_skb", Order: 1, Condition: "Error recorded or ignored"},
+		{From: "tcp_v4_err", To: "tcp_v4_mtu_reduced", Order: 2, Condition: "Fragmentation needed, socket not owned by user"},
+		{From: "tcp_v4_mtu_reduced", To: "consume_skb", Order: 1},
+		{From: "tcp_v4_rcv", To: "tcp_filter", Order: 1, Condition: "Socket found"},
+		{From: "tcp_v4_rcv", To: "tcp_check_req", Order: 2, Condition: "Request sock found (final ACK of handshake)"},
+		{From: "tcp_v4_rcv", To: "tcp_timewait_state_process", Order: 3, Condition: "TIME_WAIT socket found"},
+		{From: "tcp_v4_rcv", To: "tcp_v4_send_reset", Order: 4, Condition: "No socket found", IsErrorPath: true},
+		{From: "tcp_v4_rcv", To: "kfree_skb", Order: 5, Condition: "TCP checksum invalid", IsErrorPath: true},
+		{From: "tcp_filter", To: "tcp_v4_do_rcv", Order: 1, Condition: "ALLOW, socket not owned by user"},
+		{From: "tcp_filter", To: "sk_add_backlog", Order: 2, Condition: "ALLOW, socket locked by user"},
+		{From: "tcp_filter", To: "kfree_skb", Order: 3, Condition: "DENY (filter returned 0)", IsErrorPath: true},
+		{From: "tcp_timewait_state_process", To: "kfree_skb", Order: 1, Condition: "Segment answered or discarded"},
+		{From: "tcp_v4_send_reset", To: "kfree_skb", Order: 1},
+		{From: "sk_add_backlog", To: "release_sock", Order: 1, Condition: "User releases socket lock"},
+		{From: "release_sock", To: "__release_sock", Order: 1, Condition: "Backlog not empty"},
+		{From: "__release_sock", To: "tcp_v4_do_rcv", Order: 1},
 		{From: "tcp_v4_do_rcv", To: "tcp_rcv_established", Order: 1, Condition: "Connection established"},
+		{From: "tcp_v4_do_rcv", To: "tcp_rcv_state_process", Order: 2, Condition: "Socket is listening"},
+		{From: "tcp_rcv_state_process", To: "tcp_v4_conn_request", Order: 1, Condition: "SYN received"},
+		{From: "tcp_v4_conn_request", To: "tcp_conn_request", Order: 1},
+		{From: "tcp_conn_request", To: "inet_csk_reqsk_queue_hash_add", Order: 1, Condition: "SYN queue has room"},
+		{From: "tcp_conn_request", To: "cookie_v4_init_sequence", Order: 2, Condition: "SYN queue full, net.ipv4.tcp_syncookies enabled"},
+		{From: "tcp_check_req", To: "tcp_v4_syn_recv_sock", Order: 1, Condition: "ACK acknowledges the SYN-ACK"},
+		{From: "tcp_v4_syn_recv_sock", To: "inet_csk_complete_hashdance", Order: 1, Condition: "Accept queue has room"},
+		{From: "tcp_v4_syn_recv_sock", To: "kfree_skb", Order: 2, Condition: "Accept queue full (listen overflow)", IsErrorPath: true},
+		{From: "inet_csk_complete_hashdance", To: "inet_csk_accept", Order: 1, Condition: "Application calls accept()"},
 		{From: "tcp_rcv_established", To: "tcp_data_queue", Order: 1, Condition: "Has data"},
+		{From: "tcp_rcv_established", To: "tcp_ack", Order: 2, Condition: "Pure ACK (no data)"},
+		{From: "tcp_ack", To: "tcp_clean_rtx_queue", Order: 1, Condition: "ACK acknowledges new data"},
+		{From: "tcp_clean_rtx_queue", To: "tcp_ack_update_rtt", Order: 1},
 		{From: "tcp_data_queue", To: "tcp_queue_rcv", Order: 1},
-		{From: "tcp_queue_rcv", To: "sk_data_ready", Order: 1},
+		{From: "tcp_queue_rcv", To: "sk_data_ready", Order: 1, Condition: "Queued as a separate sk_buff"},
+		{From: "tcp_queue_rcv", To: "tcp_try_coalesce", Order: 2, Condition: "Tail sk_buff is an in-order segment that can absorb the data"},
+		{From: "tcp_try_coalesce", To: "sk_data_ready", Order: 1},
+		{From: "sk_data_ready", To: "__tcp_ack_snd_check", Order: 1},
+		{From: "__tcp_ack_snd_check", To: "tcp_send_delayed_ack", Order: 1, Condition: "Less than one full segment unacknowledged"},
+		{From: "__tcp_ack_snd_check", To: "tcp_send_ack", Order: 2, Condition: "Quick-ACK mode or more than one full segment unacknowledged"},
 	}
 
 	return path
@@ -243,6 +696,8 @@ func NewSKBuffForIngress(totalSize, payloadSize int) *SKBuff {
 			{Protocol: "tcp", Offset: EthernetHeaderSize + IPv4HeaderSize, Size: TCPHeaderSize},
 		},
 	}
+	// The driver's eth_type_trans marked the Ethernet header
+	skb.ResetMacHeader()
 
 	return skb
 }
@@ -0,0 +1,84 @@
+package contract
+
+import "fmt"
+
+// IPv4 options carried by the ingress packet
+const (
+	// IPOptionRecordRoute asks every router to append its address
+	IPOptionRecordRoute = "record_route"
+
+	// IPOptionTimestamp asks every router to append a timestamp
+	IPOptionTimestamp = "timestamp"
+
+	// IPOptionSourceRoute lists the hops the packet must take (LSRR)
+	IPOptionSourceRoute = "source_route"
+
+	// IPOptionInvalid is an option with a malformed length field
+	IPOptionInvalid = "invalid"
+)
+
+// DropReasonIPOptions is a packet dropped while processing its IP options
+const DropReasonIPOptions = "IP_OPTIONS"
+
+// ipOptionSizes is the length of each modeled option in bytes, padded to a
+// multiple of 4 as IHL counts 32-bit words. Record route and timestamp use
+// the full 40 bytes of option space (9 addresses or timestamps); the source
+// route lists two hops.
+var ipOptionSizes = map[string]int{
+	IPOptionRecordRoute: 40,
+	IPOptionTimestamp:   40,
+	IPOptionSourceRoute: 12,
+	IPOptionInvalid:     4,
+}
+
+// IsValidIPOption reports whether option is a known IP option.
+func IsValidIPOption(option string) bool {
+	_, ok := ipOptionSizes[option]
+	return ok
+}
+
+// addIPOptions grows the IP header of a received packet by the size of its
+// options, moving the headers and payload behind it.
+func addIPOptions(skb *SKBuff, option string) {
+	size := ipOptionSizes[option]
+	if size == 0 {
+		return
+	}
+	grown := false
+	for i := range skb.Layers {
+		if grown {
+			skb.Layers[i].Offset += size
+		} else if skb.Layers[i].Protocol == "ip" {
+			skb.Layers[i].Size += size
+			grown = true
+		}
+	}
+	if grown {
+		skb.Tail += size
+	}
+}
+
+// effectIPOptions models ip_rcv_options parsing the options with
+// ip_options_compile and acting on them.
+func effectIPOptions(ctx *simContext, step *SimulateStep) {
+	size := ipOptionSizes[ctx.opts.IPOptions]
+	switch ctx.opts.IPOptions {
+	case IPOptionRecordRoute, IPOptionTimestamp:
+		step.annotate(AnnotationRouting, fmt.Sprintf(
+			"IP header is %d bytes (IHL %d): ip_options_compile parses %d bytes of %s option and records where it lies in IPCB(skb)->opt. "+
+				"Delivery continues; the stack only echoes the option in replies. On the slow path, options cost a full parse per packet.",
+			IPv4HeaderSize+size, (IPv4HeaderSize+size)/4, size, ctx.opts.IPOptions))
+	case IPOptionSourceRoute:
+		ctx.count("IpInHdrErrors")
+		ctx.dropReason = DropReasonIPOptions
+		step.annotate(AnnotationDrop,
+			"Loose source route option: net.ipv4.conf.*.accept_source_route is 0 by default, so the packet is dropped "+
+				"rather than re-routed to the next listed hop (source routing lets a sender bypass address-based filtering).")
+	case IPOptionInvalid:
+		ctx.count("IpInHdrErrors")
+		ctx.dropReason = DropReasonIPOptions
+		step.annotate(AnnotationDrop,
+			"ip_options_compile found a malformed option: an ICMP Parameter Problem pointing at the bad byte is sent "+
+				"back to the source, IpInHdrErrors is incremented and the packet is dropped.")
+	}
+}
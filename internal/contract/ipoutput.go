@@ -0,0 +1,181 @@
+package contract
+
+import "fmt"
+
+const (
+	// defaultMTU is the path MTU used when none is given: Ethernet's 1500
+	// bytes
+	defaultMTU = 1500
+
+	// ipv4MinMTU is the smallest MTU an IPv4 link may have (RFC 791): a
+	// 60-byte header and 8 bytes of data, the smallest fragment
+	ipv4MinMTU = 68
+
+	// ipMaxDatagram is IP_MAX_MTU, the largest IP datagram the 16-bit
+	// total length field can describe. It bounds the fragments the
+	// simulation emits one by one.
+	ipMaxDatagram = 0xFFFF
+)
+
+// IsValidMTU reports whether mtu can be the path MTU of a simulation: 0 for
+// the default, or at least the IPv4 minimum of 68 bytes.
+func IsValidMTU(mtu int) bool {
+	return mtu == 0 || mtu >= ipv4MinMTU
+}
+
+// mtu returns the path MTU of the simulation.
+func (ctx *simContext) mtu() int {
+	if ctx.opts.MTU > 0 {
+		return ctx.opts.MTU
+	}
+	return defaultMTU
+}
+
+// gso reports whether the simulated egress sk_buff is a GSO packet
+// (skb_is_gso): a UDP_SEGMENT send that needs more than one datagram, a
+// TCP send larger than one MSS, which tcp_sendmsg builds as a single TSO
+// sk_buff because every modern device advertises GSO unless it is turned
+// off, or a forwarded packet that GRO coalesced from several segments.
+func (ctx *simContext) gso() bool {
+	payload := ctx.opts.PayloadSize
+	if ctx.direction == "forward" {
+		return ctx.opts.GROFlush != "" && payload > tcpDefaultMSS
+	}
+	if gsoSize := ctx.opts.UDPSegment; gsoSize > 0 {
+		return payload > gsoSize && udpGSOSegments(payload, gsoSize) <= udpMaxSegments
+	}
+	return ctx.skb.HasLayer("tcp") && ctx.skb.GSO != nil
+}
+
+// ipFragments returns the number of fragments ip_do_fragment splits an IP
+// packet of the given length into. Every fragment but the last carries a
+// multiple of 8 payload bytes.
+func ipFragments(length, mtu int) int {
+	per := (mtu - IPv4HeaderSize) &^ 7
+	data := length - IPv4HeaderSize
+	if per <= 0 {
+		return 0
+	}
+	return (data + per - 1) / per
+}
+
+// ipFragmentSKBuffs returns the sk_buffs ip_do_fragment splits skb, which
+// starts with its IP header, into. Each fragment is laid out like skb, with
+// its own copy of the IP header in front of its share of the data; only
+// the first carries the transport header.
+func ipFragmentSKBuffs(skb *SKBuff, mtu int) []SKBuff {
+	if len(skb.Layers) == 0 {
+		return nil
+	}
+	hlen := skb.Layers[0].Size
+	per := (mtu - hlen) &^ 7
+	data := skb.Len() - hlen
+	if per <= 0 {
+		return nil
+	}
+
+	var frags []SKBuff
+	for offset := 0; offset < data; offset += per {
+		size := min(per, data-offset)
+		frag := skb.Clone()
+		frag.Tail = frag.Data + hlen + size
+		frag.GSO = nil
+		frag.Layers = []ProtocolHeader{skb.Layers[0]}
+		if offset == 0 {
+			for _, layer := range skb.Layers[1:] {
+				if layer.Offset+layer.Size <= hlen+size {
+					frag.Layers = append(frag.Layers, layer)
+				}
+			}
+		}
+		frag.ResetNetworkHeader()
+		frag.SetTransportHeader(hlen)
+		frags = append(frags, *frag)
+	}
+	return frags
+}
+
+// effectIPMaxDatagram models __ip_append_data refusing a datagram larger
+// than IP_MAX_MTU with EMSGSIZE, before anything is copied.
+func effectIPMaxDatagram(ctx *simContext, step *SimulateStep) {
+	length := ctx.opts.PayloadSize + UDPHeaderSize + IPv4HeaderSize
+	if length <= ipMaxDatagram {
+		return
+	}
+	step.annotate(AnnotationSegmentation, fmt.Sprintf(
+		"The %d-byte payload makes a %d-byte datagram, more than the %d bytes the IP total length field can describe (IP_MAX_MTU): "+
+			"__ip_append_data fails with EMSGSIZE and send() returns it. Fragmentation cannot help, since every fragment "+
+			"carries an offset into a datagram of at most that size.",
+		ctx.opts.PayloadSize, length, ipMaxDatagram))
+}
+
+// effectIPOutputDecision models __ip_finish_output choosing between the
+// three ways an IP packet leaves the network layer. GSO is checked first,
+// so a GSO packet is never fragmented here however large it is.
+func effectIPOutputDecision(ctx *simContext, step *SimulateStep) {
+	length, mtu := ctx.skb.Len(), ctx.mtu()
+	switch {
+	case ctx.gso():
+		ctx.branch("__ip_finish_output", "ip_finish_output_gso", "the sk_buff is a GSO packet")
+		step.annotate(AnnotationSegmentation, fmt.Sprintf(
+			"GSO sk_buff: the %d-byte packet exceeds the %d-byte MTU but is not fragmented. "+
+				"It is segmented later (by the NIC or validate_xmit_skb) into packets that each fit the MTU.",
+			length, mtu))
+	case length > ipMaxDatagram:
+		step.annotate(AnnotationSegmentation, fmt.Sprintf(
+			"The %d-byte packet exceeds IP_MAX_MTU (%d bytes), so a sender never gets this far: EMSGSIZE is returned before it is built. "+
+				"The simulation follows it unfragmented.",
+			length, ipMaxDatagram))
+	case length > mtu:
+		ctx.branch("__ip_finish_output", "ip_fragment", fmt.Sprintf("the %d-byte packet is not GSO and exceeds the %d-byte MTU", length, mtu))
+		step.annotate(AnnotationSegmentation, fmt.Sprintf(
+			"The %d-byte packet is not GSO and exceeds the %d-byte MTU: it is split into %d IP fragments, "+
+				"reassembled only by the receiver.",
+			length, mtu, ipFragments(length, mtu)))
+	}
+}
+
+// effectFragment models ip_fragment splitting the packet. Every fragment
+// but the last is emitted as its own step; the step itself lists them all
+// and the simulation continues with the last, which is sent last.
+func effectFragment(ctx *simContext, step *SimulateStep) {
+	length, mtu := ctx.skb.Len(), ctx.mtu()
+	if length > ipMaxDatagram {
+		return
+	}
+	frags := ipFragmentSKBuffs(ctx.skb, mtu)
+	if len(frags) < 2 {
+		return
+	}
+
+	offset := 0
+	for i := range frags {
+		ctx.count("FragCreates")
+		frag := &frags[i]
+		data := frag.Len() - frag.Layers[0].Size
+		more := 0
+		if i < len(frags)-1 {
+			more = 1
+		}
+		msg := fmt.Sprintf(
+			"Fragment %d/%d: the IP header and %d bytes of data at offset %d (fragment offset field %d), More Fragments %d, total length %d.",
+			i+1, len(frags), data, offset, offset/8, more, frag.Len())
+		offset += data
+
+		*ctx.skb = *frag.Clone()
+		if more == 0 {
+			step.annotate(AnnotationSegmentation, msg)
+			break
+		}
+		out := *step
+		out.annotate(AnnotationSegmentation, msg)
+		ctx.emit(out)
+	}
+
+	step.Fragments = frags
+	step.annotate(AnnotationSegmentation, fmt.Sprintf(
+		"ip_do_fragment split the %d-byte packet into %d fragments that fit the %d-byte MTU: it copies the IP header into each, "+
+			"sets its fragment offset and More Fragments flag, and calls ip_finish_output2 once per fragment. "+
+			"The simulation follows the last fragment, sent after the others. Losing any one fragment loses the whole datagram.",
+		length, len(frags), mtu))
+}
@@ -0,0 +1,175 @@
+package contract
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestIPFragmentation(t *testing.T) {
+	steps := BuildUDPIPv4EgressPath().SimulateWithOptions(SimulateOptions{
+		BufferSize:  8192,
+		PayloadSize: 4000,
+		MTU:         1500,
+	})
+
+	// 4000 bytes of payload behind a UDP header are 4008 bytes of IP data:
+	// 1480 (the largest multiple of 8 fitting 1500 - 20), 1480 and 1048
+	var fragmentSteps []int
+	var frags []SKBuff
+	for _, step := range steps {
+		if step.Function.ID != "ip_fragment" {
+			continue
+		}
+		fragmentSteps = append(fragmentSteps, step.SKBuffState.Len())
+		if step.Fragments != nil {
+			frags = step.Fragments
+		}
+	}
+	if want := []int{1500, 1500, 1068}; !slices.Equal(fragmentSteps, want) {
+		t.Errorf("ip_fragment steps send %v bytes, want %v", fragmentSteps, want)
+	}
+	if len(frags) != 3 {
+		t.Fatalf("%d fragments, want 3", len(frags))
+	}
+
+	wantData := []int{1480, 1480, 1048}
+	for i, frag := range frags {
+		if got := frag.Len() - frag.Layers[0].Size; got != wantData[i] {
+			t.Errorf("fragment %d carries %d bytes of data, want %d", i, got, wantData[i])
+		}
+		if frag.Layers[0].Protocol != "ip" {
+			t.Errorf("fragment %d starts with %s, want its own IP header", i, frag.Layers[0].Protocol)
+		}
+		if frag.GSO != nil {
+			t.Errorf("fragment %d has GSO metadata", i)
+		}
+		if got, want := frag.HasLayer("udp"), i == 0; got != want {
+			t.Errorf("fragment %d has the UDP header: %v, want %v", i, got, want)
+		}
+	}
+	if got := ipFragments(4028, 1500); got != 3 {
+		t.Errorf("ipFragments(4028, 1500) = %d, want 3", got)
+	}
+
+	last := steps[len(steps)-1]
+	if last.Function.ID != "ndo_start_xmit" || last.SKBuffState.Len() != 1068+EthernetHeaderSize {
+		t.Errorf("last step %s sends %d bytes, want the last fragment framed at ndo_start_xmit", last.Function.ID, last.SKBuffState.Len())
+	}
+}
+
+func TestIPFragmentationSkipsGSO(t *testing.T) {
+	steps := BuildTCPIPv4EgressPath().SimulateWithOptions(SimulateOptions{
+		BufferSize:  8192,
+		PayloadSize: 4000,
+		MTU:         1500,
+	})
+	for _, step := range steps {
+		if step.Function.ID == "ip_fragment" {
+			t.Fatalf("step %d fragments a GSO packet", step.StepNumber)
+		}
+	}
+}
+
+func TestIsValidMTU(t *testing.T) {
+	tests := []struct {
+		mtu  int
+		want bool
+	}{
+		{0, true},
+		{1, false},
+		{IPv4HeaderSize, false},
+		{24, false},
+		{ipv4MinMTU - 1, false},
+		{ipv4MinMTU, true},
+		{defaultMTU, true},
+		{-1, false},
+	}
+	for _, tt := range tests {
+		if got := IsValidMTU(tt.mtu); got != tt.want {
+			t.Errorf("IsValidMTU(%d) = %v, want %v", tt.mtu, got, tt.want)
+		}
+	}
+}
+
+func TestIPFragmentsSmallMTU(t *testing.T) {
+	tests := []struct {
+		mtu  int
+		want int
+	}{
+		// No room for 8 bytes of data behind the header
+		{0, 0},
+		{24, 0},
+		// 48 bytes of data per fragment for 4008 bytes of data
+		{ipv4MinMTU, 84},
+	}
+	for _, tt := range tests {
+		if got := ipFragments(4028, tt.mtu); got != tt.want {
+			t.Errorf("ipFragments(4028, %d) = %d, want %d", tt.mtu, got, tt.want)
+		}
+	}
+
+	steps := BuildUDPIPv4EgressPath().SimulateWithOptions(SimulateOptions{
+		BufferSize:  8192,
+		PayloadSize: 4000,
+		MTU:         ipv4MinMTU,
+	})
+	fragments := 0
+	for _, step := range steps {
+		if step.Function.ID == "ip_fragment" {
+			fragments++
+		}
+	}
+	if fragments != 84 {
+		t.Errorf("%d ip_fragment steps at MTU %d, want 84", fragments, ipv4MinMTU)
+	}
+}
+
+func TestIPMaxDatagram(t *testing.T) {
+	tests := []struct {
+		name      string
+		payload   int
+		wantFrags int
+		emsgsize  bool
+	}{
+		// 65507 bytes behind UDP and IP headers is the largest datagram:
+		// 65515 bytes of IP data in 44 fragments of 1480 and one of 395
+		{"largest datagram", ipMaxDatagram - UDPHeaderSize - IPv4HeaderSize, 45, false},
+		{"one byte over", ipMaxDatagram - UDPHeaderSize - IPv4HeaderSize + 1, 0, true},
+		{"100 MB", 100_000_000, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := SimulateOptions{BufferSize: 2 * tt.payload, PayloadSize: tt.payload}
+			steps := BuildUDPIPv4EgressPath().SimulateWithOptions(opts)
+
+			frags := 0
+			for _, step := range steps {
+				if step.Function.ID == "ip_fragment" {
+					frags++
+				}
+			}
+			if frags != tt.wantFrags {
+				t.Errorf("%d ip_fragment steps, want %d", frags, tt.wantFrags)
+			}
+			annotated := false
+			for _, a := range stepAt(t, steps, "ip_make_skb").Annotations {
+				annotated = annotated || strings.Contains(a.Message, "EMSGSIZE")
+			}
+			if annotated != tt.emsgsize {
+				t.Errorf("ip_make_skb reports EMSGSIZE: %v, want %v", annotated, tt.emsgsize)
+			}
+		})
+	}
+
+	// Forcing the fragmentation branch of an oversized datagram does not
+	// fan it out either
+	root := BuildUDPIPv4EgressPath().SimulateAllWithOptions(SimulateOptions{BufferSize: 200_000_000, PayloadSize: 100_000_000})
+	n := findNode(root, "ip_fragment")
+	if n == nil {
+		t.Fatal("SimulateAll does not explore the ip_fragment branch")
+	}
+	if len(n.Step.Fragments) != 0 {
+		t.Errorf("ip_fragment splits the oversized datagram into %d fragments", len(n.Step.Fragments))
+	}
+}
@@ -0,0 +1,111 @@
+package contract
+
+// BuildIPsecESPEgressPath constructs the TCP over IPv4 egress path for a
+// packet that matches an XFRM policy with an ESP tunnel-mode state, based
+// on Linux Kernel 5.10.8.
+//
+// After the LOCAL_OUT hook, the route lookup has returned an XFRM bundle, so
+// dst_output enters xfrm4_output instead of ip_output. The inner packet is
+// encrypted and wrapped in ESP and an outer IPv4 header, after which the
+// encapsulated packet re-enters the normal IP output path.
+func BuildIPsecESPEgressPath() *PacketPath {
+	path := BuildTCPIPv4EgressPath()
+	path.ID = "tcp_ipv4_esp_egress"
+	path.Name = "TCP/IPv4 IPsec ESP Egress Path"
+	path.Description = "The path of a TCP packet protected by an IPsec ESP tunnel: encrypted, encapsulated, then transmitted (Linux 5.10.8)"
+
+	xfrm := []KernelFunction{
+		{
+			ID:               "xfrm4_output",
+			Name:             "xfrm4_output",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/xfrm4_output.c",
+			LineNumber:       29,
+			ExecutionContext: ContextProcess,
+			Description:      "Output function of the XFRM bundle route. Entered from dst_output instead of ip_output when an IPsec policy matches.",
+			ConfigDeps:       []string{"CONFIG_XFRM"},
+		},
+		{
+			ID:               "xfrm_output",
+			Name:             "xfrm_output",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/xfrm/xfrm_output.c",
+			LineNumber:       580,
+			ExecutionContext: ContextProcess,
+			Description:      "Applies each transform in the bundle. Handles GSO segmentation before encryption, since ciphertext cannot be segmented.",
+			ConfigDeps:       []string{"CONFIG_XFRM"},
+		},
+		{
+			ID:               "esp_output",
+			Name:             "esp_output",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/esp4.c",
+			LineNumber:       619,
+			ExecutionContext: ContextProcess,
+			Description:      "Inserts the ESP header (SPI and sequence number) in front of the inner IP packet and appends the ESP trailer.",
+			SKBMutation:      NewPushMutation("esp", ESPHeaderSize),
+			ConfigDeps:       []string{"CONFIG_INET_ESP"},
+		},
+		{
+			ID:               "esp_output_tail",
+			Name:             "esp_output_tail",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/esp4.c",
+			LineNumber:       440,
+			ExecutionContext: ContextProcess,
+			Description:      "Encrypts the inner IP packet and payload with the SA's AEAD cipher. Everything after the ESP header becomes opaque ciphertext.",
+			SKBMutation:      NewEncryptMutation("esp"),
+			ConfigDeps:       []string{"CONFIG_INET_ESP"},
+		},
+		{
+			ID:               "xfrm4_tunnel_encap_add",
+			Name:             "xfrm4_tunnel_encap_add",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/xfrm/xfrm_output.c",
+			LineNumber:       267,
+			ExecutionContext: ContextProcess,
+			Description:      "Tunnel mode: builds the outer IPv4 header addressed to the remote gateway. The kernel reserves this space before ESP runs; it is shown last here so the header stack reads outer-to-inner.",
+			SKBMutation:      NewPushMutation("ip", IPv4HeaderSize),
+			ConfigDeps:       []string{"CONFIG_XFRM"},
+		},
+		{
+			ID:               "xfrm_output_resume",
+			Name:             "xfrm_output_resume",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/xfrm/xfrm_output.c",
+			LineNumber:       502,
+			ExecutionContext: ContextProcess,
+			Description:      "All transforms applied. Sends the encapsulated packet back through dst_output on the route to the gateway.",
+			ConfigDeps:       []string{"CONFIG_XFRM"},
+		},
+	}
+
+	functions := []KernelFunction{}
+	for _, fn := range path.Functions {
+		functions = append(functions, fn)
+		if fn.ID == "__ip_local_out" {
+			functions = append(functions, xfrm...)
+		}
+	}
+	path.Functions = functions
+
+	edges := []FunctionEdge{}
+	for _, edge := range path.Edges {
+		if edge.From == "__ip_local_out" && edge.To == "ip_output" {
+			edges = append(edges,
+				FunctionEdge{From: "__ip_local_out", To: "xfrm4_output", Order: 1, Condition: "XFRM policy matches"},
+				FunctionEdge{From: "xfrm4_output", To: "xfrm_output", Order: 1},
+				FunctionEdge{From: "xfrm_output", To: "esp_output", Order: 1, Condition: "ESP tunnel-mode SA"},
+				FunctionEdge{From: "esp_output", To: "esp_output_tail", Order: 1},
+				FunctionEdge{From: "esp_output_tail", To: "xfrm4_tunnel_encap_add", Order: 1},
+				FunctionEdge{From: "xfrm4_tunnel_encap_add", To: "xfrm_output_resume", Order: 1},
+				FunctionEdge{From: "xfrm_output_resume", To: "ip_output", Order: 1},
+			)
+			continue
+		}
+		edges = append(edges, edge)
+	}
+	path.Edges = edges
+
+	return path
+}
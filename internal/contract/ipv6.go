@@ -0,0 +1,163 @@
+package contract
+
+import "fmt"
+
+// AnnotationHeadroom marks steps that explain how much headroom a header
+// consumes.
+const AnnotationHeadroom = "headroom"
+
+// ipv4OutputFunctions are the IPv4 output functions of the TCP egress path,
+// which the IPv6 path replaces with its own.
+var ipv4OutputFunctions = map[string]bool{
+	"ip_queue_xmit":        true,
+	"fib_rules_lookup":     true,
+	"ip_local_out":         true,
+	"__ip_local_out":       true,
+	"ip_output":            true,
+	"ip_finish_output":     true,
+	"__ip_finish_output":   true,
+	"ip_finish_output_gso": true,
+	"ip_fragment":          true,
+	"ip_finish_output2":    true,
+}
+
+// BuildTCPIPv6EgressPath constructs the TCP over IPv6 egress path, based on
+// Linux Kernel 5.10.8.
+//
+// The socket and TCP layers are the same as for IPv4: __tcp_transmit_skb
+// calls the queue_xmit of the socket's icsk_af_ops, which for an IPv6
+// socket is inet6_csk_xmit instead of ip_queue_xmit. ip6_xmit pushes the
+// 40-byte fixed IPv6 header, twice the size of an IPv4 header without
+// options, and the packet joins the neighbour and device layers the IPv4
+// path uses.
+func BuildTCPIPv6EgressPath() *PacketPath {
+	tcp := BuildTCPIPv4EgressPath()
+
+	outputHook := NewOutputHook()
+	outputHook.Description = "Locally generated packets. Firewall rules (ip6tables -A OUTPUT) are evaluated here."
+
+	ipv6 := []KernelFunction{
+		{
+			ID:               "inet6_csk_xmit",
+			Name:             "inet6_csk_xmit",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv6/inet6_connection_sock.c",
+			LineNumber:       117,
+			ExecutionContext: ContextProcess,
+			Description:      "IPv6 transmission entry point from TCP (the queue_xmit of ipv6_specific). Revalidates the socket's cached route, or looks one up with inet6_csk_route_socket, and passes the segment to ip6_xmit.",
+			RCUProtected:     true,
+			RCUNote:          "The socket's IPv6 options and cached dst are read under rcu_read_lock.",
+			ConfigDeps:       []string{"CONFIG_IPV6"},
+		},
+		{
+			ID:               "ip6_xmit",
+			Name:             "ip6_xmit",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv6/ip6_output.c",
+			LineNumber:       235,
+			ExecutionContext: ContextProcess,
+			Description:      "Builds the 40-byte IPv6 header: version, traffic class, flow label, payload length, next header and hop limit. There is no header checksum and no fragmentation field, so nothing needs updating hop by hop. Invokes the LOCAL_OUT netfilter hook.",
+			SKBMutation:      NewPushMutation("ipv6", IPv6HeaderSize),
+			NetfilterHook:    outputHook,
+			EstimatedCostNs:  350,
+			ConfigDeps:       []string{"CONFIG_IPV6"},
+		},
+		{
+			ID:               "ip6_output",
+			Name:             "ip6_output",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv6/ip6_output.c",
+			LineNumber:       214,
+			ExecutionContext: ContextProcess,
+			Description:      "dst_output of IPv6 routes. Drops the packet if IPv6 is disabled on the device, then invokes the POST_ROUTING netfilter hook.",
+			NetfilterHook:    NewPostroutingHook(),
+			ConfigDeps:       []string{"CONFIG_IPV6"},
+		},
+		{
+			ID:               "ip6_finish_output",
+			Name:             "ip6_finish_output",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv6/ip6_output.c",
+			LineNumber:       191,
+			ExecutionContext: ContextProcess,
+			Description:      "BPF cgroup egress hook point. Passes the packet on to the fragmentation decision.",
+			BPFHook:          NewCgroupSKBHook("egress"),
+			ConfigDeps:       []string{"CONFIG_IPV6", "CONFIG_CGROUP_BPF"},
+		},
+		{
+			ID:               "__ip6_finish_output",
+			Name:             "__ip6_finish_output",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv6/ip6_output.c",
+			LineNumber:       163,
+			ExecutionContext: ContextProcess,
+			Description:      "Output decision. Routers never fragment IPv6 and TCP sizes its segments to the path MTU, so a TCP segment, GSO or not, goes straight to ip6_finish_output2; only the sender of a larger datagram fragments it, with ip6_fragment.",
+			ConfigDeps:       []string{"CONFIG_IPV6"},
+		},
+		{
+			ID:               "ip6_finish_output2",
+			Name:             "ip6_finish_output2",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv6/ip6_output.c",
+			LineNumber:       59,
+			ExecutionContext: ContextProcess,
+			Description:      "Resolves the next-hop neighbour, with Neighbour Discovery (NDISC) rather than ARP, and prepares for L2 transmission.",
+			RCUProtected:     true,
+			RCUNote:          "rcu_read_lock_bh protects the neighbour entry lookup for the next hop.",
+			ConfigDeps:       []string{"CONFIG_IPV6"},
+		},
+	}
+
+	b := NewPathBuilder("tcp_ipv6_egress", "TCP/IPv6 Egress Path", "egress", "TCP").
+		Family(FamilyIPv6).
+		Description("The path of a TCP packet over IPv6 from user space through the kernel to the network interface (Linux 5.10.8)")
+
+	// The IPv6 output functions take the place of the IPv4 ones, keeping the
+	// functions top-down
+	shared := make(map[string]bool)
+	for _, fn := range tcp.Functions {
+		if fn.ID == "ip_queue_xmit" {
+			for _, v6 := range ipv6 {
+				b.AddFunction(v6)
+			}
+		}
+		if ipv4OutputFunctions[fn.ID] {
+			continue
+		}
+		shared[fn.ID] = true
+		b.AddFunction(fn)
+	}
+	for _, edge := range tcp.Edges {
+		if !shared[edge.From] || !shared[edge.To] {
+			continue
+		}
+		opts := []EdgeOption{WithCondition(edge.Condition)}
+		if edge.IsErrorPath {
+			opts = append(opts, AsErrorPath())
+		}
+		b.Connect(edge.From, edge.To, opts...)
+	}
+
+	return b.
+		Connect("__tcp_transmit_skb", "inet6_csk_xmit").
+		Connect("inet6_csk_xmit", "ip6_xmit").
+		Connect("ip6_xmit", "ip6_output", WithCondition("NF_ACCEPT at LOCAL_OUT (dst_output)")).
+		Connect("ip6_output", "ip6_finish_output").
+		Connect("ip6_finish_output", "__ip6_finish_output").
+		Connect("__ip6_finish_output", "ip6_finish_output2").
+		Connect("ip6_finish_output2", "neigh_output").
+		SetEntry(tcp.EntryPoint).
+		SetExit(tcp.ExitPoints...).
+		MustBuild()
+}
+
+// effectIPv6Header explains the headroom the IPv6 header takes compared to
+// an IPv4 header without options.
+func effectIPv6Header(ctx *simContext, step *SimulateStep) {
+	step.annotate(AnnotationHeadroom, fmt.Sprintf(
+		"The IPv6 header takes %d bytes of headroom, %d more than IPv4's %d, leaving %d bytes of headroom for the link layer. "+
+			"tcp_sendmsg reserves MAX_TCP_HEADER, sized for the larger of the two, so the same allocation fits either family. "+
+			"The larger header also costs payload: a %d-byte MTU fits an MSS of %d bytes over IPv6, against %d over IPv4.",
+		IPv6HeaderSize, IPv6HeaderSize-IPv4HeaderSize, IPv4HeaderSize, ctx.skb.Headroom(),
+		ctx.mtu(), ctx.mtu()-IPv6HeaderSize-TCPHeaderSize, ctx.mtu()-IPv4HeaderSize-TCPHeaderSize))
+}
@@ -0,0 +1,325 @@
+package contract
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// journeyVersion is the version of the permalink encoding. Bump it when the
+// meaning of an existing field changes; adding a field does not require it.
+const journeyVersion = 1
+
+// journeyPrefix starts every token of the current version, so tokens of
+// another version are rejected before they are parsed.
+var journeyPrefix = fmt.Sprintf("j%d.", journeyVersion)
+
+// JourneyState is a shareable visualization state: the path, the step being
+// viewed, and the simulation options that select its branches.
+type JourneyState struct {
+	// PathID is the ID of the simulated path
+	PathID string
+
+	// Step is the 1-indexed step being viewed (0 = the start)
+	Step int
+
+	// Options are the simulation parameters and branch selections
+	Options SimulateOptions
+}
+
+// journeyWire is the encoded form of a JourneyState. Its short keys are the
+// stable format of permalinks and must not change within a version; zero
+// values are omitted to keep tokens short.
+type journeyWire struct {
+	PathID            string           `json:"p"`
+	Step              int              `json:"s,omitempty"`
+	BufferSize        int              `json:"b,omitempty"`
+	PayloadSize       int              `json:"l,omitempty"`
+	SendBufferSize    int              `json:"sb,omitempty"`
+	RecvBufferSize    int              `json:"rb,omitempty"`
+	Flow              *FlowKey         `json:"f,omitempty"`
+	Mark              uint32           `json:"m,omitempty"`
+	ZeroCopy          bool             `json:"zc,omitempty"`
+	NATRules          []journeyNATRule `json:"nat,omitempty"`
+	NonBlocking       bool             `json:"nb,omitempty"`
+	ConntrackHelper   string           `json:"ch,omitempty"`
+	ICMPError         string           `json:"ie,omitempty"`
+	FragmentCount     int              `json:"fr,omitempty"`
+	PacketTaps        int              `json:"t,omitempty"`
+	GROFlush          string           `json:"g,omitempty"`
+	SocketLookup      string           `json:"lk,omitempty"`
+	GenericXDP        string           `json:"x,omitempty"`
+	UDPSegment        int              `json:"us,omitempty"`
+	GSOPartial        int              `json:"gp,omitempty"`
+	MTU               int              `json:"mtu,omitempty"`
+	MSS               int              `json:"ms,omitempty"`
+	NoGSO             bool             `json:"ng,omitempty"`
+	QuickAck          bool             `json:"qa,omitempty"`
+	ListenBacklog     int              `json:"bl,omitempty"`
+	SynQueueLen       int              `json:"sq,omitempty"`
+	AcceptQueueLen    int              `json:"aq,omitempty"`
+	SocketFilter      string           `json:"sf,omitempty"`
+	SocketFilterLen   int              `json:"sl,omitempty"`
+	DeviceBusy        bool             `json:"db,omitempty"`
+	QdiscBacklog      int              `json:"qb,omitempty"`
+	TSODefer          bool             `json:"td,omitempty"`
+	IPOptions         string           `json:"io,omitempty"`
+	RTTSample         int              `json:"rt,omitempty"`
+	TxQueues          int              `json:"tq,omitempty"`
+	RecvCoalesce      bool             `json:"rc,omitempty"`
+	TCIngress         string           `json:"ti,omitempty"`
+	SocketOwnedByUser bool             `json:"ou,omitempty"`
+	BridgeFDB         string           `json:"bf,omitempty"`
+	BridgePorts       int              `json:"bp,omitempty"`
+	ARPOperation      string           `json:"ar,omitempty"`
+	XPS               bool             `json:"xp,omitempty"`
+	CPU               int              `json:"cpu,omitempty"`
+	CorruptHeader     string           `json:"c,omitempty"`
+}
+
+// journeyNATRule is the encoded form of a NATRule, with the keys of the
+// first version of permalinks.
+type journeyNATRule struct {
+	Type string `json:"t"`
+	To   string `json:"to,omitempty"`
+}
+
+// journeyNATRules returns the encoded form of rules (nil if there are none).
+func journeyNATRules(rules []NATRule) []journeyNATRule {
+	var wire []journeyNATRule
+	for _, rule := range rules {
+		wire = append(wire, journeyNATRule{Type: rule.Type, To: rule.To})
+	}
+	return wire
+}
+
+// natRules returns the rules encoded in wire (nil if there are none).
+func natRules(wire []journeyNATRule) []NATRule {
+	var rules []NATRule
+	for _, rule := range wire {
+		rules = append(rules, NATRule{Type: rule.Type, To: rule.To})
+	}
+	return rules
+}
+
+// EncodeJourney encodes a visualization state as a compact URL-safe token.
+func EncodeJourney(state JourneyState) string {
+	opts := state.Options
+	wire := journeyWire{
+		PathID:            state.PathID,
+		Step:              state.Step,
+		BufferSize:        opts.BufferSize,
+		PayloadSize:       opts.PayloadSize,
+		SendBufferSize:    opts.SendBufferSize,
+		RecvBufferSize:    opts.RecvBufferSize,
+		Mark:              opts.Mark,
+		ZeroCopy:          opts.ZeroCopy,
+		NATRules:          journeyNATRules(opts.NATRules),
+		NonBlocking:       opts.NonBlocking,
+		ConntrackHelper:   opts.ConntrackHelper,
+		ICMPError:         opts.ICMPError,
+		FragmentCount:     opts.FragmentCount,
+		PacketTaps:        opts.PacketTaps,
+		GROFlush:          opts.GROFlush,
+		SocketLookup:      opts.SocketLookup,
+		GenericXDP:        opts.GenericXDP,
+		UDPSegment:        opts.UDPSegment,
+		GSOPartial:        opts.GSOPartial,
+		MTU:               opts.MTU,
+		MSS:               opts.MSS,
+		NoGSO:             opts.NoGSO,
+		QuickAck:          opts.QuickAck,
+		ListenBacklog:     opts.ListenBacklog,
+		SynQueueLen:       opts.SynQueueLen,
+		AcceptQueueLen:    opts.AcceptQueueLen,
+		SocketFilter:      opts.SocketFilter,
+		SocketFilterLen:   opts.SocketFilterLen,
+		DeviceBusy:        opts.DeviceBusy,
+		QdiscBacklog:      opts.QdiscBacklog,
+		TSODefer:          opts.TSODefer,
+		IPOptions:         opts.IPOptions,
+		RTTSample:         opts.RTTSample,
+		TxQueues:          opts.TxQueues,
+		RecvCoalesce:      opts.RecvCoalesce,
+		TCIngress:         opts.TCIngress,
+		SocketOwnedByUser: opts.SocketOwnedByUser,
+		BridgeFDB:         opts.BridgeFDB,
+		BridgePorts:       opts.BridgePorts,
+		ARPOperation:      opts.ARPOperation,
+		XPS:               opts.XPS,
+		CPU:               opts.CPU,
+	}
+	if !opts.Flow.IsZero() {
+		flow := opts.Flow
+		wire.Flow = &flow
+	}
+	if opts.CorruptChecksum {
+		wire.CorruptHeader = opts.CorruptHeader
+		if wire.CorruptHeader == "" {
+			wire.CorruptHeader = "tcp"
+		}
+	}
+
+	// Marshaling a struct of basic types cannot fail
+	data, _ := json.Marshal(wire)
+	return journeyPrefix + base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeJourney decodes a token produced by EncodeJourney. The state is
+// validated against the default registry and a simulation of the path, so
+// a stale or corrupted token returns an error instead of a state that would
+// produce a different simulation than the one that was shared.
+func DecodeJourney(token string) (JourneyState, error) {
+	encoded, ok := strings.CutPrefix(token, journeyPrefix)
+	if !ok {
+		return JourneyState{}, fmt.Errorf("unsupported journey token version (want prefix %q)", journeyPrefix)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return JourneyState{}, fmt.Errorf("decoding journey token: %w", err)
+	}
+
+	var wire journeyWire
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&wire); err != nil {
+		return JourneyState{}, fmt.Errorf("parsing journey token: %w", err)
+	}
+
+	state := JourneyState{
+		PathID: wire.PathID,
+		Step:   wire.Step,
+		Options: SimulateOptions{
+			BufferSize:        wire.BufferSize,
+			PayloadSize:       wire.PayloadSize,
+			SendBufferSize:    wire.SendBufferSize,
+			RecvBufferSize:    wire.RecvBufferSize,
+			Mark:              wire.Mark,
+			ZeroCopy:          wire.ZeroCopy,
+			NATRules:          natRules(wire.NATRules),
+			NonBlocking:       wire.NonBlocking,
+			ConntrackHelper:   wire.ConntrackHelper,
+			ICMPError:         wire.ICMPError,
+			FragmentCount:     wire.FragmentCount,
+			PacketTaps:        wire.PacketTaps,
+			GROFlush:          wire.GROFlush,
+			SocketLookup:      wire.SocketLookup,
+			GenericXDP:        wire.GenericXDP,
+			UDPSegment:        wire.UDPSegment,
+			GSOPartial:        wire.GSOPartial,
+			MTU:               wire.MTU,
+			MSS:               wire.MSS,
+			NoGSO:             wire.NoGSO,
+			QuickAck:          wire.QuickAck,
+			ListenBacklog:     wire.ListenBacklog,
+			SynQueueLen:       wire.SynQueueLen,
+			AcceptQueueLen:    wire.AcceptQueueLen,
+			SocketFilter:      wire.SocketFilter,
+			SocketFilterLen:   wire.SocketFilterLen,
+			DeviceBusy:        wire.DeviceBusy,
+			QdiscBacklog:      wire.QdiscBacklog,
+			TSODefer:          wire.TSODefer,
+			IPOptions:         wire.IPOptions,
+			RTTSample:         wire.RTTSample,
+			TxQueues:          wire.TxQueues,
+			RecvCoalesce:      wire.RecvCoalesce,
+			TCIngress:         wire.TCIngress,
+			SocketOwnedByUser: wire.SocketOwnedByUser,
+			BridgeFDB:         wire.BridgeFDB,
+			BridgePorts:       wire.BridgePorts,
+			ARPOperation:      wire.ARPOperation,
+			XPS:               wire.XPS,
+			CPU:               wire.CPU,
+			CorruptChecksum:   wire.CorruptHeader != "",
+			CorruptHeader:     wire.CorruptHeader,
+		},
+	}
+	if wire.Flow != nil {
+		state.Options.Flow = *wire.Flow
+	}
+
+	if err := state.validate(); err != nil {
+		return JourneyState{}, fmt.Errorf("invalid journey token: %w", err)
+	}
+	return state, nil
+}
+
+// validate checks that the state names an existing path, valid options and
+// a step the simulation actually reaches.
+func (state JourneyState) validate() error {
+	path, ok := DefaultRegistry().Build(state.PathID)
+	if !ok {
+		return fmt.Errorf("unknown path %q", state.PathID)
+	}
+
+	opts := state.Options
+	counts := []struct {
+		name  string
+		value int
+	}{
+		{"send buffer size", opts.SendBufferSize},
+		{"receive buffer size", opts.RecvBufferSize},
+		{"fragment count", opts.FragmentCount},
+		{"packet taps", opts.PacketTaps},
+		{"UDP segment size", opts.UDPSegment},
+		{"GSO partial segments", opts.GSOPartial},
+		{"qdisc backlog", opts.QdiscBacklog},
+		{"listen backlog", opts.ListenBacklog},
+		{"SYN queue length", opts.SynQueueLen},
+		{"accept queue length", opts.AcceptQueueLen},
+		{"socket filter length", opts.SocketFilterLen},
+		{"bridge ports", opts.BridgePorts},
+		{"TX queues", opts.TxQueues},
+		{"CPU", opts.CPU},
+		{"RTT sample", opts.RTTSample},
+	}
+	for _, count := range counts {
+		if count.value < 0 {
+			return fmt.Errorf("%s must not be negative, got %d", count.name, count.value)
+		}
+	}
+
+	switch {
+	case opts.BufferSize <= 0 || opts.PayloadSize < 0:
+		return errors.New("buffer size must be positive and payload size non-negative")
+	case !IsValidMSS(opts.MSS):
+		return fmt.Errorf("MSS %d is below TCP_MIN_MSS (%d)", opts.MSS, tcpMinMSS)
+	case !IsValidMTU(opts.MTU):
+		return fmt.Errorf("MTU %d is below the IPv4 minimum of %d", opts.MTU, ipv4MinMTU)
+	case opts.GROFlush != "" && !IsValidGROFlushReason(opts.GROFlush):
+		return fmt.Errorf("unknown GRO flush reason %q", opts.GROFlush)
+	case opts.SocketLookup != "" && !IsValidSocketLookup(opts.SocketLookup):
+		return fmt.Errorf("unknown socket lookup %q", opts.SocketLookup)
+	case opts.GenericXDP != "" && !IsValidGenericXDPVerdict(opts.GenericXDP):
+		return fmt.Errorf("unknown generic XDP verdict %q", opts.GenericXDP)
+	case opts.SocketFilter != "" && !IsValidSocketFilterVerdict(opts.SocketFilter):
+		return fmt.Errorf("unknown socket filter verdict %q", opts.SocketFilter)
+	case opts.IPOptions != "" && !IsValidIPOption(opts.IPOptions):
+		return fmt.Errorf("unknown IP option %q", opts.IPOptions)
+	case opts.TCIngress != "" && !IsValidTCAction(opts.TCIngress):
+		return fmt.Errorf("unknown TC verdict %q", opts.TCIngress)
+	case opts.BridgeFDB != "" && !IsValidBridgeFDB(opts.BridgeFDB):
+		return fmt.Errorf("unknown FDB result %q", opts.BridgeFDB)
+	case opts.ARPOperation != "" && !IsValidARPOperation(opts.ARPOperation):
+		return fmt.Errorf("unknown ARP operation %q", opts.ARPOperation)
+	case opts.ConntrackHelper != "" && !IsValidConntrackHelper(opts.ConntrackHelper):
+		return fmt.Errorf("unknown conntrack helper %q", opts.ConntrackHelper)
+	case opts.ICMPError != "" && !IsValidICMPError(opts.ICMPError):
+		return fmt.Errorf("unknown ICMP error %q", opts.ICMPError)
+	case opts.CorruptChecksum && !IsValidCorruptHeader(opts.CorruptHeader):
+		return fmt.Errorf("unknown corrupted header %q", opts.CorruptHeader)
+	}
+	for _, rule := range opts.NATRules {
+		if err := rule.validate(); err != nil {
+			return err
+		}
+	}
+
+	steps := path.SimulateWithOptions(opts)
+	if state.Step < 0 || state.Step > len(steps) {
+		return fmt.Errorf("step %d is outside the %d-step simulation of %s", state.Step, len(steps), state.PathID)
+	}
+	return nil
+}
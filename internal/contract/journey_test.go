@@ -0,0 +1,119 @@
+package contract
+
+import (
+	"encoding/base64"
+	"slices"
+	"testing"
+)
+
+// natJourneyToken is a permalink of the first version with NAT rules, which
+// encodes each rule's type under "t".
+const natJourneyToken = "j1.eyJwIjoidGNwX2lwdjRfZWdyZXNzIiwicyI6MywiYiI6MjA0OCwibCI6MTAwMCwibmF0IjpbeyJ0IjoiU05BVCIsInRvIjoiMjAzLjAuMTEzLjcifSx7InQiOiJNQVNRVUVSQURFIn1dfQ"
+
+func TestJourneyNATRules(t *testing.T) {
+	state := JourneyState{
+		PathID: "tcp_ipv4_egress",
+		Step:   3,
+		Options: SimulateOptions{
+			BufferSize:  2048,
+			PayloadSize: 1000,
+			NATRules:    []NATRule{{Type: NATTypeSNAT, To: "203.0.113.7"}, {Type: NATTypeMasquerade}},
+		},
+	}
+
+	if got := EncodeJourney(state); got != natJourneyToken {
+		t.Errorf("EncodeJourney = %s, want the permalink format unchanged: %s", got, natJourneyToken)
+	}
+	decoded, err := DecodeJourney(natJourneyToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(decoded.Options.NATRules, state.Options.NATRules) {
+		t.Errorf("decoded NAT rules = %v, want %v", decoded.Options.NATRules, state.Options.NATRules)
+	}
+}
+
+func TestIsValidOptionValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid func(string) bool
+		good  []string
+		bad   []string
+	}{
+		{"generic XDP verdict", IsValidGenericXDPVerdict, []string{XDPVerdictPass, XDPVerdictDrop}, []string{"", "PASS", "aborted", "tx"}},
+		{"socket filter verdict", IsValidSocketFilterVerdict, []string{SocketFilterAllow, SocketFilterDeny}, []string{"", "Allow", "drop"}},
+		{"corrupt header", IsValidCorruptHeader, []string{"ip", "tcp"}, []string{"", "udp", "TCP"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, v := range tt.good {
+				if !tt.valid(v) {
+					t.Errorf("%q rejected", v)
+				}
+			}
+			for _, v := range tt.bad {
+				if tt.valid(v) {
+					t.Errorf("%q accepted", v)
+				}
+			}
+		})
+	}
+}
+
+func TestJourneyRejectsUnknownOptionValues(t *testing.T) {
+	base := SimulateOptions{BufferSize: 2048, PayloadSize: 1000}
+	if _, err := DecodeJourney(EncodeJourney(JourneyState{PathID: "tcp_ipv4_ingress", Options: base})); err != nil {
+		t.Fatalf("DecodeJourney rejected the base options: %v", err)
+	}
+	smallest := base
+	smallest.MTU = ipv4MinMTU
+	if _, err := DecodeJourney(EncodeJourney(JourneyState{PathID: "udp_ipv4_egress", Options: smallest})); err != nil {
+		t.Errorf("DecodeJourney rejected MTU %d: %v", ipv4MinMTU, err)
+	}
+	tests := []struct {
+		name   string
+		modify func(*SimulateOptions)
+	}{
+		{"generic XDP verdict", func(o *SimulateOptions) { o.GenericXDP = "aborted" }},
+		{"socket filter verdict", func(o *SimulateOptions) { o.SocketFilter = "drop" }},
+		{"corrupt header", func(o *SimulateOptions) { o.CorruptChecksum, o.CorruptHeader = true, "udp" }},
+		{"mtu below the IPv4 minimum", func(o *SimulateOptions) { o.MTU = 24 }},
+		{"negative mtu", func(o *SimulateOptions) { o.MTU = -1500 }},
+		{"mss below TCP_MIN_MSS", func(o *SimulateOptions) { o.MSS = 1 }},
+		{"negative mss", func(o *SimulateOptions) { o.MSS = -5 }},
+		{"negative fragment count", func(o *SimulateOptions) { o.FragmentCount = -2 }},
+		{"negative tx queues", func(o *SimulateOptions) { o.TxQueues = -1 }},
+		{"negative udp segment", func(o *SimulateOptions) { o.UDPSegment = -1400 }},
+		{"negative bridge ports", func(o *SimulateOptions) { o.BridgePorts = -4 }},
+		{"negative cpu", func(o *SimulateOptions) { o.CPU = -1 }},
+		{"negative send buffer", func(o *SimulateOptions) { o.SendBufferSize = -1 }},
+		{"negative rtt sample", func(o *SimulateOptions) { o.RTTSample = -1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := base
+			tt.modify(&opts)
+			token := EncodeJourney(JourneyState{PathID: "tcp_ipv4_ingress", Options: opts})
+			if _, err := DecodeJourney(token); err == nil {
+				t.Errorf("DecodeJourney accepted %+v", opts)
+			}
+		})
+	}
+}
+
+func TestDecodeJourneyCraftedToken(t *testing.T) {
+	// Hand-written, not produced by EncodeJourney: the simulation must not
+	// run with these options
+	crafted := []string{
+		`{"p":"udp_ipv4_egress","b":2048,"l":1000,"mtu":24}`,
+		`{"p":"udp_ipv4_egress","b":2048,"l":1000,"mtu":-1}`,
+		`{"p":"tcp_ipv4_egress","b":2048,"l":1000,"ms":1}`,
+		`{"p":"tcp_ipv4_ingress","b":2048,"l":1000,"fr":-3}`,
+	}
+	for _, data := range crafted {
+		token := journeyPrefix + base64.RawURLEncoding.EncodeToString([]byte(data))
+		if _, err := DecodeJourney(token); err == nil {
+			t.Errorf("DecodeJourney accepted %s", data)
+		}
+	}
+}
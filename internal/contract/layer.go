@@ -34,6 +34,16 @@ const (
 	LayerDriver
 )
 
+// allLayers lists every layer in rendering order (top to bottom).
+var allLayers = []Layer{
+	LayerUserSpace,
+	LayerSocket,
+	LayerTransport,
+	LayerNetwork,
+	LayerDataLink,
+	LayerDriver,
+}
+
 // String returns the human-readable name of the layer.
 func (l Layer) String() string {
 	switch l {
@@ -74,6 +84,18 @@ func (l Layer) CSSClass() string {
 	}
 }
 
+// ParseLayer returns the layer named s, given either as its String name
+// ("Network Layer") or its CSS class without the "layer-" prefix
+// ("network").
+func ParseLayer(s string) (Layer, bool) {
+	for _, layer := range allLayers {
+		if s == layer.String() || "layer-"+s == layer.CSSClass() {
+			return layer, true
+		}
+	}
+	return 0, false
+}
+
 // MarshalJSON implements custom JSON marshaling for Layer.
 func (l Layer) MarshalJSON() ([]byte, error) {
 	return []byte(`"` + l.String() + `"`), nil
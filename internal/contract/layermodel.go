@@ -0,0 +1,79 @@
+package contract
+
+// LayerMapping relates a kernel layer to the OSI and TCP/IP reference
+// models. The kernel does not follow either model strictly, so the mapping
+// is approximate and Note explains where it breaks down.
+type LayerMapping struct {
+	// Layer is the kernel layer
+	Layer Layer `json:"layer"`
+
+	// OSILayer is the number of the closest OSI layer (1-7)
+	OSILayer int `json:"osiLayer"`
+
+	// OSIName is the name of that OSI layer
+	OSIName string `json:"osiName"`
+
+	// TCPIPLayer is the closest layer of the four-layer TCP/IP model
+	// (RFC 1122): "Application", "Transport", "Internet" or "Link"
+	TCPIPLayer string `json:"tcpipLayer"`
+
+	// Protocols are example protocols handled at this layer
+	Protocols []string `json:"protocols"`
+
+	// Note explains how the kernel layer differs from the reference models
+	Note string `json:"note,omitempty"`
+}
+
+// LayerModelMapping returns the OSI and TCP/IP mapping of every kernel
+// layer, in rendering order.
+func LayerModelMapping() []LayerMapping {
+	return []LayerMapping{
+		{
+			Layer:      LayerUserSpace,
+			OSILayer:   7,
+			OSIName:    "Application",
+			TCPIPLayer: "Application",
+			Protocols:  []string{"HTTP", "DNS", "TLS", "SSH"},
+			Note:       "OSI layers 5-7 all live in the application; TLS encryption (presentation) usually runs in a userspace library unless kTLS is used.",
+		},
+		{
+			Layer:      LayerSocket,
+			OSILayer:   5,
+			OSIName:    "Session",
+			TCPIPLayer: "Application",
+			Protocols:  []string{"BSD sockets"},
+			Note:       "The socket layer is an API boundary rather than a protocol layer; it is the closest the kernel comes to the OSI session layer.",
+		},
+		{
+			Layer:      LayerTransport,
+			OSILayer:   4,
+			OSIName:    "Transport",
+			TCPIPLayer: "Transport",
+			Protocols:  []string{"TCP", "UDP", "SCTP"},
+		},
+		{
+			Layer:      LayerNetwork,
+			OSILayer:   3,
+			OSIName:    "Network",
+			TCPIPLayer: "Internet",
+			Protocols:  []string{"IPv4", "IPv6", "ICMP", "ESP"},
+			Note:       "Netfilter and XFRM (IPsec) hook in here, so firewalling, NAT and encryption happen at this layer too.",
+		},
+		{
+			Layer:      LayerDataLink,
+			OSILayer:   2,
+			OSIName:    "Data Link",
+			TCPIPLayer: "Link",
+			Protocols:  []string{"Ethernet", "ARP", "802.1Q VLAN"},
+			Note:       "Besides framing and neighbour resolution, the kernel's data link layer holds queueing disciplines (traffic control) and bridging.",
+		},
+		{
+			Layer:      LayerDriver,
+			OSILayer:   1,
+			OSIName:    "Physical",
+			TCPIPLayer: "Link",
+			Protocols:  []string{"NIC ring buffers", "DMA"},
+			Note:       "The driver moves frames between memory and the NIC; the physical layer proper (signalling, PHY) is implemented by the hardware.",
+		},
+	}
+}
@@ -0,0 +1,89 @@
+package contract
+
+// CSS classes of the regions returned by SKBuff.LayoutRects. Header regions
+// carry LayoutClassHeader plus a per-protocol class, e.g. "skb-header
+// skb-header-tcp".
+const (
+	LayoutClassHeadroom  = "skb-headroom"
+	LayoutClassHeader    = "skb-header"
+	LayoutClassPayload   = "skb-payload"
+	LayoutClassEncrypted = "skb-encrypted"
+	LayoutClassTailroom  = "skb-tailroom"
+)
+
+// LayoutRect is one region of the sk_buff buffer, scaled for drawing.
+type LayoutRect struct {
+	// Label names the region (e.g. "headroom", "tcp", "payload")
+	Label string `json:"label"`
+
+	// Class is the space-separated list of CSS classes for the region
+	Class string `json:"class"`
+
+	// Offset is the region's start as a buffer offset
+	Offset int `json:"offset"`
+
+	// Size is the region's length in bytes
+	Size int `json:"size"`
+
+	// X is the region's start in drawing units, from 0 at Head
+	X float64 `json:"x"`
+
+	// Width is the region's width in drawing units
+	Width float64 `json:"width"`
+}
+
+// LayoutRects returns the regions of the buffer from Head to End, in order,
+// scaled so the whole buffer spans totalWidth units: headroom, each protocol
+// header (outermost first), payload and tailroom. Every renderer draws from
+// these so they agree on proportions.
+//
+// Empty regions are left out rather than returned with zero width: there is
+// no headroom rect once headers fill the headroom (or on ingress, where the
+// packet starts at Head), and no payload rect for a header-only packet such
+// as a pure ACK. A buffer with no allocated space returns nil.
+func (s *SKBuff) LayoutRects(totalWidth int) []LayoutRect {
+	size := s.End - s.Head
+	if size <= 0 || totalWidth <= 0 {
+		return nil
+	}
+	scale := float64(totalWidth) / float64(size)
+
+	var rects []LayoutRect
+	add := func(label, class string, start, end int) {
+		if end <= start {
+			return
+		}
+		rects = append(rects, LayoutRect{
+			Label:  label,
+			Class:  class,
+			Offset: start,
+			Size:   end - start,
+			X:      float64(start-s.Head) * scale,
+			Width:  float64(end-start) * scale,
+		})
+	}
+
+	add("headroom", LayoutClassHeadroom, s.Head, s.Data)
+
+	headerEnd := s.Data
+	for _, layer := range s.Layers {
+		start := s.Data + layer.Offset
+		class := LayoutClassHeader + " " + LayoutClassHeader + "-" + layer.Protocol
+		if layer.Encrypted {
+			class += " " + LayoutClassEncrypted
+		}
+		add(layer.Protocol, class, start, start+layer.Size)
+		if start+layer.Size > headerEnd {
+			headerEnd = start + layer.Size
+		}
+	}
+
+	if s.PayloadEncrypted {
+		add("payload (encrypted)", LayoutClassPayload+" "+LayoutClassEncrypted, headerEnd, s.Tail)
+	} else {
+		add("payload", LayoutClassPayload, headerEnd, s.Tail)
+	}
+
+	add("tailroom", LayoutClassTailroom, s.Tail, s.End)
+	return rects
+}
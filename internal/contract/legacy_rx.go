@@ -0,0 +1,102 @@
+package contract
+
+// BuildLegacyRxPath constructs the TCP over IPv4 ingress path for drivers
+// that use the legacy netif_rx interface instead of NAPI polling, based on
+// Linux Kernel 5.10.8.
+//
+// The driver's interrupt handler calls netif_rx, which queues the sk_buff on
+// a per-CPU backlog. The backlog is drained by process_backlog, which is
+// itself registered as a NAPI instance, so from __netif_receive_skb upward
+// the path is identical to the NAPI ingress path.
+func BuildLegacyRxPath() *PacketPath {
+	path := BuildTCPIPv4IngressPath()
+	path.ID = "tcp_ipv4_legacy_ingress"
+	path.Name = "TCP/IPv4 Legacy (netif_rx) Ingress Path"
+	path.Description = "The path of a TCP packet received by a non-NAPI driver through netif_rx and the per-CPU backlog (Linux 5.10.8)"
+	path.EntryPoint = "netif_rx"
+
+	legacy := []KernelFunction{
+		{
+			ID:               "netif_rx",
+			Name:             "netif_rx",
+			Layer:            LayerDriver,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       4836,
+			ExecutionContext: ContextHardIRQ,
+			Description:      "Legacy receive entry point called from the driver's interrupt handler with a fully built sk_buff.",
+			IsEntryPoint:     true,
+		},
+		{
+			ID:               "netif_rx_internal",
+			Name:             "netif_rx_internal",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       4788,
+			ExecutionContext: ContextHardIRQ,
+			Description:      "Timestamps the packet and picks the target CPU (RPS if enabled, otherwise the current CPU).",
+			RCUProtected:     true,
+			RCUNote:          "rcu_read_lock covers RPS CPU selection from the device's rps_map.",
+		},
+		{
+			ID:               "enqueue_to_backlog",
+			Name:             "enqueue_to_backlog",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       4423,
+			ExecutionContext: ContextHardIRQ,
+			Description:      "Appends the sk_buff to the per-CPU softnet_data input queue and schedules the backlog NAPI instance. Drops the packet if the queue exceeds netdev_max_backlog.",
+		},
+		{
+			ID:               "net_rx_action",
+			Name:             "net_rx_action",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       6836,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "NET_RX_SOFTIRQ handler. Polls every scheduled NAPI instance, including the per-CPU backlog.",
+		},
+		{
+			ID:               "process_backlog",
+			Name:             "process_backlog",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       6331,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Poll function of the backlog NAPI instance. Dequeues packets from the input queue and hands each to __netif_receive_skb.",
+		},
+	}
+
+	// Drop the NAPI/GRO front end; the stack from __netif_receive_skb up is shared
+	napiOnly := map[string]bool{
+		"napi_poll":                  true,
+		"napi_gro_receive":           true,
+		"napi_gro_complete":          true,
+		"napi_skb_finish":            true,
+		"netif_receive_skb":          true,
+		"netif_receive_skb_internal": true,
+	}
+
+	functions := append([]KernelFunction{}, legacy...)
+	for _, fn := range path.Functions {
+		if !napiOnly[fn.ID] {
+			functions = append(functions, fn)
+		}
+	}
+	path.Functions = functions
+
+	edges := []FunctionEdge{
+		{From: "netif_rx", To: "netif_rx_internal", Order: 1},
+		{From: "netif_rx_internal", To: "enqueue_to_backlog", Order: 1},
+		{From: "enqueue_to_backlog", To: "net_rx_action", Order: 1, Condition: "Backlog not full, NET_RX_SOFTIRQ raised"},
+		{From: "net_rx_action", To: "process_backlog", Order: 1},
+		{From: "process_backlog", To: "__netif_receive_skb", Order: 1},
+	}
+	for _, edge := range path.Edges {
+		if !napiOnly[edge.From] {
+			edges = append(edges, edge)
+		}
+	}
+	path.Edges = edges
+
+	return path
+}
@@ -0,0 +1,111 @@
+package contract
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Lint reports authoring issues that do not make the path invalid but make
+// it less useful to visualize: missing metadata and ambiguous or duplicate
+// edges. Unlike Validate, a path with lint findings still simulates
+// correctly.
+func (p *PacketPath) Lint() []string {
+	graph := NewFunctionGraph(p)
+	var findings []string
+
+	for _, fn := range p.Functions {
+		if fn.Description == "" {
+			findings = append(findings, fmt.Sprintf("function %q has no description", fn.ID))
+		}
+		if fn.SourceFile == "" || fn.LineNumber <= 0 {
+			findings = append(findings, fmt.Sprintf("function %q has no source location", fn.ID))
+		}
+		if fn.ExecutionContext == "" {
+			findings = append(findings, fmt.Sprintf("function %q has no execution context", fn.ID))
+		}
+	}
+
+	seenEdges := make(map[[2]string]bool)
+	orders := make(map[string]map[int]string)
+	for _, edge := range p.Edges {
+		key := [2]string{edge.From, edge.To}
+		if seenEdges[key] {
+			findings = append(findings, fmt.Sprintf("edge %s -> %s is declared more than once", edge.From, edge.To))
+		}
+		seenEdges[key] = true
+		if edge.Order == 0 {
+			continue
+		}
+		if orders[edge.From] == nil {
+			orders[edge.From] = make(map[int]string)
+		}
+		if other, ok := orders[edge.From][edge.Order]; ok {
+			findings = append(findings, fmt.Sprintf("edges %s -> %s and %s -> %s share order %d",
+				edge.From, other, edge.From, edge.To, edge.Order))
+		}
+		orders[edge.From][edge.Order] = edge.To
+	}
+
+	for _, id := range p.sortedBranchPoints(graph) {
+		for _, edge := range graph.GetOutgoingEdges(id) {
+			if edge.Condition == "" && edge.Order > 1 {
+				findings = append(findings, fmt.Sprintf("edge %s -> %s is an alternative branch without a condition", edge.From, edge.To))
+			}
+		}
+	}
+	return findings
+}
+
+// sortedBranchPoints returns the IDs of functions with more than one
+// outgoing edge, in sorted order.
+func (p *PacketPath) sortedBranchPoints(graph *FunctionGraph) []string {
+	var ids []string
+	for _, fn := range p.Functions {
+		if len(graph.GetOutgoingEdges(fn.ID)) > 1 {
+			ids = append(ids, fn.ID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Cycles returns every cycle in the call graph reachable from the entry
+// point, each as the function IDs along it starting and ending with the
+// same function. A simulation stops when it would revisit a function, so a
+// cycle is shown in the diagram but never followed twice.
+func (p *PacketPath) Cycles() [][]string {
+	graph := NewFunctionGraph(p)
+	const (
+		unvisited = iota
+		onStack
+		done
+	)
+	state := make(map[string]int)
+	var stack []string
+	var cycles [][]string
+
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = onStack
+		stack = append(stack, id)
+		for _, next := range graph.GetNextFunctions(id) {
+			switch state[next] {
+			case unvisited:
+				visit(next)
+			case onStack:
+				start := len(stack) - 1
+				for stack[start] != next {
+					start--
+				}
+				cycle := append(append([]string{}, stack[start:]...), next)
+				cycles = append(cycles, cycle)
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = done
+	}
+	if graph.GetFunction(p.EntryPoint) != nil {
+		visit(p.EntryPoint)
+	}
+	return cycles
+}
@@ -0,0 +1,31 @@
+package contract_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/rzkiamr/linux-packet-visualizer/internal/contract/contracttest"
+)
+
+func TestCycles(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []contracttest.TestPathOption
+		want [][]string
+	}{
+		{"linear", nil, nil},
+		{"branching", []contracttest.TestPathOption{contracttest.WithBranches(2), contracttest.WithDropPoint()}, nil},
+		{"cycle", []contracttest.TestPathOption{contracttest.WithLength(4), contracttest.WithCycle()},
+			[][]string{{"fn0", "fn1", "fn2", "fn0"}}},
+		{"shortest cycle", []contracttest.TestPathOption{contracttest.WithLength(2), contracttest.WithCycle()},
+			[][]string{{"fn0", "fn0"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := contracttest.NewTestPath(tt.opts...).Cycles()
+			if !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("Cycles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package contract
+
+import "fmt"
+
+// defaultListenBacklog is the backlog used when none is given: listen()
+// clamps the requested backlog to net.core.somaxconn, 4096 since Linux 5.4.
+const defaultListenBacklog = 4096
+
+// DropReasonListenOverflow is a handshake-completing ACK dropped because
+// the listener's accept queue is full
+const DropReasonListenOverflow = "LISTEN_OVERFLOW"
+
+// ListenQueue is the state of a listening socket's two queues: request
+// socks for half-open connections (the SYN queue) and fully established
+// children waiting for accept() (the accept queue).
+type ListenQueue struct {
+	// SynQueue is the number of request socks in SYN_RECV
+	// (inet_csk_reqsk_queue_len)
+	SynQueue int `json:"synQueue"`
+
+	// AcceptQueue is the number of established connections not yet
+	// accepted (sk_ack_backlog)
+	AcceptQueue int `json:"acceptQueue"`
+
+	// Backlog is the accept queue limit from listen() (sk_max_ack_backlog)
+	Backlog int `json:"backlog"`
+}
+
+// Clone creates a copy of the queue state.
+func (q *ListenQueue) Clone() *ListenQueue {
+	clone := *q
+	return &clone
+}
+
+// SynQueueFull reports whether the SYN queue has reached the backlog, the
+// point at which inet_csk_reqsk_queue_is_full makes new SYNs use cookies.
+func (q *ListenQueue) SynQueueFull() bool {
+	return q.SynQueue >= q.Backlog
+}
+
+// AcceptQueueFull reports whether another connection cannot be queued.
+// Like sk_acceptq_is_full, the queue only counts as full once it holds more
+// than Backlog connections, so Backlog+1 connections can wait.
+func (q *ListenQueue) AcceptQueueFull() bool {
+	return q.AcceptQueue > q.Backlog
+}
+
+// newListenQueue returns the listener's queue state for a simulation, or
+// nil if the packet is not handled by a listening socket.
+func newListenQueue(opts SimulateOptions) *ListenQueue {
+	if opts.SocketLookup != SocketLookupListen && opts.SocketLookup != SocketLookupSynRecv {
+		return nil
+	}
+	backlog := opts.ListenBacklog
+	if backlog <= 0 {
+		backlog = defaultListenBacklog
+	}
+	q := &ListenQueue{
+		SynQueue:    opts.SynQueueLen,
+		AcceptQueue: opts.AcceptQueueLen,
+		Backlog:     backlog,
+	}
+	// The final ACK belongs to a request sock that is already queued
+	if opts.SocketLookup == SocketLookupSynRecv && q.SynQueue == 0 {
+		q.SynQueue = 1
+	}
+	return q
+}
+
+// effectSynQueueAdd models inet_csk_reqsk_queue_hash_add: the new request
+// sock joins the SYN queue until the final ACK arrives.
+func effectSynQueueAdd(ctx *simContext, step *SimulateStep) {
+	q := ctx.listenQueue
+	if q == nil {
+		return
+	}
+	q.SynQueue++
+	step.annotate(AnnotationRouting, fmt.Sprintf(
+		"SYN queue now holds %d of %d request socks. Each costs far less than a full socket, but a SYN flood "+
+			"that never completes handshakes fills this queue; once it is full, new SYNs are answered with SYN cookies.",
+		q.SynQueue, q.Backlog))
+}
+
+// effectAcceptQueueCheck models tcp_v4_syn_recv_sock refusing to create
+// the child socket when the accept queue is full.
+func effectAcceptQueueCheck(ctx *simContext, step *SimulateStep) {
+	q := ctx.listenQueue
+	if q == nil || !q.AcceptQueueFull() {
+		return
+	}
+	ctx.count("ListenOverflows")
+	ctx.count("ListenDrops")
+	ctx.dropReason = DropReasonListenOverflow
+	step.annotate(AnnotationBackpressure, fmt.Sprintf(
+		"Accept queue full (%d connections waiting, backlog %d): the application is not calling accept() fast enough. "+
+			"The final ACK is dropped (ListenOverflows) and the request sock stays in the SYN queue, so the client's "+
+			"retransmission can complete the handshake later (unless tcp_abort_on_overflow resets it).",
+		q.AcceptQueue, q.Backlog))
+}
+
+// effectCompleteHashdance models inet_csk_complete_hashdance moving the
+// connection from the SYN queue to the accept queue.
+func effectCompleteHashdance(ctx *simContext, step *SimulateStep) {
+	ctx.conntrack = NewConntrackEntry(ConntrackEstablished)
+	step.ConntrackState = ctx.conntrack
+	q := ctx.listenQueue
+	if q == nil {
+		return
+	}
+	if q.SynQueue > 0 {
+		q.SynQueue--
+	}
+	q.AcceptQueue++
+	step.annotate(AnnotationRouting, fmt.Sprintf(
+		"Handshake complete: the request sock leaves the SYN queue and the new child socket is parked in the accept queue "+
+			"(%d of %d waiting). The listener is woken so a blocked accept() can return.",
+		q.AcceptQueue, q.Backlog))
+}
+
+// effectAccept models accept() dequeuing the established connection.
+func effectAccept(ctx *simContext, step *SimulateStep) {
+	q := ctx.listenQueue
+	if q == nil || q.AcceptQueue == 0 {
+		return
+	}
+	q.AcceptQueue--
+	step.annotate(AnnotationRouting,
+		"accept() dequeues the child socket from the accept queue and returns a new file descriptor for it. "+
+			"Until now the connection was established in the kernel but invisible to the application.")
+}
+
+// effectSynCookie models cookie_v4_init_sequence answering a SYN without
+// adding a request sock to the SYN queue.
+func effectSynCookie(ctx *simContext, step *SimulateStep) {
+	ctx.count("SyncookiesSent")
+	step.annotate(AnnotationRouting,
+		"SYN-ACK sent with a SYN cookie as its sequence number; the SYN queue is not touched, so a flood cannot exhaust it. "+
+			"The connection exists only if the client's final ACK returns a valid cookie (SyncookiesRecv). "+
+			"The cookie has room for just a few MSS values, and window scaling and SACK survive only if TCP timestamps are on.")
+}
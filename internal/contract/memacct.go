@@ -0,0 +1,121 @@
+package contract
+
+import "fmt"
+
+// skbOverhead approximates the per-sk_buff metadata charged on top of the
+// data buffer: sizeof(struct sk_buff) plus sizeof(struct skb_shared_info),
+// as in SKB_TRUESIZE().
+const skbOverhead = 576
+
+// TrueSize returns skb->truesize, the memory charged to the owning socket
+// for this sk_buff: the data buffer plus struct overhead.
+func (s *SKBuff) TrueSize() int {
+	return s.End - s.Head + skbOverhead
+}
+
+// SocketMemory models the socket's memory accounting counters. Every sk_buff
+// owned by a socket charges its truesize to one of them, and its destructor
+// (sock_wfree/tcp_wfree or sock_rfree) uncharges it when the sk_buff is freed.
+type SocketMemory struct {
+	// WmemAlloc is sk_wmem_alloc: bytes charged by sk_buffs being sent
+	WmemAlloc int `json:"wmemAlloc"`
+
+	// RmemAlloc is sk_rmem_alloc: bytes charged by sk_buffs being received
+	RmemAlloc int `json:"rmemAlloc"`
+
+	// SndBuf is the SO_SNDBUF limit sk_wmem_alloc is compared against (0 = unlimited)
+	SndBuf int `json:"sndBuf,omitempty"`
+
+	// RcvBuf is the SO_RCVBUF limit sk_rmem_alloc is compared against (0 = unlimited)
+	RcvBuf int `json:"rcvBuf,omitempty"`
+}
+
+// Clone creates a copy of the socket memory counters.
+func (m *SocketMemory) Clone() *SocketMemory {
+	clone := *m
+	return &clone
+}
+
+// Annotation kind for socket memory accounting
+const AnnotationMemory = "memory_accounting"
+
+// socketMemory returns the context's socket memory counters, creating them
+// on first use.
+func (ctx *simContext) socketMemory() *SocketMemory {
+	if ctx.memory == nil {
+		ctx.memory = &SocketMemory{
+			SndBuf: ctx.opts.SendBufferSize,
+			RcvBuf: ctx.opts.RecvBufferSize,
+		}
+	}
+	return ctx.memory
+}
+
+// effectWmemCharge models skb_set_owner_w: the sk_buff allocated by
+// tcp_sendmsg_locked charges its truesize to sk_wmem_alloc. Once the charge
+// reaches SO_SNDBUF, further sends wait in sk_stream_wait_memory.
+func effectWmemCharge(ctx *simContext, step *SimulateStep) {
+	mem := ctx.socketMemory()
+	truesize := ctx.skb.TrueSize()
+	mem.WmemAlloc += truesize
+
+	if mem.SndBuf > 0 && mem.WmemAlloc >= mem.SndBuf {
+		step.annotate(AnnotationMemory, fmt.Sprintf(
+			"sk_wmem_alloc charged %d bytes (truesize) and now holds %d, reaching SO_SNDBUF (%d). "+
+				"The next send() blocks in sk_stream_wait_memory until transmit completions free memory.",
+			truesize, mem.WmemAlloc, mem.SndBuf))
+		return
+	}
+	step.annotate(AnnotationMemory, fmt.Sprintf(
+		"sk_wmem_alloc charged %d bytes (truesize); the socket now holds %d bytes of send memory.",
+		truesize, mem.WmemAlloc))
+}
+
+// effectWmemRelease models transmit completion: once the driver has sent the
+// sk_buff, freeing it runs its destructor (tcp_wfree), which uncharges
+// sk_wmem_alloc and may wake a sender blocked on memory.
+func effectWmemRelease(ctx *simContext, step *SimulateStep) {
+	if ctx.memory == nil || ctx.memory.WmemAlloc == 0 {
+		return
+	}
+	released := ctx.memory.WmemAlloc
+	ctx.memory.WmemAlloc = 0
+	step.annotate(AnnotationMemory, fmt.Sprintf(
+		"TX completion frees the sk_buff; its destructor (tcp_wfree) uncharges %d bytes from sk_wmem_alloc "+
+			"and wakes any sender waiting for memory.",
+		released))
+}
+
+// effectRmemCharge models skb_set_owner_r: an sk_buff accepted onto the
+// receive queue charges its truesize to sk_rmem_alloc. Segments dropped by
+// the receive buffer limit are never charged, and coalesced ones are
+// charged by effectTryCoalesce.
+func effectRmemCharge(ctx *simContext, step *SimulateStep) {
+	if limit := ctx.opts.RecvBufferSize; limit > 0 && ctx.opts.PayloadSize > limit {
+		return
+	}
+	if ctx.coalescing() {
+		return
+	}
+	mem := ctx.socketMemory()
+	truesize := ctx.skb.TrueSize()
+	mem.RmemAlloc += truesize
+	step.annotate(AnnotationMemory, fmt.Sprintf(
+		"sk_rmem_alloc charged %d bytes (truesize); the socket now holds %d bytes of receive memory.",
+		truesize, mem.RmemAlloc))
+}
+
+// effectRmemRelease models the reader woken by sk_data_ready consuming the
+// data: recvmsg frees the sk_buff, and its destructor (sock_rfree) uncharges
+// sk_rmem_alloc, reopening the receive window.
+func effectRmemRelease(ctx *simContext, step *SimulateStep) {
+	if ctx.memory == nil || ctx.memory.RmemAlloc == 0 {
+		return
+	}
+	released := ctx.memory.RmemAlloc
+	ctx.memory.RmemAlloc = 0
+	step.annotate(AnnotationMemory, fmt.Sprintf(
+		"sk_data_ready wakes the reader; once recvmsg consumes the data, the sk_buff destructor (sock_rfree) "+
+			"uncharges %d bytes from sk_rmem_alloc.",
+		released))
+}
@@ -0,0 +1,81 @@
+package contract
+
+// GraphMetrics summarizes the size and shape of a function graph.
+type GraphMetrics struct {
+	// NodeCount is the number of functions
+	NodeCount int `json:"nodeCount"`
+
+	// EdgeCount is the number of call edges
+	EdgeCount int `json:"edgeCount"`
+
+	// MaxDepth is the largest number of edges on a shortest route from the
+	// entry point to any reachable function
+	MaxDepth int `json:"maxDepth"`
+
+	// BranchingFactor is the average out-degree of non-terminal functions
+	BranchingFactor float64 `json:"branchingFactor"`
+
+	// MaxOutDegree is the largest number of outgoing edges of any function
+	MaxOutDegree int `json:"maxOutDegree"`
+
+	// ConditionalEdges is the number of edges with a condition
+	ConditionalEdges int `json:"conditionalEdges"`
+
+	// HookNodes is the number of functions carrying a netfilter or BPF hook
+	HookNodes int `json:"hookNodes"`
+}
+
+// Metrics computes summary statistics for the graph.
+func (g *FunctionGraph) Metrics() GraphMetrics {
+	m := GraphMetrics{
+		NodeCount: len(g.functions),
+		EdgeCount: g.edgeCount,
+	}
+
+	for _, fn := range g.functions {
+		if fn.NetfilterHook != nil || fn.BPFHook != nil {
+			m.HookNodes++
+		}
+	}
+
+	nonTerminal, outEdges := 0, 0
+	for _, edges := range g.adjacency {
+		if len(edges) == 0 {
+			continue
+		}
+		nonTerminal++
+		outEdges += len(edges)
+		if len(edges) > m.MaxOutDegree {
+			m.MaxOutDegree = len(edges)
+		}
+		for _, edge := range edges {
+			if edge.Condition != "" {
+				m.ConditionalEdges++
+			}
+		}
+	}
+	if nonTerminal > 0 {
+		m.BranchingFactor = float64(outEdges) / float64(nonTerminal)
+	}
+
+	// Breadth-first search from the entry point for depth
+	if g.functions[g.entryPoint] != nil {
+		depth := map[string]int{g.entryPoint: 0}
+		queue := []string{g.entryPoint}
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			if depth[id] > m.MaxDepth {
+				m.MaxDepth = depth[id]
+			}
+			for _, next := range g.GetNextFunctions(id) {
+				if _, seen := depth[next]; !seen {
+					depth[next] = depth[id] + 1
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,109 @@
+package contract
+
+// MutationEffect is one sk_buff mutation on the primary path together with
+// the running buffer figures after it is applied.
+type MutationEffect struct {
+	// FunctionID is the function performing the mutation
+	FunctionID string `json:"functionId"`
+
+	// Mutation is the declared mutation
+	Mutation SKBMutation `json:"mutation"`
+
+	// Headroom is the space left before the data after the mutation
+	Headroom int `json:"headroom"`
+
+	// Length is the packet length (Tail - Data) after the mutation
+	Length int `json:"length"`
+
+	// Sufficient is false if the buffer could not accommodate the mutation
+	// (not enough headroom for a push or tailroom for a put)
+	Sufficient bool `json:"sufficient"`
+}
+
+// MutationSummary lists every SKBMutation on the primary path in order, with
+// the headroom and packet length after each, starting from the default
+// buffer and payload sizes. It is a static "header budget" check: any entry
+// with Sufficient false means the default buffer is too small for the path,
+// without needing to run a simulation.
+func (p *PacketPath) MutationSummary() []MutationEffect {
+	skb := p.initialSKBuff(SimulateOptions{BufferSize: GetDefaultBufferSize(), PayloadSize: GetDefaultPayloadSize()})
+	effects := []MutationEffect{}
+
+	for _, fn := range p.primaryPath() {
+		m := fn.SKBMutation
+		if m == nil {
+			continue
+		}
+
+		ok := true
+		switch m.Operation {
+		case "push":
+			ok = skb.Push(m.HeaderType, m.Size)
+		case "pull":
+			ok = skb.Pull(m.Size)
+		case "put":
+			ok = skb.Put(m.Size)
+		case "encrypt":
+			skb.Encrypt(m.HeaderType)
+		case "csum":
+			ok = skb.SetCsum(m.CsumState, m.HeaderType)
+		}
+
+		effects = append(effects, MutationEffect{
+			FunctionID: fn.ID,
+			Mutation:   *m,
+			Headroom:   skb.Headroom(),
+			Length:     skb.Len(),
+			Sufficient: ok,
+		})
+	}
+	return effects
+}
+
+// MinimumBufferSize returns the smallest BufferSize for which no push on
+// any branch of the path runs out of headroom, for the given payload size.
+// It is the initial packet length plus the largest net growth (pushes minus
+// pulls) reached at any point along any route from the entry point.
+func (p *PacketPath) MinimumBufferSize(payloadSize int) int {
+	graph := NewFunctionGraph(p)
+
+	// growth[id] is the largest net growth reached from id onward
+	growth := make(map[string]int)
+	visiting := make(map[string]bool)
+	var walk func(id string) int
+	walk = func(id string) int {
+		if g, ok := growth[id]; ok {
+			return g
+		}
+		fn := graph.GetFunction(id)
+		if fn == nil || visiting[id] {
+			return 0
+		}
+		visiting[id] = true
+		defer delete(visiting, id)
+
+		delta := 0
+		if m := fn.SKBMutation; m != nil {
+			switch m.Operation {
+			case "push":
+				delta = m.Size
+			case "pull":
+				delta = -m.Size
+			}
+		}
+		best := 0
+		for _, edge := range graph.GetOutgoingEdges(id) {
+			if g := walk(edge.To); g > best {
+				best = g
+			}
+		}
+		growth[id] = delta + best
+		return growth[id]
+	}
+
+	need := walk(p.EntryPoint)
+	if need < 0 {
+		need = 0
+	}
+	return p.initialSKBuff(SimulateOptions{BufferSize: payloadSize, PayloadSize: payloadSize}).Len() + need
+}
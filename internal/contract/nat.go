@@ -0,0 +1,158 @@
+package contract
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// NAT rule types
+const (
+	// NATTypeSNAT rewrites the source address at POSTROUTING
+	NATTypeSNAT = "SNAT"
+
+	// NATTypeMasquerade rewrites the source address at POSTROUTING to the
+	// address of the output device
+	NATTypeMasquerade = "MASQUERADE"
+
+	// NATTypeDNAT rewrites the destination address at PREROUTING, or at
+	// OUTPUT for locally generated packets
+	NATTypeDNAT = "DNAT"
+)
+
+// AnnotationNAT marks NAT address rewrites.
+const AnnotationNAT = "nat"
+
+// NATRule is a modeled nat table rule. It matches every packet of the
+// simulated flow.
+type NATRule struct {
+	// Type is the rule's target (see NATTypeSNAT)
+	Type string `json:"type"`
+
+	// To is the address the rule rewrites to (unused by MASQUERADE)
+	To string `json:"to,omitempty"`
+}
+
+// ParseNATRule parses a rule written as "snat:ADDR", "dnat:ADDR" or
+// "masquerade".
+func ParseNATRule(s string) (NATRule, error) {
+	target, to, _ := strings.Cut(s, ":")
+	rule := NATRule{Type: strings.ToUpper(target), To: to}
+	if err := rule.validate(); err != nil {
+		return NATRule{}, err
+	}
+	return rule, nil
+}
+
+// String formats the rule as accepted by ParseNATRule.
+func (r NATRule) String() string {
+	if r.Type == NATTypeMasquerade {
+		return strings.ToLower(r.Type)
+	}
+	return strings.ToLower(r.Type) + ":" + r.To
+}
+
+// validate checks the rule's type and address.
+func (r NATRule) validate() error {
+	switch r.Type {
+	case NATTypeMasquerade:
+		if r.To != "" {
+			return fmt.Errorf("masquerade takes no address, got %q", r.To)
+		}
+		return nil
+	case NATTypeSNAT, NATTypeDNAT:
+		if addr, err := netip.ParseAddr(r.To); err != nil || !addr.Is4() {
+			return fmt.Errorf("%s needs an IPv4 address, got %q", strings.ToLower(r.Type), r.To)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown NAT rule %q (want snat:ADDR, dnat:ADDR or masquerade)", r.String())
+}
+
+// hook returns the netfilter hook the rule applies at for a path direction.
+func (r NATRule) hook(direction string) string {
+	switch {
+	case r.Type != NATTypeDNAT:
+		return HookPostrouting
+	case direction == "egress":
+		return HookOutput
+	}
+	return HookPrerouting
+}
+
+// NATRewrite is the address translation applied at a step, with the
+// flow's addresses before and after.
+type NATRewrite struct {
+	// OrigSrc is the source address before the rewrite
+	OrigSrc string `json:"origSrc"`
+
+	// OrigDst is the destination address before the rewrite
+	OrigDst string `json:"origDst"`
+
+	// NewSrc is the source address after the rewrite
+	NewSrc string `json:"newSrc"`
+
+	// NewDst is the destination address after the rewrite
+	NewDst string `json:"newDst"`
+
+	// Type is the rule that applied (see NATTypeSNAT)
+	Type string `json:"type"`
+}
+
+// masqueradeAddress returns the address of the output device, which
+// MASQUERADE rewrites the source to. On egress that is the host's own
+// source address; a forwarding host is taken to be the .1 router of the
+// network its output device is on.
+func (ctx *simContext) masqueradeAddress() string {
+	if ctx.direction != "forward" {
+		return ctx.flow.SrcIP
+	}
+	dst, err := netip.ParseAddr(ctx.flow.DstIP)
+	if err != nil || !dst.Is4() {
+		return ctx.flow.SrcIP
+	}
+	subnet, _ := dst.Prefix(routePrefixLen)
+	addr := subnet.Addr().As4()
+	addr[3] = 1
+	return netip.AddrFrom4(addr).String()
+}
+
+// effectNAT applies the first NAT rule of the step's netfilter hook to the
+// flow, as a NAT target ends traversal of the nat chain. nf_nat_setup_info
+// stores the mapping in the conntrack entry when the connection's first
+// packet passes the nat table; every later packet, and every reply in
+// reverse, is translated from that mapping without consulting the rules
+// again.
+func effectNAT(ctx *simContext, step *SimulateStep) {
+	hook := step.Function.NetfilterHook
+	if hook == nil || ctx.dropReason != "" {
+		return
+	}
+	for _, rule := range ctx.opts.NATRules {
+		if rule.hook(ctx.direction) != hook.Hook {
+			continue
+		}
+
+		rewrite := &NATRewrite{
+			OrigSrc: ctx.flow.SrcIP,
+			OrigDst: ctx.flow.DstIP,
+			Type:    rule.Type,
+		}
+		switch rule.Type {
+		case NATTypeSNAT:
+			ctx.flow.SrcIP = rule.To
+		case NATTypeMasquerade:
+			ctx.flow.SrcIP = ctx.masqueradeAddress()
+		case NATTypeDNAT:
+			ctx.flow.DstIP = rule.To
+		}
+		rewrite.NewSrc, rewrite.NewDst = ctx.flow.SrcIP, ctx.flow.DstIP
+		step.NAT = rewrite
+
+		step.annotate(AnnotationNAT, fmt.Sprintf(
+			"%s at %s rewrites %s -> %s to %s -> %s and fixes up the IP and %s checksums. "+
+				"Conntrack stores the mapping with the connection, so later packets are translated without the rules and replies are translated back.",
+			rule.Type, hook.Hook, rewrite.OrigSrc, rewrite.OrigDst, rewrite.NewSrc, rewrite.NewDst, ctx.flow.Protocol))
+		return
+	}
+}
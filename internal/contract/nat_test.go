@@ -0,0 +1,20 @@
+package contract
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNATRuleJSON(t *testing.T) {
+	rule, err := ParseNATRule("snat:203.0.113.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `{"type":"SNAT","to":"203.0.113.7"}`; got != want {
+		t.Errorf("json = %s, want %s", got, want)
+	}
+}
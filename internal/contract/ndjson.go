@@ -0,0 +1,22 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// ToNDJSON renders the simulation as newline-delimited JSON: one compact
+// JSON object per step, each terminated by a newline. Every line can be
+// parsed on its own, so the output can be tailed or fed to line-oriented
+// tools such as grep and jq.
+func (steps SimulateSteps) ToNDJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, step := range steps {
+		// Encode appends the trailing newline
+		if err := enc.Encode(step); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,102 @@
+package contract
+
+// Version presence tags set by OverlayVersions. Functions and edges present
+// in both versions are left untagged.
+const (
+	// PresenceV1Only marks a function or edge found only in the first version
+	PresenceV1Only = "v1_only"
+
+	// PresenceV2Only marks a function or edge found only in the second version
+	PresenceV2Only = "v2_only"
+)
+
+// OverlayVersions merges two versions of a path (e.g., the same path built
+// for two kernel releases) into one graph. Functions and edges present in
+// both are kept untagged; the rest are tagged PresenceV1Only or
+// PresenceV2Only. Where a function exists in both, the v2 definition is
+// used. Functions keep v1's order, with v2-only functions following.
+func OverlayVersions(v1, v2 *PacketPath) *PacketPath {
+	overlay := &PacketPath{
+		ID:          v1.ID + "+" + v2.ID,
+		Name:        v1.Name + " / " + v2.Name,
+		Description: "Overlay of " + v1.Description + " and " + v2.Description,
+		Direction:   v2.Direction,
+		Protocol:    v2.Protocol,
+		Family:      v2.Family,
+		Functions:   []KernelFunction{},
+		Edges:       []FunctionEdge{},
+		EntryPoint:  v2.EntryPoint,
+		ExitPoints:  []string{},
+	}
+
+	v2Functions := make(map[string]KernelFunction, len(v2.Functions))
+	for _, fn := range v2.Functions {
+		v2Functions[fn.ID] = fn
+	}
+	v1Functions := make(map[string]bool, len(v1.Functions))
+	for _, fn := range v1.Functions {
+		v1Functions[fn.ID] = true
+		if newer, ok := v2Functions[fn.ID]; ok {
+			fn = newer
+		} else {
+			fn.VersionPresence = PresenceV1Only
+		}
+		overlay.Functions = append(overlay.Functions, fn)
+	}
+	for _, fn := range v2.Functions {
+		if !v1Functions[fn.ID] {
+			fn.VersionPresence = PresenceV2Only
+			overlay.Functions = append(overlay.Functions, fn)
+		}
+	}
+
+	type edgeKey struct{ from, to string }
+	v2Edges := make(map[edgeKey]FunctionEdge, len(v2.Edges))
+	for _, edge := range v2.Edges {
+		v2Edges[edgeKey{edge.From, edge.To}] = edge
+	}
+	v1Edges := make(map[edgeKey]bool, len(v1.Edges))
+	for _, edge := range v1.Edges {
+		key := edgeKey{edge.From, edge.To}
+		v1Edges[key] = true
+		if newer, ok := v2Edges[key]; ok {
+			edge = newer
+		} else {
+			edge.VersionPresence = PresenceV1Only
+		}
+		overlay.Edges = append(overlay.Edges, edge)
+	}
+	for _, edge := range v2.Edges {
+		if !v1Edges[edgeKey{edge.From, edge.To}] {
+			edge.VersionPresence = PresenceV2Only
+			overlay.Edges = append(overlay.Edges, edge)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, id := range append(append([]string{}, v1.ExitPoints...), v2.ExitPoints...) {
+		if !seen[id] {
+			seen[id] = true
+			overlay.ExitPoints = append(overlay.ExitPoints, id)
+		}
+	}
+
+	return overlay
+}
+
+// isOverlay reports whether the path is an overlay of two versions that
+// differ. Such a path is the union of two valid graphs, with the entry point
+// of the second, and need not validate as one.
+func (p *PacketPath) isOverlay() bool {
+	for _, fn := range p.Functions {
+		if fn.VersionPresence != "" {
+			return true
+		}
+	}
+	for _, edge := range p.Edges {
+		if edge.VersionPresence != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package contract
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// AnnotationChecksum marks steps that explain how a checksum is computed.
+const AnnotationChecksum = "checksum"
+
+// ipProtocolNumbers maps transport protocols to their IP protocol numbers.
+var ipProtocolNumbers = map[string]byte{
+	"tcp": 6,
+	"udp": 17,
+}
+
+// transportHeaderSizes maps transport protocols to their header sizes.
+var transportHeaderSizes = map[string]int{
+	"tcp": TCPHeaderSize,
+	"udp": UDPHeaderSize,
+}
+
+// PseudoHeaderFor returns the pseudo-header the TCP or UDP checksum covers
+// for a segment of the flow carrying payloadLen bytes after the transport
+// header. It is never transmitted: the sender and receiver each rebuild it
+// from the IP header, which is what ties the transport checksum to the IP
+// addresses. IPv4 flows get the 12-byte form (RFC 793) and IPv6 flows the
+// 40-byte form (RFC 8200). It returns nil for an unknown protocol or
+// unparsable addresses.
+func PseudoHeaderFor(protocol string, flow FlowKey, payloadLen int) []byte {
+	protocol = strings.ToLower(protocol)
+	number, ok := ipProtocolNumbers[protocol]
+	if !ok {
+		return nil
+	}
+	src, dst := net.ParseIP(flow.SrcIP), net.ParseIP(flow.DstIP)
+	if src == nil || dst == nil {
+		return nil
+	}
+	length := transportHeaderSizes[protocol] + payloadLen
+
+	if src4, dst4 := src.To4(), dst.To4(); src4 != nil && dst4 != nil {
+		b := make([]byte, 12)
+		copy(b[0:4], src4)
+		copy(b[4:8], dst4)
+		b[9] = number
+		binary.BigEndian.PutUint16(b[10:12], uint16(length))
+		return b
+	}
+
+	b := make([]byte, 40)
+	copy(b[0:16], src.To16())
+	copy(b[16:32], dst.To16())
+	binary.BigEndian.PutUint32(b[32:36], uint32(length))
+	b[39] = number
+	return b
+}
+
+// onesComplementSum returns the folded 16-bit one's complement sum of b,
+// the building block of the Internet checksum (RFC 1071).
+func onesComplementSum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return uint16(sum)
+}
+
+// effectPseudoHeader models the transport layer seeding its checksum with
+// the pseudo-header (tcp_v4_send_check, udp4_hwcsum) and marking the
+// sk_buff CHECKSUM_PARTIAL, so the NIC or skb_checksum_help can finish it
+// over the header and payload.
+func effectPseudoHeader(ctx *simContext, step *SimulateStep) {
+	protocol := "tcp"
+	if !ctx.skb.HasLayer("tcp") {
+		protocol = "udp"
+	}
+	segment := ctx.skb.Len()
+	payload := segment - transportHeaderSizes[protocol]
+	ctx.skb.SetCsum(CsumPartial, protocol)
+	pseudo := PseudoHeaderFor(protocol, ctx.flow, payload)
+	if pseudo == nil {
+		return
+	}
+	step.annotate(AnnotationChecksum, fmt.Sprintf(
+		"The %s checksum also covers a pseudo-header that is never sent: source %s, destination %s, protocol %d and length %d (% x). "+
+			"Its one's complement sum is 0x%04x; with CHECKSUM_PARTIAL the kernel stores it in the checksum field and the NIC adds the header and payload. "+
+			"Because the IP addresses are part of the sum, NAT must also fix up the %s checksum, and a segment delivered to the wrong address fails validation.",
+		strings.ToUpper(protocol), ctx.flow.SrcIP, ctx.flow.DstIP, ipProtocolNumbers[protocol], segment, pseudo,
+		onesComplementSum(pseudo), strings.ToUpper(protocol)))
+}
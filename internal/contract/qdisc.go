@@ -0,0 +1,10 @@
+package contract
+
+// effectRequeue models dev_requeue_skb putting back a packet the driver
+// refused with NETDEV_TX_BUSY.
+func effectRequeue(ctx *simContext, step *SimulateStep) {
+	step.annotate(AnnotationBackpressure,
+		"The driver returned NETDEV_TX_BUSY (its TX ring is full), so the sk_buff goes back to the qdisc as gso_skb, "+
+			"ahead of everything else queued. The qdisc is rescheduled and net_tx_action retries it once the driver "+
+			"wakes the queue; the transmit is not one-shot, and the qdisc requeues counter (tc -s qdisc) increments.")
+}
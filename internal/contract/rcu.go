@@ -0,0 +1,24 @@
+package contract
+
+// RCUSections returns the contiguous runs of RCU-protected functions along
+// the primary path, in entry-to-exit order. Each run is a list of function
+// IDs that execute inside a single read-side critical section from the
+// visualization's point of view.
+func (p *PacketPath) RCUSections() [][]string {
+	sections := [][]string{}
+	var current []string
+	for _, fn := range p.primaryPath() {
+		if fn.RCUProtected {
+			current = append(current, fn.ID)
+			continue
+		}
+		if len(current) > 0 {
+			sections = append(sections, current)
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		sections = append(sections, current)
+	}
+	return sections
+}
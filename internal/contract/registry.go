@@ -0,0 +1,70 @@
+package contract
+
+// PathRegistry holds the packet paths available for export, in
+// registration order.
+type PathRegistry struct {
+	ids      []string
+	builders map[string]func() *PacketPath
+}
+
+// NewPathRegistry creates an empty registry.
+func NewPathRegistry() *PathRegistry {
+	return &PathRegistry{
+		builders: make(map[string]func() *PacketPath),
+	}
+}
+
+// DefaultRegistry returns a registry containing every built-in path.
+func DefaultRegistry() *PathRegistry {
+	r := NewPathRegistry()
+	r.Register("tcp_ipv4_egress", BuildTCPIPv4EgressPath)
+	r.Register("tcp_ipv6_egress", BuildTCPIPv6EgressPath)
+	r.Register("tcp_ipv4_ingress", BuildTCPIPv4IngressPath)
+	r.Register("tcp_ipv4_teardown", BuildTCPTeardownPath)
+	r.Register("tcp_ipv4_legacy_ingress", BuildLegacyRxPath)
+	r.Register("tcp_ipv4_esp_egress", BuildIPsecESPEgressPath)
+	r.Register("udp_ipv4_egress", BuildUDPIPv4EgressPath)
+	r.Register("tcp_ipv4_forward", BuildTCPIPv4ForwardPath)
+	r.Register("tcp_ipv4_bridge", BuildBridgePath)
+	r.Register("arp_ingress", BuildARPIngressPath)
+	return r
+}
+
+// Register adds a path builder under the given ID. Registering an existing
+// ID replaces its builder but keeps its original position.
+func (r *PathRegistry) Register(id string, build func() *PacketPath) {
+	if _, ok := r.builders[id]; !ok {
+		r.ids = append(r.ids, id)
+	}
+	r.builders[id] = build
+}
+
+// IDs returns the registered path IDs in registration order.
+func (r *PathRegistry) IDs() []string {
+	ids := make([]string, len(r.ids))
+	copy(ids, r.ids)
+	return ids
+}
+
+// Len returns the number of registered paths.
+func (r *PathRegistry) Len() int {
+	return len(r.ids)
+}
+
+// Build constructs a fresh copy of the path with the given ID.
+func (r *PathRegistry) Build(id string) (*PacketPath, bool) {
+	build, ok := r.builders[id]
+	if !ok {
+		return nil, false
+	}
+	return build(), true
+}
+
+// Paths constructs every registered path in registration order.
+func (r *PathRegistry) Paths() []*PacketPath {
+	paths := make([]*PacketPath, 0, len(r.ids))
+	for _, id := range r.ids {
+		paths = append(paths, r.builders[id]())
+	}
+	return paths
+}
@@ -0,0 +1,144 @@
+package contract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Renderer turns an export into one output format.
+type Renderer interface {
+	// Render encodes the export
+	Render(export *ExportPacket) ([]byte, error)
+
+	// Format is the name the renderer is selected by (e.g. "json", "dot")
+	Format() string
+}
+
+// SinglePathRenderer is implemented by renderers that render exactly one
+// path. Callers should build the export from a registry holding only the
+// path to render.
+type SinglePathRenderer interface {
+	Renderer
+
+	// RendersSinglePath marks the renderer as single-path
+	RendersSinglePath()
+}
+
+// RendererRegistry holds the renderers available for export, by format.
+type RendererRegistry struct {
+	renderers map[string]Renderer
+}
+
+// NewRendererRegistry creates an empty renderer registry.
+func NewRendererRegistry() *RendererRegistry {
+	return &RendererRegistry{renderers: make(map[string]Renderer)}
+}
+
+// DefaultRenderers returns a registry containing every built-in renderer.
+// Pretty selects indented JSON.
+func DefaultRenderers(pretty bool) *RendererRegistry {
+	r := NewRendererRegistry()
+	r.Register(JSONRenderer{Pretty: pretty})
+	r.Register(newStepsRenderer("trace", SimulateSteps.ToChromeTrace))
+	r.Register(newStepsRenderer("ndjson", SimulateSteps.ToNDJSON))
+	r.Register(newStepsRenderer("svg", SimulateSteps.ToAnimatedSVG))
+	r.Register(newDiagramRenderer("dot", (*PacketPath).ToDOT))
+	r.Register(newDiagramRenderer("mermaid", (*PacketPath).ToMermaid))
+	r.Register(newDiagramRenderer("canonical", (*PacketPath).Canonical))
+	return r
+}
+
+// Register adds a renderer under its format, replacing any renderer
+// already registered for that format.
+func (r *RendererRegistry) Register(renderer Renderer) {
+	r.renderers[renderer.Format()] = renderer
+}
+
+// Lookup returns the renderer for a format.
+func (r *RendererRegistry) Lookup(format string) (Renderer, bool) {
+	renderer, ok := r.renderers[format]
+	return renderer, ok
+}
+
+// Formats returns the registered format names in sorted order.
+func (r *RendererRegistry) Formats() []string {
+	formats := make([]string, 0, len(r.renderers))
+	for format := range r.renderers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// defaultIndentWidth is the indentation of pretty JSON output when none is
+// given
+const defaultIndentWidth = 2
+
+// JSONRenderer renders the export as the JSON data contract.
+type JSONRenderer struct {
+	// Pretty enables indented output
+	Pretty bool
+
+	// IndentWidth is the number of spaces per indentation level when
+	// Pretty is set (0 = 2)
+	IndentWidth int
+}
+
+// Format implements Renderer.
+func (JSONRenderer) Format() string { return "json" }
+
+// Render implements Renderer.
+func (r JSONRenderer) Render(export *ExportPacket) ([]byte, error) {
+	if r.Pretty {
+		width := r.IndentWidth
+		if width <= 0 {
+			width = defaultIndentWidth
+		}
+		return json.MarshalIndent(export, "", strings.Repeat(" ", width))
+	}
+	return json.Marshal(export)
+}
+
+// pathRenderer renders the single path of an export.
+type pathRenderer struct {
+	format string
+	render func(p *PathWithSimulation) ([]byte, error)
+}
+
+// newStepsRenderer returns a single-path renderer of the path's simulation.
+func newStepsRenderer(format string, render func(SimulateSteps) ([]byte, error)) pathRenderer {
+	return pathRenderer{format: format, render: func(p *PathWithSimulation) ([]byte, error) {
+		if p.Simulation == nil {
+			return nil, errors.New("requires the full simulation (not excluded or delta-encoded)")
+		}
+		return render(p.Simulation)
+	}}
+}
+
+// newDiagramRenderer returns a single-path renderer of the path's graph.
+func newDiagramRenderer(format string, render func(*PacketPath) string) pathRenderer {
+	return pathRenderer{format: format, render: func(p *PathWithSimulation) ([]byte, error) {
+		return []byte(render(&p.Path)), nil
+	}}
+}
+
+// Format implements Renderer.
+func (r pathRenderer) Format() string { return r.format }
+
+// RendersSinglePath implements SinglePathRenderer.
+func (pathRenderer) RendersSinglePath() {}
+
+// Render implements Renderer.
+func (r pathRenderer) Render(export *ExportPacket) ([]byte, error) {
+	if len(export.Paths) != 1 {
+		return nil, fmt.Errorf("format %s renders a single path, but the export has %d", r.format, len(export.Paths))
+	}
+	data, err := r.render(&export.Paths[0])
+	if err != nil {
+		return nil, fmt.Errorf("format %s: %w", r.format, err)
+	}
+	return data, nil
+}
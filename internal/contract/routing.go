@@ -0,0 +1,116 @@
+package contract
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// effectPolicyRouting models a fwmark ip rule selecting an alternate routing
+// table. By convention the example rule maps the mark to the table with the
+// same number.
+func effectPolicyRouting(ctx *simContext, step *SimulateStep) {
+	mark := ctx.skb.Mark
+	step.annotate(AnnotationRouting, fmt.Sprintf(
+		"skb->mark 0x%x matches rule \"ip rule add fwmark 0x%x table %d\". "+
+			"The route is looked up in table %d instead of main, which can select a different gateway and egress device.",
+		mark, mark, mark, mark))
+}
+
+// RouteDecision is the result of a FIB lookup: where the packet goes next.
+type RouteDecision struct {
+	// OutputDevice is the device the route points at ("lo" for local delivery)
+	OutputDevice string `json:"outputDevice"`
+
+	// Gateway is the next-hop router ("" = destination is on-link)
+	Gateway string `json:"gateway,omitempty"`
+
+	// Scope is the route scope: "host" (local address), "link" (directly
+	// connected) or "universe" (via a gateway)
+	Scope string `json:"scope"`
+
+	// Table is the routing table that matched ("local", "main", or the
+	// table selected by a policy rule)
+	Table string `json:"table"`
+
+	// Local is true if the packet is delivered to this host
+	Local bool `json:"local"`
+}
+
+// Route scopes
+const (
+	RouteScopeHost     = "host"
+	RouteScopeLink     = "link"
+	RouteScopeUniverse = "universe"
+)
+
+// The modeled host has one Ethernet interface on a /24 with the default
+// gateway at .1, plus loopback.
+const (
+	routeDevice    = "eth0"
+	routeLoopback  = "lo"
+	routePrefixLen = 24
+)
+
+// LookupRoute models the FIB lookup for a flow. For egress the host owns the
+// flow's source address; for ingress it owns the destination address; for
+// forward it owns neither. A
+// non-zero mark selects the policy routing table of the same number (see
+// effectPolicyRouting) instead of main.
+func LookupRoute(flow FlowKey, direction string, mark uint32) RouteDecision {
+	table := "main"
+	if mark != 0 {
+		table = fmt.Sprint(mark)
+	}
+	route := RouteDecision{OutputDevice: routeDevice, Scope: RouteScopeLink, Table: table}
+	// A forwarded packet is addressed to neither end of the modeled host;
+	// its destination is taken to be on the network behind routeDevice
+	if direction == "forward" {
+		return route
+	}
+
+	localIP := flow.SrcIP
+	if direction == "ingress" {
+		localIP = flow.DstIP
+	}
+	local, errLocal := netip.ParseAddr(localIP)
+	dst, errDst := netip.ParseAddr(flow.DstIP)
+
+	if errDst == nil && (dst.IsLoopback() || dst == local) {
+		return RouteDecision{OutputDevice: routeLoopback, Scope: RouteScopeHost, Table: "local", Local: true}
+	}
+
+	// Only IPv4 addresses are modeled; anything else is treated as on-link
+	if errLocal != nil || errDst != nil || !local.Is4() {
+		return route
+	}
+	subnet, _ := local.Prefix(routePrefixLen)
+	if subnet.Contains(dst) {
+		return route
+	}
+	gw := subnet.Addr().As4()
+	gw[3] = 1
+	route.Gateway = netip.AddrFrom4(gw).String()
+	route.Scope = RouteScopeUniverse
+	return route
+}
+
+// effectRouteLookup records the FIB lookup result of the flow at the step
+// that performs the routing decision.
+func effectRouteLookup(ctx *simContext, step *SimulateStep) {
+	route := LookupRoute(ctx.flow, ctx.direction, ctx.skb.Mark)
+	step.Route = &route
+
+	var msg string
+	switch {
+	case route.Local:
+		msg = fmt.Sprintf("Route lookup for %s: local address (table local, scope host), delivered to this host via %s.",
+			ctx.flow.DstIP, route.OutputDevice)
+	case route.Gateway != "":
+		msg = fmt.Sprintf("Route lookup for %s: via gateway %s dev %s (table %s). The neighbour lookup will resolve the gateway's MAC, not the destination's.",
+			ctx.flow.DstIP, route.Gateway, route.OutputDevice, route.Table)
+	default:
+		msg = fmt.Sprintf("Route lookup for %s: directly connected on dev %s (table %s, scope link).",
+			ctx.flow.DstIP, route.OutputDevice, route.Table)
+	}
+	step.annotate(AnnotationRouting, msg)
+}
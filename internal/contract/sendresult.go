@@ -0,0 +1,51 @@
+package contract
+
+// SendResult is the outcome of the simulated send() as the application sees
+// it: how much of the requested data the socket accepted, and where the
+// accepted bytes are when the simulation ends.
+type SendResult struct {
+	// Requested is the number of bytes passed to send()
+	Requested int `json:"requested"`
+
+	// Accepted is the number of bytes copied into the socket, which is what
+	// send() returns (Queued + InFlight)
+	Accepted int `json:"accepted"`
+
+	// Queued are accepted bytes still on the write queue, held back by
+	// congestion control or TSO deferral
+	Queued int `json:"queued"`
+
+	// InFlight are accepted bytes transmitted but not yet acknowledged
+	InFlight int `json:"inFlight"`
+
+	// Blocked are bytes the socket did not accept because the send buffer
+	// is full: a blocking send() waits for them, a non-blocking send()
+	// returns a short write (or EAGAIN if nothing was accepted)
+	Blocked int `json:"blocked"`
+}
+
+// SendResult derives the send() outcome from the final write queue state of
+// an egress simulation. Returns nil if the simulation never enqueued data.
+func (steps SimulateSteps) SendResult(requested int) *SendResult {
+	var queue *WriteQueue
+	for i := len(steps) - 1; i >= 0; i-- {
+		if steps[i].WriteQueue != nil {
+			queue = steps[i].WriteQueue
+			break
+		}
+	}
+	if queue == nil {
+		return nil
+	}
+
+	result := &SendResult{Requested: requested}
+	for i := range queue.Queued {
+		result.Queued += queue.Queued[i].Len()
+	}
+	for i := range queue.InFlight {
+		result.InFlight += queue.InFlight[i].Len()
+	}
+	result.Accepted = result.Queued + result.InFlight
+	result.Blocked = requested - result.Accepted
+	return result
+}
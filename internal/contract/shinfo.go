@@ -0,0 +1,120 @@
+package contract
+
+const (
+	// maxSKBFrags is MAX_SKB_FRAGS, the most paged fragments an sk_buff
+	// can hold with 4 KiB pages
+	maxSKBFrags = 17
+
+	// pageSize is PAGE_SIZE on the modeled architecture
+	pageSize = 4096
+)
+
+// SKBFrag is a paged fragment (skb_frag_t): a piece of a page holding
+// packet data that follows the linear buffer.
+type SKBFrag struct {
+	// Size is the bytes of packet data in the fragment
+	Size int `json:"size"`
+
+	// PageOffset is the offset of the data in its page
+	PageOffset int `json:"pageOffset"`
+}
+
+// SharedInfo models skb_shared_info, which sits at the end of the data
+// buffer and is shared by clones. It describes the packet data outside the
+// linear buffer: paged fragments, as built by GRO, TSO sends and
+// MSG_ZEROCOPY, and further sk_buffs chained on frag_list. Its gso_size and
+// gso_type are modeled by SKBuff.GSO.
+type SharedInfo struct {
+	// NrFrags is nr_frags, the number of paged fragments
+	NrFrags int `json:"nrFrags"`
+
+	// Frags are the paged fragments, in packet order
+	Frags []SKBFrag `json:"frags,omitempty"`
+
+	// FragList are the sk_buffs on frag_list, whose data follows the paged
+	// fragments
+	FragList []SKBuff `json:"fragList,omitempty"`
+}
+
+// Clone creates a deep copy of the shared info.
+func (si *SharedInfo) Clone() *SharedInfo {
+	clone := &SharedInfo{NrFrags: si.NrFrags}
+	if si.Frags != nil {
+		clone.Frags = append([]SKBFrag{}, si.Frags...)
+	}
+	for i := range si.FragList {
+		clone.FragList = append(clone.FragList, *si.FragList[i].Clone())
+	}
+	return clone
+}
+
+// AddFrag appends a paged fragment of size bytes to the packet. Like
+// skb_page_frag_refill, it places the data right after the previous
+// fragment if the rest of that page holds it, and at the start of a new
+// page otherwise. Returns false if the sk_buff already has MAX_SKB_FRAGS
+// fragments.
+func (s *SKBuff) AddFrag(size int) bool {
+	if size <= 0 {
+		return false
+	}
+	if s.SharedInfo == nil {
+		s.SharedInfo = &SharedInfo{}
+	}
+	si := s.SharedInfo
+	if si.NrFrags >= maxSKBFrags {
+		return false
+	}
+
+	offset := 0
+	if si.NrFrags > 0 {
+		last := si.Frags[si.NrFrags-1]
+		if end := last.PageOffset + last.Size; end+size <= pageSize {
+			offset = end
+		}
+	}
+	si.Frags = append(si.Frags, SKBFrag{Size: size, PageOffset: offset})
+	si.NrFrags++
+	return true
+}
+
+// DataLen returns skb->data_len, the bytes of packet data outside the linear
+// buffer.
+func (s *SKBuff) DataLen() int {
+	if s.SharedInfo == nil {
+		return 0
+	}
+	length := 0
+	for _, frag := range s.SharedInfo.Frags {
+		length += frag.Size
+	}
+	for i := range s.SharedInfo.FragList {
+		length += s.SharedInfo.FragList[i].Len()
+	}
+	return length
+}
+
+// HeadLen returns skb_headlen, the bytes of packet data in the linear
+// buffer.
+func (s *SKBuff) HeadLen() int {
+	return s.Tail - s.Data
+}
+
+// IsNonLinear reports whether part of the packet data is outside the linear
+// buffer (skb_is_nonlinear).
+func (s *SKBuff) IsNonLinear() bool {
+	return s.DataLen() > 0
+}
+
+// Linearize copies the paged fragments and the frag_list sk_buffs into the
+// linear buffer after Tail, as skb_linearize does, and drops the shared
+// info. If the tailroom is too small the buffer is reallocated larger, as
+// pskb_expand_head would, which moves End.
+func (s *SKBuff) Linearize() {
+	dataLen := s.DataLen()
+	s.SharedInfo = nil
+	if dataLen == 0 {
+		return
+	}
+	s.Tail += dataLen
+	s.End = max(s.End, s.Tail)
+}
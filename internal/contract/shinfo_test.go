@@ -0,0 +1,122 @@
+package contract
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAddFrag(t *testing.T) {
+	skb := NewSKBuffWithPayload(2048, 100)
+
+	for _, size := range []int{1000, 2000, 1500, 4000} {
+		if !skb.AddFrag(size) {
+			t.Fatalf("AddFrag(%d) = false", size)
+		}
+	}
+	// 1500 bytes no longer fit the first page after 3000, so they start a
+	// new one, and so do 4000 bytes after them
+	want := []SKBFrag{{1000, 0}, {2000, 1000}, {1500, 0}, {4000, 0}}
+	if got := skb.SharedInfo.Frags; !slices.Equal(got, want) {
+		t.Errorf("frags = %v, want %v", got, want)
+	}
+	if skb.SharedInfo.NrFrags != 4 {
+		t.Errorf("NrFrags = %d, want 4", skb.SharedInfo.NrFrags)
+	}
+	if skb.DataLen() != 8500 || skb.HeadLen() != 100 || skb.Len() != 8600 {
+		t.Errorf("data_len %d, headlen %d, len %d, want 8500, 100 and 8600", skb.DataLen(), skb.HeadLen(), skb.Len())
+	}
+	if !skb.IsNonLinear() {
+		t.Error("sk_buff with paged fragments is linear")
+	}
+	if skb.AddFrag(0) {
+		t.Error("AddFrag(0) = true")
+	}
+}
+
+func TestAddFragLimit(t *testing.T) {
+	skb := NewSKBuff(2048)
+	for i := range maxSKBFrags {
+		if !skb.AddFrag(100) {
+			t.Fatalf("AddFrag failed at fragment %d", i)
+		}
+	}
+	if skb.AddFrag(100) {
+		t.Errorf("AddFrag accepted fragment %d, beyond MAX_SKB_FRAGS", maxSKBFrags+1)
+	}
+	if skb.SharedInfo.NrFrags != maxSKBFrags || skb.DataLen() != 100*maxSKBFrags {
+		t.Errorf("NrFrags %d, data_len %d after a refused fragment", skb.SharedInfo.NrFrags, skb.DataLen())
+	}
+}
+
+func TestLinearize(t *testing.T) {
+	tests := []struct {
+		name    string
+		end     int
+		wantEnd int
+	}{
+		{"fits tailroom", 8192, 8192},
+		{"expands head", 2048, 5000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			skb := &SKBuff{Data: 1000, Tail: 2000, End: tt.end}
+			skb.AddFrag(1500)
+			skb.AddFrag(500)
+			skb.SharedInfo.FragList = []SKBuff{*NewSKBuffWithPayload(2048, 1000)}
+
+			skb.Linearize()
+			if skb.SharedInfo != nil {
+				t.Error("shared info kept after Linearize")
+			}
+			if skb.Len() != 4000 || skb.HeadLen() != 4000 || skb.IsNonLinear() {
+				t.Errorf("len %d, headlen %d, nonlinear %v, want 4000 bytes all linear", skb.Len(), skb.HeadLen(), skb.IsNonLinear())
+			}
+			if skb.End != tt.wantEnd {
+				t.Errorf("End = %d, want %d", skb.End, tt.wantEnd)
+			}
+		})
+	}
+
+	linear := NewSKBuffWithPayload(2048, 100)
+	before := *linear
+	linear.Linearize()
+	if linear.Tail != before.Tail || linear.End != before.End {
+		t.Error("Linearize changed a linear sk_buff")
+	}
+}
+
+func TestCloneDeepCopiesSharedInfo(t *testing.T) {
+	skb := NewSKBuffWithPayload(2048, 100)
+	skb.AddFrag(1000)
+	inner := NewSKBuffWithPayload(2048, 500)
+	inner.AddFrag(200)
+	skb.SharedInfo.FragList = []SKBuff{*inner}
+
+	clone := skb.Clone()
+	if clone.SharedInfo == skb.SharedInfo {
+		t.Fatal("clone shares the shared info")
+	}
+	if !skbuffEqual(clone, skb) {
+		t.Fatal("clone differs from the original")
+	}
+
+	clone.AddFrag(300)
+	clone.SharedInfo.Frags[0].Size = 1
+	clone.SharedInfo.FragList[0].SharedInfo.Frags[0].Size = 1
+	clone.SharedInfo.FragList[0].Tail++
+	clone.SharedInfo.FragList = append(clone.SharedInfo.FragList, *NewSKBuff(64))
+
+	si := skb.SharedInfo
+	if si.NrFrags != 1 || len(si.Frags) != 1 || si.Frags[0].Size != 1000 {
+		t.Errorf("original frags changed through the clone: %+v", si.Frags)
+	}
+	if len(si.FragList) != 1 {
+		t.Fatalf("original frag_list has %d sk_buffs, want 1", len(si.FragList))
+	}
+	if got := si.FragList[0]; got.Len() != 700 || got.SharedInfo.Frags[0].Size != 200 {
+		t.Errorf("original frag_list sk_buff changed through the clone: len %d, frags %+v", got.Len(), got.SharedInfo.Frags)
+	}
+	if skb.Len() != 1800 {
+		t.Errorf("original len = %d, want 1800", skb.Len())
+	}
+}
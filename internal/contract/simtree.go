@@ -0,0 +1,121 @@
+package contract
+
+// maxSimulateAllForks is the most edges other than the one the options
+// select that a single branch of SimulateAll follows, bounding the tree on
+// paths with many forks.
+const maxSimulateAllForks = 8
+
+// SimulationNode is a step of a branching simulation. Where the step's
+// function has more than one outgoing edge, each edge leads to a child.
+type SimulationNode struct {
+	// Step is the simulation step; its EdgeTaken is the edge that led to it
+	// (nil for the entry point and for further steps of the same function)
+	Step SimulateStep `json:"step"`
+
+	// Children are the steps that can follow this one, the one the options
+	// select first (nil at the end of a branch)
+	Children []*SimulationNode `json:"children,omitempty"`
+}
+
+// Leaves returns the number of branches below the node, counting the node
+// itself as one if it has no children.
+func (n *SimulationNode) Leaves() int {
+	if len(n.Children) == 0 {
+		return 1
+	}
+	leaves := 0
+	for _, child := range n.Children {
+		leaves += child.Leaves()
+	}
+	return leaves
+}
+
+// SimulateAll simulates the path with default options along every route:
+// see SimulateAllWithOptions.
+func (path *PacketPath) SimulateAll() *SimulationNode {
+	return path.SimulateAllWithOptions(SimulateOptions{
+		BufferSize:  GetDefaultBufferSize(),
+		PayloadSize: GetDefaultPayloadSize(),
+	})
+}
+
+// SimulateAllWithOptions simulates the path along every route and returns
+// the steps as a tree. At each function with more than one outgoing edge,
+// error edges included, the tree forks: the first child continues the
+// simulation SimulateWithOptions runs, and each other edge is simulated as
+// if its condition held, whatever the options say. A branch ends where the
+// linear simulation would, at an exit point or a function already visited,
+// and forks at most maxSimulateAllForks times. Returns nil if the path has
+// no entry point.
+func (path *PacketPath) SimulateAllWithOptions(opts SimulateOptions) *SimulationNode {
+	steps := path.run(opts, path.initialSKBuff(opts), nil).steps
+	if len(steps) == 0 {
+		return nil
+	}
+	root := &SimulationNode{Step: steps[0]}
+	path.growBranch(NewFunctionGraph(path), opts, root, steps, map[string]string{})
+	return root
+}
+
+// growBranch attaches the steps of a simulation run with the forced choices
+// below node, the first step where the run leaves its parent branch, and
+// forks at every function after it.
+func (path *PacketPath) growBranch(graph *FunctionGraph, opts SimulateOptions, node *SimulationNode, steps SimulateSteps, forced map[string]string) {
+	// Steps before the first one of this branch are shared with its parent
+	first := node.Step.StepNumber - 1
+	for i := first; i < len(steps); i++ {
+		fromID := steps[i].Function.ID
+		taken := ""
+		var next *SimulationNode
+		if i+1 < len(steps) {
+			taken = steps[i+1].Function.ID
+			next = &SimulationNode{Step: steps[i+1]}
+			node.Children = append(node.Children, next)
+			if taken == fromID {
+				// An intermediate step the same function emitted
+				node = next
+				continue
+			}
+			next.Step.EdgeTaken = edgeBetween(graph, fromID, taken)
+		}
+
+		if len(forced) < maxSimulateAllForks {
+			for _, edge := range graph.GetOutgoingEdges(fromID) {
+				if edge.To == taken {
+					continue
+				}
+				alt := make(map[string]string, len(forced)+1)
+				for from, to := range forced {
+					alt[from] = to
+				}
+				alt[fromID] = edge.To
+
+				// The other edge may lead to a function already visited
+				altSteps := path.run(opts, path.initialSKBuff(opts), alt).steps
+				if len(altSteps) <= i+1 || altSteps[i+1].Function.ID != edge.To {
+					continue
+				}
+				child := &SimulationNode{Step: altSteps[i+1]}
+				child.Step.EdgeTaken = &edge
+				node.Children = append(node.Children, child)
+				path.growBranch(graph, opts, child, altSteps, alt)
+			}
+		}
+
+		if next == nil {
+			return
+		}
+		node = next
+	}
+}
+
+// edgeBetween returns a copy of the edge from one function to another (nil
+// if there is none).
+func edgeBetween(graph *FunctionGraph, fromID, toID string) *FunctionEdge {
+	for _, edge := range graph.GetOutgoingEdges(fromID) {
+		if edge.To == toID {
+			return &edge
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package contract
+
+import "testing"
+
+// findNode returns the first node of the tree, depth first, whose step is
+// in the function with the given ID.
+func findNode(n *SimulationNode, id string) *SimulationNode {
+	if n.Step.Function.ID == id {
+		return n
+	}
+	for _, child := range n.Children {
+		if found := findNode(child, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// branchEnd follows the first child from n to the end of its branch.
+func branchEnd(n *SimulationNode) *SimulationNode {
+	for len(n.Children) > 0 {
+		n = n.Children[0]
+	}
+	return n
+}
+
+func TestSimulateAllNeighOutputBranches(t *testing.T) {
+	root := BuildTCPIPv4EgressPath().SimulateAll()
+	if root == nil {
+		t.Fatal("SimulateAll returned no tree")
+	}
+	neigh := findNode(root, "neigh_output")
+	if neigh == nil {
+		t.Fatal("no neigh_output step")
+	}
+
+	tests := []struct {
+		to        string
+		condition string
+	}{
+		// The options select the cached header first
+		{"neigh_hh_output", "Hardware header cached"},
+		{"neigh_resolve_output", "No cached hardware header"},
+	}
+	if len(neigh.Children) != len(tests) {
+		t.Fatalf("neigh_output has %d children, want %d", len(neigh.Children), len(tests))
+	}
+	before := neigh.Step.SKBuffState.Len()
+	for i, tt := range tests {
+		t.Run(tt.to, func(t *testing.T) {
+			child := neigh.Children[i]
+			if child.Step.Function.ID != tt.to {
+				t.Fatalf("child %d = %s, want %s", i, child.Step.Function.ID, tt.to)
+			}
+			if edge := child.Step.EdgeTaken; edge == nil || edge.From != "neigh_output" || edge.Condition != tt.condition {
+				t.Errorf("edge taken = %+v, want neigh_output -> %s when %q", edge, tt.to, tt.condition)
+			}
+
+			skb := child.Step.SKBuffState
+			if got := skb.Len() - before; got != EthernetHeaderSize {
+				t.Errorf("%s grows the sk_buff by %d bytes, want the %d-byte Ethernet header", tt.to, got, EthernetHeaderSize)
+			}
+			if !skb.HasLayer("ethernet") {
+				t.Errorf("%s leaves no Ethernet header", tt.to)
+			}
+
+			if len(child.Children) == 0 || child.Children[0].Step.Function.ID != "dev_queue_xmit" {
+				t.Fatalf("%s is not followed by dev_queue_xmit", tt.to)
+			}
+			if end := branchEnd(child); end.Step.Function.ID != "ndo_start_xmit" {
+				t.Errorf("branch through %s ends at %s, want ndo_start_xmit", tt.to, end.Step.Function.ID)
+			}
+		})
+	}
+}
+
+func TestSimulateAllFirstBranchIsLinearSimulation(t *testing.T) {
+	path := BuildTCPIPv4EgressPath()
+	steps := path.SimulateWithOptions(DefaultSimulateOptions())
+
+	root := path.SimulateAll()
+	n := root
+	for i, step := range steps {
+		if n == nil {
+			t.Fatalf("tree ends before step %d (%s)", step.StepNumber, step.Function.ID)
+		}
+		if n.Step.Function.ID != step.Function.ID {
+			t.Fatalf("step %d: tree has %s, linear simulation %s", i+1, n.Step.Function.ID, step.Function.ID)
+		}
+		if step.Function.ID == "neigh_resolve_output" {
+			t.Errorf("linear simulation takes neigh_resolve_output with the hardware header cached")
+		}
+		n = firstChild(n)
+	}
+	if root.Leaves() < 2 {
+		t.Errorf("tree has %d leaves, want the branches of the egress path", root.Leaves())
+	}
+}
+
+// firstChild returns the first child of n, or nil at the end of a branch.
+func firstChild(n *SimulationNode) *SimulationNode {
+	if len(n.Children) == 0 {
+		return nil
+	}
+	return n.Children[0]
+}
@@ -0,0 +1,703 @@
+package contract
+
+import "fmt"
+
+// SimulateOptions configures a simulation run.
+type SimulateOptions struct {
+	// BufferSize is the sk_buff allocation size
+	BufferSize int
+
+	// PayloadSize is the application payload size in bytes
+	PayloadSize int
+
+	// SendBufferSize models SO_SNDBUF in bytes (0 = unlimited)
+	SendBufferSize int
+
+	// RecvBufferSize models SO_RCVBUF in bytes (0 = unlimited)
+	RecvBufferSize int
+
+	// Flow is the 5-tuple of the simulated packet (zero = DefaultFlowKey)
+	Flow FlowKey
+
+	// Mark is the skb->mark of the simulated packet (0 = unmarked)
+	Mark uint32
+
+	// ZeroCopy sends with MSG_ZEROCOPY, pinning user pages instead of copying
+	ZeroCopy bool
+
+	// NATRules are nat table rules matching the simulated flow; each
+	// applies at its hook (see NATRule)
+	NATRules []NATRule
+
+	// NonBlocking sends on a non-blocking socket (O_NONBLOCK or
+	// MSG_DONTWAIT), so a full send buffer returns -EAGAIN instead of
+	// sleeping in sk_stream_wait_memory
+	NonBlocking bool
+
+	// FragmentCount is the number of IP fragments the ingress packet
+	// arrives in (0 or 1 = unfragmented)
+	FragmentCount int
+
+	// PacketTaps is the number of AF_PACKET sockets (sniffers) receiving a
+	// copy of each ingress packet
+	PacketTaps int
+
+	// GROFlush coalesces the ingress packet into a held GRO flow, released
+	// for the given reason (see GROFlushFlowLimit; "" = not coalesced)
+	GROFlush string
+
+	// SocketLookup is the result of tcp_v4_rcv's socket lookup
+	// (see SocketLookupEstablished; "" = established)
+	SocketLookup string
+
+	// GenericXDP is the verdict of a generic-mode XDP program on ingress:
+	// "pass" or "drop" ("" = no program attached)
+	GenericXDP string
+
+	// ICMPError makes the ingress packet an ICMP error quoting a segment
+	// of the connection (see ICMPErrorFragNeeded; "" = a TCP segment)
+	ICMPError string
+
+	// ConntrackHelper is the conntrack helper assigned to the connection
+	// (see ConntrackHelperFTP; "" = none)
+	ConntrackHelper string
+
+	// UDPSegment is the UDP_SEGMENT size for UDP egress (0 = no GSO)
+	UDPSegment int
+
+	// GSOPartial gives the transmitting device NETIF_F_GSO_PARTIAL with a
+	// boundary of this many segments: software GSO cuts a packet into
+	// chunks of up to that many segments, which the NIC finishes
+	// (0 = full software segmentation)
+	GSOPartial int
+
+	// MTU is the path MTU for egress (0 = 1500), at least 68 (see IsValidMTU)
+	MTU int
+
+	// MSS is the MSS of the sending TCP connection, the gso_size of a TSO
+	// sk_buff (0 = 1460, or 1440 over IPv6)
+	MSS int
+
+	// NoGSO takes GSO and TSO off the egress route (ethtool -K gso off tso
+	// off), so tcp_write_xmit sends a payload larger than the MSS as
+	// separate MSS-sized sk_buffs, each passing down the stack on its own
+	NoGSO bool
+
+	// DeviceBusy makes the driver refuse the egress packet with
+	// NETDEV_TX_BUSY, so it is requeued to the qdisc
+	DeviceBusy bool
+
+	// QdiscBacklog is the bytes (truesize) of the sending socket's earlier
+	// segments still in the qdisc and driver ring, charged to
+	// sk_wmem_alloc until TX completion; above the TSQ limit tcp_write_xmit
+	// stops (0 = none)
+	QdiscBacklog int
+
+	// TSODefer makes tcp_tso_should_defer hold back a TSO send the
+	// congestion window cannot send whole, waiting for ACKs to open it
+	// instead of sending a small part now (egress TCP with GSO only)
+	TSODefer bool
+
+	// QuickAck puts the receiver in quick-ACK mode (TCP_QUICKACK, the start
+	// of a connection, or after out-of-order data), so data is ACKed at once
+	QuickAck bool
+
+	// ListenBacklog is the listen() backlog of the listening socket
+	// (0 = net.core.somaxconn, 4096)
+	ListenBacklog int
+
+	// SynQueueLen is the number of half-open connections already in the
+	// listener's SYN queue
+	SynQueueLen int
+
+	// AcceptQueueLen is the number of established connections already
+	// waiting in the listener's accept queue
+	AcceptQueueLen int
+
+	// SocketFilter is the verdict of a socket filter attached to the
+	// receiving socket: "allow" or "deny" ("" = no filter attached)
+	SocketFilter string
+
+	// SocketFilterLen is the number of bytes an allowing socket filter keeps
+	// from the TCP header on (0 = the whole packet)
+	SocketFilterLen int
+
+	// SocketOwnedByUser makes a process own the receiving socket, so the
+	// ingress segment is queued on the socket backlog
+	SocketOwnedByUser bool
+
+	// TCIngress is the verdict of the ingress qdisc's classifiers (see
+	// TCActOK; "" = no ingress qdisc)
+	TCIngress string
+
+	// RecvCoalesce puts an in-order segment of the same connection at the
+	// tail of the receive queue, so the received data is coalesced into it
+	RecvCoalesce bool
+
+	// BridgeFDB is the bridge's FDB lookup result for the frame's
+	// destination MAC (see BridgeFDBKnown; "" = known)
+	BridgeFDB string
+
+	// BridgePorts is the number of ports of the bridge (0 = 4)
+	BridgePorts int
+
+	// ARPOperation is the operation of the received ARP packet (see
+	// ARPOpRequest; "" = request)
+	ARPOperation string
+
+	// TxQueues is the number of TX queues of the transmitting device
+	// (0 = 4)
+	TxQueues int
+
+	// XPS enables transmit packet steering: the TX queue is taken from
+	// the sending CPU's XPS map instead of the flow hash
+	XPS bool
+
+	// CPU is the CPU the sender runs on (used by XPS)
+	CPU int
+
+	// RTTSample is the round-trip time, in microseconds, measured by an
+	// ingress pure ACK (0 = 20 ms)
+	RTTSample int
+
+	// IPOptions is the IPv4 option the ingress packet carries
+	// (see IPOptionRecordRoute; "" = none)
+	IPOptions string
+
+	// CorruptChecksum makes the ingress packet fail checksum validation
+	CorruptChecksum bool
+
+	// CorruptHeader selects the checksum that fails: "ip" (checked in
+	// ip_rcv) or "tcp" (checked in tcp_v4_rcv, the default)
+	CorruptHeader string
+}
+
+// DefaultSimulateOptions returns the options used for pre-computed simulations.
+func DefaultSimulateOptions() SimulateOptions {
+	return SimulateOptions{
+		BufferSize:  GetDefaultBufferSize(),
+		PayloadSize: GetDefaultPayloadSize(),
+	}
+}
+
+// StepAnnotation explains an outcome of the simulation at a particular step
+// that is not visible from the sk_buff state alone.
+type StepAnnotation struct {
+	// Kind classifies the annotation (e.g., "partial_copy", "backpressure")
+	Kind string `json:"kind"`
+
+	// Message is a human-readable explanation
+	Message string `json:"message"`
+}
+
+// Annotation kinds
+const (
+	AnnotationPartialCopy  = "partial_copy"
+	AnnotationBackpressure = "backpressure"
+	AnnotationClone        = "clone"
+	AnnotationZeroCopy     = "zerocopy"
+	AnnotationRouting      = "routing"
+)
+
+// SimulateWithOptions walks through the packet path using the given options.
+// The initial sk_buff is chosen from the path direction: egress starts with
+// the bare payload, ingress starts with the full packet as received.
+func (path *PacketPath) SimulateWithOptions(opts SimulateOptions) SimulateSteps {
+	return path.simulate(opts, path.initialSKBuff(opts))
+}
+
+// initialSKBuff returns the sk_buff a simulation of this path with the
+// given options starts with.
+func (path *PacketPath) initialSKBuff(opts SimulateOptions) *SKBuff {
+	bufferSize, payloadSize := opts.BufferSize, opts.PayloadSize
+	if path.Protocol == "ARP" {
+		return NewSKBuffForARP(bufferSize)
+	}
+	if path.Direction == "ingress" && opts.ICMPError != "" {
+		return NewSKBuffForICMPError(bufferSize, payloadSize)
+	}
+	if path.Direction == "ingress" || path.Direction == "forward" {
+		return NewSKBuffForIngress(bufferSize, payloadSize)
+	}
+	return NewSKBuffWithPayload(bufferSize, payloadSize)
+}
+
+// SimulateRange runs the full simulation and returns only the steps from
+// fromFnID through toFnID (inclusive). Because the whole path is simulated,
+// the first returned step carries the sk_buff state exactly as it is at
+// fromFnID. Returns an error if either function is not on the simulated
+// path or toFnID comes before fromFnID.
+func (path *PacketPath) SimulateRange(fromFnID, toFnID string, bufferSize, payloadSize int) (SimulateSteps, error) {
+	steps := path.SimulateWithOptions(SimulateOptions{
+		BufferSize:  bufferSize,
+		PayloadSize: payloadSize,
+	})
+
+	from, to := -1, -1
+	for i, step := range steps {
+		if from < 0 && step.Function.ID == fromFnID {
+			from = i
+		}
+		if step.Function.ID == toFnID {
+			to = i
+		}
+	}
+
+	switch {
+	case from < 0:
+		return nil, fmt.Errorf("function %q is not on the simulated path of %s", fromFnID, path.ID)
+	case to < 0:
+		return nil, fmt.Errorf("function %q is not on the simulated path of %s", toFnID, path.ID)
+	case to < from:
+		return nil, fmt.Errorf("function %q comes before %q on the simulated path of %s", toFnID, fromFnID, path.ID)
+	}
+	return steps[from : to+1], nil
+}
+
+// simContext holds the mutable state threaded through a simulation run.
+type simContext struct {
+	opts SimulateOptions
+	skb  *SKBuff
+	flow FlowKey
+
+	// direction is the simulated path's direction ("egress", "ingress" or
+	// "forward")
+	direction string
+
+	// family is the simulated path's address family (see FamilyIPv4)
+	family string
+
+	// conntrack is the current connection tracking entry
+	conntrack *ConntrackEntry
+
+	// writeQueue is the socket write queue (nil until data is enqueued)
+	writeQueue *WriteQueue
+
+	// queueSnapshot is the copy of writeQueue at queueVersion that steps
+	// share until the queue changes (nil before the first step with a
+	// queue)
+	queueSnapshot *WriteQueue
+	queueVersion  int
+
+	// memory is the socket memory accounting (nil until the first charge)
+	memory *SocketMemory
+
+	// listenQueue is the listening socket's SYN and accept queues (nil
+	// unless the packet is handled by a listener)
+	listenQueue *ListenQueue
+
+	// socketLock is the state of the socket lock (see SocketLockOwned; ""
+	// until the socket is first locked)
+	socketLock string
+
+	// counters are the modeled SNMP error counters (nil until incremented)
+	counters map[string]int
+
+	// dropReason is the reason recorded when the packet reaches a drop point
+	dropReason string
+
+	// branches maps a function ID to the callee the simulation should
+	// follow instead of the default edge
+	branches map[string]branchChoice
+
+	// forced maps a function ID to the callee the simulation follows in
+	// place of any branch, to explore the other edges (see SimulateAll)
+	forced map[string]string
+
+	// segments are the sk_buffs still to be sent after the current one when
+	// a payload is sent as separate segments, out of segmentTotal
+	segments     []SKBuff
+	segmentTotal int
+
+	// steps accumulates the emitted simulation steps
+	steps SimulateSteps
+}
+
+// count increments a modeled SNMP counter.
+func (ctx *simContext) count(name string) {
+	if ctx.counters == nil {
+		ctx.counters = make(map[string]int)
+	}
+	ctx.counters[name]++
+}
+
+// branchChoice is a non-default edge selected for the simulation.
+type branchChoice struct {
+	// to is the callee to follow
+	to string
+
+	// reason explains what selected the edge, for ExplainBranch
+	reason string
+}
+
+// branch makes the simulation follow the edge from fromID to toID instead
+// of the default edge. Effects may call it to branch on the state at their
+// step.
+func (ctx *simContext) branch(fromID, toID, reason string) {
+	ctx.branches[fromID] = branchChoice{to: toID, reason: reason}
+}
+
+// configureBranches selects the non-default edges implied by the options.
+func (ctx *simContext) configureBranches() {
+	if ctx.opts.Mark != 0 {
+		ctx.branch("ip_queue_xmit", "fib_rules_lookup", fmt.Sprintf("skb->mark is %d", ctx.opts.Mark))
+	}
+	if ctx.opts.ZeroCopy {
+		ctx.branch("tcp_sendmsg_locked", "skb_zerocopy_iter_stream", "the send uses MSG_ZEROCOPY")
+	}
+	if ctx.sendBufferFull() {
+		ctx.branch("tcp_sendmsg_locked", "sk_stream_wait_memory", "the payload does not fit in the send buffer")
+	}
+	if ctx.tsoDeferred() {
+		ctx.branch("tcp_write_xmit", "tcp_tso_should_defer", "the congestion window allows only a small part of the TSO sk_buff")
+	} else if ctx.tsqThrottled() {
+		ctx.branch("tcp_write_xmit", "tcp_small_queue_check", "the socket's bytes in the qdisc and driver exceed the TSQ limit")
+	}
+	if ctx.opts.DeviceBusy {
+		ctx.branch("sch_direct_xmit", "dev_requeue_skb", "the driver returns NETDEV_TX_BUSY")
+	}
+	if ctx.direction == "forward" && ctx.gso() {
+		ctx.branch("__dev_queue_xmit", "validate_xmit_skb", "the forwarded GRO packet is a GSO packet")
+	}
+	switch ctx.opts.IPOptions {
+	case "":
+	case IPOptionSourceRoute, IPOptionInvalid:
+		ctx.branch("ip_rcv_finish", "ip_rcv_options", "the IP header has options")
+		ctx.branch("ip_rcv_options", "kfree_skb", fmt.Sprintf("the %s option is rejected", ctx.opts.IPOptions))
+	default:
+		ctx.branch("ip_rcv_finish", "ip_rcv_options", "the IP header has options")
+	}
+	if ctx.opts.BridgeFDB == BridgeFDBUnknown {
+		ctx.branch("br_handle_frame_finish", "br_flood", "the destination MAC is not in the FDB")
+	}
+	if ctx.arpOperation() == ARPOpReply {
+		ctx.branch("neigh_update", "neigh_output", "the ARP reply resolves an entry with packets queued")
+	}
+	if ctx.coalescing() {
+		ctx.branch("tcp_queue_rcv", "tcp_try_coalesce", "the tail sk_buff of the receive queue can absorb the data")
+	}
+	if ctx.pureAck() {
+		ctx.branch("tcp_rcv_established", "tcp_ack", "the segment is a pure ACK (no payload)")
+	}
+	if ctx.opts.FragmentCount > 1 {
+		ctx.branch("ip_local_deliver", "ip_defrag", fmt.Sprintf("the packet arrives in %d fragments", ctx.opts.FragmentCount))
+	}
+	if gso := ctx.opts.UDPSegment; gso > 0 && ctx.opts.PayloadSize > gso &&
+		udpGSOSegments(ctx.opts.PayloadSize, gso) <= udpMaxSegments {
+		ctx.branch("__dev_queue_xmit", "validate_xmit_skb", "the UDP_SEGMENT send is a GSO packet")
+	}
+	if verdict := ctx.opts.TCIngress; verdict != "" {
+		ctx.branch("__netif_receive_skb_core", "sch_handle_ingress", "an ingress qdisc with filters is attached")
+		switch verdict {
+		case TCActRedirect:
+			ctx.branch("sch_handle_ingress", "skb_do_redirect", "a filter returns TC_ACT_REDIRECT")
+		case TCActShot:
+			ctx.branch("sch_handle_ingress", "kfree_skb", "a filter returns TC_ACT_SHOT")
+		}
+	}
+	switch ctx.opts.GenericXDP {
+	case XDPVerdictPass:
+		ctx.branch("__netif_receive_skb_core", "do_xdp_generic", "a generic XDP program is attached")
+		if ctx.opts.TCIngress != "" {
+			ctx.branch("do_xdp_generic", "sch_handle_ingress", "the XDP program returns XDP_PASS and an ingress qdisc is attached")
+		}
+	case XDPVerdictDrop:
+		ctx.branch("__netif_receive_skb_core", "do_xdp_generic", "a generic XDP program is attached")
+		ctx.branch("do_xdp_generic", "kfree_skb", "the XDP program returns XDP_DROP")
+	}
+	if ctx.immediateAck() {
+		ctx.branch("__tcp_ack_snd_check", "tcp_send_ack", "the receiver is in quick-ACK mode or more than one full segment is unacknowledged")
+	}
+	if ctx.opts.GROFlush != "" {
+		ctx.branch("napi_gro_receive", "napi_gro_complete", fmt.Sprintf("GRO coalesces the packet and flushes it (%s)", ctx.opts.GROFlush))
+	}
+	if ctx.opts.SocketOwnedByUser {
+		ctx.branch("tcp_filter", "sk_add_backlog", "a process owns the socket (lock_sock)")
+	}
+	switch ctx.opts.SocketLookup {
+	case SocketLookupListen:
+		ctx.branch("tcp_v4_do_rcv", "tcp_rcv_state_process", "the socket lookup found a listener")
+	case SocketLookupSynRecv:
+		ctx.branch("tcp_v4_rcv", "tcp_check_req", "the socket lookup found a request sock (SYN_RECV)")
+		if ctx.listenQueue.AcceptQueueFull() {
+			ctx.branch("tcp_v4_syn_recv_sock", "kfree_skb", "the listener's accept queue is full")
+		}
+	case SocketLookupTimeWait:
+		ctx.branch("tcp_v4_rcv", "tcp_timewait_state_process", "the socket lookup found a TIME_WAIT socket")
+	case SocketLookupNone:
+		ctx.branch("tcp_v4_rcv", "tcp_v4_send_reset", "the socket lookup found no socket")
+	}
+	if ctx.opts.SocketFilter == SocketFilterDeny {
+		ctx.branch("tcp_filter", "kfree_skb", "the socket filter denies the packet")
+	}
+	if kind := ctx.opts.ICMPError; kind != "" {
+		ctx.branch("ip_protocol_deliver_rcu", "icmp_rcv", "the packet is an ICMP error quoting a TCP segment")
+		lookup := ctx.opts.SocketLookup
+		if kind == ICMPErrorFragNeeded && (lookup == "" || lookup == SocketLookupEstablished) && !ctx.opts.SocketOwnedByUser {
+			ctx.branch("tcp_v4_err", "tcp_v4_mtu_reduced", "the error reports a smaller MTU for an established connection not owned by a process")
+		}
+	}
+	// A bad checksum is detected before the socket lookup
+	if ctx.opts.CorruptChecksum {
+		validator := checksumValidator(ctx.opts.CorruptHeader)
+		ctx.branch(validator, "kfree_skb", fmt.Sprintf("the checksum verified by %s is invalid", validator))
+	}
+}
+
+// branched reports whether the simulation leaves a function by a
+// configured or forced branch rather than its default edge.
+func (ctx *simContext) branched(fromID string) bool {
+	_, configured := ctx.branches[fromID]
+	_, forced := ctx.forced[fromID]
+	return configured || forced
+}
+
+// nextEdge picks the edge to follow out of a function: a configured branch
+// if one matches, otherwise the first non-error edge.
+func (ctx *simContext) nextEdge(fromID string, edges []FunctionEdge) *FunctionEdge {
+	if to, ok := ctx.forced[fromID]; ok {
+		for i := range edges {
+			if edges[i].To == to {
+				return &edges[i]
+			}
+		}
+	}
+	if choice, ok := ctx.branches[fromID]; ok {
+		for i := range edges {
+			if edges[i].To == choice.to {
+				return &edges[i]
+			}
+		}
+	}
+	for i := range edges {
+		if !edges[i].IsErrorPath {
+			return &edges[i]
+		}
+	}
+	return nil
+}
+
+// emit snapshots the current simulation state into step and appends it.
+// Effects may call emit directly to record intermediate states within a
+// single function.
+func (ctx *simContext) emit(step SimulateStep) {
+	step.StepNumber = len(ctx.steps) + 1
+	step.SKBuffState = *ctx.skb.Clone()
+	if q := ctx.writeQueue; q != nil {
+		// Copying the whole queue at every step would cost as much as the
+		// queue is long, and a payload sent as many segments grows it
+		if ctx.queueSnapshot == nil || ctx.queueVersion != q.version {
+			ctx.queueSnapshot, ctx.queueVersion = q.Clone(), q.version
+		}
+		step.WriteQueue = ctx.queueSnapshot
+	}
+	if ctx.memory != nil {
+		step.SocketMemory = ctx.memory.Clone()
+	}
+	if ctx.listenQueue != nil {
+		step.ListenQueue = ctx.listenQueue.Clone()
+	}
+	step.SocketLock = ctx.socketLock
+	if ctx.counters != nil {
+		step.ErrorCounters = make(map[string]int, len(ctx.counters))
+		for name, n := range ctx.counters {
+			step.ErrorCounters[name] = n
+		}
+	}
+	ctx.steps = append(ctx.steps, step)
+}
+
+// stepEffect applies function-specific behavior that is not captured by the
+// declarative SKBMutation. It runs after the mutation and before the step's
+// sk_buff snapshot is taken.
+type stepEffect func(ctx *simContext, step *SimulateStep)
+
+// stepEffects maps function IDs to their simulation effects, applied in order.
+var stepEffects = map[string][]stepEffect{
+	// Egress
+	"tcp_sendmsg":              {effectLockSock},
+	"tcp_sendmsg_locked":       {effectSendLocked, effectSendBufferLimit, effectFCloneAlloc, effectWmemCharge, effectWriteQueueEnqueue},
+	"skb_zerocopy_iter_stream": {effectZeroCopy},
+	"sk_stream_wait_memory":    {effectWaitMemory},
+	"tcp_write_xmit":           {effectTCPSegments, effectWriteQueueTransmit, effectTSOSegs},
+	"__tcp_transmit_skb":       {effectResetTransportHeader, effectTransmitClone, effectPseudoHeader},
+	"ip_queue_xmit":            {effectResetNetworkHeader, effectRouteLookup},
+	"ip_send_skb":              {effectResetNetworkHeader},
+	"inet6_csk_xmit":           {effectRouteLookup},
+	"ip6_xmit":                 {effectResetNetworkHeader, effectIPv6Header},
+	"esp_output":               {effectResetTransportHeader},
+	"xfrm4_tunnel_encap_add":   {effectResetNetworkHeader},
+	"__ip_local_out":           {effectNAT},
+	"ip_output":                {effectNAT, effectConntrackHelper},
+	"fib_rules_lookup":         {effectPolicyRouting},
+	"tcp_tso_should_defer":     {effectTSODefer},
+	"tcp_small_queue_check":    {effectTSQThrottle},
+	"__dev_xmit_skb":           {effectQdiscBacklog},
+	"dev_requeue_skb":          {effectRequeue},
+	"ndo_start_xmit":           {effectWmemRelease},
+	"__ip_finish_output":       {effectIPOutputDecision},
+	"ip_fragment":              {effectFragment},
+	"udp_sendmsg":              {effectRouteLookup},
+	"ip_make_skb":              {effectIPMaxDatagram},
+	"udp_send_skb":             {effectResetTransportHeader, effectUDPGSO, effectPseudoHeader},
+	"__udp_gso_segment":        {effectUDPGSOSegment},
+	"__skb_gso_segment":        {effectGSOSegment},
+
+	// Ingress
+	"napi_gro_receive":              {effectFlowHash},
+	"napi_gro_complete":             {effectGROFlush, effectGROGSO},
+	"do_xdp_generic":                {effectGenericXDP},
+	"__netif_receive_skb_core":      {effectReceiveHeaders},
+	"deliver_skb":                   {effectPacketTapFanout},
+	"ip_rcv":                        {effectIPTransportHeader, effectTapReferencesReleased, effectChecksumError, effectNAT},
+	"ip_rcv_finish":                 {effectRouteLookup},
+	"ip_rcv_options":                {effectIPOptions},
+	"ip_defrag":                     {effectIPDefrag},
+	"ip_local_deliver_finish":       {effectConntrackHelper},
+	"tcp_v4_err":                    {effectTCPv4Err},
+	"tcp_v4_mtu_reduced":            {effectMTUReduced},
+	"tcp_v4_rcv":                    {effectChecksumError, effectBHLockSock},
+	"sk_add_backlog":                {effectBacklogged},
+	"release_sock":                  {effectReleaseSock},
+	"tcp_filter":                    {effectSocketFilter},
+	"tcp_conn_request":              {effectConnRequest},
+	"inet_csk_reqsk_queue_hash_add": {effectSynQueueAdd},
+	"cookie_v4_init_sequence":       {effectSynCookie},
+	"tcp_v4_syn_recv_sock":          {effectAcceptQueueCheck},
+	"inet_csk_complete_hashdance":   {effectCompleteHashdance},
+	"inet_csk_accept":               {effectAccept},
+	"tcp_timewait_state_process":    {effectTimeWait},
+	"tcp_v4_send_reset":             {effectSendReset},
+	"kfree_skb":                     {effectDrop},
+	"br_handle_frame_finish":        {effectFDBLookup},
+	"br_forward":                    {effectBridgeForward},
+	"br_flood":                      {effectBridgeFlood},
+	"neigh_update":                  {effectNeighUpdate},
+	"arp_send_dst":                  {effectARPReply},
+	"__dev_queue_xmit":              {effectResetMacHeader, effectPickTx},
+	"tcp_ack":                       {effectTCPAck},
+	"tcp_clean_rtx_queue":           {effectCleanRtxQueue},
+	"tcp_ack_update_rtt":            {effectRTTSample},
+	"tcp_queue_rcv":                 {effectRecvBufferLimit, effectRmemCharge},
+	"sch_handle_ingress":            {effectTCIngress},
+	"skb_do_redirect":               {effectTCRedirect},
+	"tcp_try_coalesce":              {effectTryCoalesce},
+	"sk_data_ready":                 {effectRmemRelease},
+	"tcp_send_delayed_ack":          {effectDelayedAck},
+	"tcp_send_ack":                  {effectQuickAck},
+
+	// Teardown
+	"tcp_close":             {effectTCPClose},
+	"tcp_send_fin":          {effectSendFIN},
+	"tcp_send_active_reset": {effectActiveReset},
+	"tcp_rcv_state_process": {effectFinAckReceived},
+	"tcp_fin":               {effectTCPFin},
+	"tcp_time_wait":         {effectTCPTimeWait},
+	"inet_twsk_kill":        {effectTimeWaitKill},
+}
+
+// simulate is the shared simulation loop for all directions.
+func (path *PacketPath) simulate(opts SimulateOptions, skb *SKBuff) SimulateSteps {
+	return path.run(opts, skb, nil).steps
+}
+
+// run simulates the path and returns the final simulation state, including
+// the branches taken. forced maps function IDs to callees the simulation
+// follows whatever the options select (nil = none).
+func (path *PacketPath) run(opts SimulateOptions, skb *SKBuff, forced map[string]string) *simContext {
+	graph := NewFunctionGraph(path)
+
+	ctx := &simContext{
+		opts:        opts,
+		direction:   path.Direction,
+		family:      path.Family,
+		skb:         skb,
+		flow:        opts.Flow,
+		conntrack:   NewConntrackEntry(initialConntrackState(opts.SocketLookup)),
+		listenQueue: newListenQueue(opts),
+		branches:    make(map[string]branchChoice),
+		forced:      forced,
+		steps:       SimulateSteps{},
+	}
+	if ctx.flow.IsZero() {
+		ctx.flow = DefaultFlowKey()
+		if path.Family == FamilyIPv6 {
+			ctx.flow = DefaultFlowKeyIPv6()
+		}
+	}
+	ctx.configureBranches()
+	ctx.skb.Mark = opts.Mark
+
+	if path.Direction == "ingress" && opts.FragmentCount > 1 {
+		trimToFirstFragment(ctx.skb, opts)
+	}
+	if path.Direction != "egress" && opts.IPOptions != "" {
+		addIPOptions(ctx.skb, opts.IPOptions)
+	}
+
+	// Start at entry point
+	currentID := path.EntryPoint
+
+	visited := make(map[string]bool)
+
+	for currentID != "" && !visited[currentID] {
+		visited[currentID] = true
+
+		fn := graph.GetFunction(currentID)
+		if fn == nil {
+			break
+		}
+
+		// Apply mutation if present
+		if fn.SKBMutation != nil {
+			switch fn.SKBMutation.Operation {
+			case "push":
+				ctx.skb.Push(fn.SKBMutation.HeaderType, fn.SKBMutation.Size)
+			case "pull":
+				// Variable-length headers (IP options) are pulled whole
+				size := fn.SKBMutation.Size
+				if len(ctx.skb.Layers) > 0 && ctx.skb.Layers[0].Protocol == fn.SKBMutation.HeaderType {
+					size = ctx.skb.Layers[0].Size
+				}
+				ctx.skb.Pull(size)
+			case "put":
+				ctx.skb.Put(fn.SKBMutation.Size)
+			case "encrypt":
+				ctx.skb.Encrypt(fn.SKBMutation.HeaderType)
+			case "csum":
+				ctx.skb.SetCsum(fn.SKBMutation.CsumState, fn.SKBMutation.HeaderType)
+			}
+		}
+
+		step := SimulateStep{
+			Function:       *fn,
+			ConntrackState: ctx.conntrack,
+		}
+		if fn.ConntrackEvent != "" {
+			ctx.conntrackEvent(&step, fn.ConntrackEvent)
+		}
+		for _, effect := range stepEffects[fn.ID] {
+			effect(ctx, &step)
+		}
+		ctx.emit(step)
+
+		// Follow the configured branch, or the first non-error edge
+		next := ctx.nextEdge(currentID, graph.GetOutgoingEdges(currentID))
+		currentID = ""
+		if next != nil {
+			currentID = next.To
+		} else if len(ctx.segments) > 0 && ctx.dropReason == "" {
+			// The next segment goes down the same functions again
+			currentID = ctx.sendNextSegment(graph)
+			visited = map[string]bool{segmentingFunction: true}
+		}
+	}
+
+	ctx.releaseSocketLock()
+	return ctx
+}
+
+// annotate appends an annotation to the step.
+func (step *SimulateStep) annotate(kind, message string) {
+	step.Annotations = append(step.Annotations, StepAnnotation{Kind: kind, Message: message})
+}
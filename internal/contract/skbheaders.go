@@ -0,0 +1,74 @@
+package contract
+
+// Header offsets record where the kernel last marked each header, as
+// offsets from Head, independently of Data. A layer sets its header
+// offset when it builds or parses its header, so upper layers still find
+// their headers after lower ones are pulled, and lower layers find the
+// packet's start after upper ones pushed theirs.
+
+// headerOffset returns a new offset value for a header field.
+func headerOffset(offset int) *int {
+	return &offset
+}
+
+// ResetMacHeader marks the current Data position as the link-layer header
+// (skb_reset_mac_header).
+func (s *SKBuff) ResetMacHeader() {
+	s.MacHeader = headerOffset(s.Data - s.Head)
+}
+
+// ResetNetworkHeader marks the current Data position as the network
+// header (skb_reset_network_header).
+func (s *SKBuff) ResetNetworkHeader() {
+	s.NetworkHeader = headerOffset(s.Data - s.Head)
+}
+
+// ResetTransportHeader marks the current Data position as the transport
+// header (skb_reset_transport_header).
+func (s *SKBuff) ResetTransportHeader() {
+	s.TransportHeader = headerOffset(s.Data - s.Head)
+}
+
+// SetTransportHeader marks the transport header offset bytes past Data
+// (skb_set_transport_header).
+func (s *SKBuff) SetTransportHeader(offset int) {
+	s.TransportHeader = headerOffset(s.Data - s.Head + offset)
+}
+
+// effectResetMacHeader models skb_reset_mac_header once the link-layer
+// header is in front of Data, as __dev_queue_xmit does before queueing.
+func effectResetMacHeader(ctx *simContext, step *SimulateStep) {
+	ctx.skb.ResetMacHeader()
+}
+
+// effectResetNetworkHeader models skb_reset_network_header right after
+// the IP header is pushed.
+func effectResetNetworkHeader(ctx *simContext, step *SimulateStep) {
+	ctx.skb.ResetNetworkHeader()
+}
+
+// effectResetTransportHeader models skb_reset_transport_header right after
+// the transport (or ESP) header is pushed.
+func effectResetTransportHeader(ctx *simContext, step *SimulateStep) {
+	ctx.skb.ResetTransportHeader()
+}
+
+// effectReceiveHeaders models __netif_receive_skb_core: with the Ethernet
+// header pulled by eth_type_trans, Data is at the network header, and the
+// transport header is provisionally set there too until IP parses its
+// header.
+func effectReceiveHeaders(ctx *simContext, step *SimulateStep) {
+	ctx.skb.ResetNetworkHeader()
+	if ctx.skb.TransportHeader == nil {
+		ctx.skb.ResetTransportHeader()
+	}
+}
+
+// effectIPTransportHeader models ip_rcv_core pointing the transport header
+// past the IP header and its options, while Data still points at the IP
+// header.
+func effectIPTransportHeader(ctx *simContext, step *SimulateStep) {
+	if ip, ok := ctx.skb.Header("ip"); ok {
+		ctx.skb.SetTransportHeader(ip.Offset + ip.Size)
+	}
+}
@@ -42,6 +42,63 @@ type SKBuff struct {
 	// Layers tracks which protocol headers are currently present
 	// in the buffer, in order from outermost to innermost.
 	Layers []ProtocolHeader `json:"layers"`
+
+	// FClone indicates the sk_buff was allocated from the fclone cache,
+	// which reserves a companion slot so one clone can be made cheaply.
+	FClone bool `json:"fclone,omitempty"`
+
+	// Cloned indicates this sk_buff is a clone sharing its data buffer
+	// with another sk_buff.
+	Cloned bool `json:"cloned,omitempty"`
+
+	// ZeroCopy indicates the payload references pinned user pages rather
+	// than a kernel copy of the data (MSG_ZEROCOPY)
+	ZeroCopy bool `json:"zeroCopy,omitempty"`
+
+	// PayloadEncrypted indicates the payload is ciphertext (e.g., after ESP)
+	PayloadEncrypted bool `json:"payloadEncrypted,omitempty"`
+
+	// Mark is skb->mark, set by SO_MARK, iptables MARK or tc (0 = unmarked)
+	Mark uint32 `json:"mark,omitempty"`
+
+	// Users is the skb->users reference count while it is shared
+	// (0 = not modeled, the sk_buff has a single owner)
+	Users int `json:"users,omitempty"`
+
+	// FlowHash is skb->hash, computed by flow dissection (0 if not yet set)
+	FlowHash uint32 `json:"flowHash,omitempty"`
+
+	// MacHeader is skb->mac_header, the offset from Head of the link-layer
+	// header (nil until set)
+	MacHeader *int `json:"macHeader,omitempty"`
+
+	// NetworkHeader is skb->network_header, the offset from Head of the
+	// network header (nil until set)
+	NetworkHeader *int `json:"networkHeader,omitempty"`
+
+	// TransportHeader is skb->transport_header, the offset from Head of
+	// the transport header (nil until set)
+	TransportHeader *int `json:"transportHeader,omitempty"`
+
+	// GSO is the segmentation metadata of a GSO sk_buff (nil = sent as a
+	// single packet)
+	GSO *GSOInfo `json:"gso,omitempty"`
+
+	// SharedInfo holds the packet data outside the linear buffer (nil =
+	// linear sk_buff, all the data is between Data and Tail)
+	SharedInfo *SharedInfo `json:"sharedInfo,omitempty"`
+
+	// CsumState is skb->ip_summed, the state of the transport checksum
+	// (see CsumPartial; "" = CHECKSUM_NONE, the zero value)
+	CsumState string `json:"csumState,omitempty"`
+
+	// CsumStart is csum_start, the offset from Head where checksumming
+	// starts: the transport header (CHECKSUM_PARTIAL only)
+	CsumStart int `json:"csumStart,omitempty"`
+
+	// CsumOffset is csum_offset, the offset from CsumStart of the checksum
+	// field the result is stored in (CHECKSUM_PARTIAL only)
+	CsumOffset int `json:"csumOffset,omitempty"`
 }
 
 // ProtocolHeader represents a single protocol header within the sk_buff.
@@ -54,6 +111,10 @@ type ProtocolHeader struct {
 
 	// Size is the header size in bytes
 	Size int `json:"size"`
+
+	// Encrypted indicates the header is ciphertext and cannot be parsed
+	// by anything on the path until it is decrypted
+	Encrypted bool `json:"encrypted,omitempty"`
 }
 
 // NewSKBuff creates a new sk_buff with the specified total buffer size.
@@ -143,6 +204,22 @@ func (s *SKBuff) Put(size int) bool {
 	return true
 }
 
+// Encrypt marks every header after the first header of the given protocol,
+// and the payload, as encrypted. Returns false if no such header is present.
+func (s *SKBuff) Encrypt(protocol string) bool {
+	for i := range s.Layers {
+		if s.Layers[i].Protocol != protocol {
+			continue
+		}
+		for j := i + 1; j < len(s.Layers); j++ {
+			s.Layers[j].Encrypted = true
+		}
+		s.PayloadEncrypted = true
+		return true
+	}
+	return false
+}
+
 // Headroom returns the available space before the Data pointer.
 func (s *SKBuff) Headroom() int {
 	return s.Data - s.Head
@@ -153,20 +230,54 @@ func (s *SKBuff) Tailroom() int {
 	return s.End - s.Tail
 }
 
-// Len returns the current packet length (Data to Tail).
+// Len returns the current packet length: Data to Tail, plus the data
+// outside the linear buffer of a non-linear sk_buff.
 func (s *SKBuff) Len() int {
-	return s.Tail - s.Data
+	return s.Tail - s.Data + s.DataLen()
+}
+
+// Header returns the outermost header of the given protocol currently in
+// the buffer. The returned pointer refers to the entry in Layers. For
+// tunneled packets, where a protocol can appear more than once (outer and
+// inner IP), use Headers.
+func (s *SKBuff) Header(protocol string) (*ProtocolHeader, bool) {
+	for i := range s.Layers {
+		if s.Layers[i].Protocol == protocol {
+			return &s.Layers[i], true
+		}
+	}
+	return nil, false
+}
+
+// Headers returns every header of the given protocol currently in the
+// buffer, outermost first. The pointers refer to the entries in Layers.
+func (s *SKBuff) Headers(protocol string) []*ProtocolHeader {
+	var headers []*ProtocolHeader
+	for i := range s.Layers {
+		if s.Layers[i].Protocol == protocol {
+			headers = append(headers, &s.Layers[i])
+		}
+	}
+	return headers
+}
+
+// HasLayer reports whether a header of the given protocol is present.
+func (s *SKBuff) HasLayer(protocol string) bool {
+	_, ok := s.Header(protocol)
+	return ok
 }
 
 // Clone creates a deep copy of the sk_buff.
 func (s *SKBuff) Clone() *SKBuff {
-	clone := &SKBuff{
-		Head:   s.Head,
-		Data:   s.Data,
-		Tail:   s.Tail,
-		End:    s.End,
-		Layers: make([]ProtocolHeader, len(s.Layers)),
-	}
+	clone := *s
+	clone.Layers = make([]ProtocolHeader, len(s.Layers))
 	copy(clone.Layers, s.Layers)
-	return clone
+	if s.GSO != nil {
+		gso := *s.GSO
+		clone.GSO = &gso
+	}
+	if s.SharedInfo != nil {
+		clone.SharedInfo = s.SharedInfo.Clone()
+	}
+	return &clone
 }
@@ -0,0 +1,83 @@
+package contract
+
+import "fmt"
+
+// effectSendBufferLimit models SO_SNDBUF enforcement in tcp_sendmsg_locked.
+// When the payload exceeds the send buffer, only the part that fits is
+// copied into the sk_buff; the rest is left to a later send() call.
+func effectSendBufferLimit(ctx *simContext, step *SimulateStep) {
+	limit := ctx.opts.SendBufferSize
+	payload := ctx.skb.Len()
+	if limit <= 0 || payload <= limit {
+		return
+	}
+
+	// Drop the uncopied bytes from the front of the payload
+	ctx.skb.Data += payload - limit
+
+	step.annotate(AnnotationPartialCopy, fmt.Sprintf(
+		"Send buffer full: only %d of %d bytes fit in SO_SNDBUF (%d). "+
+			"A blocking send() waits for space; a non-blocking send() returns a short write of %d bytes.",
+		limit, payload, limit, limit))
+}
+
+// effectRecvBufferLimit models SO_RCVBUF enforcement in tcp_queue_rcv.
+// The advertised receive window shrinks as the queue fills, and data that
+// does not fit is dropped.
+func effectRecvBufferLimit(ctx *simContext, step *SimulateStep) {
+	limit := ctx.opts.RecvBufferSize
+	if limit <= 0 {
+		return
+	}
+
+	payload := ctx.opts.PayloadSize
+	if payload > limit {
+		step.annotate(AnnotationBackpressure, fmt.Sprintf(
+			"Receive queue full: %d-byte segment exceeds SO_RCVBUF (%d) and is dropped. "+
+				"The advertised window closes to 0 until the application reads.",
+			payload, limit))
+		return
+	}
+
+	step.annotate(AnnotationBackpressure, fmt.Sprintf(
+		"Receive queue holds %d of %d bytes (SO_RCVBUF). Advertised window shrinks to %d bytes.",
+		payload, limit, limit-payload))
+}
+
+// sendBufferFull reports whether the simulated send does not fit in
+// SO_SNDBUF, so tcp_sendmsg_locked has to wait for memory.
+func (ctx *simContext) sendBufferFull() bool {
+	limit := ctx.opts.SendBufferSize
+	return limit > 0 && ctx.opts.PayloadSize > limit
+}
+
+// effectWaitMemory models sk_stream_wait_memory once the send buffer is
+// full. A blocking sender sleeps until tcp_wfree frees send memory; a
+// non-blocking one gets -EAGAIN, which tcp_sendmsg_locked turns into a
+// short write of the bytes already copied. Either way the copied bytes are
+// pushed, and the simulation follows them.
+func effectWaitMemory(ctx *simContext, step *SimulateStep) {
+	copied := ctx.skb.Len()
+	left := ctx.opts.PayloadSize - copied
+	wmem := 0
+	if ctx.memory != nil {
+		wmem = ctx.memory.WmemAlloc
+	}
+
+	if ctx.opts.NonBlocking {
+		step.annotate(AnnotationBackpressure, fmt.Sprintf(
+			"Non-blocking socket: sk_stream_wait_memory returns -EAGAIN immediately (sk_wmem_alloc %d, SO_SNDBUF %d). "+
+				"send() returns a short write of %d bytes; the other %d are left to the application, which polls for POLLOUT before retrying. "+
+				"Had nothing been copied, send() would fail with EAGAIN.",
+			wmem, ctx.opts.SendBufferSize, copied, left))
+		return
+	}
+
+	step.annotate(AnnotationBackpressure, fmt.Sprintf(
+		"Blocking socket: send() sleeps in sk_stream_wait_memory with %d bytes still to copy (sk_wmem_alloc %d, SO_SNDBUF %d). "+
+			"The bytes already copied are pushed first, so their transmit completion (tcp_wfree) can free memory and wake the sender via sk_stream_write_space. "+
+			"send() returns only once all %d bytes are copied, or SO_SNDTIMEO expires.",
+		left, wmem, ctx.opts.SendBufferSize, ctx.opts.PayloadSize))
+	step.annotate(AnnotationLocking,
+		"sk_wait_event releases the socket lock while sleeping, so release_sock processes the backlog and softirq can handle incoming ACKs, and retakes it on wakeup.")
+}
@@ -0,0 +1,52 @@
+package contract
+
+import "fmt"
+
+// Socket filter verdicts
+const (
+	// SocketFilterAllow passes the packet, optionally trimmed
+	SocketFilterAllow = "allow"
+
+	// SocketFilterDeny drops the packet for this socket
+	SocketFilterDeny = "deny"
+)
+
+// IsValidSocketFilterVerdict reports whether verdict is a known socket filter
+// verdict.
+func IsValidSocketFilterVerdict(verdict string) bool {
+	switch verdict {
+	case SocketFilterAllow, SocketFilterDeny:
+		return true
+	}
+	return false
+}
+
+// effectSocketFilter models sk_filter_trim_cap running the classic BPF
+// program attached with SO_ATTACH_FILTER. The program's return value is the
+// number of bytes to keep: 0 drops the packet, anything smaller than the
+// packet trims it, but never below the TCP header (the cap).
+func effectSocketFilter(ctx *simContext, step *SimulateStep) {
+	switch ctx.opts.SocketFilter {
+	case SocketFilterDeny:
+		ctx.dropReason = DropReasonSocketFilter
+		step.annotate(AnnotationDrop,
+			"Socket filter returned 0 (DENY): the packet is dropped for this socket only. "+
+				"Other sockets and AF_PACKET taps are unaffected, which is how pcap filters discard uninteresting traffic in the kernel.")
+	case SocketFilterAllow:
+		keep := ctx.opts.SocketFilterLen
+		if keep <= 0 || keep >= ctx.skb.Len() {
+			step.annotate(AnnotationRouting,
+				"Socket filter accepted the whole packet (ALLOW).")
+			return
+		}
+		if keep < TCPHeaderSize {
+			keep = TCPHeaderSize
+		}
+		trimmed := ctx.skb.Len() - keep
+		ctx.skb.Tail = ctx.skb.Data + keep
+		step.annotate(AnnotationRouting, fmt.Sprintf(
+			"Socket filter accepted %d bytes (ALLOW with snap length): the packet is trimmed by %d bytes. "+
+				"sk_filter_trim_cap never trims below the %d-byte TCP header.",
+			keep, trimmed, TCPHeaderSize))
+	}
+}
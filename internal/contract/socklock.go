@@ -0,0 +1,95 @@
+package contract
+
+// States of the socket lock (struct socket_lock_t): a spinlock taken by
+// softirq receive processing plus an "owned" flag that process context sets
+// with lock_sock to keep softirqs off the socket while it sleeps.
+const (
+	// SocketLockUnlocked means nobody holds the socket
+	SocketLockUnlocked = "unlocked"
+
+	// SocketLockSoftIRQ means softirq receive processing holds the
+	// spinlock (bh_lock_sock)
+	SocketLockSoftIRQ = "locked_softirq"
+
+	// SocketLockOwned means a process owns the socket (lock_sock); softirq
+	// receive processing must defer packets to the backlog
+	SocketLockOwned = "owned_by_user"
+)
+
+// AnnotationLocking marks socket lock transitions.
+const AnnotationLocking = "locking"
+
+// effectLockSock models tcp_sendmsg taking ownership of the socket with
+// lock_sock before calling tcp_sendmsg_locked.
+func effectLockSock(ctx *simContext, step *SimulateStep) {
+	ctx.socketLock = SocketLockOwned
+	step.annotate(AnnotationLocking,
+		"lock_sock: takes the socket spinlock, marks the socket owned by this process and drops the spinlock again. "+
+			"The owner may now sleep (waiting for memory, for instance); segments arriving for this socket meanwhile are queued on its backlog by softirq.")
+}
+
+// effectSendLocked notes that tcp_sendmsg_locked relies on the caller's
+// socket ownership.
+func effectSendLocked(ctx *simContext, step *SimulateStep) {
+	if ctx.socketLock != SocketLockOwned {
+		return
+	}
+	step.annotate(AnnotationLocking,
+		"Runs with the socket owned by the caller, so the write queue and send state cannot change under it. In-kernel users that already own the socket call this variant directly.")
+}
+
+// effectBHLockSock models tcp_v4_rcv taking the socket spinlock in softirq
+// with bh_lock_sock_nested and checking sock_owned_by_user.
+func effectBHLockSock(ctx *simContext, step *SimulateStep) {
+	if ctx.dropReason != "" {
+		return
+	}
+	switch ctx.opts.SocketLookup {
+	case SocketLookupNone, SocketLookupTimeWait:
+		// No full socket to lock
+		return
+	}
+	ctx.socketLock = SocketLockSoftIRQ
+	if ctx.opts.SocketOwnedByUser {
+		step.annotate(AnnotationLocking,
+			"bh_lock_sock_nested takes the socket spinlock, but sock_owned_by_user() is true: a process holds the socket with lock_sock, so the segment cannot be processed now.")
+		return
+	}
+	step.annotate(AnnotationLocking,
+		"bh_lock_sock_nested takes the socket spinlock; sock_owned_by_user() is false, so the segment is processed right away in softirq.")
+}
+
+// effectBacklogged models the segment waiting on the backlog until the
+// owning process releases the socket.
+func effectBacklogged(ctx *simContext, step *SimulateStep) {
+	step.annotate(AnnotationLocking,
+		"The segment is queued on sk->sk_backlog (charged against sk_rcvbuf + sk_sndbuf) and softirq drops the spinlock. Processing resumes in process context when the owner calls release_sock.")
+}
+
+// effectReleaseSock models the owning process releasing the socket and
+// processing the backlog under its ownership.
+func effectReleaseSock(ctx *simContext, step *SimulateStep) {
+	ctx.socketLock = SocketLockOwned
+	step.annotate(AnnotationLocking,
+		"The owner's release_sock finds the backlog non-empty and drains it before clearing ownership: the segment is now processed in process context, on the owner's CPU and time.")
+}
+
+// releaseSocketLock annotates the final step with the socket lock being
+// released as the call chain unwinds.
+func (ctx *simContext) releaseSocketLock() {
+	if len(ctx.steps) == 0 {
+		return
+	}
+	last := &ctx.steps[len(ctx.steps)-1]
+	switch ctx.socketLock {
+	case SocketLockOwned:
+		last.annotate(AnnotationLocking,
+			"As the call chain unwinds, release_sock processes any backlogged segments and clears ownership: the socket is unlocked.")
+	case SocketLockSoftIRQ:
+		last.annotate(AnnotationLocking,
+			"As tcp_v4_rcv returns, bh_unlock_sock drops the socket spinlock: the socket is unlocked.")
+	default:
+		return
+	}
+	ctx.socketLock = SocketLockUnlocked
+}
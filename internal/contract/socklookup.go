@@ -0,0 +1,87 @@
+package contract
+
+import "fmt"
+
+// Socket lookup results in tcp_v4_rcv
+const (
+	// SocketLookupEstablished finds the connection's full socket
+	SocketLookupEstablished = "established"
+
+	// SocketLookupListen finds only a listening socket (the segment is a SYN)
+	SocketLookupListen = "listen"
+
+	// SocketLookupSynRecv finds a request sock: the segment is the final
+	// ACK of a handshake the listener answered with a SYN-ACK
+	SocketLookupSynRecv = "syn_recv"
+
+	// SocketLookupTimeWait finds a TIME_WAIT mini socket
+	SocketLookupTimeWait = "timewait"
+
+	// SocketLookupNone finds no socket at all
+	SocketLookupNone = "none"
+)
+
+// IsValidSocketLookup reports whether lookup is a known socket lookup result.
+func IsValidSocketLookup(lookup string) bool {
+	switch lookup {
+	case SocketLookupEstablished, SocketLookupListen, SocketLookupSynRecv, SocketLookupTimeWait, SocketLookupNone:
+		return true
+	}
+	return false
+}
+
+// initialConntrackState returns the conntrack state of the flow as the
+// simulated packet arrives, given the socket lookup result.
+func initialConntrackState(lookup string) ConntrackState {
+	switch lookup {
+	case SocketLookupListen:
+		return ConntrackSynSent
+	case SocketLookupSynRecv:
+		return ConntrackSynRecv
+	case SocketLookupTimeWait:
+		return ConntrackTimeWait
+	case SocketLookupNone:
+		return ConntrackNew
+	}
+	// For TCP data transfer, connection is already established
+	return ConntrackEstablished
+}
+
+// effectConnRequest models the listener answering a SYN: a request sock is
+// queued and the SYN-ACK moves the conntrack entry to SYN_RECV.
+func effectConnRequest(ctx *simContext, step *SimulateStep) {
+	ctx.conntrack = NewConntrackEntry(ConntrackSynRecv)
+	step.ConntrackState = ctx.conntrack
+	if q := ctx.listenQueue; q != nil && q.SynQueueFull() {
+		ctx.count("TCPReqQFullDoCookies")
+		ctx.branch("tcp_conn_request", "cookie_v4_init_sequence", "the SYN queue is full")
+		step.annotate(AnnotationBackpressure, fmt.Sprintf(
+			"SYN queue full (%d request socks, backlog %d), typically a SYN flood: tcp_syn_flood_action logs "+
+				"\"Possible SYN flooding\" and the listener switches to SYN cookies (TCPReqQFullDoCookies).",
+			q.SynQueue, q.Backlog))
+		return
+	}
+	step.annotate(AnnotationRouting,
+		"Listening socket matched: a request sock is added to the SYN queue and a SYN-ACK is sent. "+
+			"No full socket exists yet; accept() sees the connection only after the final ACK completes the handshake.")
+}
+
+// effectTimeWait models a segment arriving for a connection in TIME_WAIT.
+func effectTimeWait(ctx *simContext, step *SimulateStep) {
+	ctx.dropReason = DropReasonTimeWait
+	step.annotate(AnnotationRouting,
+		"TIME_WAIT socket matched: the connection is closed, so the segment is re-ACKed (or reset) "+
+			"and discarded. A new SYN with a higher sequence number may instead reuse the port.")
+}
+
+// effectSendReset models tcp_v4_rcv finding no socket: TCP replies with a
+// RST and drops the segment.
+func effectSendReset(ctx *simContext, step *SimulateStep) {
+	ctx.count("TcpOutRsts")
+	ctx.dropReason = DropReasonNoSocket
+	ctx.conntrack = NewConntrackEntry(ConntrackClosed)
+	step.ConntrackState = ctx.conntrack
+	step.annotate(AnnotationDrop,
+		"No socket matches the 4-tuple: a RST is sent to the peer (TcpOutRsts incremented) and the segment is dropped. "+
+			"Unlike UDP, TCP does not answer with ICMP port unreachable.")
+}
@@ -0,0 +1,191 @@
+package contract
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Layout of the animated sk_buff diagram, in SVG user units.
+const (
+	svgWidth      = 800
+	svgHeight     = 240
+	svgMargin     = 40
+	svgBarY       = 80
+	svgBarHeight  = 56
+	svgStepSecs   = 1.2
+	svgMinLabelPx = 28
+)
+
+// svgProtocolColors are the fill colors of each protocol header in the
+// animated sk_buff diagram. Unknown protocols use svgDefaultHeaderColor.
+var svgProtocolColors = map[string]string{
+	"ethernet": "#f5b041",
+	"ip":       "#5dade2",
+	"ipv6":     "#48c9b0",
+	"tcp":      "#58d68d",
+	"udp":      "#af7ac5",
+	"icmp":     "#ec7063",
+	"esp":      "#f1948a",
+}
+
+const (
+	svgDefaultHeaderColor = "#bdc3c7"
+	svgPayloadColor       = "#d6eaf8"
+	svgEncryptedColor     = "#e8daef"
+	svgRoomColor          = "#f4f6f6"
+)
+
+// ToAnimatedSVG renders the simulation as a self-contained SVG that walks
+// the sk_buff diagram through each step: headroom, protocol headers,
+// payload and tailroom drawn to scale between Head and End. Each step is a
+// frame shown for a fixed time by a looping SMIL animation, so the file
+// animates in any browser without scripts; renderers without SMIL show the
+// first step.
+func (steps SimulateSteps) ToAnimatedSVG() ([]byte, error) {
+	if len(steps) == 0 {
+		return nil, errors.New("no simulation steps to render")
+	}
+
+	// Scale every frame to the largest buffer so frames are comparable
+	bufSize := 0
+	for _, step := range steps {
+		if n := step.SKBuffState.End - step.SKBuffState.Head; n > bufSize {
+			bufSize = n
+		}
+	}
+	if bufSize == 0 {
+		return nil, errors.New("sk_buff has no allocated buffer")
+	}
+	scale := float64(svgWidth-2*svgMargin) / float64(bufSize)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Helvetica, Arial, sans-serif">`+"\n",
+		svgWidth, svgHeight, svgWidth, svgHeight)
+	b.WriteString(`<rect width="100%" height="100%" fill="white"/>` + "\n")
+
+	total := svgStepSecs * float64(len(steps))
+	for i, step := range steps {
+		opacity := 0
+		if i == 0 {
+			opacity = 1
+		}
+		fmt.Fprintf(&b, `<g opacity="%d">`+"\n", opacity)
+		fmt.Fprintf(&b, `<animate attributeName="opacity" calcMode="discrete" dur="%gs" repeatCount="indefinite" %s/>`+"\n",
+			total, svgFrameTiming(i, len(steps)))
+		writeSVGFrame(&b, step, scale)
+		b.WriteString("</g>\n")
+	}
+
+	b.WriteString("</svg>\n")
+	return []byte(b.String()), nil
+}
+
+// svgFrameTiming returns the values and keyTimes attributes that make frame
+// i of n visible during its share of the animation loop.
+func svgFrameTiming(i, n int) string {
+	start := float64(i) / float64(n)
+	end := float64(i+1) / float64(n)
+	switch {
+	case n == 1:
+		return `values="1" keyTimes="0"`
+	case i == 0:
+		return fmt.Sprintf(`values="1;0" keyTimes="0;%.4f"`, end)
+	case i == n-1:
+		return fmt.Sprintf(`values="0;1" keyTimes="0;%.4f"`, start)
+	default:
+		return fmt.Sprintf(`values="0;1;0" keyTimes="0;%.4f;%.4f"`, start, end)
+	}
+}
+
+// writeSVGFrame draws one step: the function being executed, the buffer
+// regions and the four sk_buff pointers.
+func writeSVGFrame(b *strings.Builder, step SimulateStep, scale float64) {
+	skb := step.SKBuffState
+	x := func(offset int) float64 {
+		return svgMargin + float64(offset-skb.Head)*scale
+	}
+
+	fn := step.Function
+	svgText(b, svgMargin, 30, 16, "bold", fmt.Sprintf("Step %d: %s", step.StepNumber, fn.Name))
+	svgText(b, svgMargin, 52, 12, "normal", fmt.Sprintf("%s | len %d | headroom %d | tailroom %d",
+		fn.Layer, skb.Len(), skb.Headroom(), skb.Tailroom()))
+
+	// The buffer regions, laid out to this frame's share of the width
+	width := int(float64(skb.End-skb.Head)*scale + 0.5)
+	for _, rect := range skb.LayoutRects(width) {
+		svgRegion(b, svgMargin+rect.X, svgMargin+rect.X+rect.Width, svgRegionColor(rect), rect.Label)
+	}
+
+	// Pointers below the bar; data and tail are staggered so they stay
+	// readable when the packet is small
+	pointers := []struct {
+		name   string
+		offset int
+		row    int
+	}{
+		{"head", skb.Head, 0},
+		{"data", skb.Data, 1},
+		{"tail", skb.Tail, 2},
+		{"end", skb.End, 0},
+	}
+	for _, p := range pointers {
+		px := x(p.offset)
+		y := svgBarY + svgBarHeight + 16 + 14*p.row
+		fmt.Fprintf(b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#34495e"/>`+"\n",
+			px, svgBarY+svgBarHeight, px, y-10)
+		anchor := "middle"
+		if p.name == "head" {
+			anchor = "start"
+		} else if p.name == "end" {
+			anchor = "end"
+		}
+		fmt.Fprintf(b, `<text x="%.1f" y="%d" font-size="11" text-anchor="%s" fill="#34495e">%s=%d</text>`+"\n",
+			px, y, anchor, p.name, p.offset)
+	}
+
+	if len(step.Annotations) > 0 {
+		svgText(b, svgMargin, svgHeight-20, 11, "normal", step.Annotations[0].Message)
+	}
+}
+
+// svgRegionColor returns the fill color of a buffer region.
+func svgRegionColor(rect LayoutRect) string {
+	switch {
+	case strings.Contains(rect.Class, LayoutClassEncrypted):
+		return svgEncryptedColor
+	case strings.HasPrefix(rect.Class, LayoutClassHeader):
+		if color, ok := svgProtocolColors[rect.Label]; ok {
+			return color
+		}
+		return svgDefaultHeaderColor
+	case rect.Class == LayoutClassPayload:
+		return svgPayloadColor
+	default:
+		return svgRoomColor
+	}
+}
+
+// svgRegion draws one region of the buffer between x1 and x2, labeled if
+// it is wide enough to hold text.
+func svgRegion(b *strings.Builder, x1, x2 float64, fill, label string) {
+	if x2 <= x1 {
+		return
+	}
+	fmt.Fprintf(b, `<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s" stroke="#7f8c8d"/>`+"\n",
+		x1, svgBarY, x2-x1, svgBarHeight, fill)
+	if x2-x1 < svgMinLabelPx {
+		return
+	}
+	fmt.Fprintf(b, `<text x="%.1f" y="%d" font-size="11" text-anchor="middle">`, (x1+x2)/2, svgBarY+svgBarHeight/2+4)
+	xml.EscapeText(b, []byte(label))
+	b.WriteString("</text>\n")
+}
+
+// svgText writes a left-aligned, XML-escaped line of text.
+func svgText(b *strings.Builder, x, y, size int, weight, text string) {
+	fmt.Fprintf(b, `<text x="%d" y="%d" font-size="%d" font-weight="%s">`, x, y, size, weight)
+	xml.EscapeText(b, []byte(text))
+	b.WriteString("</text>\n")
+}
@@ -0,0 +1,36 @@
+package contract
+
+import "fmt"
+
+// effectPacketTapFanout models __netif_receive_skb_core delivering the
+// sk_buff to every ptype_all handler (AF_PACKET sniffers) before the IPv4
+// handler. Rather than copying, deliver_skb takes a reference for each
+// handler (refcount_inc on skb->users), so the sk_buff becomes shared.
+// Each packet_rcv then clones it and queues the clone on its own socket.
+func effectPacketTapFanout(ctx *simContext, step *SimulateStep) {
+	taps := ctx.opts.PacketTaps
+	if taps <= 0 {
+		return
+	}
+	ctx.skb.Users = 1 + taps
+
+	step.annotate(AnnotationClone, fmt.Sprintf(
+		"Fan-out to %d AF_PACKET tap(s): deliver_skb increments skb->users for each handler (users=%d). "+
+			"Because the sk_buff is shared, packet_rcv makes an skb_clone that shares the data buffer, "+
+			"queues the clone on its packet socket and drops its reference to the original.",
+		taps, ctx.skb.Users))
+}
+
+// effectTapReferencesReleased models ip_rcv's skb_share_check after the taps
+// have finished: every tap has dropped its reference, so IP sees an unshared
+// sk_buff and processes the original without copying it.
+func effectTapReferencesReleased(ctx *simContext, step *SimulateStep) {
+	if ctx.skb.Users <= 1 {
+		return
+	}
+	ctx.skb.Users = 1
+
+	step.annotate(AnnotationClone,
+		"All taps have released their references (users=1), so skb_share_check finds the sk_buff "+
+			"unshared and IP processes the original. Sniffing never alters what the stack receives.")
+}
@@ -0,0 +1,60 @@
+package contract
+
+import "fmt"
+
+// Verdicts of the classifiers attached to the ingress (clsact) qdisc
+const (
+	// TCActOK lets the packet continue up the stack (TC_ACT_OK)
+	TCActOK = "ok"
+
+	// TCActShot drops the packet (TC_ACT_SHOT)
+	TCActShot = "shot"
+
+	// TCActRedirect sends the packet out of another interface
+	// (TC_ACT_REDIRECT, from bpf_redirect or the mirred action)
+	TCActRedirect = "redirect"
+)
+
+// IsValidTCAction reports whether verdict is a known ingress classifier verdict.
+func IsValidTCAction(verdict string) bool {
+	switch verdict {
+	case TCActOK, TCActShot, TCActRedirect:
+		return true
+	}
+	return false
+}
+
+// DropReasonTCIngress is the drop reason for packets shot by an ingress
+// classifier.
+const DropReasonTCIngress = "TC_INGRESS"
+
+// tcRedirectDevice is the interface the modeled redirect sends packets out of.
+const tcRedirectDevice = "eth1"
+
+// effectTCIngress models sch_handle_ingress running the classifiers of the
+// ingress or clsact qdisc and acting on their verdict.
+func effectTCIngress(ctx *simContext, step *SimulateStep) {
+	const classify = "The ingress qdisc has no queue: tcf_classify runs its filters (cls_bpf, u32, flower, ...) in order on the sk_buff, " +
+		"which has been through GRO but not the IP layer. "
+	switch ctx.opts.TCIngress {
+	case TCActShot:
+		ctx.dropReason = DropReasonTCIngress
+		ctx.count("TCIngressDrop")
+		step.annotate(AnnotationDrop, classify+"A filter returns TC_ACT_SHOT: the packet is dropped and counted in the qdisc's drop statistics (tc -s qdisc show dev eth0 ingress).")
+	case TCActRedirect:
+		step.annotate(AnnotationRouting, classify+fmt.Sprintf(
+			"A filter returns TC_ACT_REDIRECT (bpf_redirect or the mirred action) with %s as the target: the packet leaves the receive path here, before the IP layer, routing or netfilter see it.",
+			tcRedirectDevice))
+	default:
+		step.annotate(AnnotationRouting, classify+"The filters return TC_ACT_OK: the packet continues to the protocol handlers unchanged.")
+	}
+}
+
+// effectTCRedirect models skb_do_redirect sending an ingress packet out of
+// another device.
+func effectTCRedirect(ctx *simContext, step *SimulateStep) {
+	step.annotate(AnnotationRouting, fmt.Sprintf(
+		"The Ethernet header pulled on receive is pushed back and skb->dev becomes %s. dev_queue_xmit then queues the frame on %s's egress qdisc, "+
+			"so it is transmitted without any L3 processing. This is how TC-based datapaths (Cilium, OVS offload fallbacks, container networking) move packets between interfaces.",
+		tcRedirectDevice, tcRedirectDevice))
+}
@@ -0,0 +1,106 @@
+package contract
+
+import "fmt"
+
+// defaultRTTSample is the round-trip time measured by a pure ACK when none
+// is given, in microseconds
+const defaultRTTSample = 20000
+
+// tcpRTOMin is TCP_RTO_MIN, the floor of the retransmission timeout's
+// variance term, in microseconds
+const tcpRTOMin = 200000
+
+// pureAckSegments is the number of in-flight segments a modeled pure ACK
+// acknowledges: one ACK per two full segments, as a delayed-ACK receiver
+// sends them.
+const pureAckSegments = 2
+
+// RTTEstimate is the socket's RTT state after an ACK supplies a sample.
+// All values are in microseconds.
+type RTTEstimate struct {
+	// Sample is the RTT measured from the acknowledged segment's send time
+	Sample int `json:"sample"`
+
+	// SRTT is the smoothed RTT (tp->srtt_us / 8)
+	SRTT int `json:"srtt"`
+
+	// RTTVar is the RTT variance term used for the timeout, at least
+	// TCP_RTO_MIN (tp->rttvar_us)
+	RTTVar int `json:"rttvar"`
+
+	// RTO is the retransmission timeout, SRTT + RTTVar
+	RTO int `json:"rto"`
+}
+
+// newRTTEstimate returns the estimate after the connection's first RTT
+// sample (tcp_rtt_estimator with no history): the smoothed RTT is the
+// sample itself and the variance term is 4 x sample/2, floored at
+// TCP_RTO_MIN.
+func newRTTEstimate(sample int) *RTTEstimate {
+	rttvar := 2 * sample
+	if rttvar < tcpRTOMin {
+		rttvar = tcpRTOMin
+	}
+	return &RTTEstimate{
+		Sample: sample,
+		SRTT:   sample,
+		RTTVar: rttvar,
+		RTO:    sample + rttvar,
+	}
+}
+
+// pureAck reports whether the ingress segment carries no data, so it is
+// handled by tcp_ack alone.
+func (ctx *simContext) pureAck() bool {
+	lookup := ctx.opts.SocketLookup
+	return ctx.direction == "ingress" && ctx.opts.PayloadSize == 0 &&
+		(lookup == "" || lookup == SocketLookupEstablished)
+}
+
+// effectTCPAck models tcp_ack finding segments of the socket's
+// retransmission queue acknowledged by the incoming ACK.
+func effectTCPAck(ctx *simContext, step *SimulateStep) {
+	if ctx.writeQueue == nil {
+		ctx.writeQueue = NewWriteQueue()
+		for i := 0; i < pureAckSegments; i++ {
+			ctx.writeQueue.InFlight = append(ctx.writeQueue.InFlight,
+				*NewSKBuffWithPayload(GetDefaultBufferSize(), tcpDefaultMSS))
+		}
+	}
+	acked := 0
+	for _, skb := range ctx.writeQueue.InFlight {
+		acked += skb.Len()
+	}
+	step.annotate(AnnotationRouting, fmt.Sprintf(
+		"The ACK number covers %d in-flight segments (%d bytes): snd_una advances and the send window is updated from the ACK's window field. "+
+			"Congestion control (tcp_cong_control) then grows cwnd for the newly acknowledged data.",
+		len(ctx.writeQueue.InFlight), acked))
+}
+
+// effectCleanRtxQueue models tcp_clean_rtx_queue freeing the acknowledged
+// segments, which no longer need to be kept for retransmission.
+func effectCleanRtxQueue(ctx *simContext, step *SimulateStep) {
+	if ctx.writeQueue == nil {
+		return
+	}
+	freed := ctx.writeQueue.release()
+	step.annotate(AnnotationRouting, fmt.Sprintf(
+		"%d acknowledged sk_buffs are unlinked from the retransmission queue and freed, returning their memory to the socket's send buffer "+
+			"and waking writers blocked on it. The send time of the newest one is kept as the RTT sample.",
+		freed))
+}
+
+// effectRTTSample models tcp_ack_update_rtt feeding the sample into the
+// RTT estimator and recomputing the retransmission timeout.
+func effectRTTSample(ctx *simContext, step *SimulateStep) {
+	sample := ctx.opts.RTTSample
+	if sample <= 0 {
+		sample = defaultRTTSample
+	}
+	step.RTT = newRTTEstimate(sample)
+	step.annotate(AnnotationRouting, fmt.Sprintf(
+		"RTT sample %d us (taken from the TCP timestamp echo, or the send time of a segment that was never retransmitted, per Karn's algorithm). "+
+			"As the first sample it sets srtt = %d us and rttvar = max(2 x sample, TCP_RTO_MIN) = %d us, so RTO = %d us. "+
+			"Later samples are smoothed in with gains of 1/8 and 1/4.",
+		sample, step.RTT.SRTT, step.RTT.RTTVar, step.RTT.RTO))
+}
@@ -0,0 +1,243 @@
+package contract
+
+import "fmt"
+
+// AnnotationConntrack marks conntrack state transitions.
+const AnnotationConntrack = "conntrack"
+
+// BuildTCPTeardownPath constructs the path of an active close: close() on
+// an established TCP/IPv4 connection sends a FIN down the egress path, the
+// peer answers with its own FIN, and the socket waits in TIME_WAIT until
+// its timer frees it. The functions where conntrack sees each segment carry
+// its conntrack event, which moves the entry from ESTABLISHED to FIN_WAIT,
+// LAST_ACK and TIME_WAIT, each state with its default timeout.
+func BuildTCPTeardownPath() *PacketPath {
+	egress := BuildTCPIPv4EgressPath()
+	ingress := BuildTCPIPv4IngressPath()
+
+	closing := []KernelFunction{
+		{
+			ID:               "tcp_close",
+			Name:             "tcp_close",
+			Layer:            LayerSocket,
+			SourceFile:       "net/ipv4/tcp.c",
+			LineNumber:       2560,
+			ExecutionContext: ContextProcess,
+			Description:      "close() on the last reference to the socket. Frees unread data and, through tcp_close_state, moves an established socket to FIN_WAIT1 and sends a FIN.",
+			IsEntryPoint:     true,
+		},
+		{
+			ID:               "tcp_send_fin",
+			Name:             "tcp_send_fin",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_output.c",
+			LineNumber:       3333,
+			ExecutionContext: ContextProcess,
+			Description:      "Queues a FIN: sets TCPHDR_FIN on the last unsent sk_buff if there is one, otherwise allocates an empty sk_buff for it, and pushes it out with __tcp_push_pending_frames.",
+		},
+		{
+			ID:               "tcp_send_active_reset",
+			Name:             "tcp_send_active_reset",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_output.c",
+			LineNumber:       3383,
+			ExecutionContext: ContextProcess,
+			Description:      "Aborts the connection with a RST instead of a FIN, as RFC 2525 asks when unread data is discarded, and moves the socket straight to CLOSE.",
+			ConntrackEvent:   ConntrackEventRST,
+			IsExitPoint:      true,
+		},
+	}
+
+	closed := []KernelFunction{
+		{
+			ID:               "tcp_fin",
+			Name:             "tcp_fin",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_input.c",
+			LineNumber:       4296,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Handles the peer's FIN. In FIN_WAIT2 it ACKs the FIN and, with both directions closed, calls tcp_time_wait.",
+			ConntrackEvent:   ConntrackEventACK,
+		},
+		{
+			ID:               "tcp_time_wait",
+			Name:             "tcp_time_wait",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/tcp_minisocks.c",
+			LineNumber:       267,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Replaces the full socket with a small inet_timewait_sock in TIME_WAIT, hashed in its place, and arms its timer for TCP_TIMEWAIT_LEN (60 seconds).",
+		},
+		{
+			ID:               "tw_timer_handler",
+			Name:             "tw_timer_handler",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/inet_timewait_sock.c",
+			LineNumber:       144,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "The TIME_WAIT timer: fires when TCP_TIMEWAIT_LEN has elapsed without the port being reused.",
+		},
+		{
+			ID:               "inet_twsk_kill",
+			Name:             "inet_twsk_kill",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/inet_timewait_sock.c",
+			LineNumber:       47,
+			ExecutionContext: ContextSoftIRQ,
+			Description:      "Unhashes the timewait socket from the established and bind hashes and frees it: the 4-tuple can be used again.",
+			ConntrackEvent:   ConntrackEventTimeout,
+			IsExitPoint:      true,
+		},
+	}
+
+	b := NewPathBuilder("tcp_ipv4_teardown", "TCP/IPv4 Connection Teardown", "egress", "TCP").
+		Family(FamilyIPv4).
+		Description("The active close of a TCP/IPv4 connection: the FIN sent by close(), the peer's FIN-ACK and TIME_WAIT, with conntrack timeouts (Linux 5.10.8)")
+
+	for _, fn := range closing {
+		b.AddFunction(fn)
+	}
+
+	// The FIN takes the egress path from the push of pending frames to the
+	// driver. It carries no payload, so it is never deferred, throttled,
+	// segmented or fragmented.
+	transmit := map[string]bool{
+		"__tcp_push_pending_frames": true,
+		"tcp_write_xmit":            true,
+		"__tcp_transmit_skb":        true,
+		"ip_queue_xmit":             true,
+		"ip_local_out":              true,
+		"__ip_local_out":            true,
+		"ip_output":                 true,
+		"ip_finish_output":          true,
+		"__ip_finish_output":        true,
+		"ip_finish_output2":         true,
+		"neigh_output":              true,
+		"neigh_hh_output":           true,
+		"neigh_resolve_output":      true,
+		"dev_queue_xmit":            true,
+		"__dev_queue_xmit":          true,
+		"__dev_xmit_skb":            true,
+		"sch_direct_xmit":           true,
+		"dev_requeue_skb":           true,
+		"dev_hard_start_xmit":       true,
+		"ndo_start_xmit":            true,
+	}
+	shared := make(map[string]bool)
+	for _, fn := range egress.Functions {
+		if !transmit[fn.ID] {
+			continue
+		}
+		if fn.ID == "__ip_local_out" {
+			fn.ConntrackEvent = ConntrackEventFIN
+		}
+		fn.IsEntryPoint, fn.IsExitPoint = false, false
+		shared[fn.ID] = true
+		b.AddFunction(fn)
+	}
+
+	// The peer's FIN-ACK reaches the state machine through the receive path
+	// of the ingress path, which is not repeated here
+	for _, fn := range ingress.Functions {
+		if fn.ID == "tcp_rcv_state_process" {
+			fn.Description = "TCP state machine for every state except ESTABLISHED. In FIN_WAIT1, an ACK covering the FIN moves the socket to FIN_WAIT2, and a FIN in the same segment goes to tcp_fin."
+			fn.ConntrackEvent = ConntrackEventReplyFIN
+			b.AddFunction(fn)
+		}
+	}
+	for _, fn := range closed {
+		b.AddFunction(fn)
+	}
+
+	for _, edge := range egress.Edges {
+		if !shared[edge.From] || !shared[edge.To] {
+			continue
+		}
+		opts := []EdgeOption{WithCondition(edge.Condition)}
+		if edge.IsErrorPath {
+			opts = append(opts, AsErrorPath())
+		}
+		b.Connect(edge.From, edge.To, opts...)
+	}
+
+	return b.
+		Connect("tcp_close", "tcp_send_fin", WithCondition("Established, no unread data (TCP_ACTION_FIN)")).
+		Connect("tcp_close", "tcp_send_active_reset", WithCondition("Unread data discarded, or SO_LINGER with a zero timeout")).
+		Connect("tcp_send_fin", "__tcp_push_pending_frames").
+		Connect("ndo_start_xmit", "tcp_rcv_state_process", WithCondition("The peer's FIN-ACK arrives")).
+		Connect("tcp_rcv_state_process", "tcp_fin", WithCondition("ACK covers the FIN, segment carries FIN")).
+		Connect("tcp_fin", "tcp_time_wait").
+		Connect("tcp_time_wait", "tw_timer_handler", WithCondition("TCP_TIMEWAIT_LEN (60 s) elapses")).
+		Connect("tw_timer_handler", "inet_twsk_kill").
+		SetEntry("tcp_close").
+		SetExit("tcp_send_active_reset", "dev_requeue_skb", "inet_twsk_kill").
+		MustBuild()
+}
+
+// effectTCPClose models tcp_close on an established connection. Closing
+// sends no data, so the simulation continues with an empty sk_buff.
+func effectTCPClose(ctx *simContext, step *SimulateStep) {
+	*ctx.skb = *NewSKBuff(ctx.skb.End)
+	ctx.setConntrack(step, ConntrackEstablished)
+	step.annotate(AnnotationConntrack, fmt.Sprintf(
+		"The conntrack entry is ESTABLISHED, expiring after %d seconds (5 days) without traffic. "+
+			"close() moves the socket to FIN_WAIT1 before the FIN is even built.",
+		ctx.conntrack.Timeout))
+}
+
+// effectSendFIN models tcp_send_fin allocating the FIN.
+func effectSendFIN(ctx *simContext, step *SimulateStep) {
+	ctx.skb.FClone = true
+	step.annotate(AnnotationConntrack,
+		"The write queue is empty, so the FIN is a new header-only sk_buff. Conntrack sees it at the OUTPUT hook.")
+}
+
+// effectActiveReset models tcp_send_active_reset ending the connection with
+// a RST.
+func effectActiveReset(ctx *simContext, step *SimulateStep) {
+	ctx.count("TcpOutRsts")
+	*ctx.skb = *NewSKBuff(ctx.skb.End)
+	step.annotate(AnnotationConntrack,
+		"A RST is sent instead of a FIN (TcpOutRsts incremented) and the socket is closed at once, with no TIME_WAIT.")
+}
+
+// effectFinAckReceived models the peer's FIN-ACK reaching the state machine
+// of a socket in FIN_WAIT1. The simulation continues with the received
+// segment.
+func effectFinAckReceived(ctx *simContext, step *SimulateStep) {
+	if step.Function.ConntrackEvent != ConntrackEventReplyFIN {
+		return
+	}
+	finAck := NewSKBuffForIngress(ctx.skb.End, 0)
+	finAck.Pull(EthernetHeaderSize)
+	finAck.Pull(IPv4HeaderSize)
+	finAck.Pull(TCPHeaderSize)
+	*ctx.skb = *finAck
+	step.annotate(AnnotationConntrack,
+		"The peer ACKs the FIN and closes its side in the same segment, which conntrack saw at PREROUTING. "+
+			"The ACK moves the socket to FIN_WAIT2; the FIN goes on to tcp_fin.")
+}
+
+// effectTCPFin models tcp_fin ACKing the peer's FIN.
+func effectTCPFin(ctx *simContext, step *SimulateStep) {
+	step.annotate(AnnotationConntrack,
+		"tcp_send_ack ACKs the peer's FIN, and conntrack sees the ACK at the OUTPUT hook. "+
+			"The conntrack entry stays in TIME_WAIT for 2 MSL so that late segments of the connection still match it.")
+}
+
+// effectTCPTimeWait models tcp_time_wait swapping the socket for a timewait
+// socket.
+func effectTCPTimeWait(ctx *simContext, step *SimulateStep) {
+	step.annotate(AnnotationConntrack, fmt.Sprintf(
+		"The socket waits in TIME_WAIT for 60 seconds (TCP_TIMEWAIT_LEN, fixed in the kernel), half the %d seconds of the conntrack entry: "+
+			"the two timers are independent.",
+		ctx.conntrack.Timeout))
+}
+
+// effectTimeWaitKill models inet_twsk_kill freeing the timewait socket, the
+// end of the connection.
+func effectTimeWaitKill(ctx *simContext, step *SimulateStep) {
+	step.annotate(AnnotationConntrack,
+		"The timewait socket is freed and the 4-tuple is free again. The conntrack entry outlives it: its own TIME_WAIT timeout "+
+			"runs out 60 seconds later, and conntrack then removes it without further packets.")
+}
@@ -0,0 +1,72 @@
+package contract
+
+import (
+	"slices"
+	"testing"
+)
+
+// conntrackChange is a step where the conntrack entry changed.
+type conntrackChange struct {
+	function string
+	state    ConntrackState
+	timeout  int
+}
+
+// conntrackChanges returns the steps where the conntrack entry differs from
+// the previous step's.
+func conntrackChanges(steps SimulateSteps) []conntrackChange {
+	var changes []conntrackChange
+	var prev ConntrackEntry
+	for _, step := range steps {
+		entry := step.ConntrackState
+		if entry == nil || (entry.State == prev.State && entry.Timeout == prev.Timeout) {
+			continue
+		}
+		changes = append(changes, conntrackChange{step.Function.ID, entry.State, entry.Timeout})
+		prev = *entry
+	}
+	return changes
+}
+
+func TestTeardownConntrackProgression(t *testing.T) {
+	path := BuildTCPTeardownPath()
+	opts := DefaultSimulateOptions()
+
+	tests := []struct {
+		name   string
+		forced map[string]string
+		want   []conntrackChange
+	}{
+		{
+			name: "fin",
+			want: []conntrackChange{
+				{"tcp_close", ConntrackEstablished, 432000},
+				{"__ip_local_out", ConntrackFinWait, 120},
+				{"tcp_rcv_state_process", ConntrackLastAck, 30},
+				{"tcp_fin", ConntrackTimeWait, 120},
+				{"inet_twsk_kill", ConntrackClosed, 10},
+			},
+		},
+		{
+			name:   "rst",
+			forced: map[string]string{"tcp_close": "tcp_send_active_reset"},
+			want: []conntrackChange{
+				{"tcp_close", ConntrackEstablished, 432000},
+				{"tcp_send_active_reset", ConntrackClosed, 10},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps := path.run(opts, path.initialSKBuff(opts), tt.forced).steps
+
+			if got := conntrackChanges(steps); !slices.Equal(got, tt.want) {
+				t.Errorf("conntrack changes = %+v, want %+v", got, tt.want)
+			}
+			last := steps[len(steps)-1]
+			if last.Function.ID != tt.want[len(tt.want)-1].function {
+				t.Errorf("simulation ends at %s, want %s", last.Function.ID, tt.want[len(tt.want)-1].function)
+			}
+		})
+	}
+}
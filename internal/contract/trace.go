@@ -0,0 +1,62 @@
+package contract
+
+import "encoding/json"
+
+// chromeTraceEvent is a single event in the Chrome trace event format,
+// as read by chrome://tracing and Perfetto.
+type chromeTraceEvent struct {
+	Name  string         `json:"name"`
+	Cat   string         `json:"cat,omitempty"`
+	Phase string         `json:"ph"`
+	TS    float64        `json:"ts"`
+	Dur   float64        `json:"dur,omitempty"`
+	PID   int            `json:"pid"`
+	TID   int            `json:"tid"`
+	Args  map[string]any `json:"args,omitempty"`
+}
+
+// chromeTrace is the top-level JSON object of the trace event format.
+type chromeTrace struct {
+	TraceEvents     []chromeTraceEvent `json:"traceEvents"`
+	DisplayTimeUnit string             `json:"displayTimeUnit"`
+}
+
+// ToChromeTrace renders the simulation in the Chrome trace event format.
+// Each step becomes a duration event lasting its estimated cost, and steps
+// are grouped into one track per kernel layer.
+func (steps SimulateSteps) ToChromeTrace() ([]byte, error) {
+	events := []chromeTraceEvent{
+		{Name: "process_name", Phase: "M", PID: 1, Args: map[string]any{"name": "Linux packet path"}},
+	}
+	for i, layer := range allLayers {
+		events = append(events,
+			chromeTraceEvent{Name: "thread_name", Phase: "M", PID: 1, TID: int(layer), Args: map[string]any{"name": layer.String()}},
+			chromeTraceEvent{Name: "thread_sort_index", Phase: "M", PID: 1, TID: int(layer), Args: map[string]any{"sort_index": i}},
+		)
+	}
+
+	// Timestamps are in microseconds; costs are in nanoseconds
+	ts := 0.0
+	for _, step := range steps {
+		fn := step.Function
+		dur := float64(fn.CostEstimate()) / 1000
+		events = append(events, chromeTraceEvent{
+			Name:  fn.Name,
+			Cat:   fn.Layer.CSSClass(),
+			Phase: "X",
+			TS:    ts,
+			Dur:   dur,
+			PID:   1,
+			TID:   int(fn.Layer),
+			Args: map[string]any{
+				"step":       step.StepNumber,
+				"sourceFile": fn.SourceFile,
+				"skbLen":     step.SKBuffState.Len(),
+				"headroom":   step.SKBuffState.Headroom(),
+			},
+		})
+		ts += dur
+	}
+
+	return json.Marshal(chromeTrace{TraceEvents: events, DisplayTimeUnit: "ns"})
+}
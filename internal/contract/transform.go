@@ -0,0 +1,234 @@
+package contract
+
+// Clone creates a copy of the path with independent Functions, Edges and
+// ExitPoints slices. Mutation and hook annotations are shared, as they are
+// never modified after construction.
+func (p *PacketPath) Clone() *PacketPath {
+	clone := *p
+	clone.Functions = make([]KernelFunction, len(p.Functions))
+	copy(clone.Functions, p.Functions)
+	clone.Edges = make([]FunctionEdge, len(p.Edges))
+	copy(clone.Edges, p.Edges)
+	clone.ExitPoints = make([]string, len(p.ExitPoints))
+	copy(clone.ExitPoints, p.ExitPoints)
+	return &clone
+}
+
+// RemoveFunction returns a copy of the path without the given function.
+// Every caller of the removed function is re-linked to each of its callees,
+// keeping the caller's edge order and the first non-empty condition. If the
+// entry point is removed, its first callee becomes the new entry point.
+func (p *PacketPath) RemoveFunction(id string) *PacketPath {
+	out := p.Clone()
+
+	var incoming, outgoing, kept []FunctionEdge
+	for _, edge := range out.Edges {
+		switch {
+		case edge.From == id && edge.To == id:
+			// self-loop disappears with the function
+		case edge.To == id:
+			incoming = append(incoming, edge)
+		case edge.From == id:
+			outgoing = append(outgoing, edge)
+		default:
+			kept = append(kept, edge)
+		}
+	}
+
+	exists := make(map[[2]string]bool)
+	for _, edge := range kept {
+		exists[[2]string{edge.From, edge.To}] = true
+	}
+
+	// Insert re-linked edges where the caller's edge used to be
+	edges := []FunctionEdge{}
+	for _, edge := range out.Edges {
+		if edge.To == id && edge.From != id {
+			for _, next := range outgoing {
+				key := [2]string{edge.From, next.To}
+				if exists[key] {
+					continue
+				}
+				exists[key] = true
+
+				linked := FunctionEdge{
+					From:        edge.From,
+					To:          next.To,
+					Condition:   edge.Condition,
+					IsErrorPath: edge.IsErrorPath || next.IsErrorPath,
+					Order:       edge.Order,
+				}
+				if linked.Condition == "" {
+					linked.Condition = next.Condition
+				}
+				edges = append(edges, linked)
+			}
+			continue
+		}
+		if edge.From == id || edge.To == id {
+			continue
+		}
+		edges = append(edges, edge)
+	}
+	out.Edges = edges
+
+	functions := make([]KernelFunction, 0, len(out.Functions))
+	for _, fn := range out.Functions {
+		if fn.ID != id {
+			functions = append(functions, fn)
+		}
+	}
+	out.Functions = functions
+
+	exits := make([]string, 0, len(out.ExitPoints))
+	for _, exit := range out.ExitPoints {
+		if exit != id {
+			exits = append(exits, exit)
+		}
+	}
+	out.ExitPoints = exits
+
+	if out.EntryPoint == id {
+		out.EntryPoint = ""
+		if len(outgoing) > 0 {
+			out.EntryPoint = outgoing[0].To
+			for i := range out.Functions {
+				if out.Functions[i].ID == out.EntryPoint {
+					out.Functions[i].IsEntryPoint = true
+				}
+			}
+		}
+	}
+
+	return out
+}
+
+// WithoutConfig returns a copy of the path as it would look on a kernel
+// built without the given CONFIG options. Functions that only exist for a
+// disabled option are removed and their callers re-linked. Functions that
+// carry a hook for a disabled option remain, since they do other work, but
+// lose the hook annotation.
+func (p *PacketPath) WithoutConfig(disabled ...string) *PacketPath {
+	off := make(map[string]bool, len(disabled))
+	for _, opt := range disabled {
+		off[opt] = true
+	}
+
+	out := p.Clone()
+	var remove []string
+	for i := range out.Functions {
+		fn := &out.Functions[i]
+
+		deps := []string{}
+		gated := false
+		for _, dep := range fn.ConfigDeps {
+			if off[dep] {
+				gated = true
+			} else {
+				deps = append(deps, dep)
+			}
+		}
+		if !gated {
+			continue
+		}
+
+		if fn.NetfilterHook == nil && fn.BPFHook == nil {
+			remove = append(remove, fn.ID)
+			continue
+		}
+		fn.NetfilterHook = nil
+		fn.BPFHook = nil
+		fn.ConfigDeps = deps
+		if len(deps) == 0 {
+			fn.ConfigDeps = nil
+		}
+	}
+
+	for _, id := range remove {
+		out = out.RemoveFunction(id)
+	}
+	return out
+}
+
+// CollapseWrappers returns a copy of the path without its thin wrapper
+// functions, for a higher-level view. A function is removed when its
+// WrapperOf target is also on the path and it carries no hook; its callers
+// are re-linked to the wrapped function.
+func (p *PacketPath) CollapseWrappers() *PacketPath {
+	present := make(map[string]bool, len(p.Functions))
+	for _, fn := range p.Functions {
+		present[fn.ID] = true
+	}
+
+	var remove []string
+	for _, fn := range p.Functions {
+		if fn.WrapperOf == "" || !present[fn.WrapperOf] {
+			continue
+		}
+		if fn.NetfilterHook != nil || fn.BPFHook != nil {
+			continue
+		}
+		remove = append(remove, fn.ID)
+	}
+
+	out := p.Clone()
+	for _, id := range remove {
+		out = out.RemoveFunction(id)
+	}
+	return out
+}
+
+// LayerSubgraph returns a copy of the path with only the functions of one
+// layer and the edges entering, leaving or inside it, so a renderer can
+// load a large graph layer by layer. The functions of other layers at the
+// far end of those edges are kept as stub nodes: they carry only their ID,
+// name and layer, which tells them apart from the layer's own functions.
+// The entry and exit points are kept if they are in the layer.
+func (p *PacketPath) LayerSubgraph(layer Layer) *PacketPath {
+	out := p.Clone()
+
+	inLayer := make(map[string]bool)
+	for _, fn := range p.Functions {
+		if fn.Layer == layer {
+			inLayer[fn.ID] = true
+		}
+	}
+
+	stubs := make(map[string]bool)
+	edges := []FunctionEdge{}
+	for _, edge := range p.Edges {
+		if !inLayer[edge.From] && !inLayer[edge.To] {
+			continue
+		}
+		for _, id := range []string{edge.From, edge.To} {
+			if !inLayer[id] {
+				stubs[id] = true
+			}
+		}
+		edges = append(edges, edge)
+	}
+	out.Edges = edges
+
+	functions := []KernelFunction{}
+	for _, fn := range p.Functions {
+		switch {
+		case inLayer[fn.ID]:
+			functions = append(functions, fn)
+		case stubs[fn.ID]:
+			functions = append(functions, KernelFunction{ID: fn.ID, Name: fn.Name, Layer: fn.Layer})
+		}
+	}
+	out.Functions = functions
+
+	if !inLayer[out.EntryPoint] {
+		out.EntryPoint = ""
+	}
+	exits := []string{}
+	for _, exit := range p.ExitPoints {
+		if inLayer[exit] {
+			exits = append(exits, exit)
+		}
+	}
+	out.ExitPoints = exits
+	return out
+}
@@ -0,0 +1,69 @@
+package contract_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/rzkiamr/linux-packet-visualizer/internal/contract"
+	"github.com/rzkiamr/linux-packet-visualizer/internal/contract/contracttest"
+)
+
+// edgeKeys returns each edge as "from->to" in slice order.
+func edgeKeys(edges []contract.FunctionEdge) []string {
+	keys := make([]string, len(edges))
+	for i, edge := range edges {
+		keys[i] = edge.From + "->" + edge.To
+	}
+	return keys
+}
+
+func TestRemoveFunction(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      *contract.PacketPath
+		remove    string
+		wantEntry string
+		wantEdges []string
+	}{
+		{
+			name:      "middle of the line",
+			path:      contracttest.NewTestPath(contracttest.WithLength(4)),
+			remove:    "fn1",
+			wantEntry: "fn0",
+			wantEdges: []string{"fn0->fn2", "fn2->fn3"},
+		},
+		{
+			name:      "branch",
+			path:      contracttest.NewTestPath(contracttest.WithBranches(2)),
+			remove:    "branch0",
+			wantEntry: "fn0",
+			wantEdges: []string{"fn0->fn1", "fn1->fn2", "fn0->fn2", "fn0->branch1", "branch1->fn2"},
+		},
+		{
+			name:      "entry point",
+			path:      contracttest.NewTestPath(contracttest.WithLength(3)),
+			remove:    "fn0",
+			wantEntry: "fn1",
+			wantEdges: []string{"fn1->fn2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := edgeKeys(tt.path.Edges)
+			got := tt.path.RemoveFunction(tt.remove)
+
+			if got.EntryPoint != tt.wantEntry {
+				t.Errorf("entry point = %s, want %s", got.EntryPoint, tt.wantEntry)
+			}
+			if keys := edgeKeys(got.Edges); !slices.Equal(keys, tt.wantEdges) {
+				t.Errorf("edges = %v, want %v", keys, tt.wantEdges)
+			}
+			if slices.ContainsFunc(got.Functions, func(fn contract.KernelFunction) bool { return fn.ID == tt.remove }) {
+				t.Errorf("%s is still a function of the path", tt.remove)
+			}
+			if after := edgeKeys(tt.path.Edges); !slices.Equal(after, before) {
+				t.Errorf("RemoveFunction changed the original path's edges to %v", after)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package contract
+
+// LayerTransition marks the point in a simulation where the packet crosses
+// from one kernel layer into another.
+type LayerTransition struct {
+	// StepIndex is the 0-based index of the first step in the new layer
+	StepIndex int `json:"stepIndex"`
+
+	// FunctionID is the function that owns the transition (first in the new layer)
+	FunctionID string `json:"functionId"`
+
+	// From is the layer the packet is leaving
+	From Layer `json:"from"`
+
+	// To is the layer the packet is entering
+	To Layer `json:"to"`
+}
+
+// LayerTransitions returns every point where consecutive steps belong to
+// different layers. The step that enters the new layer owns the transition,
+// so the frontend can trigger its effect when that step becomes active.
+func (steps SimulateSteps) LayerTransitions() []LayerTransition {
+	transitions := []LayerTransition{}
+	for i := 1; i < len(steps); i++ {
+		from := steps[i-1].Function.Layer
+		to := steps[i].Function.Layer
+		if from == to {
+			continue
+		}
+		transitions = append(transitions, LayerTransition{
+			StepIndex:  i,
+			FunctionID: steps[i].Function.ID,
+			From:       from,
+			To:         to,
+		})
+	}
+	return transitions
+}
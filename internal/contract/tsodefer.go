@@ -0,0 +1,26 @@
+package contract
+
+import "fmt"
+
+// tcpTSOWinDivisor is net.ipv4.tcp_tso_win_divisor: a TSO sk_buff is sent
+// at once if the window allows at least this fraction of it
+const tcpTSOWinDivisor = 3
+
+// tsoDeferred reports whether tcp_tso_should_defer holds back the send.
+// tcp_write_xmit only asks it about a TSO sk_buff, one carrying more than
+// one MSS.
+func (ctx *simContext) tsoDeferred() bool {
+	return ctx.opts.TSODefer && ctx.direction == "egress" && !ctx.opts.NoGSO && payloadLen(ctx.skb) > ctx.mss()
+}
+
+// effectTSODefer models tcp_tso_should_defer deferring the TSO sk_buff: it
+// stays whole on the write queue and tcp_write_xmit stops.
+func effectTSODefer(ctx *simContext, step *SimulateStep) {
+	step.DeferredBytes = payloadLen(ctx.skb)
+	step.annotate(AnnotationBackpressure, fmt.Sprintf(
+		"The congestion window allows only part of the %d-byte TSO sk_buff, less than 1/%d of the window (tcp_tso_win_divisor). "+
+			"Sending that part now would cut a small TSO packet, so tcp_tso_should_defer defers and tcp_write_xmit stops: "+
+			"the %d bytes stay on the write queue and the next ACK, expected within half an RTT, opens the window for a full-sized one. "+
+			"The send is not deferred if the last transmit was over 1 ms ago or the ACK would come later than that.",
+		step.DeferredBytes, tcpTSOWinDivisor, step.DeferredBytes))
+}
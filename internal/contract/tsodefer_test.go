@@ -0,0 +1,48 @@
+package contract
+
+import "testing"
+
+func TestTSODefer(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         SimulateOptions
+		wantLast     string
+		wantDeferred int
+	}{
+		{"tso send", SimulateOptions{BufferSize: 8192, PayloadSize: 5000, TSODefer: true}, "tcp_tso_should_defer", 5000},
+		{"deferral before tsq", SimulateOptions{BufferSize: 8192, PayloadSize: 5000, TSODefer: true, QdiscBacklog: 1 << 20}, "tcp_tso_should_defer", 5000},
+		{"off", SimulateOptions{BufferSize: 8192, PayloadSize: 5000}, "ndo_start_xmit", 0},
+		{"single segment", SimulateOptions{BufferSize: 8192, PayloadSize: 1000, TSODefer: true}, "ndo_start_xmit", 0},
+		{"no gso", SimulateOptions{BufferSize: 8192, PayloadSize: 5000, NoGSO: true, TSODefer: true}, "ndo_start_xmit", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps := BuildTCPIPv4EgressPath().SimulateWithOptions(tt.opts)
+
+			last := steps[len(steps)-1]
+			if last.Function.ID != tt.wantLast {
+				t.Fatalf("last step = %s, want %s", last.Function.ID, tt.wantLast)
+			}
+			deferred := 0
+			for _, step := range steps {
+				deferred += step.DeferredBytes
+			}
+			if deferred != tt.wantDeferred {
+				t.Errorf("deferred bytes = %d, want %d", deferred, tt.wantDeferred)
+			}
+			if tt.wantDeferred == 0 {
+				return
+			}
+
+			if last.DeferredBytes != tt.wantDeferred {
+				t.Errorf("deferring step records %d bytes, want %d", last.DeferredBytes, tt.wantDeferred)
+			}
+			if q := last.WriteQueue; q == nil || len(q.Queued) != 1 || len(q.InFlight) != 0 {
+				t.Errorf("write queue = %+v, want the deferred sk_buff still queued", q)
+			}
+			if prev := steps[len(steps)-2].Function.ID; prev != "tcp_write_xmit" {
+				t.Errorf("deferred after %s, want tcp_write_xmit", prev)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package contract
+
+import "fmt"
+
+const (
+	// tcpLimitOutputBytes is net.ipv4.tcp_limit_output_bytes, the most TSQ
+	// lets an unpaced socket have queued below TCP
+	tcpLimitOutputBytes = 1 << 20
+
+	// tsqPacingRate is the modeled sk_pacing_rate in bytes per second
+	// (1 Gbit/s)
+	tsqPacingRate = 125_000_000
+
+	// tsqPacingShift is sk_pacing_shift: TSQ allows 2^-shift seconds of
+	// data at the pacing rate, about 1 ms
+	tsqPacingShift = 10
+)
+
+// tsqLimit returns the sk_wmem_alloc above which tcp_small_queue_check
+// throttles a socket sending skb: two sk_buffs or about 1 ms of data at the
+// pacing rate, whichever is larger, capped at tcp_limit_output_bytes.
+func tsqLimit(skb *SKBuff) int {
+	return min(max(2*skb.TrueSize(), tsqPacingRate>>tsqPacingShift), tcpLimitOutputBytes)
+}
+
+// tsqThrottled reports whether the socket's earlier sk_buffs still in the
+// qdisc and driver exceed the TSQ limit, so tcp_write_xmit stops before
+// sending.
+func (ctx *simContext) tsqThrottled() bool {
+	return ctx.opts.QdiscBacklog > tsqLimit(ctx.skb)
+}
+
+// effectTSQThrottle models tcp_small_queue_check throttling the socket:
+// the segment stays in the write queue until TX completions drain the
+// socket's backlog below TCP.
+func effectTSQThrottle(ctx *simContext, step *SimulateStep) {
+	step.annotate(AnnotationBackpressure, fmt.Sprintf(
+		"TCP Small Queues: sk_wmem_alloc holds %d bytes of this socket's earlier segments still in the qdisc and driver ring, above the TSQ limit of %d "+
+			"(the larger of two sk_buffs and 1 ms at the %d Mbit/s pacing rate, capped by tcp_limit_output_bytes, %d). "+
+			"tcp_small_queue_check sets TSQ_THROTTLED in sk_tsq_flags and tcp_write_xmit stops: the %d bytes stay in the write queue instead of joining the backlog. "+
+			"When TX completion frees a queued sk_buff, tcp_wfree sees TSQ_THROTTLED and schedules the per-CPU TSQ tasklet, whose tcp_tsq_handler calls tcp_write_xmit again. "+
+			"The NIC stays busy while the qdisc stays short, bounding the latency other flows see behind this one (bufferbloat).",
+		ctx.opts.QdiscBacklog, tsqLimit(ctx.skb), tsqPacingRate*8/1_000_000, tcpLimitOutputBytes, ctx.skb.Len()))
+}
+
+// effectQdiscBacklog models __dev_xmit_skb enqueueing a TCP segment behind
+// the socket's earlier ones, whose bytes count toward the TSQ limit of the
+// next transmission.
+func effectQdiscBacklog(ctx *simContext, step *SimulateStep) {
+	backlog := ctx.opts.QdiscBacklog
+	if backlog == 0 || ctx.direction != "egress" || !ctx.skb.HasLayer("tcp") {
+		return
+	}
+	wmem, limit := backlog+ctx.skb.TrueSize(), tsqLimit(ctx.skb)
+	next := "so the next tcp_write_xmit may send more."
+	if wmem > limit {
+		next = "so the next tcp_write_xmit is throttled until TX completions free some of it."
+	}
+	step.annotate(AnnotationBackpressure, fmt.Sprintf(
+		"The qdisc is not empty, so the packet cannot bypass it: q->enqueue adds it behind %d bytes of this socket's earlier segments and __qdisc_run sends them first. "+
+			"Enqueue returns NET_XMIT_SUCCESS; at the qdisc's limit it would return NET_XMIT_DROP, and __tcp_transmit_skb would react with tcp_enter_cwr, "+
+			"reducing cwnd and keeping the segment for retransmission. TSQ keeps a single socket well below that: sk_wmem_alloc is now %d of the %d-byte limit, %s",
+		backlog, wmem, limit, next))
+}
@@ -0,0 +1,86 @@
+package contract
+
+import "fmt"
+
+// defaultTxQueues is the number of TX queues of the modeled multiqueue NIC
+// when none is given
+const defaultTxQueues = 4
+
+// TX queue selection methods used by netdev_pick_tx
+const (
+	// TxQueueSingle means the device has a single TX queue
+	TxQueueSingle = "single"
+
+	// TxQueueXPS means the queue comes from the sending CPU's XPS map
+	TxQueueXPS = "xps"
+
+	// TxQueueHash means the queue is derived from the flow hash
+	TxQueueHash = "hash"
+)
+
+// AnnotationTxQueue marks the step where the TX queue is selected.
+const AnnotationTxQueue = "tx_queue"
+
+// TxQueueSelection is the transmit queue chosen for the packet on a
+// multiqueue device.
+type TxQueueSelection struct {
+	// Index is the selected queue (skb->queue_mapping)
+	Index int `json:"index"`
+
+	// Queues is the device's number of real TX queues
+	Queues int `json:"queues"`
+
+	// Method is how the queue was chosen: TxQueueSingle, TxQueueXPS or
+	// TxQueueHash
+	Method string `json:"method"`
+}
+
+// txQueues returns the number of TX queues of the simulated device.
+func (ctx *simContext) txQueues() int {
+	if ctx.opts.TxQueues > 0 {
+		return ctx.opts.TxQueues
+	}
+	return defaultTxQueues
+}
+
+// pickTx models netdev_pick_tx: XPS maps the sending CPU to a queue when
+// configured, otherwise skb_tx_hash scales the flow hash onto the queue
+// range with reciprocal_scale.
+func pickTx(queues int, xps bool, cpu int, hash uint32) TxQueueSelection {
+	switch {
+	case queues <= 1:
+		return TxQueueSelection{Index: 0, Queues: 1, Method: TxQueueSingle}
+	case xps:
+		return TxQueueSelection{Index: cpu % queues, Queues: queues, Method: TxQueueXPS}
+	default:
+		index := int((uint64(hash) * uint64(queues)) >> 32)
+		return TxQueueSelection{Index: index, Queues: queues, Method: TxQueueHash}
+	}
+}
+
+// effectPickTx models __dev_queue_xmit selecting the TX queue, and with it
+// the per-queue qdisc, before enqueueing the packet.
+func effectPickTx(ctx *simContext, step *SimulateStep) {
+	if ctx.skb.FlowHash == 0 {
+		// Locally generated packets carry the socket's sk_txhash
+		ctx.skb.FlowHash = ctx.flow.Hash()
+	}
+	sel := pickTx(ctx.txQueues(), ctx.opts.XPS, ctx.opts.CPU, ctx.skb.FlowHash)
+	step.TxQueue = &sel
+
+	var how string
+	switch sel.Method {
+	case TxQueueSingle:
+		how = "The device has a single TX queue, so every packet uses queue 0."
+	case TxQueueXPS:
+		how = fmt.Sprintf("XPS is configured: the sender runs on CPU %d, whose xps_cpus map selects queue %d of %d. "+
+			"Keeping a flow's transmissions on the queue owned by its CPU avoids contention on the queue lock.",
+			ctx.opts.CPU, sel.Index, sel.Queues)
+	default:
+		how = fmt.Sprintf("No XPS map applies, so skb_tx_hash scales skb->hash 0x%08x onto %d queues: queue %d. "+
+			"Every packet of the flow hashes to the same queue, which keeps it in order.",
+			ctx.skb.FlowHash, sel.Queues, sel.Index)
+	}
+	step.annotate(AnnotationTxQueue, "netdev_core_pick_tx sets skb->queue_mapping. "+how+
+		" Each TX queue has its own qdisc (mq) and driver ring.")
+}
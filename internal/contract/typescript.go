@@ -0,0 +1,159 @@
+package contract
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// tsEnumFields maps struct fields that hold plain strings but only accept a
+// known set of values to the TypeScript union type that describes them.
+var tsEnumFields = map[string]string{
+	"NetfilterHook.Hook":            "NetfilterHookName",
+	"BPFHook.Type":                  "BPFHookType",
+	"SKBuff.CsumState":              "ChecksumState",
+	"SKBMutation.CsumState":         "ChecksumState",
+	"KernelFunction.ConntrackEvent": "ConntrackEvent",
+}
+
+// GenerateTypeScript returns TypeScript interface definitions for
+// ExportPacket and every type reachable from it. The definitions are derived
+// by reflection from the Go structs, so they always track the Go source.
+func GenerateTypeScript() string {
+	gen := &tsGenerator{seen: make(map[reflect.Type]bool)}
+
+	var b strings.Builder
+	b.WriteString("// Code generated by cmd/contract -ts. DO NOT EDIT.\n")
+	b.WriteString("// Source of truth: internal/contract (Go).\n\n")
+
+	layers := make([]string, len(allLayers))
+	for i, l := range allLayers {
+		layers[i] = l.String()
+	}
+	states := make([]string, len(allConntrackStates))
+	for i, s := range allConntrackStates {
+		states[i] = string(s)
+	}
+	writeTSUnion(&b, "Layer", layers)
+	writeTSUnion(&b, "ConntrackState", states)
+	writeTSUnion(&b, "ConntrackEvent", allConntrackEvents)
+	writeTSUnion(&b, "NetfilterHookName", []string{
+		HookPrerouting, HookInput, HookForward, HookOutput, HookPostrouting,
+	})
+	writeTSUnion(&b, "BPFHookType", []string{
+		BPFHookXDP, BPFHookTCIngress, BPFHookTCEgress, BPFHookCgroupSKB, BPFHookSocket,
+	})
+	writeTSUnion(&b, "ChecksumState", []string{CsumNone, CsumUnnecessary, CsumComplete, CsumPartial})
+	writeTSUnion(&b, "XDPMode", []string{XDPModeNative, XDPModeGeneric, XDPModeOffload})
+	writeTSUnion(&b, "AnimationEasing", []string{EasingEaseOut, EasingEaseIn, EasingEaseInOut, EasingLinear})
+	writeTSUnion(&b, "AnimationDuration", []string{DurationShort, DurationMedium, DurationLong})
+
+	gen.collect(reflect.TypeOf(ExportPacket{}))
+	for _, t := range gen.order {
+		gen.writeInterface(&b, t)
+	}
+	return b.String()
+}
+
+// writeTSUnion writes a string literal union type alias.
+func writeTSUnion(b *strings.Builder, name string, values []string) {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	fmt.Fprintf(b, "export type %s =\n  | %s;\n\n", name, strings.Join(quoted, "\n  | "))
+}
+
+// tsGenerator collects struct types in discovery order.
+type tsGenerator struct {
+	seen  map[reflect.Type]bool
+	order []reflect.Type
+}
+
+// collect walks t depth-first, recording each struct type once.
+func (g *tsGenerator) collect(t reflect.Type) {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		g.collect(t.Elem())
+	case reflect.Struct:
+		if g.seen[t] {
+			return
+		}
+		g.seen[t] = true
+		g.order = append(g.order, t)
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.IsExported() {
+				g.collect(f.Type)
+			}
+		}
+	}
+}
+
+// writeInterface writes the TypeScript interface for a struct type.
+func (g *tsGenerator) writeInterface(b *strings.Builder, t reflect.Type) {
+	fmt.Fprintf(b, "export interface %s {\n", t.Name())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		optional := ""
+		if strings.Contains(opts, "omitempty") {
+			optional = "?"
+		}
+
+		tsType, ok := tsEnumFields[t.Name()+"."+f.Name]
+		if !ok {
+			tsType = tsTypeOf(f.Type)
+		}
+		if f.Type.Kind() == reflect.Ptr && optional == "" {
+			tsType += " | null"
+		}
+		fmt.Fprintf(b, "  %s%s: %s;\n", name, optional, tsType)
+	}
+	b.WriteString("}\n\n")
+}
+
+// tsTypeOf maps a Go type to its TypeScript equivalent.
+func tsTypeOf(t reflect.Type) string {
+	switch t {
+	case reflect.TypeOf(Layer(0)):
+		return "Layer"
+	case reflect.TypeOf(ConntrackState("")):
+		return "ConntrackState"
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return tsTypeOf(t.Elem())
+	case reflect.Struct:
+		return t.Name()
+	case reflect.Slice, reflect.Array:
+		elem := tsTypeOf(t.Elem())
+		if strings.Contains(elem, " ") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]"
+	case reflect.Map:
+		return "Record<" + tsTypeOf(t.Key()) + ", " + tsTypeOf(t.Elem()) + ">"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,191 @@
+package contract
+
+import "fmt"
+
+// AnnotationSegmentation marks a step where a GSO packet is split or its
+// segmentation metadata is set
+const AnnotationSegmentation = "segmentation"
+
+// udpMaxSegments is UDP_MAX_SEGMENTS, the most datagrams a single UDP GSO
+// send may produce
+const udpMaxSegments = 64
+
+// BuildUDPIPv4EgressPath constructs the UDP over IPv4 egress path based on
+// Linux Kernel 5.10.8.
+//
+// udp_sendmsg builds the whole datagram (headers and data) in one call and
+// hands it to the same IP output path TCP uses. With UDP_SEGMENT set, the
+// payload is kept as one large GSO sk_buff down to the device, where it is
+// segmented into gso_size datagrams by the NIC or, if the device lacks UDP
+// segmentation offload, in software by validate_xmit_skb.
+func BuildUDPIPv4EgressPath() *PacketPath {
+	tcp := BuildTCPIPv4EgressPath()
+
+	udp := []KernelFunction{
+		{
+			ID:               "udp_sendmsg",
+			Name:             "udp_sendmsg",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/udp.c",
+			LineNumber:       1039,
+			ExecutionContext: ContextProcess,
+			Description:      "Entry point for UDP send operations. Resolves the destination and route, and reads the GSO segment size from UDP_SEGMENT (socket option or cmsg).",
+			IsEntryPoint:     true,
+		},
+		{
+			ID:               "ip_make_skb",
+			Name:             "ip_make_skb",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/ip_output.c",
+			LineNumber:       1616,
+			ExecutionContext: ContextProcess,
+			Description:      "Builds the datagram without corking: __ip_append_data copies the user data (into one sk_buff of up to 64 KB when GSO is used) and __ip_make_skb fills in the IP header.",
+			SKBMutation:      NewAllocMutation(2048, "Allocate sk_buff with headroom for UDP, IP and link-layer headers"),
+			EstimatedCostNs:  1000,
+		},
+		{
+			ID:               "udp_send_skb",
+			Name:             "udp_send_skb",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/udp.c",
+			LineNumber:       891,
+			ExecutionContext: ContextProcess,
+			Description:      "Fills in the UDP header and checksum. For UDP_SEGMENT sends, sets gso_size and SKB_GSO_UDP_L4 so the sk_buff is segmented later.",
+			SKBMutation:      NewPushMutation("udp", UDPHeaderSize),
+		},
+		{
+			ID:               "ip_send_skb",
+			Name:             "ip_send_skb",
+			Layer:            LayerNetwork,
+			SourceFile:       "net/ipv4/ip_output.c",
+			LineNumber:       1571,
+			ExecutionContext: ContextProcess,
+			Description:      "Sends the finished datagram with ip_local_out. The IP header built by __ip_make_skb sits in front of the UDP header.",
+			SKBMutation:      NewPushMutation("ip", IPv4HeaderSize),
+		},
+	}
+
+	gso := []KernelFunction{
+		{
+			ID:               "validate_xmit_skb",
+			Name:             "validate_xmit_skb",
+			Layer:            LayerDataLink,
+			SourceFile:       "net/core/dev.c",
+			LineNumber:       3637,
+			ExecutionContext: ContextProcess,
+			Description:      "Checks the sk_buff against the device features. A GSO packet the device cannot segment is passed to skb_gso_segment.",
+		},
+		{
+			ID:               "__udp_gso_segment",
+			Name:             "__udp_gso_segment",
+			Layer:            LayerTransport,
+			SourceFile:       "net/ipv4/udp_offload.c",
+			LineNumber:       190,
+			ExecutionContext: ContextProcess,
+			Description:      "Software UDP segmentation: splits the payload into gso_size datagrams, each with its own UDP header, length and checksum, and IP header.",
+			EstimatedCostNs:  1500,
+		},
+	}
+
+	b := NewPathBuilder("udp_ipv4_egress", "UDP/IPv4 Egress Path", "egress", "UDP").
+		Family(FamilyIPv4).
+		Description("The path of a UDP datagram from user space through the kernel to the network interface, with optional UDP GSO (Linux 5.10.8)")
+	for _, fn := range udp {
+		b.AddFunction(fn)
+	}
+
+	// Below ip_local_out the datagram takes the same path as a TCP segment
+	shared := make(map[string]bool)
+	reached := false
+	for _, fn := range tcp.Functions {
+		if fn.ID == "ip_local_out" {
+			reached = true
+		}
+		if !reached || fn.ID == "fib_rules_lookup" {
+			continue
+		}
+		shared[fn.ID] = true
+		b.AddFunction(fn)
+		if fn.ID == "__dev_queue_xmit" {
+			for _, g := range gso {
+				b.AddFunction(g)
+			}
+		}
+	}
+
+	b.Connect("udp_sendmsg", "ip_make_skb").
+		Connect("ip_make_skb", "udp_send_skb").
+		Connect("udp_send_skb", "ip_send_skb").
+		Connect("ip_send_skb", "ip_local_out")
+	for _, edge := range tcp.Edges {
+		if !shared[edge.From] || !shared[edge.To] {
+			continue
+		}
+		b.Connect(edge.From, edge.To, WithCondition(edge.Condition))
+		if edge.From == "__dev_queue_xmit" {
+			b.Connect("__dev_queue_xmit", "validate_xmit_skb", WithCondition("GSO sk_buff, device lacks NETIF_F_GSO_UDP_L4"))
+		}
+	}
+
+	return b.
+		Connect("validate_xmit_skb", "__udp_gso_segment").
+		Connect("__udp_gso_segment", "__dev_xmit_skb").
+		SetEntry("udp_sendmsg").
+		SetExit("ndo_start_xmit", "dev_requeue_skb").
+		MustBuild()
+}
+
+// udpGSOSegments returns the number of datagrams a payload is split into
+// with the given segment size.
+func udpGSOSegments(payload, gsoSize int) int {
+	return (payload + gsoSize - 1) / gsoSize
+}
+
+// effectUDPGSO models udp_send_skb setting the GSO metadata of a
+// UDP_SEGMENT send.
+func effectUDPGSO(ctx *simContext, step *SimulateStep) {
+	gsoSize := ctx.opts.UDPSegment
+	if gsoSize <= 0 {
+		return
+	}
+	payload := ctx.opts.PayloadSize
+	if payload <= gsoSize {
+		step.annotate(AnnotationSegmentation, fmt.Sprintf(
+			"UDP_SEGMENT is %d bytes but the %d-byte payload fits in one datagram: sent as a normal datagram without GSO.",
+			gsoSize, payload))
+		return
+	}
+	segs := udpGSOSegments(payload, gsoSize)
+	if segs > udpMaxSegments {
+		step.annotate(AnnotationSegmentation, fmt.Sprintf(
+			"%d-byte payload needs %d segments of %d bytes, more than UDP_MAX_SEGMENTS (%d): udp_send_skb fails with EINVAL.",
+			payload, segs, gsoSize, udpMaxSegments))
+		return
+	}
+	ctx.skb.GSO = newGSOInfo(GSOTypeUDPL4, payload, gsoSize)
+	step.annotate(AnnotationSegmentation, fmt.Sprintf(
+		"GSO sk_buff: gso_size=%d, gso_segs=%d, gso_type=SKB_GSO_UDP_L4. One sk_buff carries all %d bytes through the stack; "+
+			"routing, netfilter and qdisc run once instead of %d times. A NIC with UDP segmentation offload splits it in hardware.",
+		gsoSize, segs, payload, segs))
+}
+
+// effectUDPGSOSegment models __udp_gso_segment splitting the GSO sk_buff.
+// The simulation continues with the first segment.
+func effectUDPGSOSegment(ctx *simContext, step *SimulateStep) {
+	gsoSize := ctx.opts.UDPSegment
+	payload := ctx.skb.Len()
+	for _, layer := range ctx.skb.Layers {
+		payload -= layer.Size
+	}
+	if gsoSize <= 0 || payload <= gsoSize {
+		return
+	}
+	segs := udpGSOSegments(payload, gsoSize)
+	last := payload - (segs-1)*gsoSize
+	ctx.skb.GSO = nil
+	ctx.skb.Tail -= payload - gsoSize
+	step.annotate(AnnotationSegmentation, fmt.Sprintf(
+		"Software segmentation: the %d-byte payload becomes %d datagrams (%d x %d bytes, last %d bytes), "+
+			"each with copied UDP and IP headers and its own length and checksum. The simulation follows the first datagram.",
+		payload, segs, segs-1, gsoSize, last))
+}
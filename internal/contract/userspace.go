@@ -0,0 +1,95 @@
+package contract
+
+// userspaceEquivalents maps kernel function IDs to the analogous function or
+// concept in gVisor's netstack (pkg/tcpip), a TCP/IP stack written in Go
+// that runs entirely in userspace.
+var userspaceEquivalents = map[string]string{
+	// Egress
+	"tcp_sendmsg":               "tcp.endpoint.Write",
+	"tcp_sendmsg_locked":        "tcp.endpoint.Write appending to the send queue (sndQueue)",
+	"skb_zerocopy_iter_stream":  "No equivalent: payload is always copied into a buffer.Buffer",
+	"tcp_push":                  "tcp.sender.sendData",
+	"__tcp_push_pending_frames": "tcp.sender.sendData",
+	"tcp_write_xmit":            "tcp.sender.sendData loop bounded by the congestion window",
+	"tcp_tso_should_defer":      "tcp.sender.sendData holding back a segment smaller than the MSS",
+	"tcp_small_queue_check":     "No equivalent: netstack does not limit the bytes queued below the sender",
+	"__tcp_transmit_skb":        "tcp.sender.sendSegment and sendTCP, which build the TCP header",
+	"ip_queue_xmit":             "ipv4.endpoint.WritePacket on the connection's stack.Route",
+	"fib_rules_lookup":          "stack.Stack.FindRoute matching the route table in order",
+	"ip_local_out":              "ipv4.endpoint.writePacket",
+	"__ip_local_out":            "stack.IPTables.CheckOutput (Output hook)",
+	"ip_output":                 "ipv4.endpoint.writePacket",
+	"ip_finish_output":          "stack.IPTables.CheckPostrouting (Postrouting hook)",
+	"__ip_finish_output":        "ipv4.endpoint.handleFragments when the packet exceeds the MTU",
+	"ip_finish_output2":         "stack.Route.ResolvedFields via the neighbor cache",
+	"neigh_output":              "stack.neighborCache entry lookup",
+	"neigh_hh_output":           "stack.nic.WritePacket filling in the link address",
+	"neigh_resolve_output":      "stack.neighborEntry resolution, queuing packets until the link address is known",
+	"dev_queue_xmit":            "stack.nic.WritePacket",
+	"__dev_queue_xmit":          "stack.nic.WritePacket into the queueing discipline",
+	"__dev_xmit_skb":            "qdisc/fifo.discipline.WritePacket",
+	"sch_direct_xmit":           "qdisc/fifo dispatcher goroutine draining the queue",
+	"dev_hard_start_xmit":       "stack.LinkEndpoint.WritePackets",
+	"ndo_start_xmit":            "fdbased.endpoint.WritePackets (sendmmsg on the host FD)",
+
+	// Ingress
+	"napi_poll":                    "fdbased dispatcher loop (recvmmsg on the host FD)",
+	"napi_gro_receive":             "stack.GRO coalescing in the fdbased dispatcher",
+	"napi_skb_finish":              "stack.GRO flush",
+	"netif_receive_skb":            "stack.nic.DeliverNetworkPacket",
+	"netif_receive_skb_internal":   "stack.nic.DeliverNetworkPacket",
+	"__netif_receive_skb":          "stack.nic.DeliverNetworkPacket",
+	"__netif_receive_skb_one_core": "stack.nic.DeliverNetworkPacket",
+	"__netif_receive_skb_core":     "stack.nic.DeliverNetworkPacket delivering to packet endpoints first",
+	"deliver_skb":                  "stack.nic.DeliverNetworkPacket dispatching by NetworkProtocolNumber",
+	"packet_rcv":                   "packet.endpoint.HandlePacket",
+	"ip_rcv":                       "ipv4.endpoint.HandlePacket (header validation, Prerouting hook)",
+	"ip_rcv_finish":                "ipv4.endpoint.handleValidatedPacket (local or forward decision)",
+	"ip_local_deliver":             "ipv4.endpoint.deliverPacketLocally (Input hook)",
+	"ip_defrag":                    "fragmentation.Fragmentation.Process",
+	"ip_local_deliver_finish":      "ipv4.endpoint.deliverPacketLocally",
+	"ip_protocol_deliver_rcu":      "stack.nic.DeliverTransportPacket",
+	"tcp_v4_rcv":                   "stack.transportDemuxer.deliverPacket (endpoint lookup)",
+	"sk_add_backlog":               "tcp.endpoint.enqueueSegment onto the segment queue",
+	"release_sock":                 "tcp.endpoint.UnlockUser",
+	"__release_sock":               "tcp.processor goroutine draining queued segments",
+	"tcp_v4_do_rcv":                "tcp.endpoint.handleSegmentsLocked",
+	"tcp_rcv_established":          "tcp.receiver.handleRcvdSegment",
+	"tcp_data_queue":               "tcp.receiver.consumeSegment",
+	"tcp_queue_rcv":                "tcp.endpoint.readyToRead appending to the receive queue (rcvQueue)",
+	"sk_data_ready":                "waiter.Queue.Notify(waiter.ReadableEvents)",
+	"kfree_skb":                    "stack.PacketBuffer.DecRef, with the drop counted in tcpip.Stats",
+
+	// Legacy netif_rx
+	"netif_rx":           "channel.Endpoint.InjectInbound",
+	"netif_rx_internal":  "channel.Endpoint.InjectInbound",
+	"enqueue_to_backlog": "No equivalent: packets are delivered inline from the dispatcher",
+	"net_rx_action":      "fdbased dispatcher goroutine",
+	"process_backlog":    "fdbased dispatcher goroutine",
+
+	// IPsec
+	"xfrm4_output": "No equivalent: netstack does not implement IPsec",
+
+	// Teardown
+	"tcp_close":             "tcp.endpoint.Close, which shuts down both directions",
+	"tcp_send_fin":          "tcp.sender.sendData sending a segment with header.TCPFlagFin",
+	"tcp_send_active_reset": "tcp.endpoint.resetConnectionLocked",
+	"tcp_fin":               "tcp.endpoint.handleSegment seeing FIN in StateFinWait2",
+	"tcp_time_wait":         "tcp.endpoint.doTimeWait",
+	"tw_timer_handler":      "The timer in tcp.endpoint.doTimeWait",
+	"inet_twsk_kill":        "tcp.endpoint.cleanupLocked releasing the port reservation",
+}
+
+// UserspaceEquivalent returns the analogous function or concept in gVisor's
+// netstack for a kernel function ID, or "" if none is recorded.
+func UserspaceEquivalent(functionID string) string {
+	return userspaceEquivalents[functionID]
+}
+
+// annotateUserspaceEquivalents fills in UserspaceEquivalent on every function
+// of the path that has a recorded equivalent.
+func (p *PacketPath) annotateUserspaceEquivalents() {
+	for i := range p.Functions {
+		p.Functions[i].UserspaceEquivalent = UserspaceEquivalent(p.Functions[i].ID)
+	}
+}
@@ -0,0 +1,127 @@
+package contract
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationError lists every problem found while validating a path.
+type ValidationError struct {
+	// PathID is the ID of the invalid path
+	PathID string
+
+	// Problems are human-readable descriptions of each issue found
+	Problems []string
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("path %s is invalid: %s", e.PathID, strings.Join(e.Problems, "; "))
+}
+
+// Validate checks the structural integrity of the path and returns a
+// *ValidationError describing every problem found, or nil if the path is
+// valid.
+func (p *PacketPath) Validate() error {
+	graph := NewFunctionGraph(p)
+	var problems []string
+
+	// Edges must connect functions of the path
+	for _, edge := range p.Edges {
+		for _, id := range []string{edge.From, edge.To} {
+			if graph.GetFunction(id) == nil {
+				problems = append(problems, fmt.Sprintf("edge %s -> %s refers to function %q, which is not on the path", edge.From, edge.To, id))
+			}
+		}
+	}
+
+	// Reachability is only meaningful from an existing entry point, whose
+	// absence flagProblems reports
+	hasEntry := graph.GetFunction(p.EntryPoint) != nil
+	reachable := p.reachableFrom(p.EntryPoint)
+	if hasEntry {
+		for _, fn := range p.Functions {
+			if !reachable[fn.ID] {
+				problems = append(problems, fmt.Sprintf("function %q is not reachable from entry point %q", fn.ID, p.EntryPoint))
+			}
+		}
+	}
+
+	// Declared exit points must exist, be reachable and be genuinely
+	// terminal
+	declared := make(map[string]bool, len(p.ExitPoints))
+	for _, id := range p.ExitPoints {
+		declared[id] = true
+		if graph.GetFunction(id) == nil {
+			problems = append(problems, fmt.Sprintf("exit point %q is not a function of the path", id))
+		} else if hasEntry && !reachable[id] {
+			problems = append(problems, fmt.Sprintf("exit point %q is not reachable from entry point %q", id, p.EntryPoint))
+		}
+		for _, edge := range graph.GetOutgoingEdges(id) {
+			if !edge.IsErrorPath {
+				problems = append(problems, fmt.Sprintf("exit point %q is not terminal: it has an edge to %q", id, edge.To))
+				break
+			}
+		}
+	}
+
+	// Every terminal function must be a declared exit or a drop point,
+	// otherwise a simulation reaching it would stop silently
+	for _, fn := range p.Functions {
+		if len(graph.GetOutgoingEdges(fn.ID)) > 0 || declared[fn.ID] || fn.IsDropPoint() {
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("function %q has no outgoing edges but is neither an exit point nor a drop point", fn.ID))
+	}
+
+	for _, fn := range p.Functions {
+		if fn.ConntrackEvent != "" && !IsValidConntrackEvent(fn.ConntrackEvent) {
+			problems = append(problems, fmt.Sprintf("function %q has unknown conntrack event %q", fn.ID, fn.ConntrackEvent))
+		}
+	}
+
+	problems = append(problems, p.flagProblems(declared)...)
+
+	if len(problems) > 0 {
+		return &ValidationError{PathID: p.ID, Problems: problems}
+	}
+	return nil
+}
+
+// flagProblems reconciles the functions' IsEntryPoint/IsExitPoint flags
+// with the path's EntryPoint and ExitPoints, which are maintained separately.
+func (p *PacketPath) flagProblems(declared map[string]bool) []string {
+	var problems []string
+	entryFound := false
+	for _, fn := range p.Functions {
+		if fn.ID == p.EntryPoint {
+			entryFound = true
+			if !fn.IsEntryPoint {
+				problems = append(problems, fmt.Sprintf("entry point %q is not flagged IsEntryPoint", fn.ID))
+			}
+		} else if fn.IsEntryPoint {
+			problems = append(problems, fmt.Sprintf("function %q is flagged IsEntryPoint but the path's entry point is %q", fn.ID, p.EntryPoint))
+		}
+
+		if fn.IsExitPoint && !declared[fn.ID] {
+			problems = append(problems, fmt.Sprintf("function %q is flagged IsExitPoint but is not in ExitPoints", fn.ID))
+		} else if !fn.IsExitPoint && declared[fn.ID] {
+			problems = append(problems, fmt.Sprintf("exit point %q is not flagged IsExitPoint", fn.ID))
+		}
+	}
+	if !entryFound {
+		problems = append(problems, fmt.Sprintf("entry point %q is not a function of the path", p.EntryPoint))
+	}
+	return problems
+}
+
+// reachableFrom returns the set of function IDs reachable from startID,
+// following all edges including error paths.
+func (p *PacketPath) reachableFrom(startID string) map[string]bool {
+	seen := make(map[string]bool)
+	p.Walk(startID, func(fn *KernelFunction, _ *FunctionEdge) bool {
+		seen[fn.ID] = true
+		return true
+	})
+	return seen
+}
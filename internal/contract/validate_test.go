@@ -0,0 +1,149 @@
+package contract_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/rzkiamr/linux-packet-visualizer/internal/contract"
+	"github.com/rzkiamr/linux-packet-visualizer/internal/contract/contracttest"
+)
+
+// TestValidate breaks the fixture path fn0 -> fn1 -> fn2 one way at a time.
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(p *contract.PacketPath)
+		want   []string
+	}{
+		{
+			name:   "valid",
+			modify: func(p *contract.PacketPath) {},
+		},
+		{
+			name: "dangling edge",
+			modify: func(p *contract.PacketPath) {
+				p.Edges = append(p.Edges, contract.FunctionEdge{From: "fn1", To: "missing", Order: 2})
+			},
+			want: []string{`edge fn1 -> missing refers to function "missing", which is not on the path`},
+		},
+		{
+			name: "unreachable function",
+			modify: func(p *contract.PacketPath) {
+				p.Functions = append(p.Functions, contract.KernelFunction{ID: "orphan", Name: "orphan", IsExitPoint: true})
+				p.ExitPoints = append(p.ExitPoints, "orphan")
+			},
+			want: []string{
+				`function "orphan" is not reachable from entry point "fn0"`,
+				`exit point "orphan" is not reachable from entry point "fn0"`,
+			},
+		},
+		{
+			name: "non-terminal exit",
+			modify: func(p *contract.PacketPath) {
+				p.Functions[1].IsExitPoint = true
+				p.ExitPoints = []string{"fn1", "fn2"}
+			},
+			want: []string{`exit point "fn1" is not terminal: it has an edge to "fn2"`},
+		},
+		{
+			name: "error edge out of exit",
+			modify: func(p *contract.PacketPath) {
+				p.Edges = append(p.Edges, contract.FunctionEdge{From: "fn2", To: "fn0", Order: 1, IsErrorPath: true})
+			},
+		},
+		{
+			name: "undeclared terminal function",
+			modify: func(p *contract.PacketPath) {
+				p.Functions[2].IsExitPoint = false
+				p.ExitPoints = nil
+			},
+			want: []string{`function "fn2" has no outgoing edges but is neither an exit point nor a drop point`},
+		},
+		{
+			name: "terminal drop point",
+			modify: func(p *contract.PacketPath) {
+				p.Functions = append(p.Functions, contract.KernelFunction{ID: "kfree_skb", SKBMutation: contract.NewFreeMutation("drop")})
+				p.Edges = append(p.Edges, contract.FunctionEdge{From: "fn0", To: "kfree_skb", Order: 2, IsErrorPath: true})
+			},
+		},
+		{
+			name: "missing exit point",
+			modify: func(p *contract.PacketPath) {
+				p.ExitPoints = append(p.ExitPoints, "missing")
+			},
+			want: []string{`exit point "missing" is not a function of the path`},
+		},
+		{
+			name: "missing entry point",
+			modify: func(p *contract.PacketPath) {
+				p.EntryPoint = "missing"
+			},
+			want: []string{
+				`function "fn0" is flagged IsEntryPoint but the path's entry point is "missing"`,
+				`entry point "missing" is not a function of the path`,
+			},
+		},
+		{
+			name: "unknown conntrack event",
+			modify: func(p *contract.PacketPath) {
+				p.Functions[1].ConntrackEvent = "bogus"
+			},
+			want: []string{`function "fn1" has unknown conntrack event "bogus"`},
+		},
+		{
+			name: "entry flag",
+			modify: func(p *contract.PacketPath) {
+				p.Functions[0].IsEntryPoint = false
+				p.Functions[1].IsEntryPoint = true
+			},
+			want: []string{
+				`entry point "fn0" is not flagged IsEntryPoint`,
+				`function "fn1" is flagged IsEntryPoint but the path's entry point is "fn0"`,
+			},
+		},
+		{
+			name: "exit flag",
+			modify: func(p *contract.PacketPath) {
+				p.Functions[1].IsExitPoint = true
+				p.Functions[2].IsExitPoint = false
+			},
+			want: []string{
+				`function "fn1" is flagged IsExitPoint but is not in ExitPoints`,
+				`exit point "fn2" is not flagged IsExitPoint`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := contracttest.NewTestPath(contracttest.WithID("test"))
+			tt.modify(path)
+
+			err := path.Validate()
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			var verr *contract.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Validate() = %v, want a *contract.ValidationError", err)
+			}
+			if verr.PathID != "test" {
+				t.Errorf("PathID = %q, want %q", verr.PathID, "test")
+			}
+			if !slices.Equal(verr.Problems, tt.want) {
+				t.Errorf("problems:\n%q\nwant\n%q", verr.Problems, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRegisteredPaths(t *testing.T) {
+	for _, path := range contract.DefaultRegistry().Paths() {
+		if err := path.Validate(); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package contract
+
+// Walk traverses the call graph depth-first from entryID, following every
+// edge, error paths included, in the order they appear in Edges. visit is
+// called once per reachable function with the edge taken to reach it (nil
+// for entryID itself); returning false prunes the functions below it, which
+// may still be visited through another edge. A function is visited at most
+// once, so cycles end the walk rather than repeating it. Nothing is visited
+// if entryID is not a function of the path.
+func (p *PacketPath) Walk(entryID string, visit func(fn *KernelFunction, edge *FunctionEdge) bool) {
+	graph := NewFunctionGraph(p)
+	visited := make(map[string]bool)
+
+	var walk func(id string, edge *FunctionEdge)
+	walk = func(id string, edge *FunctionEdge) {
+		fn := graph.GetFunction(id)
+		if fn == nil || visited[id] {
+			return
+		}
+		visited[id] = true
+		if !visit(fn, edge) {
+			return
+		}
+		for _, next := range graph.GetOutgoingEdges(id) {
+			walk(next.To, &next)
+		}
+	}
+	walk(entryID, nil)
+}
@@ -0,0 +1,105 @@
+package contract
+
+// WriteQueue models the TCP socket write queue (sk_write_queue) together with
+// the retransmission queue of segments that have been sent but not yet
+// acknowledged. Data stays referenced here until ACKed, which is what makes
+// TCP pipelining and retransmission possible.
+type WriteQueue struct {
+	// Queued are sk_buffs waiting to be transmitted
+	Queued []SKBuff `json:"queued"`
+
+	// InFlight are sk_buffs that were transmitted but are not yet acknowledged
+	InFlight []SKBuff `json:"inFlight"`
+
+	// Current is the sk_buff being transmitted at this step (also in InFlight)
+	Current *SKBuff `json:"current,omitempty"`
+
+	// version counts the changes made through the queue's methods, so
+	// that steps can share a snapshot until the next one
+	version int
+}
+
+// NewWriteQueue creates an empty write queue.
+func NewWriteQueue() *WriteQueue {
+	return &WriteQueue{
+		Queued:   []SKBuff{},
+		InFlight: []SKBuff{},
+	}
+}
+
+// Enqueue appends a copy of the sk_buff to the tail of the write queue.
+func (q *WriteQueue) Enqueue(skb *SKBuff) {
+	q.Queued = append(q.Queued, *skb.Clone())
+	q.version++
+}
+
+// Transmit moves the head of the write queue to the in-flight list and marks
+// it as the current sk_buff. Returns false if nothing is queued.
+func (q *WriteQueue) Transmit() bool {
+	q.version++
+	if len(q.Queued) == 0 {
+		q.Current = nil
+		return false
+	}
+	head := q.Queued[0]
+	q.Queued = q.Queued[1:]
+	q.InFlight = append(q.InFlight, head)
+	q.Current = head.Clone()
+	return true
+}
+
+// splitHead replaces the head of the write queue with the sk_buffs it is
+// split into.
+func (q *WriteQueue) splitHead(segs []SKBuff) {
+	if len(q.Queued) == 0 {
+		return
+	}
+	q.Queued = append(append([]SKBuff{}, segs...), q.Queued[1:]...)
+	q.version++
+}
+
+// release frees the in-flight sk_buffs, once they are acknowledged, and
+// returns how many there were.
+func (q *WriteQueue) release() int {
+	freed := len(q.InFlight)
+	q.InFlight = []SKBuff{}
+	q.version++
+	return freed
+}
+
+// Clone creates a deep copy of the write queue.
+func (q *WriteQueue) Clone() *WriteQueue {
+	clone := &WriteQueue{
+		Queued:   make([]SKBuff, len(q.Queued)),
+		InFlight: make([]SKBuff, len(q.InFlight)),
+	}
+	for i := range q.Queued {
+		clone.Queued[i] = *q.Queued[i].Clone()
+	}
+	for i := range q.InFlight {
+		clone.InFlight[i] = *q.InFlight[i].Clone()
+	}
+	if q.Current != nil {
+		clone.Current = q.Current.Clone()
+	}
+	return clone
+}
+
+// effectWriteQueueEnqueue models tcp_sendmsg_locked appending the freshly
+// filled sk_buff to the socket write queue.
+func effectWriteQueueEnqueue(ctx *simContext, step *SimulateStep) {
+	if ctx.writeQueue == nil {
+		ctx.writeQueue = NewWriteQueue()
+	}
+	ctx.writeQueue.Enqueue(ctx.skb)
+}
+
+// effectWriteQueueTransmit models tcp_write_xmit taking the head of the
+// write queue for transmission and keeping it for retransmission. A send
+// deferred by tcp_tso_should_defer or throttled by TSQ leaves it queued.
+func effectWriteQueueTransmit(ctx *simContext, step *SimulateStep) {
+	if ctx.writeQueue == nil || ctx.tsoDeferred() || ctx.tsqThrottled() {
+		return
+	}
+	ctx.writeQueue.Transmit()
+}
@@ -0,0 +1,33 @@
+package contract
+
+// Verdicts of a modeled XDP program
+const (
+	// XDPVerdictPass lets the packet continue up the stack (XDP_PASS)
+	XDPVerdictPass = "pass"
+
+	// XDPVerdictDrop discards the packet (XDP_DROP)
+	XDPVerdictDrop = "drop"
+)
+
+// IsValidGenericXDPVerdict reports whether verdict is a known generic XDP
+// program verdict.
+func IsValidGenericXDPVerdict(verdict string) bool {
+	switch verdict {
+	case XDPVerdictPass, XDPVerdictDrop:
+		return true
+	}
+	return false
+}
+
+// effectGenericXDP models do_xdp_generic running an SKB-mode XDP program.
+func effectGenericXDP(ctx *simContext, step *SimulateStep) {
+	const cost = " Unlike native XDP, the sk_buff has already been allocated and GRO has run, " +
+		"so generic mode saves none of that work; it exists so XDP programs work on any driver."
+	switch ctx.opts.GenericXDP {
+	case XDPVerdictDrop:
+		ctx.dropReason = DropReasonXDP
+		step.annotate(AnnotationDrop, "Generic XDP program returned XDP_DROP: the sk_buff is freed before any protocol handler or packet tap sees it."+cost)
+	case XDPVerdictPass:
+		step.annotate(AnnotationRouting, "Generic XDP program returned XDP_PASS: the sk_buff continues to the protocol handlers."+cost)
+	}
+}
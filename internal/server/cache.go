@@ -0,0 +1,73 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// maxCacheEntries bounds the export cache; when full, expired entries are
+// evicted first and then the entry closest to expiry.
+const maxCacheEntries = 256
+
+// cacheEntry is a rendered export and when it stops being served.
+type cacheEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+// exportCache holds rendered exports keyed by ExportOptions.Hash.
+type exportCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]cacheEntry
+}
+
+// newExportCache creates a cache whose entries live for ttl.
+func newExportCache(ttl time.Duration) *exportCache {
+	return &exportCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// get returns the cached export for key, if present and not expired.
+func (c *exportCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !c.now().Before(entry.expires) {
+		return nil, false
+	}
+	return entry.data, true
+}
+
+// put stores an export under key, evicting an entry if the cache is full.
+func (c *exportCache) put(key string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= maxCacheEntries {
+		c.evict(now)
+	}
+	c.entries[key] = cacheEntry{data: data, expires: now.Add(c.ttl)}
+}
+
+// evict removes every expired entry, or the entry closest to expiry if none
+// has expired. The caller holds c.mu.
+func (c *exportCache) evict(now time.Time) {
+	oldest := ""
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+			continue
+		}
+		if oldest == "" || entry.expires.Before(c.entries[oldest].expires) {
+			oldest = key
+		}
+	}
+	if len(c.entries) >= maxCacheEntries && oldest != "" {
+		delete(c.entries, oldest)
+	}
+}
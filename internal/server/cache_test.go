@@ -0,0 +1,97 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable clock for the now field of the cache and limiter.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func TestExportCacheTTL(t *testing.T) {
+	clock := newFakeClock()
+	c := newExportCache(time.Minute)
+	c.now = clock.now
+
+	if _, ok := c.get("a"); ok {
+		t.Fatal("empty cache returned an entry")
+	}
+	c.put("a", []byte("export"))
+	if data, ok := c.get("a"); !ok || string(data) != "export" {
+		t.Fatalf("get after put = %q, %v", data, ok)
+	}
+
+	clock.advance(time.Minute - time.Nanosecond)
+	if _, ok := c.get("a"); !ok {
+		t.Error("entry expired before its TTL")
+	}
+	clock.advance(time.Nanosecond)
+	if _, ok := c.get("a"); ok {
+		t.Error("entry served at its expiry")
+	}
+
+	// Storing again renews the entry
+	c.put("a", []byte("renewed"))
+	clock.advance(time.Minute / 2)
+	if data, ok := c.get("a"); !ok || string(data) != "renewed" {
+		t.Errorf("get after renewal = %q, %v", data, ok)
+	}
+}
+
+func TestExportCacheEviction(t *testing.T) {
+	clock := newFakeClock()
+	c := newExportCache(time.Hour)
+	c.now = clock.now
+
+	// Entries stored a second apart expire in the order they were stored
+	start := clock.t
+	for i := range maxCacheEntries {
+		c.put(fmt.Sprint(i), nil)
+		clock.advance(time.Second)
+	}
+
+	// Replacing an existing key never evicts
+	c.put("1", nil)
+	if len(c.entries) != maxCacheEntries {
+		t.Fatalf("%d entries after replacing one, want %d", len(c.entries), maxCacheEntries)
+	}
+	if _, ok := c.get("0"); !ok {
+		t.Fatal("replacing an entry evicted another")
+	}
+
+	// A new key evicts the entry closest to expiry
+	c.put("new", nil)
+	if len(c.entries) != maxCacheEntries {
+		t.Errorf("%d entries, want the cache bounded at %d", len(c.entries), maxCacheEntries)
+	}
+	if _, ok := c.get("0"); ok {
+		t.Error("entry closest to expiry was not evicted")
+	}
+	for _, key := range []string{"1", "2", "new"} {
+		if _, ok := c.get(key); !ok {
+			t.Errorf("entry %s evicted", key)
+		}
+	}
+
+	// Once entries have expired, all of them are evicted at once: 2 to 10,
+	// as 0 is gone and 1 was renewed
+	clock.t = start.Add(time.Hour + 10*time.Second)
+	c.put("newer", nil)
+	if want := maxCacheEntries - 9 + 1; len(c.entries) != want {
+		t.Errorf("%d entries after expiry, want %d", len(c.entries), want)
+	}
+	if _, ok := c.get("11"); !ok {
+		t.Error("unexpired entry evicted along with the expired ones")
+	}
+}
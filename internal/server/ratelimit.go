@@ -0,0 +1,88 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxRateBuckets bounds the number of clients tracked; when exceeded, the
+// buckets of clients that have fully refilled are forgotten.
+const maxRateBuckets = 4096
+
+// bucket is one client's token bucket.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter is a per-client-IP token bucket limiter: each client may make
+// burst requests at once and then rate requests per second.
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	now     func() time.Time
+	buckets map[string]*bucket
+}
+
+// newRateLimiter creates a limiter allowing perMinute requests per minute
+// per client, with bursts of up to perMinute requests.
+func newRateLimiter(perMinute int) *rateLimiter {
+	return &rateLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(perMinute),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow reports whether the client may make a request now, consuming a
+// token if so.
+func (l *rateLimiter) allow(client string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+
+	b, ok := l.buckets[client]
+	if !ok {
+		if len(l.buckets) >= maxRateBuckets {
+			l.prune(now)
+		}
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// prune forgets clients whose bucket has refilled, as a fresh bucket would
+// behave the same. The caller holds l.mu.
+func (l *rateLimiter) prune(now time.Time) {
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// clientIP returns the IP address of the request's peer. Forwarding headers
+// are ignored, as they are trivially spoofed.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
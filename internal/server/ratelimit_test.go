@@ -0,0 +1,97 @@
+package server
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterRefill(t *testing.T) {
+	clock := newFakeClock()
+	l := newRateLimiter(60)
+	l.now = clock.now
+
+	for i := range 60 {
+		if !l.allow("192.0.2.1") {
+			t.Fatalf("request %d of the burst refused", i+1)
+		}
+	}
+	if l.allow("192.0.2.1") {
+		t.Fatal("request beyond the burst allowed")
+	}
+	if !l.allow("192.0.2.2") {
+		t.Fatal("another client limited by the first one's requests")
+	}
+
+	// 60 per minute is a token a second
+	clock.advance(999 * time.Millisecond)
+	if l.allow("192.0.2.1") {
+		t.Error("allowed before a token was refilled")
+	}
+	clock.advance(time.Millisecond)
+	if !l.allow("192.0.2.1") {
+		t.Error("refused after a token was refilled")
+	}
+	if l.allow("192.0.2.1") {
+		t.Error("allowed twice on one refilled token")
+	}
+
+	// The bucket never holds more than the burst
+	clock.advance(time.Hour)
+	for i := range 60 {
+		if !l.allow("192.0.2.1") {
+			t.Fatalf("request %d of the refilled burst refused", i+1)
+		}
+	}
+	if l.allow("192.0.2.1") {
+		t.Error("bucket refilled beyond the burst")
+	}
+}
+
+func TestRateLimiterPrune(t *testing.T) {
+	clock := newFakeClock()
+	l := newRateLimiter(60)
+	l.now = clock.now
+
+	// Every client takes a token, which refills in a second
+	for i := range maxRateBuckets {
+		l.allow(fmt.Sprint(i))
+	}
+	// Exhaust one client's bucket so it is still limited when the others
+	// have refilled
+	for l.allow("0") {
+	}
+
+	clock.advance(500 * time.Millisecond)
+	l.allow("new")
+	if len(l.buckets) != maxRateBuckets+1 {
+		t.Fatalf("%d buckets, want none pruned before the others refill", len(l.buckets))
+	}
+
+	clock.advance(500 * time.Millisecond)
+	l.allow("newer")
+	if _, ok := l.buckets["0"]; !ok {
+		t.Error("pruned a client still limited")
+	}
+	for _, client := range []string{"1", fmt.Sprint(maxRateBuckets - 1)} {
+		if _, ok := l.buckets[client]; ok {
+			t.Errorf("client %s not pruned after refilling", client)
+		}
+	}
+	if _, ok := l.buckets["new"]; !ok {
+		t.Error("pruned a client still refilling")
+	}
+	if len(l.buckets) != 3 {
+		t.Errorf("%d buckets after pruning, want 3: the limited client, new and newer", len(l.buckets))
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/export", nil)
+	r.RemoteAddr = "192.0.2.1:4242"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7")
+	if got := clientIP(r); got != "192.0.2.1" {
+		t.Errorf("clientIP = %q, want the peer address", got)
+	}
+}
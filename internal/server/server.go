@@ -0,0 +1,432 @@
+// Package server exposes the packet path contract over HTTP so the frontend
+// can request exports with custom simulation parameters at runtime.
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rzkiamr/linux-packet-visualizer/internal/contract"
+)
+
+// Defaults for the export cache and rate limiter
+const (
+	// DefaultCacheTTL is how long a generated export is served from memory
+	DefaultCacheTTL = 5 * time.Minute
+
+	// DefaultRateLimit is the number of export requests a client IP may
+	// make per minute
+	DefaultRateLimit = 60
+)
+
+// Upper bounds of the query parameters that scale the work of an export, so
+// a single request cannot make the server generate an arbitrarily large one
+const (
+	// maxBufferSize bounds the sk_buff buffer size
+	maxBufferSize = 1 << 20
+
+	// maxPayloadSize is the largest IP datagram, and the most a TSO sk_buff
+	// carries
+	maxPayloadSize = 0xFFFF
+
+	// maxFragments bounds the IP fragments the ingress packet arrives in
+	maxFragments = 64
+
+	// maxPacketTaps bounds the AF_PACKET sniffers a packet is cloned for
+	maxPacketTaps = 64
+
+	// maxBridgePorts is BR_MAX_PORTS, the most ports a bridge can have
+	maxBridgePorts = 1024
+)
+
+// Server serves the contract API for a path registry.
+type Server struct {
+	registry *contract.PathRegistry
+	mux      *http.ServeMux
+	cache    *exportCache
+	limiter  *rateLimiter
+
+	// revision identifies the model served, so ETags from a server running
+	// a different model never match
+	revision string
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithCacheTTL sets how long generated exports are cached (0 disables the
+// cache).
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(s *Server) {
+		s.cache = nil
+		if ttl > 0 {
+			s.cache = newExportCache(ttl)
+		}
+	}
+}
+
+// WithRateLimit sets the number of export requests a client IP may make per
+// minute (0 disables rate limiting).
+func WithRateLimit(perMinute int) Option {
+	return func(s *Server) {
+		s.limiter = nil
+		if perMinute > 0 {
+			s.limiter = newRateLimiter(perMinute)
+		}
+	}
+}
+
+// New creates a server for the given registry and registers its routes.
+// Exports are cached for DefaultCacheTTL and limited to DefaultRateLimit
+// requests per minute per client unless overridden by opts.
+func New(registry *contract.PathRegistry, opts ...Option) *Server {
+	s := &Server{
+		registry: registry,
+		mux:      http.NewServeMux(),
+		cache:    newExportCache(DefaultCacheTTL),
+		limiter:  newRateLimiter(DefaultRateLimit),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.revision = modelRevision(registry)
+	s.mux.HandleFunc("GET /api/export", s.handleExport)
+	s.mux.HandleFunc("GET /api/info", s.handleInfo)
+	s.mux.HandleFunc("GET /api/paths/{id}/layers/{layer}", s.handleLayer)
+	s.mux.HandleFunc("GET /healthz", s.handleHealthz)
+	s.mux.HandleFunc("GET /readyz", s.handleReadyz)
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleExport serves the full contract. Simulation parameters may be
+// overridden with query parameters matching the CLI flags. The hash of the
+// resulting options is the ETag, so clients revalidating an export they
+// already hold get 304 Not Modified without it being generated.
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	if s.limiter != nil && !s.limiter.allow(clientIP(r)) {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+		return
+	}
+
+	opts, err := exportOptionsFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := opts.Hash()
+	etag := `"` + s.revision + "-" + key + `"`
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	data, ok := []byte(nil), false
+	if s.cache != nil {
+		data, ok = s.cache.get(key)
+	}
+	if !ok {
+		data, err = contract.ExportRegistry(s.registry, opts)
+		if err != nil {
+			http.Error(w, "export failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if s.cache != nil {
+			s.cache.put(key, data)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// handleInfo describes what the server supports: the schema version, the
+// paths it serves, and the export formats and simulation parameters.
+func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	info := contract.PackageInfo()
+	info.Paths = s.registry.IDs()
+	// The export endpoint only serves the JSON contract
+	info.Formats = []string{"json"}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// handleLayer serves one layer of a path (see PacketPath.LayerSubgraph), so
+// the frontend can load a large graph layer by layer. The layer is named by
+// its CSS class without the "layer-" prefix, e.g. "network".
+func (s *Server) handleLayer(w http.ResponseWriter, r *http.Request) {
+	path, ok := s.registry.Build(r.PathValue("id"))
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown path %q", r.PathValue("id")), http.StatusNotFound)
+		return
+	}
+	layer, ok := contract.ParseLayer(r.PathValue("layer"))
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown layer %q", r.PathValue("layer")), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(path.LayerSubgraph(layer))
+}
+
+// modelRevision returns a short digest of the registry's default export,
+// which changes whenever the model does.
+func modelRevision(registry *contract.PathRegistry) string {
+	opts := contract.DefaultExportOptions()
+	opts.Pretty = false
+	data, err := contract.ExportRegistry(registry, opts)
+	if err != nil {
+		return "0"
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:4])
+}
+
+// etagMatches reports whether an If-None-Match header value lists etag.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// handleHealthz reports that the process is alive.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+// handleReadyz reports whether the server can serve exports: the registry
+// must be populated and a minimal export must succeed.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if s.registry.Len() == 0 {
+		http.Error(w, "not ready: path registry is empty", http.StatusServiceUnavailable)
+		return
+	}
+
+	opts := contract.DefaultExportOptions()
+	opts.Pretty = false
+	opts.IncludeSimulation = false
+	if _, err := contract.ExportRegistry(s.registry, opts); err != nil {
+		http.Error(w, "not ready: export failed: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ready")
+}
+
+// exportOptionsFromQuery builds export options from the request query,
+// starting from the defaults.
+func exportOptionsFromQuery(r *http.Request) (contract.ExportOptions, error) {
+	opts := contract.DefaultExportOptions()
+	opts.Pretty = false
+
+	q := r.URL.Query()
+	ints := []struct {
+		name string
+		dst  *int
+		max  int // 0 = unbounded
+	}{
+		{"buffer", &opts.BufferSize, maxBufferSize},
+		{"payload", &opts.PayloadSize, maxPayloadSize},
+		{"sndbuf", &opts.SendBufferSize, 0},
+		{"rcvbuf", &opts.RecvBufferSize, 0},
+		{"fragments", &opts.FragmentCount, maxFragments},
+		{"taps", &opts.PacketTaps, maxPacketTaps},
+		{"sockfilterlen", &opts.SocketFilterLen, 0},
+		{"udpsegment", &opts.UDPSegment, 0},
+		{"gsopartial", &opts.GSOPartial, 0},
+		{"mtu", &opts.MTU, 0},
+		{"mss", &opts.MSS, 0},
+		{"backlog", &opts.ListenBacklog, 0},
+		{"synq", &opts.SynQueueLen, 0},
+		{"acceptq", &opts.AcceptQueueLen, 0},
+		{"rttsample", &opts.RTTSample, 0},
+		{"txqueues", &opts.TxQueues, 0},
+		{"bridgeports", &opts.BridgePorts, maxBridgePorts},
+		{"cpu", &opts.CPU, 0},
+		{"qdiscbacklog", &opts.QdiscBacklog, 0},
+	}
+	for _, p := range ints {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return opts, fmt.Errorf("invalid %s: %q", p.name, v)
+		}
+		if p.max > 0 && n > p.max {
+			return opts, fmt.Errorf("invalid %s: %q (at most %d)", p.name, v, p.max)
+		}
+		*p.dst = n
+	}
+
+	if !contract.IsValidMSS(opts.MSS) {
+		return opts, fmt.Errorf("invalid mss: %q", q.Get("mss"))
+	}
+
+	if !contract.IsValidMTU(opts.MTU) {
+		return opts, fmt.Errorf("invalid mtu: %q", q.Get("mtu"))
+	}
+
+	if reason := q.Get("groflush"); reason != "" {
+		if !contract.IsValidGROFlushReason(reason) {
+			return opts, fmt.Errorf("invalid groflush: %q", reason)
+		}
+		opts.GROFlush = reason
+	}
+
+	if lookup := q.Get("lookup"); lookup != "" {
+		if !contract.IsValidSocketLookup(lookup) {
+			return opts, fmt.Errorf("invalid lookup: %q", lookup)
+		}
+		opts.SocketLookup = lookup
+	}
+
+	if option := q.Get("ipopts"); option != "" {
+		if !contract.IsValidIPOption(option) {
+			return opts, fmt.Errorf("invalid ipopts: %q", option)
+		}
+		opts.IPOptions = option
+	}
+
+	if verdict := q.Get("tcingress"); verdict != "" {
+		if !contract.IsValidTCAction(verdict) {
+			return opts, fmt.Errorf("invalid tcingress: %q", verdict)
+		}
+		opts.TCIngress = verdict
+	}
+
+	if fdb := q.Get("fdb"); fdb != "" {
+		if !contract.IsValidBridgeFDB(fdb) {
+			return opts, fmt.Errorf("invalid fdb: %q", fdb)
+		}
+		opts.BridgeFDB = fdb
+	}
+
+	if op := q.Get("arp"); op != "" {
+		if !contract.IsValidARPOperation(op) {
+			return opts, fmt.Errorf("invalid arp: %q", op)
+		}
+		opts.ARPOperation = op
+	}
+
+	if kind := q.Get("icmperror"); kind != "" {
+		if !contract.IsValidICMPError(kind) {
+			return opts, fmt.Errorf("invalid icmperror: %q", kind)
+		}
+		opts.ICMPError = kind
+	}
+
+	if helper := q.Get("cthelper"); helper != "" {
+		if !contract.IsValidConntrackHelper(helper) {
+			return opts, fmt.Errorf("invalid cthelper: %q", helper)
+		}
+		opts.ConntrackHelper = helper
+	}
+
+	for _, s := range q["nat"] {
+		rule, err := contract.ParseNATRule(s)
+		if err != nil {
+			return opts, fmt.Errorf("invalid nat: %w", err)
+		}
+		opts.NATRules = append(opts.NATRules, rule)
+	}
+
+	if verdict := q.Get("xdpgeneric"); verdict != "" {
+		if !contract.IsValidGenericXDPVerdict(verdict) {
+			return opts, fmt.Errorf("invalid xdpgeneric: %q", verdict)
+		}
+		opts.GenericXDP = verdict
+	}
+
+	if verdict := q.Get("sockfilter"); verdict != "" {
+		if !contract.IsValidSocketFilterVerdict(verdict) {
+			return opts, fmt.Errorf("invalid sockfilter: %q", verdict)
+		}
+		opts.SocketFilter = verdict
+	}
+
+	if header := q.Get("corrupt"); header != "" {
+		if !contract.IsValidCorruptHeader(header) {
+			return opts, fmt.Errorf("invalid corrupt: %q", header)
+		}
+		opts.CorruptChecksum = true
+		opts.CorruptHeader = header
+	}
+
+	if q.Get("sim") == "0" {
+		opts.IncludeSimulation = false
+	}
+	if q.Get("transitions") == "1" {
+		opts.IncludeLayerTransitions = true
+	}
+	if q.Get("animation") == "1" {
+		opts.IncludeAnimationHints = true
+	}
+	if q.Get("delta") == "1" {
+		opts.DeltaSimulation = true
+	}
+	if q.Get("userspace") == "1" {
+		opts.IncludeUserspaceEquivalents = true
+	}
+	if q.Get("collapse") == "1" {
+		opts.CollapseWrappers = true
+	}
+	if q.Get("quickack") == "1" {
+		opts.QuickAck = true
+	}
+	if q.Get("owned") == "1" {
+		opts.SocketOwnedByUser = true
+	}
+	if q.Get("coalesce") == "1" {
+		opts.RecvCoalesce = true
+	}
+	if q.Get("xps") == "1" {
+		opts.XPS = true
+	}
+	if q.Get("nonblock") == "1" {
+		opts.NonBlocking = true
+	}
+	if q.Get("txbusy") == "1" {
+		opts.DeviceBusy = true
+	}
+	if q.Get("tsodefer") == "1" {
+		opts.TSODefer = true
+	}
+	if q.Get("nogso") == "1" {
+		opts.NoGSO = true
+	}
+	if q.Get("pretty") == "1" {
+		opts.Pretty = true
+	}
+	if v := q.Get("indent"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return opts, fmt.Errorf("invalid indent: %q", v)
+		}
+		opts.IndentWidth = n
+		opts.Pretty = n > 0
+	}
+	return opts, nil
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rzkiamr/linux-packet-visualizer/internal/contract"
+)
+
+func TestExportOptionsRejectsSmallMSS(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+	}{
+		{"mss=1&nogso=1", true},
+		{"mss=87", true},
+		{"mss=88", false},
+		{"mss=0", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/api/export?"+tt.query, nil)
+		_, err := exportOptionsFromQuery(r)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("exportOptionsFromQuery(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+		}
+	}
+}
+
+func TestExportOptionsRejectsSmallMTU(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+	}{
+		{"mtu=0", false},
+		{"mtu=20", true},
+		{"mtu=24", true},
+		{"mtu=67", true},
+		{"mtu=68", false},
+		{"mtu=9000", false},
+		{"mtu=-1", true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/api/export?path=udp_ipv4_egress&"+tt.query, nil)
+		_, err := exportOptionsFromQuery(r)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("exportOptionsFromQuery(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+		}
+	}
+}
+
+func TestExportOptionsTSODefer(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/export?tsodefer=1", nil)
+	opts, err := exportOptionsFromQuery(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !opts.TSODefer || !opts.SimulateOptions().TSODefer {
+		t.Error("tsodefer=1 does not select the TSO deferral")
+	}
+}
+
+func TestExportOptionsVerdicts(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr string
+	}{
+		{"xdpgeneric=pass", ""},
+		{"xdpgeneric=drop", ""},
+		{"xdpgeneric=aborted", `invalid xdpgeneric: "aborted"`},
+		{"sockfilter=allow", ""},
+		{"sockfilter=deny", ""},
+		{"sockfilter=drop", `invalid sockfilter: "drop"`},
+		{"corrupt=ip", ""},
+		{"corrupt=tcp", ""},
+		{"corrupt=udp", `invalid corrupt: "udp"`},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/api/export?"+tt.query, nil)
+		_, err := exportOptionsFromQuery(r)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("exportOptionsFromQuery(%q) error = %v, want none", tt.query, err)
+		case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+			t.Errorf("exportOptionsFromQuery(%q) error = %v, want %s", tt.query, err, tt.wantErr)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "/api/export?corrupt=ip", nil)
+	opts, err := exportOptionsFromQuery(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !opts.CorruptChecksum || opts.CorruptHeader != "ip" {
+		t.Errorf("corrupt=ip selects CorruptChecksum %v, header %q", opts.CorruptChecksum, opts.CorruptHeader)
+	}
+}
+
+func TestExportRejectsOversizedParameters(t *testing.T) {
+	s := New(contract.DefaultRegistry(), WithCacheTTL(0), WithRateLimit(0))
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"payload=65535&buffer=131072", http.StatusOK},
+		{"payload=65536", http.StatusBadRequest},
+		{"payload=100000000&buffer=200000000", http.StatusBadRequest},
+		{"buffer=1048576", http.StatusOK},
+		{"buffer=1048577", http.StatusBadRequest},
+		{"fragments=64", http.StatusOK},
+		{"fragments=65", http.StatusBadRequest},
+		{"taps=64", http.StatusOK},
+		{"taps=65", http.StatusBadRequest},
+		{"bridgeports=1024&fdb=unknown", http.StatusOK},
+		{"bridgeports=1000000&fdb=unknown", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("GET", "/api/export?"+tt.query, nil))
+		if rec.Code != tt.want {
+			t.Errorf("GET /api/export?%s = %d, want %d: %s", tt.query, rec.Code, tt.want, rec.Body)
+		}
+	}
+}